@@ -0,0 +1,48 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const cleanUpOrphanedVolumesSubcommand = "clean-up-orphaned-volumes"
+
+// runCleanUpOrphanedVolumes implements the clean-up-orphaned-volumes subcommand. It asks the diagnostics endpoint of a
+// running controller service to delete orphaned volumes, prints the results, and returns the exit code for the
+// process.
+func runCleanUpOrphanedVolumes(args []string) int {
+	flags := flag.NewFlagSet(cleanUpOrphanedVolumesSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	volumeIDs := flags.String("volume-ids", "", "a comma-separated list of the volumeIDs printed by the orphaned-volumes subcommand")
+	allowOtherClusters := flags.Bool("allow-other-clusters", false, "also delete volumes whose metadata records a different (or no) cluster ID (their cluster may still use them)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], cleanUpOrphanedVolumesSubcommand)
+		fmt.Fprintln(flags.Output(), "Delete orphaned volumes the way DeleteVolume would (volumes with a retentionPeriod are retained).")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *volumeIDs == "" {
+		fmt.Fprintln(os.Stderr, "--volume-ids is required")
+		flags.Usage()
+		return 2
+	}
+
+	err := beegfs.CleanUpOrphanedVolumes(os.Stdout, *diagnosticsEndpoint, strings.Split(*volumeIDs, ","), *allowOtherClusters)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to clean up orphaned volumes: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const deletionStatusSubcommand = "deletion-status"
+
+// runDeletionStatus implements the deletion-status subcommand. It queries the diagnostics endpoint of a running
+// controller service for the progress of the volume deletions it is completing in the background, prints it, and
+// returns the exit code for the process.
+func runDeletionStatus(args []string) int {
+	flags := flag.NewFlagSet(deletionStatusSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], deletionStatusSubcommand)
+		fmt.Fprintln(flags.Output(), "Print the progress of volume deletions the controller service is completing in the background.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := beegfs.QueryDeletionProgress(os.Stdout, *diagnosticsEndpoint); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get deletion status: %v\n", err)
+		return 1
+	}
+	return 0
+}
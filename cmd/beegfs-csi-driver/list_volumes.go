@@ -0,0 +1,38 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const listVolumesSubcommand = "list-volumes"
+
+// runListVolumes implements the list-volumes subcommand. It queries the diagnostics endpoint of a running controller
+// service for the metadata of the volumes it finds, prints it, and returns the exit code for the process.
+func runListVolumes(args []string) int {
+	flags := flag.NewFlagSet(listVolumesSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], listVolumesSubcommand)
+		fmt.Fprintln(flags.Output(), "Print the metadata (e.g. cluster ID, PVC, and Storage Class) of the volumes the controller service finds under the volDirBasePaths of the driver's Storage Classes.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := beegfs.QueryVolumeList(os.Stdout, *diagnosticsEndpoint); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to list volumes: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -27,6 +27,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
 	"k8s.io/klog/v2"
@@ -37,18 +38,56 @@ var (
 	tlsCertsPath           = flag.String("tlscerts-path", "", "path to the file containing BeeGFS TLS certificates")
 	configPath             = flag.String("config-path", "", "path to the plugin configuration file")
 	csDataDir              = flag.String("cs-data-dir", "/tmp/beegfs-csi-data-dir", "path to the directory the controller service uses to store client configuration files and mount file systems")
-	driverName             = flag.String("driver-name", "beegfs.csi.netapp.com", "name of the CSI driver")
+	driverName             = flag.String("driver-name", beegfs.DefaultDriverName, "name of the CSI driver")
 	endpoint               = flag.String("endpoint", "unix://tmp/csi.sock", "the CSI endpoint")
+	diagnosticsEndpoint    = flag.String("diagnostics-endpoint", "", "the endpoint to serve volume diagnostics on (disabled if empty)")
+	diagnosticsService     = flag.String("diagnostics-service", beegfs.DiagnosticsServiceNode, "the service (controller or node) to serve volume diagnostics of")
+	metricsEndpoint        = flag.String("metrics-endpoint", "", "the endpoint to serve Prometheus metrics on (e.g. tcp://0.0.0.0:9809) (disabled if empty)")
 	nodeID                 = flag.String("node-id", "", "the Kubernetes node ID")
 	showVersion            = flag.Bool("version", false, "print the driver version and exit")
 	clientConfTemplatePath = flag.String("client-conf-template-path", "", "path to the template beegfs-client.conf file")
 	nodeUnstageTimeout     = flag.Uint64("node-unstage-timeout", 0, "seconds DeleteVolume waits for NodeUnstageVolume to complete on all nodes")
+	statusReportPod        = flag.String("status-report-pod", "", "the <namespace>/<name> of the Pod the controller service annotates with file system status (disabled if empty)")
+	statusReportInterval   = flag.Duration("status-report-interval", time.Minute, "how often the controller service probes file systems and reports their status")
+	nodeSharedMountDir     = flag.String("node-shared-mount-dir", "", "path to the directory the node service mounts each file system to once and bind mounts volumes from (disabled if empty)")
+	csMountIdleTimeout     = flag.Duration("cs-mount-idle-timeout", 5*time.Minute, "how long the controller service keeps a file system mounted after its last use (0 to unmount immediately)")
+	clusterID              = flag.String("cluster-id", "", "an identifier for the cluster the controller service records in the metadata of each volume it creates")
+	csDeletionWorkers      = flag.Int("cs-deletion-workers", 16, "the number of directories the controller service removes from the trash of deleted volumes in parallel")
+	csOrphanScanInterval   = flag.Duration("cs-orphan-scan-interval", 0, "how often the controller service scans for volumes without a PersistentVolume and reports them (disabled if 0)")
 
 	// Set by the build process
 	version = ""
 )
 
+// terminationMessagePath is the default value of a Kubernetes container's terminationMessagePath.
+const terminationMessagePath = "/dev/termination-log"
+
+// driverNameEnvVar passes the driver name to chwrap (cmd/chwrap), which searches the plugin directory of the driver for
+// BeeGFS utilities. chwrap inherits it because the commands it wraps are executed by the driver.
+const driverNameEnvVar = "BEEGFS_CSI_DRIVER_NAME"
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case validateConfigSubcommand:
+			os.Exit(runValidateConfig(os.Args[2:]))
+		case volumeInfoSubcommand:
+			os.Exit(runVolumeInfo(os.Args[2:]))
+		case deletionStatusSubcommand:
+			os.Exit(runDeletionStatus(os.Args[2:]))
+		case retainedVolumesSubcommand:
+			os.Exit(runRetainedVolumes(os.Args[2:]))
+		case restoreVolumeSubcommand:
+			os.Exit(runRestoreVolume(os.Args[2:]))
+		case listVolumesSubcommand:
+			os.Exit(runListVolumes(os.Args[2:]))
+		case orphanedVolumesSubcommand:
+			os.Exit(runOrphanedVolumes(os.Args[2:]))
+		case cleanUpOrphanedVolumesSubcommand:
+			os.Exit(runCleanUpOrphanedVolumes(os.Args[2:]))
+		}
+	}
+
 	klog.InitFlags(nil)
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		beegfs.LogFatal(context.TODO(), err, "Failed to set klog flag logtostderr=true")
@@ -66,10 +105,55 @@ func main() {
 }
 
 func handle() {
-	driver, err := beegfs.NewBeegfsDriver(*connAuthPath, *tlsCertsPath, *configPath, *csDataDir, *driverName, *endpoint, *nodeID,
-		*clientConfTemplatePath, version, *nodeUnstageTimeout)
+	if err := os.Setenv(driverNameEnvVar, *driverName); err != nil {
+		beegfs.LogFatal(context.TODO(), err, "Failed to set environment variable", "name", driverNameEnvVar)
+	}
+	driver, err := beegfs.NewBeegfsDriver(*connAuthPath, *tlsCertsPath, *configPath, *csDataDir, *driverName, *endpoint,
+		*diagnosticsEndpoint, *nodeID, *clientConfTemplatePath, version, *nodeUnstageTimeout)
 	if err != nil {
+		writeTerminationMessage(err)
 		beegfs.LogFatal(context.TODO(), err, "Failed to initialize driver")
 	}
+	if err = driver.SetDiagnosticsService(*diagnosticsService); err != nil {
+		beegfs.LogFatal(context.TODO(), err, "Failed to set diagnostics service")
+	}
+	if *metricsEndpoint != "" {
+		if err = driver.EnableMetrics(*metricsEndpoint); err != nil {
+			beegfs.LogFatal(context.TODO(), err, "Failed to enable metrics")
+		}
+	}
+	if *statusReportPod != "" {
+		if err = driver.EnableStatusReporting(*statusReportPod, *statusReportInterval); err != nil {
+			beegfs.LogFatal(context.TODO(), err, "Failed to enable status reporting")
+		}
+	}
+	if err = driver.SetMountIdleTimeout(*csMountIdleTimeout); err != nil {
+		beegfs.LogFatal(context.TODO(), err, "Failed to set mount idle timeout")
+	}
+	if err = driver.SetDeletionWorkers(*csDeletionWorkers); err != nil {
+		beegfs.LogFatal(context.TODO(), err, "Failed to set deletion workers")
+	}
+	driver.SetClusterID(*clusterID)
+	if *csOrphanScanInterval != 0 {
+		if err = driver.EnableOrphanScanning(*csOrphanScanInterval); err != nil {
+			beegfs.LogFatal(context.TODO(), err, "Failed to enable orphaned volume scanning")
+		}
+	}
+	if *nodeSharedMountDir != "" {
+		if err = driver.EnableSharedMounts(*nodeSharedMountDir); err != nil {
+			beegfs.LogFatal(context.TODO(), err, "Failed to enable shared mounts")
+		}
+	}
 	driver.Run()
 }
+
+// writeTerminationMessage writes err to the file Kubernetes reads a container's termination message from by default.
+// The message appears in the container's status (e.g. so the operator can tell that a node service Pod is failing
+// because the BeeGFS client kernel module is missing). writeTerminationMessage does nothing when the driver is not
+// running in Kubernetes, and failure to write the message is not significant.
+func writeTerminationMessage(err error) {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return
+	}
+	_ = os.WriteFile(terminationMessagePath, []byte(err.Error()), 0644)
+}
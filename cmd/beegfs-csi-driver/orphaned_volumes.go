@@ -0,0 +1,39 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const orphanedVolumesSubcommand = "orphaned-volumes"
+
+// runOrphanedVolumes implements the orphaned-volumes subcommand. It asks the diagnostics endpoint of a running
+// controller service to scan for volumes without a PersistentVolume, prints the report, and returns the exit code for
+// the process.
+func runOrphanedVolumes(args []string) int {
+	flags := flag.NewFlagSet(orphanedVolumesSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], orphanedVolumesSubcommand)
+		fmt.Fprintln(flags.Output(), "Print the volumes the controller service finds under the volDirBasePaths of the driver's Storage Classes that have no PersistentVolume.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := beegfs.QueryOrphanedVolumes(os.Stdout, *diagnosticsEndpoint); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get orphaned volumes: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const restoreVolumeSubcommand = "restore-volume"
+
+// runRestoreVolume implements the restore-volume subcommand. It asks the diagnostics endpoint of a running controller
+// service to restore a retained volume, prints a PersistentVolume manifest for the restored volume, and returns the
+// exit code for the process.
+func runRestoreVolume(args []string) int {
+	flags := flag.NewFlagSet(restoreVolumeSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	driverName := flags.String("driver-name", beegfs.DefaultDriverName, "name of the CSI driver (used in the PersistentVolume manifest)")
+	retainedVolumeID := flags.String("retained-volume-id", "", "the retainedVolumeID printed by the retained-volumes subcommand")
+	volumeName := flags.String("volume-name", "", "the name of the directory (and PersistentVolume) to restore the volume to (the original name if not provided)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], restoreVolumeSubcommand)
+		fmt.Fprintln(flags.Output(), "Restore a retained volume and print a PersistentVolume manifest for it.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *retainedVolumeID == "" {
+		fmt.Fprintln(os.Stderr, "--retained-volume-id is required")
+		flags.Usage()
+		return 2
+	}
+
+	if err := beegfs.RestoreRetainedVolume(os.Stdout, *diagnosticsEndpoint, *driverName, *retainedVolumeID,
+		*volumeName); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to restore volume: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const retainedVolumesSubcommand = "retained-volumes"
+
+// runRetainedVolumes implements the retained-volumes subcommand. It queries the diagnostics endpoint of a running
+// controller service for the deleted volumes it retains, prints them, and returns the exit code for the process.
+func runRetainedVolumes(args []string) int {
+	flags := flag.NewFlagSet(retainedVolumesSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], retainedVolumesSubcommand)
+		fmt.Fprintln(flags.Output(), "Print the deleted volumes the controller service retains until their retention periods end.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := beegfs.QueryRetainedVolumes(os.Stdout, *diagnosticsEndpoint); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get retained volumes: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const validateConfigSubcommand = "validate-config"
+
+// runValidateConfig implements the validate-config subcommand. It validates the driver's configuration files offline
+// (without a BeeGFS file system, BeeGFS CLI, or BeeGFS client kernel module), prints the effective configuration for
+// each file system, and returns the exit code for the process.
+func runValidateConfig(args []string) int {
+	flags := flag.NewFlagSet(validateConfigSubcommand, flag.ContinueOnError)
+	connAuthPath := flags.String("connauth-path", "", "path to the file containing BeeGFS connection authentication secrets")
+	tlsCertsPath := flags.String("tlscerts-path", "", "path to the file containing BeeGFS TLS certificates")
+	configPath := flags.String("config-path", "", "path to the plugin configuration file")
+	clientConfTemplatePath := flags.String("client-conf-template-path", "", "path to the template beegfs-client.conf file (default locations are searched if not provided)")
+	nodeID := flags.String("node-id", "", "the Kubernetes node ID used to select nodeSpecificConfigs")
+	nodeLabels := flags.String("node-labels", "", "comma separated key=value labels used to evaluate nodeSpecificConfigs nodeSelectors (the Kubernetes API is queried if not provided)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], validateConfigSubcommand)
+		fmt.Fprintln(flags.Output(), "Validate driver configuration files and print the effective configuration for each file system.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var labelSet labels.Set
+	if *nodeLabels != "" {
+		var err error
+		if labelSet, err = labels.ConvertSelectorToLabelsMap(*nodeLabels); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --node-labels: %v\n", err)
+			return 2
+		}
+	}
+
+	if err := beegfs.ValidateConfigFiles(os.Stdout, *connAuthPath, *tlsCertsPath, *configPath, *clientConfTemplatePath,
+		*nodeID, labelSet); err != nil {
+		fmt.Fprintf(os.Stderr, "configuration is invalid: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "configuration is valid")
+	return 0
+}
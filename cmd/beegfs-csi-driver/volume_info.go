@@ -0,0 +1,45 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/netapp/beegfs-csi-driver/pkg/beegfs"
+)
+
+const volumeInfoSubcommand = "volume-info"
+
+// runVolumeInfo implements the volume-info subcommand. It queries the diagnostics endpoint of a running driver for the
+// effective BeeGFS client configuration of a volume, prints it, and returns the exit code for the process.
+func runVolumeInfo(args []string) int {
+	flags := flag.NewFlagSet(volumeInfoSubcommand, flag.ContinueOnError)
+	diagnosticsEndpoint := flags.String("diagnostics-endpoint", "unix://csi/diagnostics.sock", "the diagnostics endpoint of the running driver")
+	volumeID := flags.String("volume-id", "", "the volume ID (e.g. beegfs://10.0.0.1/k8s/pvc-12345678)")
+	stagingTargetPath := flags.String("staging-target-path", "", "the staging target path of the volume on this node (found automatically if not provided)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], volumeInfoSubcommand)
+		fmt.Fprintln(flags.Output(), "Print the effective BeeGFS client configuration and mount state of a volume.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *volumeID == "" {
+		fmt.Fprintln(os.Stderr, "--volume-id is required")
+		flags.Usage()
+		return 2
+	}
+
+	if err := beegfs.QueryVolumeDiagnostics(os.Stdout, *diagnosticsEndpoint, *volumeID, *stagingTargetPath); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get volume info: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -41,10 +41,26 @@ func validBinary(path string) bool {
 	return true
 }
 
+// The driver sets driverNameEnvVar to its --driver-name before it executes any chwrapped command. The plugin-owned
+// directory on the host is /var/lib/kubelet/plugins/<driver name>.
+const (
+	driverNameEnvVar  = "BEEGFS_CSI_DRIVER_NAME"
+	defaultDriverName = "beegfs.csi.netapp.com"
+)
+
+// clientDir returns the client files subdirectory of the plugin-owned directory on the host (without a leading slash).
+func clientDir() string {
+	driverName := os.Getenv(driverNameEnvVar)
+	if driverName == "" || strings.Contains(driverName, "/") {
+		driverName = defaultDriverName
+	}
+	return "var/lib/kubelet/plugins/" + driverName + "/client/"
+}
+
 func findBinary(prefix, binary string) string {
 	// Some automatic worker node prep workflows put BeeGFS utilities in the plugin-owned
-	// /var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/sbin directory to avoid base OS "contamination".
-	for _, part1 := range []string{"var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/", "usr/local/", "usr/", ""} {
+	// /var/lib/kubelet/plugins/<driver name>/client/sbin directory to avoid base OS "contamination".
+	for _, part1 := range []string{clientDir(), "usr/local/", "usr/", ""} {
 		for _, part2 := range []string{"sbin", "bin"} {
 			path := "/" + part1 + part2 + "/" + binary
 			if validBinary(prefix + path) {
@@ -63,8 +79,8 @@ func modifyEnv(oldEnv []string) []string {
 		}
 	}
 	// Some automatic worker node prep workflows put BeeGFS utilities in the plugin-owned
-	// /var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/sbin directory to avoid base OS "contamination".
-	newEnv = append(newEnv, "PATH=/var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/sbin:"+
+	// /var/lib/kubelet/plugins/<driver name>/client/sbin directory to avoid base OS "contamination".
+	newEnv = append(newEnv, "PATH=/"+clientDir()+"sbin:"+
 		"/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin")
 	return newEnv
 }
@@ -55,6 +55,15 @@ const (
 	ResourceNameTLS       = "csi-beegfs-tlscerts"
 )
 
+// This is the expected CSI driver name within the manifests. It is the name of the CSI Driver object, the value of the
+// --driver-name argument, and the final element of the kubelet plugin directory path. The operator replaces it to
+// deploy a driver with a different name. deploy_test.go attempts to ensure that a developer can not change it without
+// understanding that operator code must be refactored.
+const (
+	DefaultDriverName     = "beegfs.csi.netapp.com"
+	KubeletPluginsDirPath = "/var/lib/kubelet/plugins"
+)
+
 // These are expected Config Map and Secret keys within the manifests. Some operator logic is based off the expectation
 // that keys have these names. deploy_test.go attempts to ensure that a developer can not change these names
 // without understanding that operator code must be refactored.
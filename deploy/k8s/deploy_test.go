@@ -6,6 +6,7 @@ Licensed under the Apache License, Version 2.0.
 package deploy
 
 import (
+	"path"
 	"strings"
 	"testing"
 
@@ -63,12 +64,17 @@ func TestGetControllerServiceStatefulSet(t *testing.T) {
 
 	testForKeysInContainerArgs(t, sts.Spec.Template.Spec.Containers)
 	testForResourceNamesInPodVolumes(t, sts.Spec.Template.Spec.Volumes)
+	testForDriverName(t, sts.Spec.Template.Spec)
 }
 
 func TestGetCSIDriver(t *testing.T) {
-	if _, err := GetCSIDriver(); err != nil {
+	d, err := GetCSIDriver()
+	if err != nil {
 		t.Fatal(err)
 	}
+	if d.Name != DefaultDriverName {
+		t.Fatalf("expected CSI Driver object named %s; got %s", DefaultDriverName, d.Name)
+	}
 }
 
 func TestGetNodeServiceDaemonSet(t *testing.T) {
@@ -96,6 +102,7 @@ func TestGetNodeServiceDaemonSet(t *testing.T) {
 
 	testForKeysInContainerArgs(t, ds.Spec.Template.Spec.Containers)
 	testForResourceNamesInPodVolumes(t, ds.Spec.Template.Spec.Volumes)
+	testForDriverName(t, ds.Spec.Template.Spec)
 }
 
 func testForKeysInContainerArgs(t *testing.T, containers []corev1.Container) {
@@ -147,3 +154,26 @@ func testForResourceNamesInPodVolumes(t *testing.T, volumes []corev1.Volume) {
 		t.Fatalf("expected to find a reference to %s in Pod volumes", ResourceNameTLS)
 	}
 }
+
+func testForDriverName(t *testing.T, spec corev1.PodSpec) {
+	foundArg := false
+	for _, container := range spec.Containers {
+		for _, arg := range container.Args {
+			if arg == "--driver-name="+DefaultDriverName {
+				foundArg = true
+			}
+		}
+	}
+	if !foundArg {
+		t.Fatalf("expected to find --driver-name=%s in Container args", DefaultDriverName)
+	}
+	foundPath := false
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil && volume.HostPath.Path == path.Join(KubeletPluginsDirPath, DefaultDriverName) {
+			foundPath = true
+		}
+	}
+	if !foundPath {
+		t.Fatalf("expected to find a hostPath Pod volume at %s", path.Join(KubeletPluginsDirPath, DefaultDriverName))
+	}
+}
@@ -14,6 +14,7 @@ require (
 	github.com/onsi/gomega v1.36.1
 	github.com/opencontainers/selinux v1.13.1
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.20.5
 	github.com/spf13/afero v1.9.2
 	golang.org/x/net v0.47.0
 	golang.org/x/sys v0.38.0
@@ -103,7 +104,6 @@ require (
 	github.com/opencontainers/runc v1.2.8 // indirect
 	github.com/opencontainers/runtime-spec v1.2.0 // indirect
 	github.com/otiai10/copy v1.10.0 // indirect
-	github.com/prometheus/client_golang v1.20.5 // indirect
 	github.com/prometheus/client_model v0.6.1 // indirect
 	github.com/prometheus/common v0.60.1 // indirect
 	github.com/prometheus/procfs v0.15.1 // indirect
@@ -22,13 +22,38 @@ import (
 	"encoding/json"
 
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // BeegfsDriverSpec defines the desired state of BeegfsDriver
 type BeegfsDriverSpec struct {
 	ContainerImageOverrides    ContainerImageOverrides    `json:"containerImageOverrides,omitempty"`
 	ContainerResourceOverrides ContainerResourceOverrides `json:"containerResourceOverrides,omitempty"`
+	// The name of the CSI driver (e.g. tenant-a.beegfs.csi.netapp.com). StorageClasses reference it as their
+	// provisioner. The CSI Driver object, the Cluster Roles and Cluster Role Bindings, and the kubelet plugin directory
+	// (/var/lib/kubelet/plugins/<driverName>) are derived from it, so multiple BeegfsDrivers (e.g. one per tenant
+	// namespace or a canary driver version) can coexist in one cluster as long as each has a unique driverName. It
+	// cannot be changed after the BeegfsDriver is created because existing Persistent Volumes reference it. Empty
+	// defaults to beegfs.csi.netapp.com.
+	//+kubebuilder:validation:MaxLength:=63
+	//+kubebuilder:validation:Pattern:=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Driver Name"
+	DriverName string `json:"driverName,omitempty"`
+	// A list of references to existing Secrets (in the driver namespace) containing connAuth and/or TLS certificate
+	// information for specific file systems. If any entry includes a connAuthSecretRef, the operator assembles the
+	// csi-beegfs-connauth Secret from the referenced Secrets and keeps it up to date as they change (overwriting any
+	// manual modifications). Likewise, if any entry includes a tlsCertSecretRef, the operator assembles the
+	// csi-beegfs-tlscerts Secret.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="File System Secret References"
+	FileSystemSecretRefs []FileSystemSecretRefs `json:"fileSystemSecretRefs,omitempty"`
+	// The host port the node service exposes its liveness probe on. The node service uses the host network, so
+	// BeegfsDrivers whose node services run on the same nodes must use different ports. Empty defaults to 9898.
+	//+kubebuilder:validation:Minimum:=1
+	//+kubebuilder:validation:Maximum:=65535
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Health Port Node Service"
+	HealthPortNodeService *int32 `json:"healthPortNodeService,omitempty"`
 	// The logging level of deployed containers expressed as an integer from 0 (low detail) to 5 (high detail). 0
 	// only logs errors. 3 logs most RPC requests/responses and some detail about driver actions. 5 logs all RPC
 	// requests/responses, including redundant/frequently occurring ones. Empty defaults to level 3.
@@ -48,19 +73,129 @@ type BeegfsDriverSpec struct {
 	NodeAffinityNodeService corev1.NodeAffinity `json:"nodeAffinityNodeService"`
 	//+operator-sdk:csv:customresourcedefinitions:type=spec
 	PluginConfigFromFile PluginConfigFromFile `json:"pluginConfig,omitempty"`
+	// Additional scheduling and configuration for the Pods of the controller service and node service (e.g.
+	// tolerations for tainted GPU nodes or a priority class).
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pod Template Overrides"
+	PodTemplateOverrides PodTemplateOverrides `json:"podTemplateOverrides,omitempty"`
+	// StorageClasses the operator creates and keeps up to date for this driver. The operator validates the parameters
+	// of each template with the same rules the driver applies at CreateVolume and reports invalid templates in
+	// status.invalidStorageClasses instead of creating them. StorageClasses removed from the list are deleted.
+	//+listType=map
+	//+listMapKey=name
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Storage Class Templates"
+	StorageClassTemplates []StorageClassTemplate `json:"storageClassTemplates,omitempty"`
+	// Controls how the operator rolls out a change to the images of the driver (e.g. after an operator upgrade or a
+	// change to containerImageOverrides).
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Upgrade Strategy"
+	UpgradeStrategy UpgradeStrategy `json:"upgradeStrategy,omitempty"`
 }
 
 // BeegfsDriverStatus defines the observed state of BeegfsDriver
 type BeegfsDriverStatus struct {
 	//+operator-sdk:csv:customresourcedefinitions:type=status,xDescriptors={"urn:alm:descriptor:io.kubernetes.conditions"}
 	Conditions []metav1.Condition `json:"conditions"`
+	// The reachability and BeeGFS version of each file system in the driver configuration as most recently reported
+	// by the controller service.
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="File Systems"
+	FileSystems []FileSystemStatus `json:"fileSystems,omitempty"`
+	// The number of file systems in FileSystems that are reachable out of the total (e.g. 2/3).
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="File Systems Reachable"
+	FileSystemsReachable string `json:"fileSystemsReachable,omitempty"`
+	// The number of ready node service Pods out of the number desired (e.g. 4/5).
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="Nodes Ready"
+	NodesReady string `json:"nodesReady,omitempty"`
+	// The nodes on which the node service Pod is not ready and the reason why.
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="Unready Nodes"
+	UnreadyNodes []NodeStatus `json:"unreadyNodes,omitempty"`
+	// The image used by the beegfs container of the driver.
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="Driver Image"
+	DriverImage string `json:"driverImage,omitempty"`
+	// The images used by every container of the deployed driver (including sidecars).
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="Images"
+	Images []ContainerImageStatus `json:"images,omitempty"`
+	// The progress of the most recent change to the images of the driver.
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="Upgrade"
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+	// The entries in storageClassTemplates the operator did not create or update a StorageClass for and the reason why.
+	//+operator-sdk:csv:customresourcedefinitions:type=status,displayName="Invalid Storage Classes"
+	InvalidStorageClasses []StorageClassStatus `json:"invalidStorageClasses,omitempty"`
 }
 
+// FileSystemStatus describes the state of a BeeGFS file system as observed by the controller service. The controller
+// service reports a list of FileSystemStatus as JSON in the AnnotationFileSystemStatus annotation on its own Pod.
+type FileSystemStatus struct {
+	// The sysMgmtdHost of the file system.
+	SysMgmtdHost string `json:"sysMgmtdHost"`
+	// Whether the controller service could reach the file system's management service.
+	Reachable bool `json:"reachable"`
+	// The major version of BeeGFS running on the file system (7 or 8). Empty if the file system is not reachable.
+	BeegfsVersion string `json:"beegfsVersion,omitempty"`
+	// Why the file system is not reachable.
+	Message string `json:"message,omitempty"`
+	// The last time the controller service probed the file system.
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// NodeStatus describes why the node service is not ready on a node.
+type NodeStatus struct {
+	// The name of the node.
+	NodeName string `json:"nodeName"`
+	// A CamelCase reason (e.g. BeegfsClientModuleMissing).
+	Reason string `json:"reason"`
+	// A human readable explanation.
+	Message string `json:"message,omitempty"`
+}
+
+// ContainerImageStatus records the image used by a container of the deployed driver.
+type ContainerImageStatus struct {
+	// The name of the container (e.g. csi-provisioner).
+	Name string `json:"name"`
+	// The image (including tag) used by the container.
+	Image string `json:"image"`
+}
+
+// UpgradeStatus describes the rollout of a new set of images.
+type UpgradeStatus struct {
+	// The images the driver used before the upgrade started.
+	PreviousImages []ContainerImageStatus `json:"previousImages,omitempty"`
+	// The images the upgrade rolls out.
+	TargetImages []ContainerImageStatus `json:"targetImages,omitempty"`
+	// When the operator started rolling out the target images.
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// InProgress, Complete, Paused, or RolledBack.
+	Phase string `json:"phase,omitempty"`
+	// The number of node service Pods running the target images that are ready out of the number desired (e.g. 3/5).
+	UpdatedNodesReady string `json:"updatedNodesReady,omitempty"`
+}
+
+// StorageClassStatus describes why the operator did not create or update the StorageClass for a template.
+type StorageClassStatus struct {
+	// The name of the StorageClass.
+	Name string `json:"name"`
+	// A human readable explanation (e.g. the invalid parameter).
+	Message string `json:"message"`
+}
+
+// Possible values for BeegfsDriverStatus.Upgrade.Phase.
+const (
+	// The operator is rolling out the target images.
+	UpgradePhaseInProgress = "InProgress"
+	// Every node service Pod runs the target images and is ready.
+	UpgradePhaseComplete = "Complete"
+	// An updated node service Pod failed to become ready, so the operator stopped replacing node service Pods. Pods
+	// that have not been updated keep the previous images.
+	UpgradePhasePaused = "Paused"
+	// An updated node service Pod failed to become ready, so the operator redeployed the previous images.
+	UpgradePhaseRolledBack = "RolledBack"
+)
+
 // Possible values for BeegfsDriverStatus.Conditions[].Type.
 const (
 	// Possible values for BeegfsDriverStatus.Conditions[].Type.
 	ConditionControllerServiceReady = "ControllerServiceReady"
 	ConditionNodeServiceReady       = "NodeServiceReady"
+	ConditionUpgradeProgressing     = "UpgradeProgressing"
+	ConditionStorageClassesValid    = "StorageClassesValid"
 )
 
 // Possible values for BeegfsDriverStatus.Conditions[].Reason.
@@ -69,10 +204,39 @@ const (
 	ReasonPodsNotScheduled  = "PodsNotScheduled"
 	ReasonPodsNotReady      = "PodsNotReady"
 	ReasonPodsReady         = "PodsReady"
+	// Reasons for the UpgradeProgressing condition match the possible values for BeegfsDriverStatus.Upgrade.Phase.
+	ReasonUpgradeInProgress = UpgradePhaseInProgress
+	ReasonUpgradeComplete   = UpgradePhaseComplete
+	ReasonUpgradePaused     = UpgradePhasePaused
+	ReasonUpgradeRolledBack = UpgradePhaseRolledBack
+	// Reasons for the StorageClassesValid condition.
+	ReasonStorageClassesValid   = "StorageClassesValid"
+	ReasonStorageClassesInvalid = "StorageClassesInvalid"
+)
+
+// Possible values for BeegfsDriverStatus.UnreadyNodes[].Reason.
+const (
+	ReasonPodNotReady               = "PodNotReady"
+	ReasonBeegfsClientModuleMissing = "BeegfsClientModuleMissing"
 )
 
+// AnnotationFileSystemStatus is the annotation the controller service uses to report a JSON encoded list of
+// FileSystemStatus on its own Pod.
+const AnnotationFileSystemStatus = "beegfs.csi.netapp.com/file-system-status"
+
+// BeegfsClientModuleMissingMessage is the error the driver exits with (and writes to its termination log) when the
+// BeeGFS client kernel module is not installed on its node.
+const BeegfsClientModuleMissingMessage = "the BeeGFS client kernel module is not installed"
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Controller Ready",type=string,JSONPath=`.status.conditions[?(@.type=="ControllerServiceReady")].status`
+//+kubebuilder:printcolumn:name="Nodes Ready",type=string,JSONPath=`.status.nodesReady`
+//+kubebuilder:printcolumn:name="File Systems Reachable",type=string,JSONPath=`.status.fileSystemsReachable`
+//+kubebuilder:printcolumn:name="Unready Nodes",type=string,JSONPath=`.status.unreadyNodes[*].nodeName`,priority=1
+//+kubebuilder:printcolumn:name="Driver Image",type=string,JSONPath=`.status.driverImage`,priority=1
+//+kubebuilder:printcolumn:name="Upgrade",type=string,JSONPath=`.status.upgrade.phase`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 //+operator-sdk:csv:customresourcedefinitions:displayName="BeeGFS Driver"
 //+operator-sdk:csv:customresourcedefinitions:resources={{ConfigMap,v1,},{DaemonSet,v1,},{Secret,v1,},{Secret,v1,},{StatefulSet,v1,}}
 
@@ -135,9 +299,19 @@ type ContainerImageOverride struct {
 // Each container has default requests and limits for both cpu and memory resources. Only explicitly defined
 // overrides will be applied, otherwise the default values will be used. For example, if the cpu limit for the
 // controller's beegfs container is the only resource with an override set, only the controller's beegfs container
-// cpu limit setting will be overridden. Every other value will use the default setting. Storage resources are not
-// used by the BeeGFS CSI driver. Any storage resource values configured will be ignored.
+// cpu limit setting will be overridden. Every other value will use the default setting. Any resource (e.g.
+// ephemeral-storage or hugepages-2Mi) can be overridden. The controllerContainers and nodeContainers maps can
+// override the resources of any container by name. The container specific fields predate them and are still honored,
+// but an entry in a map takes precedence over a container specific field for the same resource.
 type ContainerResourceOverrides struct {
+	// The resource specifications for containers of the BeeGFS driver controller pod keyed by container name (beegfs,
+	// csi-provisioner, or csi-resizer).
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Controller container resources"
+	ControllerContainers map[string]corev1.ResourceRequirements `json:"controllerContainers,omitempty"`
+	// The resource specifications for containers of the BeeGFS driver node pod keyed by container name (beegfs,
+	// node-driver-registrar, or liveness-probe).
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node container resources"
+	NodeContainers map[string]corev1.ResourceRequirements `json:"nodeContainers,omitempty"`
 	// The resource specifications for the beegfs container of the BeeGFS driver controller pod.
 	// The default values for requests are (cpu: 100m, memory: 16Mi).
 	// The default values for limits are (cpu: None, memory: 256Mi).
@@ -165,6 +339,130 @@ type ContainerResourceOverrides struct {
 	NodeLivenessProbeResources corev1.ResourceRequirements `json:"nodeLivenessProbe,omitempty"`
 }
 
+// The UpgradeStrategy controls how the operator rolls out a change to the images of the driver. The node service Daemon
+// Set replaces at most maxUnavailable node service Pods at a time. If an updated node service Pod is not ready within
+// progressDeadlineSeconds, the operator either pauses the rollout (the Daemon Set stops replacing Pods until the
+// images change again or the Pod becomes ready) or, if autoRollback is true, redeploys the previous images.
+type UpgradeStrategy struct {
+	// The maximum number of node service Pods that can be unavailable during a rollout, as a number or a percentage of
+	// nodes (e.g. 1 or 10%). Empty defaults to 1.
+	//+kubebuilder:validation:XIntOrString
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Max Unavailable"
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// How long an updated node service Pod may remain unready before the rollout is considered failed. Empty defaults
+	// to 600.
+	//+kubebuilder:validation:Minimum:=1
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Progress Deadline Seconds"
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// Whether to redeploy the previous images when a rollout fails instead of pausing it.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Automatic Rollback"
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+// The PodTemplateOverrides allow for customization of the Pod templates of the controller service Stateful Set and the
+// node service Daemon Set beyond node affinity, images, and resources. Overrides are applied to the Pod templates from
+// the deployment manifests with strategic merge patch semantics (the same semantics as kubectl patch): annotations
+// and nodeSelector entries are merged by key; volumes, containers, env, and volumeMounts are merged by name; and
+// tolerations and priorityClassName replace the defaults. The controller service tolerates the
+// node-role.kubernetes.io/master:NoSchedule taint by default, so include that toleration if it is still required.
+type PodTemplateOverrides struct {
+	// Overrides for the Pod template of the controller service Stateful Set.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Controller service"
+	ControllerService PodTemplateOverride `json:"controllerService,omitempty"`
+	// Overrides for the Pod template of the node service Daemon Set.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node service"
+	NodeService PodTemplateOverride `json:"nodeService,omitempty"`
+}
+
+// PodTemplateOverride contains the fields of a Pod template that can be overridden.
+type PodTemplateOverride struct {
+	// Annotations to add to the Pod.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Environment variables and volume mounts to add to specific containers of the Pod.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Containers []ContainerTemplateOverride `json:"containers,omitempty"`
+	// A selector which must match a node's labels for the Pod to be scheduled on that node.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node selector"
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// The name of the PriorityClass of the Pod (e.g. system-node-critical).
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Priority class name"
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// The tolerations of the Pod.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Additional volumes that can be mounted by containers of the Pod. Volumes are validated by the Kubernetes API
+	// server when the operator updates the Stateful Set or Daemon Set.
+	//+kubebuilder:validation:Schemaless
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Volumes []corev1.Volume `json:"volumes,omitempty"` // Schemaless because the full Volume schema makes the CRD too large to apply client-side.
+}
+
+// ContainerTemplateOverride contains the fields of a container in a Pod template that can be overridden.
+type ContainerTemplateOverride struct {
+	// The name of the container (e.g. beegfs or csi-provisioner).
+	//+kubebuilder:validation:Required
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Name string `json:"name"`
+	// Additional environment variables for the container.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// Additional volume mounts for the container. Each must reference a volume in the Pod.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Volume mounts"
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+// A StorageClass the operator creates and keeps up to date. Its provisioner is always the driverName of the
+// BeegfsDriver.
+type StorageClassTemplate struct {
+	// The name of the StorageClass.
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:MaxLength:=253
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Name string `json:"name"`
+	// Additional labels for the StorageClass.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Labels map[string]string `json:"labels,omitempty"`
+	// Additional annotations for the StorageClass (e.g. storageclass.kubernetes.io/is-default-class: "true").
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// The parameters of the StorageClass (e.g. sysMgmtdHost, volDirBasePath, stripePattern/chunkSize, or
+	// permissions/mode). sysMgmtdHost and volDirBasePath are required.
+	//+kubebuilder:validation:Required
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Parameters map[string]string `json:"parameters"`
+	// Delete or Retain. Empty defaults to Delete.
+	//+kubebuilder:validation:Enum=Delete;Retain
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Reclaim Policy"
+	ReclaimPolicy *corev1.PersistentVolumeReclaimPolicy `json:"reclaimPolicy,omitempty"`
+	// Whether Persistent Volume Claims using the StorageClass can be expanded. Empty defaults to false.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Allow Volume Expansion"
+	AllowVolumeExpansion *bool `json:"allowVolumeExpansion,omitempty"`
+	// Immediate or WaitForFirstConsumer. Empty defaults to Immediate.
+	//+kubebuilder:validation:Enum=Immediate;WaitForFirstConsumer
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Volume Binding Mode"
+	VolumeBindingMode *storagev1.VolumeBindingMode `json:"volumeBindingMode,omitempty"`
+	// Mount options for Persistent Volumes provisioned using the StorageClass.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Mount Options"
+	MountOptions []string `json:"mountOptions,omitempty"`
+}
+
+// References to existing Secrets containing connAuth and/or TLS certificate information for a specific file system.
+type FileSystemSecretRefs struct {
+	// The sysMgmtdHost of the file system the referenced Secrets apply to.
+	//+kubebuilder:validation:Required
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="SysMgmtdHost"
+	SysMgmtdHost string `json:"sysMgmtdHost"`
+	// A key in a Secret whose value is used as the connAuthFile of the file system exactly as is (e.g. as created by
+	// "kubectl create secret generic <name> --from-file=connAuthFile=/etc/beegfs/connauthfile").
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="ConnAuth Secret Reference"
+	ConnAuthSecretRef *corev1.SecretKeySelector `json:"connAuthSecretRef,omitempty"`
+	// A key in a Secret whose value is the PEM encoded TLS certificate (or certificate chain) of the file system.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="TLS Certificate Secret Reference"
+	TLSCertSecretRef *corev1.SecretKeySelector `json:"tlsCertSecretRef,omitempty"`
+}
+
 // The primary configuration structure containing all of the custom configuration (beegfs-client.conf keys/values and
 // additional CSI driver specific fields) associated with a single BeeGFS file system except for sysMgmtdHost, which is
 // specified elsewhere. WARNING: This structure includes a beegfsClientConf field. This field may not be rendered in
@@ -246,16 +544,42 @@ type FileSystemSpecificConfig struct {
 	SysMgmtdHost string `json:"sysMgmtdHost"`
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="File System Specific Config"
 	Config BeegfsConfig `json:"config"`
+	// Whether the controller service deletes volumes on this file system that have no ownership marker (e.g. volumes
+	// created by older versions of the driver or statically provisioned volumes). By default, the controller service
+	// refuses to delete any directory it did not provably create.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Allow Unmarked Volume Deletion"
+	AllowUnmarkedVolumeDeletion bool `json:"allowUnmarkedVolumeDeletion,omitempty"`
+	// A list of paths (from the BeeGFS root) volumes on this file system may be created, validated, and deleted in
+	// (e.g. "/k8s"). A volDirBasePath is allowed if it is one of these paths or lies below one of them. If empty, any
+	// volDirBasePath not denied by deniedVolDirBasePaths is allowed.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Allowed Volume Directory Base Paths"
+	AllowedVolDirBasePaths []string `json:"allowedVolDirBasePaths,omitempty"`
+	// A list of paths (from the BeeGFS root) volumes on this file system may never be created, validated, or deleted
+	// in (e.g. "/home"). A volume is denied if its directory is one of these paths or lies below one of them, even if
+	// its volDirBasePath is allowed by allowedVolDirBasePaths.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Denied Volume Directory Base Paths"
+	DeniedVolDirBasePaths []string `json:"deniedVolDirBasePaths,omitempty"`
+	// Whether the controller service also records the metadata of each volume it creates on this file system (e.g.
+	// the namespace and name of its PersistentVolumeClaim) as extended attributes (user.beegfs-csi.*) of the volume's
+	// directory. The file system must support user extended attributes (sysXAttrsEnabled in beegfs-client.conf).
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Set Volume Metadata Extended Attributes"
+	SetVolumeMetadataXAttrs bool `json:"setVolumeMetadataXAttrs,omitempty"`
 }
 
 // A node specific configuration that overrides file system specific configurations and the default configuration on
 // specific nodes.
 type NodeSpecificConfig struct {
 	// The list of nodes this configuration should be applied on. Each entry is the hostname of the node or the name
-	// assigned to the node by the container orchestrator (e.g. "node1" or "cluster05-node03").
-	//+kubebuilder:validation:Required
+	// assigned to the node by the container orchestrator (e.g. "node1" or "cluster05-node03"). At least one of
+	// nodeList or nodeSelector should be specified.
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node Names"
-	NodeList []string `json:"nodeList"`
+	NodeList []string `json:"nodeList,omitempty"`
+	// A Kubernetes label selector matched against the labels of the node the driver is running on (e.g.
+	// "matchLabels: {example.com/rdma: "true"}"). This configuration is applied on every node whose labels match,
+	// which makes it useful in clusters where node names are not known in advance. Configuration matched by nodeList
+	// takes precedence over configuration matched by nodeSelector.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node Selector"
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
 	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Default Config for Nodes"
 	DefaultConfig BeegfsConfig `json:"config"`
 	// A list of file system specific configurations that override the default configuration for specific file systems
@@ -273,6 +597,16 @@ type PluginConfig struct {
 	// A list of file system specific configurations that override the default configuration for specific file systems.
 	//+operator-sdk:csv:customresourcedefinitions:displayName="Default File System Specific Configs"
 	FileSystemSpecificConfigs []FileSystemSpecificConfig `json:"fileSystemSpecificConfigs,omitempty"`
+	// A list of paths (from the BeeGFS root) volumes on any file system may be created, validated, and deleted in. Unlike
+	// the allowedVolDirBasePaths of a file system specific configuration, it applies no matter which sysMgmtdHost (e.g.
+	// an IP address, a domain name, or an alias) a volume refers to its file system by. A volume must be allowed by both.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Allowed Volume Directory Base Paths for All File Systems"
+	AllowedVolDirBasePaths []string `json:"allowedVolDirBasePaths,omitempty"`
+	// A list of paths (from the BeeGFS root) volumes on any file system may never be created, validated, or deleted in.
+	// Unlike the deniedVolDirBasePaths of a file system specific configuration, it applies no matter which sysMgmtdHost
+	// a volume refers to its file system by.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Denied Volume Directory Base Paths for All File Systems"
+	DeniedVolDirBasePaths []string `json:"deniedVolDirBasePaths,omitempty"`
 }
 
 // The top level configuration structure containing default configuration (applied to all file systems on all nodes),
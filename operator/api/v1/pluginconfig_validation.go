@@ -0,0 +1,161 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The rules in this file are shared by the driver (which applies them when it parses its configuration file) and the
+// operator (which applies them in its admission webhooks) so that a configuration accepted by one is accepted by the
+// other.
+
+// NoEffectBeegfsConfOptions have no effect when specified in the beegfsClientConf configuration section.
+var NoEffectBeegfsConfOptions = []string{
+	"sysMgmtdHost",
+	"connClientPortUDP",
+	"connClientPort",
+	"connPortShift",
+}
+
+// UnsupportedBeegfsConfOptions are unsupported when specified in the beegfsClientConf configuration section.
+var UnsupportedBeegfsConfOptions = []string{
+	"connInterfacesFile",
+	"connNetFilterFile",
+	"connTcpOnlyFilterFile",
+	"connAuthFile",
+	"connRDMAInterfacesFile",
+}
+
+// domainRegex is used to determine whether a given string is a domain name.
+var domainRegex = regexp.MustCompile(`^(?:[_a-z0-9](?:[_a-z0-9-]{0,61}[a-z0-9]\.)|(?:[0-9]+/[0-9]{2})\.)+(?:[a-z](?:[a-z0-9-]{0,61}[a-z0-9])?)?$`)
+
+// ValidatePluginConfig checks the basic syntax of assorted fields in a PluginConfig and returns an error if it finds
+// something incorrect.
+func ValidatePluginConfig(plConfig *PluginConfig) error {
+	beegfsConfigs := []BeegfsConfig{plConfig.DefaultConfig}
+	// An empty path would allow (or deny) the entire file system, which is unlikely to be intended.
+	for _, basePath := range plConfig.AllowedVolDirBasePaths {
+		if basePath == "" {
+			return fmt.Errorf("empty AllowedVolDirBasePaths entry")
+		}
+	}
+	for _, basePath := range plConfig.DeniedVolDirBasePaths {
+		if basePath == "" {
+			return fmt.Errorf("empty DeniedVolDirBasePaths entry")
+		}
+	}
+	for _, config := range plConfig.FileSystemSpecificConfigs {
+		// sysMgmtdHost can be localhost, an IP address, or a domain name. if it is none of these, return an error
+		if config.SysMgmtdHost != "localhost" && net.ParseIP(config.SysMgmtdHost) == nil &&
+			!domainRegex.MatchString(config.SysMgmtdHost) {
+			return fmt.Errorf("invalid SysMgmtdHost %s", config.SysMgmtdHost)
+		}
+		// An empty path would allow (or deny) the entire file system, which is unlikely to be intended.
+		for _, basePath := range config.AllowedVolDirBasePaths {
+			if basePath == "" {
+				return fmt.Errorf("empty AllowedVolDirBasePaths entry for SysMgmtdHost %s", config.SysMgmtdHost)
+			}
+		}
+		for _, basePath := range config.DeniedVolDirBasePaths {
+			if basePath == "" {
+				return fmt.Errorf("empty DeniedVolDirBasePaths entry for SysMgmtdHost %s", config.SysMgmtdHost)
+			}
+		}
+		beegfsConfigs = append(beegfsConfigs, config.Config)
+	}
+
+	for _, config := range beegfsConfigs {
+		// Validate optional BeeGFS 8 mgmtd gRPC port, if provided.
+		if config.GrpcPort != "" {
+			if p, err := strconv.Atoi(config.GrpcPort); err != nil || p < 1 || p > 65535 {
+				return fmt.Errorf("invalid GrpcPort %s", config.GrpcPort)
+			}
+		}
+		for _, filter := range config.ConnNetFilter {
+			if _, _, err := net.ParseCIDR(filter); err != nil && net.ParseIP(filter) == nil {
+				return fmt.Errorf("invalid ConnNetFilter %s", filter)
+			}
+		}
+		for _, filter := range config.ConnTcpOnlyFilter {
+			if _, _, err := net.ParseCIDR(filter); err != nil && net.ParseIP(filter) == nil {
+				return fmt.Errorf("invalid ConnTCPOnlyFilter %s", filter)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidatePluginConfigFromFile applies ValidatePluginConfig to the top level configuration and to the configuration
+// in every NodeSpecificConfig. The driver only validates the configuration that applies to the node it is running on,
+// so ValidatePluginConfigFromFile is useful when the nodes a configuration will be applied to are not known (e.g. in
+// the operator). It also verifies that every nodeSelector can be converted to a label selector.
+func ValidatePluginConfigFromFile(rawConfig *PluginConfigFromFile) error {
+	if err := ValidatePluginConfig(&rawConfig.PluginConfig); err != nil {
+		return err
+	}
+	for i, nodeConfig := range rawConfig.NodeSpecificConfigs {
+		if _, err := metav1.LabelSelectorAsSelector(nodeConfig.NodeSelector); err != nil {
+			return fmt.Errorf("invalid NodeSelector in nodeSpecificConfigs[%d]: %v", i, err)
+		}
+		nodePluginConfig := PluginConfig{
+			DefaultConfig:             nodeConfig.DefaultConfig,
+			FileSystemSpecificConfigs: nodeConfig.FileSystemSpecificConfigs,
+		}
+		if err := ValidatePluginConfig(&nodePluginConfig); err != nil {
+			return fmt.Errorf("%v in nodeSpecificConfigs[%d]", err, i)
+		}
+	}
+	return nil
+}
+
+// StripPluginConfig removes any no-effect beegfsClientConf options from a PluginConfig. It returns a warning for each
+// no-effect option it removes and for each unsupported option it finds (but does not remove). See deployment.md for
+// the lists of no-effect and unsupported options.
+func StripPluginConfig(plConfig *PluginConfig) []string {
+	var warnings []string
+	beegfsConfigs := []BeegfsConfig{plConfig.DefaultConfig}
+	for _, config := range plConfig.FileSystemSpecificConfigs {
+		beegfsConfigs = append(beegfsConfigs, config.Config)
+	}
+	for _, config := range beegfsConfigs {
+		// BeegfsClientConf is a map, so deleting from the copy in beegfsConfigs modifies plConfig.
+		for _, noEffectOption := range NoEffectBeegfsConfOptions {
+			if val, present := config.BeegfsClientConf[noEffectOption]; present {
+				warnings = append(warnings, fmt.Sprintf("no-effect beegfsClientConf option %s: %s is ignored",
+					noEffectOption, val))
+				delete(config.BeegfsClientConf, noEffectOption)
+			}
+		}
+		for _, unsupportedOption := range UnsupportedBeegfsConfOptions {
+			if val, present := config.BeegfsClientConf[unsupportedOption]; present {
+				warnings = append(warnings, fmt.Sprintf("unsupported beegfsClientConf option %s: %s is not supported",
+					unsupportedOption, val))
+			}
+		}
+	}
+	return warnings
+}
+
+// StripPluginConfigFromFile applies StripPluginConfig to the top level configuration and to the configuration in every
+// NodeSpecificConfig.
+func StripPluginConfigFromFile(rawConfig *PluginConfigFromFile) []string {
+	warnings := StripPluginConfig(&rawConfig.PluginConfig)
+	for _, nodeConfig := range rawConfig.NodeSpecificConfigs {
+		nodePluginConfig := PluginConfig{
+			DefaultConfig:             nodeConfig.DefaultConfig,
+			FileSystemSpecificConfigs: nodeConfig.FileSystemSpecificConfigs,
+		}
+		warnings = append(warnings, StripPluginConfig(&nodePluginConfig)...)
+	}
+	return warnings
+}
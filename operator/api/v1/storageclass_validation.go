@@ -0,0 +1,237 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The rules in this file are shared by the driver (which applies them to the parameters of a CreateVolume request) and
+// the operator (which applies them to the StorageClasses it creates from storageClassTemplates) so that a StorageClass
+// the operator accepts does not fail at CreateVolume.
+
+// These are the StorageClass parameters the driver understands.
+const (
+	ParameterSysMgmtdHost               = "sysMgmtdHost"
+	ParameterVolDirBasePath             = "volDirBasePath"
+	ParameterStripePatternStoragePoolID = "stripePattern/storagePoolID"
+	ParameterStripePatternChunkSize     = "stripePattern/chunkSize"
+	ParameterStripePatternNumTargets    = "stripePattern/numTargets"
+	ParameterPermissionsUID             = "permissions/uid"
+	ParameterPermissionsGID             = "permissions/gid"
+	ParameterPermissionsMode            = "permissions/mode"
+	ParameterRetentionPeriod            = "retentionPeriod"
+	ParameterVolDirTemplate             = "volDirTemplate"
+)
+
+// BasePathParameterPrefix is the prefix of the StorageClass parameters (e.g. basePath/permissions/mode) that apply
+// stripePattern/* and permissions/* settings to the parent directories of a volume (e.g. volDirBasePath) the driver
+// creates instead of to the volume itself. Existing parent directories are never changed.
+const BasePathParameterPrefix = "basePath/"
+
+// These are the variables a volDirTemplate may reference. The external-provisioner passes their values to the driver
+// as CreateVolume parameters when it runs with --extra-create-metadata.
+const (
+	VolDirTemplateVarPVCNamespace = "pvc.namespace"
+	VolDirTemplateVarPVCName      = "pvc.name"
+	VolDirTemplateVarPVName       = "pv.name"
+)
+
+// reservedParameterPrefix is the prefix of StorageClass parameters (e.g. csi.storage.k8s.io/provisioner-secret-name)
+// that the external-provisioner interprets and removes before it sends a CreateVolume request to the driver.
+const reservedParameterPrefix = "csi.storage.k8s.io/"
+
+// chunkSizeRegex matches a stripePattern/chunkSize: only digits followed by a single upper or lowercase letter.
+var chunkSizeRegex = regexp.MustCompile("(^[0-9]+[a-zA-Z]$)")
+
+// volDirTemplateVarRegex matches a ${variable} reference in a volDirTemplate.
+var volDirTemplateVarRegex = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// ValidateStripePatternParameter returns an error if value is not valid for the stripePattern/* parameter key. An empty
+// value is valid and leaves the setting to BeeGFS.
+func ValidateStripePatternParameter(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	switch key {
+	case ParameterStripePatternStoragePoolID:
+		if _, err := strconv.ParseUint(value, 10, 16); err != nil {
+			return fmt.Errorf("could not parse provided StoragePoolID: %v", err)
+		}
+	case ParameterStripePatternChunkSize:
+		if !chunkSizeRegex.MatchString(value) {
+			return fmt.Errorf("could not parse provided chunkSize")
+		}
+	case ParameterStripePatternNumTargets:
+		if _, err := strconv.ParseUint(value, 10, 16); err != nil {
+			return fmt.Errorf("could not parse provided numTargets: %v", err)
+		}
+	default:
+		return fmt.Errorf("CreateVolume parameter invalid: %s", key)
+	}
+	return nil
+}
+
+// ParsePermissionsParameter parses value for the permissions/* parameter key. UIDs and GIDs are decimal and at most 32
+// bits. Modes are octal and at most 12 bits.
+func ParsePermissionsParameter(key, value string) (uint64, error) {
+	switch key {
+	case ParameterPermissionsUID:
+		uid, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse provided UID: %v", err)
+		}
+		return uid, nil
+	case ParameterPermissionsGID:
+		gid, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse provided GID: %v", err)
+		}
+		return gid, nil
+	case ParameterPermissionsMode:
+		mode, err := strconv.ParseUint(value, 8, 12)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse provided mode: %v", err)
+		}
+		return mode, nil
+	default:
+		return 0, fmt.Errorf("CreateVolume parameter invalid: %s", key)
+	}
+}
+
+// ParseRetentionPeriodParameter parses value for the retentionPeriod parameter key. A retention period is a
+// non-negative duration (e.g. "168h"). A retention period of zero disables retention.
+func ParseRetentionPeriodParameter(value string) (time.Duration, error) {
+	retentionPeriod, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse provided retentionPeriod: %v", err)
+	}
+	if retentionPeriod < 0 {
+		return 0, fmt.Errorf("retentionPeriod %s is negative", value)
+	}
+	return retentionPeriod, nil
+}
+
+// ExpandVolDirTemplate replaces each ${variable} in a volDirTemplate with its value from values and returns the
+// resulting path (relative to volDirBasePath). An error is returned if the template references an unknown variable, if
+// a referenced value is missing, or if the resulting path is not a clean relative path below volDirBasePath.
+func ExpandVolDirTemplate(template string, values map[string]string) (string, error) {
+	var expandErr error
+	expanded := volDirTemplateVarRegex.ReplaceAllStringFunc(template, func(match string) string {
+		name := volDirTemplateVarRegex.FindStringSubmatch(match)[1]
+		switch name {
+		case VolDirTemplateVarPVCNamespace, VolDirTemplateVarPVCName, VolDirTemplateVarPVName:
+		default:
+			if expandErr == nil {
+				expandErr = fmt.Errorf("volDirTemplate %s references unknown variable %s", template, match)
+			}
+			return ""
+		}
+		if values[name] == "" && expandErr == nil {
+			expandErr = fmt.Errorf("volDirTemplate %s references %s but no value was provided (is the "+
+				"csi-provisioner running with --extra-create-metadata?)", template, match)
+		}
+		return values[name]
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	if strings.Contains(expanded, "$") {
+		return "", fmt.Errorf("volDirTemplate %s contains a \"$\" that is not part of a ${variable}", template)
+	}
+	if expanded == "" || path.IsAbs(expanded) || path.Clean(expanded) != expanded {
+		return "", fmt.Errorf("volDirTemplate %s does not expand to a clean relative path: %q", template, expanded)
+	}
+	for _, element := range strings.Split(expanded, "/") {
+		if element == "." || element == ".." || element == ".csi" {
+			return "", fmt.Errorf("volDirTemplate %s expands to a path with a reserved element %s: %s", template,
+				element, expanded)
+		}
+	}
+	return expanded, nil
+}
+
+// ValidateBasePathParameter returns an error if value is not valid for the basePath/* parameter key. The rules for
+// basePath/stripePattern/* and basePath/permissions/* are those for stripePattern/* and permissions/*.
+func ValidateBasePathParameter(key, value string) error {
+	parentKey := strings.TrimPrefix(key, BasePathParameterPrefix)
+	switch {
+	case strings.HasPrefix(parentKey, "stripePattern/"):
+		return ValidateStripePatternParameter(parentKey, value)
+	case strings.HasPrefix(parentKey, "permissions/"):
+		_, err := ParsePermissionsParameter(parentKey, value)
+		return err
+	default:
+		return fmt.Errorf("CreateVolume parameter invalid: %s", key)
+	}
+}
+
+// ValidateStorageClassParameters returns an error if the driver would reject a CreateVolume request with the
+// parameters of a StorageClass: sysMgmtdHost and volDirBasePath are required, stripePattern/*, permissions/*,
+// basePath/*, retentionPeriod, and volDirTemplate parameters must be valid, and no other parameters are allowed.
+// Parameters reserved for the external-provisioner are ignored.
+func ValidateStorageClassParameters(params map[string]string) error {
+	if params[ParameterSysMgmtdHost] == "" {
+		return fmt.Errorf("sysMgmtdHost not provided")
+	}
+	if _, ok := params[ParameterVolDirBasePath]; !ok {
+		return fmt.Errorf("volDirBasePath not provided")
+	}
+	// Sort the keys so the same invalid parameters always result in the same error.
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch {
+		case key == ParameterSysMgmtdHost || key == ParameterVolDirBasePath:
+			continue
+		case strings.HasPrefix(key, reservedParameterPrefix):
+			continue
+		case strings.HasPrefix(key, BasePathParameterPrefix):
+			if err := ValidateBasePathParameter(key, params[key]); err != nil {
+				return err
+			}
+		case strings.Contains(key, "stripePattern/"):
+			if err := ValidateStripePatternParameter(key, params[key]); err != nil {
+				return err
+			}
+		case strings.HasPrefix(key, "permissions/"):
+			if _, err := ParsePermissionsParameter(key, params[key]); err != nil {
+				return err
+			}
+		case key == ParameterRetentionPeriod:
+			if _, err := ParseRetentionPeriodParameter(params[key]); err != nil {
+				return err
+			}
+		case key == ParameterVolDirTemplate:
+			if err := ValidateVolDirTemplateParameter(params[key]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("CreateVolume parameter invalid: %s", key)
+		}
+	}
+	return nil
+}
+
+// ValidateVolDirTemplateParameter returns an error if value is not a valid volDirTemplate. The template is expanded
+// with placeholder values, so errors that depend on actual PVC names (e.g. a PVC named "..") are not detected.
+func ValidateVolDirTemplateParameter(value string) error {
+	_, err := ExpandVolDirTemplate(value, map[string]string{
+		VolDirTemplateVarPVCNamespace: "namespace",
+		VolDirTemplateVarPVCName:      "name",
+		VolDirTemplateVarPVName:       "pv",
+	})
+	return err
+}
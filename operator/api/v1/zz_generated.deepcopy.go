@@ -21,8 +21,11 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -131,6 +134,18 @@ func (in *BeegfsDriverSpec) DeepCopyInto(out *BeegfsDriverSpec) {
 	*out = *in
 	out.ContainerImageOverrides = in.ContainerImageOverrides
 	in.ContainerResourceOverrides.DeepCopyInto(&out.ContainerResourceOverrides)
+	if in.FileSystemSecretRefs != nil {
+		in, out := &in.FileSystemSecretRefs, &out.FileSystemSecretRefs
+		*out = make([]FileSystemSecretRefs, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HealthPortNodeService != nil {
+		in, out := &in.HealthPortNodeService, &out.HealthPortNodeService
+		*out = new(int32)
+		**out = **in
+	}
 	if in.LogLevel != nil {
 		in, out := &in.LogLevel, &out.LogLevel
 		*out = new(int)
@@ -139,6 +154,15 @@ func (in *BeegfsDriverSpec) DeepCopyInto(out *BeegfsDriverSpec) {
 	in.NodeAffinityControllerService.DeepCopyInto(&out.NodeAffinityControllerService)
 	in.NodeAffinityNodeService.DeepCopyInto(&out.NodeAffinityNodeService)
 	in.PluginConfigFromFile.DeepCopyInto(&out.PluginConfigFromFile)
+	in.PodTemplateOverrides.DeepCopyInto(&out.PodTemplateOverrides)
+	if in.StorageClassTemplates != nil {
+		in, out := &in.StorageClassTemplates, &out.StorageClassTemplates
+		*out = make([]StorageClassTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.UpgradeStrategy.DeepCopyInto(&out.UpgradeStrategy)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BeegfsDriverSpec.
@@ -161,6 +185,33 @@ func (in *BeegfsDriverStatus) DeepCopyInto(out *BeegfsDriverStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.FileSystems != nil {
+		in, out := &in.FileSystems, &out.FileSystems
+		*out = make([]FileSystemStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnreadyNodes != nil {
+		in, out := &in.UnreadyNodes, &out.UnreadyNodes
+		*out = make([]NodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ContainerImageStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InvalidStorageClasses != nil {
+		in, out := &in.InvalidStorageClasses, &out.InvalidStorageClasses
+		*out = make([]StorageClassStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BeegfsDriverStatus.
@@ -223,9 +274,38 @@ func (in *ContainerImageOverrides) DeepCopy() *ContainerImageOverrides {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerImageStatus) DeepCopyInto(out *ContainerImageStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerImageStatus.
+func (in *ContainerImageStatus) DeepCopy() *ContainerImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerResourceOverrides) DeepCopyInto(out *ContainerResourceOverrides) {
 	*out = *in
+	if in.ControllerContainers != nil {
+		in, out := &in.ControllerContainers, &out.ControllerContainers
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.NodeContainers != nil {
+		in, out := &in.NodeContainers, &out.NodeContainers
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	in.ControllerBeegfsResources.DeepCopyInto(&out.ControllerBeegfsResources)
 	in.ControllerCsiProvisionerResources.DeepCopyInto(&out.ControllerCsiProvisionerResources)
 	in.NodeBeegfsResources.DeepCopyInto(&out.NodeBeegfsResources)
@@ -243,10 +323,74 @@ func (in *ContainerResourceOverrides) DeepCopy() *ContainerResourceOverrides {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerTemplateOverride) DeepCopyInto(out *ContainerTemplateOverride) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]corev1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerTemplateOverride.
+func (in *ContainerTemplateOverride) DeepCopy() *ContainerTemplateOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerTemplateOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileSystemSecretRefs) DeepCopyInto(out *FileSystemSecretRefs) {
+	*out = *in
+	if in.ConnAuthSecretRef != nil {
+		in, out := &in.ConnAuthSecretRef, &out.ConnAuthSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSCertSecretRef != nil {
+		in, out := &in.TLSCertSecretRef, &out.TLSCertSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSystemSecretRefs.
+func (in *FileSystemSecretRefs) DeepCopy() *FileSystemSecretRefs {
+	if in == nil {
+		return nil
+	}
+	out := new(FileSystemSecretRefs)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FileSystemSpecificConfig) DeepCopyInto(out *FileSystemSpecificConfig) {
 	*out = *in
 	in.Config.DeepCopyInto(&out.Config)
+	if in.AllowedVolDirBasePaths != nil {
+		in, out := &in.AllowedVolDirBasePaths, &out.AllowedVolDirBasePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedVolDirBasePaths != nil {
+		in, out := &in.DeniedVolDirBasePaths, &out.DeniedVolDirBasePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSystemSpecificConfig.
@@ -259,6 +403,22 @@ func (in *FileSystemSpecificConfig) DeepCopy() *FileSystemSpecificConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileSystemStatus) DeepCopyInto(out *FileSystemStatus) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSystemStatus.
+func (in *FileSystemStatus) DeepCopy() *FileSystemStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FileSystemStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSpecificConfig) DeepCopyInto(out *NodeSpecificConfig) {
 	*out = *in
@@ -267,6 +427,11 @@ func (in *NodeSpecificConfig) DeepCopyInto(out *NodeSpecificConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.DefaultConfig.DeepCopyInto(&out.DefaultConfig)
 	if in.FileSystemSpecificConfigs != nil {
 		in, out := &in.FileSystemSpecificConfigs, &out.FileSystemSpecificConfigs
@@ -287,6 +452,21 @@ func (in *NodeSpecificConfig) DeepCopy() *NodeSpecificConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
+func (in *NodeStatus) DeepCopy() *NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PluginConfig) DeepCopyInto(out *PluginConfig) {
 	*out = *in
@@ -298,6 +478,16 @@ func (in *PluginConfig) DeepCopyInto(out *PluginConfig) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AllowedVolDirBasePaths != nil {
+		in, out := &in.AllowedVolDirBasePaths, &out.AllowedVolDirBasePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedVolDirBasePaths != nil {
+		in, out := &in.DeniedVolDirBasePaths, &out.DeniedVolDirBasePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginConfig.
@@ -333,6 +523,144 @@ func (in *PluginConfigFromFile) DeepCopy() *PluginConfigFromFile {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodTemplateOverride) DeepCopyInto(out *PodTemplateOverride) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]ContainerTemplateOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]corev1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodTemplateOverride.
+func (in *PodTemplateOverride) DeepCopy() *PodTemplateOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(PodTemplateOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodTemplateOverrides) DeepCopyInto(out *PodTemplateOverrides) {
+	*out = *in
+	in.ControllerService.DeepCopyInto(&out.ControllerService)
+	in.NodeService.DeepCopyInto(&out.NodeService)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodTemplateOverrides.
+func (in *PodTemplateOverrides) DeepCopy() *PodTemplateOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(PodTemplateOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageClassStatus) DeepCopyInto(out *StorageClassStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageClassStatus.
+func (in *StorageClassStatus) DeepCopy() *StorageClassStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageClassStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageClassTemplate) DeepCopyInto(out *StorageClassTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ReclaimPolicy != nil {
+		in, out := &in.ReclaimPolicy, &out.ReclaimPolicy
+		*out = new(corev1.PersistentVolumeReclaimPolicy)
+		**out = **in
+	}
+	if in.AllowVolumeExpansion != nil {
+		in, out := &in.AllowVolumeExpansion, &out.AllowVolumeExpansion
+		*out = new(bool)
+		**out = **in
+	}
+	if in.VolumeBindingMode != nil {
+		in, out := &in.VolumeBindingMode, &out.VolumeBindingMode
+		*out = new(storagev1.VolumeBindingMode)
+		**out = **in
+	}
+	if in.MountOptions != nil {
+		in, out := &in.MountOptions, &out.MountOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageClassTemplate.
+func (in *StorageClassTemplate) DeepCopy() *StorageClassTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageClassTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLSCertConfig) DeepCopyInto(out *TLSCertConfig) {
 	*out = *in
@@ -347,3 +675,54 @@ func (in *TLSCertConfig) DeepCopy() *TLSCertConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.PreviousImages != nil {
+		in, out := &in.PreviousImages, &out.PreviousImages
+		*out = make([]ContainerImageStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetImages != nil {
+		in, out := &in.TargetImages, &out.TargetImages
+		*out = make([]ContainerImageStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStrategy) DeepCopyInto(out *UpgradeStrategy) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStrategy.
+func (in *UpgradeStrategy) DeepCopy() *UpgradeStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -18,9 +18,16 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	deploy "github.com/netapp/beegfs-csi-driver/deploy/k8s"
@@ -35,9 +42,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/yaml"
 )
 
@@ -46,43 +59,81 @@ import (
 // cluster-scoped resources before garbage collection occurs.
 const finalizerClusterResourceDeletion = "beegfs.csi.netapp.com/clusterResourceDeletion"
 
+// Cluster-scoped objects are shared by every BeegfsDriver with the same driver name. This annotation records the
+// <namespace>/<name> of the BeegfsDriver that created an object so that a BeegfsDriver never updates or deletes an object
+// that belongs to another BeegfsDriver.
+const annotationOwner = "beegfs.csi.netapp.com/owner"
+
+// The operator labels the StorageClasses it creates from storageClassTemplates with the driverName of their
+// BeegfsDriver so that it can find (and delete) those that are no longer specified.
+const labelDriverName = "beegfs.csi.netapp.com/driver-name"
+
+// fieldOwner is the field manager the operator uses to server-side apply the objects it deploys.
+const fieldOwner = "beegfs-csi-driver-operator"
+
+// The operator records a hash of the desired state of each object it applies in this annotation. If applying an object
+// changes it even though its desired state has not changed, the object was modified outside of the operator.
+const annotationDesiredStateHash = "beegfs.csi.netapp.com/desiredStateHash"
+
 // BeegfsDriverReconciler reconciles a BeegfsDriver object
 type BeegfsDriverReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	// APIReader reads directly from the Kubernetes API server. Use it to read objects (like Pods) that we do not want
+	// to cache cluster-wide.
+	APIReader client.Reader
+	Log       logr.Logger
+	Recorder  record.EventRecorder
+	Scheme    *runtime.Scheme
 }
 
+// statusRefreshInterval is how often we reconcile a BeegfsDriver even if nothing we watch changes. The controller
+// service reports file system status by annotating its Pod, and we do not watch Pods.
+const statusRefreshInterval = time.Minute
+
+// defaultProgressDeadlineSeconds is how long an updated node service Pod may remain unready before a rollout is
+// considered failed if the BeegfsDriver's upgradeStrategy does not specify progressDeadlineSeconds.
+const defaultProgressDeadlineSeconds = 600
+
 //+kubebuilder:rbac:groups=beegfs.csi.netapp.com,resources=beegfsdrivers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=beegfs.csi.netapp.com,resources=beegfsdrivers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=beegfs.csi.netapp.com,resources=beegfsdrivers/finalizers,verbs=update
 
 // The operator must have the following permissions to deploy the driver.
-//+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;create;delete;update
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=get;list;watch;create;delete
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;delete
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;delete
-//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create
-//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create
-//+kubebuilder:rbac:groups=storage.k8s.io,resources=csidrivers,verbs=get;list;watch;create;delete
-//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update
-//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;create;delete;update;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=get;list;watch;create;delete;update;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;delete;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;delete;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;patch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=csidrivers,verbs=get;list;watch;create;delete;update;patch
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=security.openshift.io,resources=securitycontextconstraints,resourceNames=privileged,verbs=use
 
 // The operator must have the following permissions in order to grant them to the driver.
 //+kubebuilder:rbac:groups=core,resources=persistentvolumes,verbs=get;list;watch;create;delete;patch
 //+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;update;patch
-//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch;create;update;delete
 //+kubebuilder:rbac:groups=core,resources=events,verbs=list;watch;create;update;patch
 //+kubebuilder:rbac:groups=storage.k8s.io,resources=csinodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
-//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch
 //+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims/status,verbs=patch
 
 // Reconcile is part of the main Kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.reconcile(ctx, req)
+	if err != nil {
+		reconcileErrorsTotal.WithLabelValues(req.Namespace, req.Name, getErrorKind(err)).Inc()
+	}
+	return result, err
+}
+
+// reconcile does the work of Reconcile. Errors that occur while reconciling an object other than the BeegfsDriver
+// itself are wrapped with withKind.
+func (r *BeegfsDriverReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("beegfsDriver", req.NamespacedName)
 	log.Info("Reconciling")
 
@@ -92,6 +143,7 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if errors.IsNotFound(err) {
 			// Request object not found and could have been deleted after reconcile request. Return and don't requeue.
 			log.Info("BeegfsDriver resource not found. It has probably already been deleted.")
+			deleteDriverMetrics(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		// Failed to read request object. Requeue and try again.
@@ -105,6 +157,11 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	rbacInterfaces, _ := deploy.GetRBAC()
 	d, _ := deploy.GetCSIDriver()
 
+	// Derive the names of cluster-scoped objects from the driver name so that multiple BeegfsDrivers with different
+	// driver names can coexist.
+	driverName := getDriverName(driver)
+	setClusterScopedObjectMetadata(req, driverName, rbacInterfaces, d)
+
 	// -----------------------------------------------------------------------------------------------------------------
 	// Start by getting everything we need to generate an up-to-date status, generating the up-to-date status, and
 	// pushing the up-to-date status to the Kubernetes API server.
@@ -117,7 +174,7 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			// Something we aren't prepared for went wrong.
-			return ctrl.Result{}, err
+			return ctrl.Result{}, withKind("StatefulSet", err)
 		}
 		// We didn't find a Stateful Set.
 		statusCondition = metav1.Condition{
@@ -158,7 +215,7 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			// Something we aren't prepared for went wrong.
-			return ctrl.Result{}, err
+			return ctrl.Result{}, withKind("DaemonSet", err)
 		}
 		statusCondition = metav1.Condition{
 			Type:    beegfsv1.ConditionNodeServiceReady,
@@ -195,6 +252,21 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 	meta.SetStatusCondition(&driver.Status.Conditions, statusCondition)
 
+	desiredImages := getDesiredImages(log, driver)
+	if err = r.setDetailedStatus(ctx, log, req.Namespace, driver, stsFromCluster, dsFromCluster,
+		desiredImages); err != nil {
+		return ctrl.Result{}, withKind("Pod", err)
+	}
+	setDriverMetrics(driver, stsFromCluster, dsFromCluster)
+
+	// StorageClasses are only created from templates that are valid and do not conflict with existing StorageClasses.
+	var storageClasses []*storagev1.StorageClass
+	if storageClasses, driver.Status.InvalidStorageClasses, err = r.getStorageClasses(ctx, req, driverName,
+		driver.Spec.StorageClassTemplates); err != nil {
+		return ctrl.Result{}, withKind("StorageClass", err)
+	}
+	setStorageClassesCondition(driver)
+
 	// Don't bother the Kubernetes API server unless we think the status has changed.
 	if !equality.Semantic.DeepEqual(driver.Status, oldStatus) {
 		log.Info("Updating status")
@@ -207,6 +279,9 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	// -----------------------------------------------------------------------------------------------------------------
 	// Handle finalizers, either by adding them if they are missing or executing on them if the CR is being deleted.
 
+	// We add our finalizer before we deploy the driver for the first time, so any expected object that is missing from
+	// a BeegfsDriver that already has it was deleted outside of the operator.
+	deployed := containsString(driver.GetFinalizers(), finalizerClusterResourceDeletion)
 	if driver.ObjectMeta.DeletionTimestamp.IsZero() {
 		// The CR is not being deleted. Let's add our finalizer and update it if necessary.
 		if !containsString(driver.GetFinalizers(), finalizerClusterResourceDeletion) {
@@ -225,16 +300,19 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			for _, i := range rbacInterfaces {
 				switch object := i.(type) {
 				case *rbacv1.ClusterRole:
-					if err = r.Delete(ctx, object); err != nil && !errors.IsNotFound(err) {
+					if err = r.deleteClusterScopedObject(ctx, log, req, object); err != nil {
 						return ctrl.Result{}, err
 					}
 				case *rbacv1.ClusterRoleBinding:
-					if err = r.Delete(ctx, object); err != nil && !errors.IsNotFound(err) {
+					if err = r.deleteClusterScopedObject(ctx, log, req, object); err != nil {
 						return ctrl.Result{}, err
 					}
 				}
 			}
-			if err = r.Delete(ctx, d); err != nil && !errors.IsNotFound(err) {
+			if err = r.deleteClusterScopedObject(ctx, log, req, d); err != nil {
+				return ctrl.Result{}, err
+			}
+			if err = r.deleteStorageClasses(ctx, log, req, driverName, nil); err != nil {
 				return ctrl.Result{}, err
 			}
 			controllerutil.RemoveFinalizer(driver, finalizerClusterResourceDeletion)
@@ -242,6 +320,7 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 				return ctrl.Result{}, err
 			}
 		}
+		deleteDriverMetrics(req.NamespacedName)
 		// There is no point in continuing to reconcile a deleting CR.
 		return ctrl.Result{}, nil
 	}
@@ -249,288 +328,206 @@ func (r *BeegfsDriverReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	// -----------------------------------------------------------------------------------------------------------------
 	// Now attempt to get the rest of the expected objects and push them to the Kubernetes API server as necessary.
 
-	// Completely recreate the Config Map to ensure all fields specified in the deployment manifest are propagated.
+	// Every object is completely recreated and server-side applied to ensure all fields specified in the deployment
+	// manifests are propagated and any modifications made to those fields outside of the operator are reverted. See
+	// applyObject for details.
+
 	var cm *corev1.ConfigMap
 	if cm, err = newConfigMap(driver); err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, withKind("ConfigMap", err)
 	}
 	if err = r.setCommonObjectMetadata(req, driver, cm); err != nil {
 		return ctrl.Result{}, err
 	}
-	cmFromCluster := new(corev1.ConfigMap)
-	err = r.Get(ctx, types.NamespacedName{Name: cm.Name, Namespace: req.Namespace}, cmFromCluster)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-		}
-		// The Config Map doesn't exist. Let's create it.
-		log.Info("Creating Config Map")
-		if err = r.Create(ctx, cm); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else if !equality.Semantic.DeepEqual(cm.Data, cmFromCluster.Data) {
-		// The Config Map needs to be updated.
-		log.Info("Updating Config Map")
-		if err = r.Update(ctx, cm); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else {
-		cm = cmFromCluster // We need the correct resourceVersion later on.
+	if err = r.applyObject(ctx, log, req, driver, deployed, cm); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// A connauth Secret named "csi-beegfs-connauth" in the operator's namespace is required for driver operation. If
 	// it does not exist, we create it, own it, and garbage collect it. If it already exists (pre-created by an
-	// administrator) we do nothing.
+	// administrator) we do nothing unless the BeegfsDriver references Secrets to assemble it from.
+	var connAuthData []byte
+	if connAuthData, err = r.assembleConnAuthFile(ctx, req.Namespace, driver.Spec.FileSystemSecretRefs); err != nil {
+		return ctrl.Result{}, withKind("Secret", err)
+	}
 	s := newSecret()
+	if connAuthData != nil {
+		s.Data[deploy.KeyNameSecret] = connAuthData
+	}
 	if err = r.setCommonObjectMetadata(req, driver, s); err != nil {
 		return ctrl.Result{}, err
 	}
-	sFromCluster := new(corev1.Secret)
-	err = r.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: req.Namespace}, sFromCluster)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-		}
-		// The Secret doesn't exist. Let's create it.
-		log.Info("Creating Secret", "ResourceName", s.Name)
-		if err = r.Create(ctx, s); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else {
-		// Many of the other objects created by this controller may need to be updated to keep them in sync with the
-		// CRD. We expect the Secret to be updated manually and have no meaningful changes to make here.
-		s = sFromCluster // We need the correct resourceVersion later on.
+	if s, err = r.applySecret(ctx, log, req, driver, deployed, s, connAuthData != nil); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// A TLS secret named "csi-beegfs-tlscerts" in the operator's namespace is required for driver
 	// operation. If it does not exist, we create it, own it, and garbage collect it. If it already
-	// exists (pre-created by an administrator) we do nothing.
+	// exists (pre-created by an administrator) we do nothing unless the BeegfsDriver references
+	// Secrets to assemble it from.
+	var tlsCertsData []byte
+	if tlsCertsData, err = r.assembleTLSCertsFile(ctx, req.Namespace, driver.Spec.FileSystemSecretRefs); err != nil {
+		return ctrl.Result{}, withKind("Secret", err)
+	}
 	t := newTLS()
+	if tlsCertsData != nil {
+		t.Data[deploy.KeyNameTLS] = tlsCertsData
+	}
 	if err = r.setCommonObjectMetadata(req, driver, t); err != nil {
 		return ctrl.Result{}, err
 	}
-	tFromCluster := new(corev1.Secret)
-	err = r.Get(ctx, types.NamespacedName{Name: t.Name, Namespace: req.Namespace}, tFromCluster)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-		}
-		// The Secret doesn't exist. Let's create it.
-		log.Info("Creating Secret", "ResourceName", t.Name)
-		if err = r.Create(ctx, t); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else {
-		// Many of the other objects created by this controller may need to be updated to keep them in sync with the
-		// CRD. We expect the Secret to be updated manually and have no meaningful changes to make here.
-		t = tFromCluster // We need the correct resourceVersion later on.
+	if t, err = r.applySecret(ctx, log, req, driver, deployed, t, tlsCertsData != nil); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// There are some number of RBAC objects from the deployment manifests on the cluster. We do not hard code
 	// the exact nature of these objects here.
 	for _, i := range rbacInterfaces {
 		switch object := i.(type) {
-		// Completely recreate the Service Account to ensure all fields specified in the deployment manifests are
-		// propagated.
 		case *corev1.ServiceAccount:
 			if err = r.setCommonObjectMetadata(req, driver, object); err != nil {
 				return ctrl.Result{}, err
 			}
-			saFromCluster := new(corev1.ServiceAccount)
-			err = r.Get(ctx, types.NamespacedName{Name: object.Name, Namespace: req.Namespace}, saFromCluster)
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-				}
-				// The Service Account doesn't exist. Let's create it.
-				log.Info("Creating Service Account", "name", object.Name)
-				if err = r.Create(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
-			} else {
-				// Intentionally empty.
-				// We do not monitor and continuously update Service Accounts because they don't have any useful fields.
+			if err = r.applyObject(ctx, log, req, driver, deployed, object); err != nil {
+				return ctrl.Result{}, err
 			}
 
 		case *rbacv1.ClusterRole:
-			// Completely recreate the Cluster Role to ensure all fields specified in the deployment manifests are
-			// propagated. Don't call setCommonObjectMetadata because this is a cluster-scoped object (it doesn't have a
-			// namespace and our namespace-scoped CRD can't own it).
-			crFromCluster := new(rbacv1.ClusterRole)
-			err = r.Get(ctx, types.NamespacedName{Name: object.Name}, crFromCluster)
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-				}
-				// The Cluster Role doesn't exist. Let's create it.
-				log.Info("Creating Cluster Role", "name", object.Name)
-				if err = r.Create(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
-			} else if !equality.Semantic.DeepEqual(object.Rules, crFromCluster.Rules) {
-				// The Cluster Role on the cluster needs to be updated.
-				log.Info("Updating Cluster Role", "name", object.Name)
-				if err = r.Update(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
+			// Don't call setCommonObjectMetadata because this is a cluster-scoped object (it doesn't have a namespace
+			// and our namespace-scoped CRD can't own it).
+			if err = r.applyObject(ctx, log, req, driver, deployed, object); err != nil {
+				return ctrl.Result{}, err
 			}
 
 		case *rbacv1.ClusterRoleBinding:
-			// Completely recreate the Cluster Role Binding to ensure all fields specified in the deployment manifests
-			// are propagated. Don't call setCommonObjectMetadata because this is a cluster-scoped object (it doesn't
-			// have a namespace and our namespace-scoped CRD can't own it).
+			// Don't call setCommonObjectMetadata because this is a cluster-scoped object (it doesn't have a namespace
+			// and our namespace-scoped CRD can't own it).
 			for j := range object.Subjects {
 				object.Subjects[j].Namespace = req.Namespace
 			}
-			crbFromCluster := new(rbacv1.ClusterRoleBinding)
-			err = r.Get(ctx, types.NamespacedName{Name: object.Name}, crbFromCluster)
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-				}
-				// The Cluster Role Binding doesn't exist. Let's create it.
-				log.Info("Creating Cluster Role Binding", "name", object.Name)
-				if err = r.Create(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
-			} else if !equality.Semantic.DeepEqual(object.Subjects, crbFromCluster.Subjects) ||
-				!equality.Semantic.DeepEqual(object.RoleRef, crbFromCluster.RoleRef) {
-				// The Cluster Role Binding on the cluster needs to be updated.
-				log.Info("Updating Cluster Role Binding", "name", object.Name)
-				if err = r.Update(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
+			if err = r.applyObject(ctx, log, req, driver, deployed, object); err != nil {
+				return ctrl.Result{}, err
 			}
 
 		case *rbacv1.Role:
-			// Completely recreate the Role o ensure all fields specified in the deployment manifest are propagated.
 			if err = r.setCommonObjectMetadata(req, driver, object); err != nil {
 				return ctrl.Result{}, err
 			}
-			rFromCluster := new(rbacv1.Role)
-			err = r.Get(ctx, types.NamespacedName{Name: object.Name, Namespace: req.Namespace}, rFromCluster)
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-				}
-				// The Role doesn't exist. Let's create it.
-				log.Info("Creating Role", "name", object.Name)
-				if err = r.Create(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
-			} else if !equality.Semantic.DeepEqual(object.Rules, rFromCluster.Rules) {
-				// The Cluster Role on the cluster needs to be updated.
-				log.Info("Updating Role", "name", object.Name)
-				if err = r.Update(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
+			if err = r.applyObject(ctx, log, req, driver, deployed, object); err != nil {
+				return ctrl.Result{}, err
 			}
 
 		case *rbacv1.RoleBinding:
-			// Completely recreate the Role Binding to ensure all fields specified in the deployment manifest are
-			// propagated.
 			if err = r.setCommonObjectMetadata(req, driver, object); err != nil {
 				return ctrl.Result{}, err
 			}
 			for j := range object.Subjects {
 				object.Subjects[j].Namespace = req.Namespace
 			}
-			crbFromCluster := new(rbacv1.RoleBinding)
-			err = r.Get(ctx, types.NamespacedName{Name: object.Name, Namespace: req.Namespace}, crbFromCluster)
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					return ctrl.Result{}, err // Something we aren't prepared for went wrong.
-				}
-				// The Role Binding doesn't exist. Let's create it.
-				log.Info("Creating Role Binding", "name", object.Name)
-				if err = r.Create(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
-			} else if !equality.Semantic.DeepEqual(object.Subjects, crbFromCluster.Subjects) ||
-				!equality.Semantic.DeepEqual(object.RoleRef, crbFromCluster.RoleRef) {
-				// The Role Binding on the cluster needs to be updated.
-				log.Info("Updating Role Binding", "name", object.Name)
-				if err = r.Update(ctx, object); err != nil {
-					return ctrl.Result{}, err
-				}
+			if err = r.applyObject(ctx, log, req, driver, deployed, object); err != nil {
+				return ctrl.Result{}, err
 			}
 		}
 	}
 
-	// Completely recreate the CSI Driver object to ensure all fields specified in the deployment manifest are
-	// propagated. Don't call setCommonObjectMetadata because this is a cluster-scoped object (it doesn't have a
-	// namespace and our namespace-scoped CRD can't own it).
-	dFromCluster := new(storagev1.CSIDriver)
-	err = r.Get(ctx, types.NamespacedName{Name: d.Name}, dFromCluster)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return ctrl.Result{}, err // Something we aren't prepared for went wrong.
+	// Don't call setCommonObjectMetadata because this is a cluster-scoped object (it doesn't have a namespace and our
+	// namespace-scoped CRD can't own it). Fields the API server drops (e.g. because a feature gate is disabled) are
+	// ignored by server-side apply.
+	err = r.applyObject(ctx, log, req, driver, deployed, d)
+	if errors.IsInvalid(err) {
+		// Most of the fields of the CSI Driver object are immutable, so it must be deleted and created again if one of
+		// them changed (e.g. after an operator upgrade). This does not affect existing Persistent Volumes.
+		log.Info("Recreating CSI Driver object", "name", d.Name)
+		if err = r.Delete(ctx, &storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: d.Name}}); err != nil &&
+			!errors.IsNotFound(err) {
+			return ctrl.Result{}, withKind("CSIDriver", err)
 		}
-		// The CSI Driver object doesn't exist. Let's create it.
-		log.Info("Creating CSI Driver object")
-		if err = r.Create(ctx, d); err != nil {
-			return ctrl.Result{}, err
+		err = r.applyObject(ctx, log, req, driver, false, d)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Completely recreate StorageClasses to ensure all fields specified in storageClassTemplates are propagated. Most of
+	// the fields of a StorageClass are immutable, so a StorageClass whose immutable fields changed must be deleted and
+	// created again. This does not affect existing Persistent Volumes.
+	for _, sc := range storageClasses {
+		scFromCluster := new(storagev1.StorageClass)
+		err = r.Get(ctx, types.NamespacedName{Name: sc.Name}, scFromCluster)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return ctrl.Result{}, withKind("StorageClass", err) // Something we aren't prepared for went wrong.
+			}
+			// The StorageClass doesn't exist. Let's create it.
+			log.Info("Creating StorageClass", "name", sc.Name)
+			if err = r.Create(ctx, sc); err != nil {
+				return ctrl.Result{}, withKind("StorageClass", err)
+			}
+		} else if storageClassNeedsRecreate(sc, scFromCluster) {
+			log.Info("Recreating StorageClass", "name", sc.Name)
+			if err = r.Delete(ctx, scFromCluster); err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, withKind("StorageClass", err)
+			}
+			if err = r.Create(ctx, sc); err != nil {
+				return ctrl.Result{}, withKind("StorageClass", err)
+			}
+		} else if storageClassNeedsUpdate(sc, scFromCluster) {
+			log.Info("Updating StorageClass", "name", sc.Name)
+			sc.ResourceVersion = scFromCluster.ResourceVersion
+			if err = r.Update(ctx, sc); err != nil {
+				return ctrl.Result{}, withKind("StorageClass", err)
+			}
 		}
-	} else {
-		// Intentionally empty.
-		// We do not monitor and continuously update the CSI Driver object for two reasons:
-		//   1. Feature gating makes it very difficult to determine whether the spec on the Kubernetes API server needs
-		//      to be updated. E.g., the API server spec shows fsGroupPolicy=nil even if we set fsGroupPolicy=true when
-		//      the CSIVolumeFSGroupPolicy feature gate is disabled.
-		//   2. Most of the fields in the spec are immutable.
-		// If we anticipate a need to change the CSI Driver object, we should advise users to delete it manually so the
-		// operator can recreate it.
+	}
+	// Delete the StorageClasses we created from templates that are no longer specified. Keep the StorageClasses of
+	// templates that are specified but invalid so that a typo does not remove a StorageClass workloads depend on.
+	keep := make(map[string]bool)
+	for _, template := range driver.Spec.StorageClassTemplates {
+		keep[template.Name] = true
+	}
+	if err = r.deleteStorageClasses(ctx, log, req, driverName, keep); err != nil {
+		return ctrl.Result{}, err
 	}
 
-	// Completely recreate the Stateful Set to ensure all fields specified in the deployment manifest are propagated.
 	if err = r.setCommonObjectMetadata(req, driver, sts); err != nil {
 		return ctrl.Result{}, err
 	}
 	setResourceVersionAnnotations(log, cm, s, t, &sts.Spec.Template)
 	setImages(log, sts.Spec.Template.Spec.Containers, driver.Spec.ContainerImageOverrides)
+	setRollbackImages(log, driver.Status.Upgrade, sts.Spec.Template.Spec.Containers)
 	setLogLevel(log, driver.Spec.LogLevel, sts.Spec.Template.Spec.Containers)
+	setDriverName(log, driverName, &sts.Spec.Template.Spec)
 	setNodeAffinity(log, &driver.Spec.NodeAffinityControllerService, &sts.Spec.Template.Spec)
+	if err = setPodTemplateOverrides(log, driver.Spec.PodTemplateOverrides.ControllerService,
+		&sts.Spec.Template); err != nil {
+		return ctrl.Result{}, withKind("StatefulSet", err)
+	}
 	setControllerResources(log, driver.Spec.ContainerResourceOverrides, sts.Spec.Template.Spec.Containers)
-	if meta.FindStatusCondition(driver.Status.Conditions, beegfsv1.ConditionControllerServiceReady).Reason ==
-		beegfsv1.ReasonServiceNotCreated {
-		// The Stateful Set doesn't exist. Let's create it.
-		log.Info("Creating controller service Stateful Set")
-		if err = r.Create(ctx, sts); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else if !equality.Semantic.DeepDerivative(sts.Spec, stsFromCluster.Spec) {
-		// The Stateful Set needs to be updated.
-		log.Info("Updating controller service Stateful Set")
-		if err = r.Update(ctx, sts); err != nil {
-			return ctrl.Result{}, err
-		}
+	if err = r.applyObject(ctx, log, req, driver, deployed, sts); err != nil {
+		return ctrl.Result{}, err
 	}
 
-	// Completely recreate the Daemon Set to ensure all fields specified in the deployment manifest are propagated.
 	if err = r.setCommonObjectMetadata(req, driver, ds); err != nil {
 		return ctrl.Result{}, err
 	}
 	setResourceVersionAnnotations(log, cm, s, t, &ds.Spec.Template)
 	setImages(log, ds.Spec.Template.Spec.Containers, driver.Spec.ContainerImageOverrides)
+	setRollbackImages(log, driver.Status.Upgrade, ds.Spec.Template.Spec.Containers)
+	setUpdateStrategy(log, driver.Spec.UpgradeStrategy, driver.Status.Upgrade, &ds.Spec)
 	setLogLevel(log, driver.Spec.LogLevel, ds.Spec.Template.Spec.Containers)
+	setDriverName(log, driverName, &ds.Spec.Template.Spec)
+	setHealthPort(log, driver.Spec.HealthPortNodeService, &ds.Spec.Template.Spec)
 	setNodeAffinity(log, &driver.Spec.NodeAffinityNodeService, &ds.Spec.Template.Spec)
+	if err = setPodTemplateOverrides(log, driver.Spec.PodTemplateOverrides.NodeService, &ds.Spec.Template); err != nil {
+		return ctrl.Result{}, withKind("DaemonSet", err)
+	}
 	setNodeResources(log, driver.Spec.ContainerResourceOverrides, ds.Spec.Template.Spec.Containers)
-	if meta.FindStatusCondition(driver.Status.Conditions, beegfsv1.ConditionNodeServiceReady).Reason ==
-		beegfsv1.ReasonServiceNotCreated {
-		// The Daemon Set doesn't exist. Let's create it.
-		log.Info("Creating node service Daemon Set")
-		if err = r.Create(ctx, ds); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else if !equality.Semantic.DeepDerivative(ds.Spec, dsFromCluster.Spec) {
-		// The Daemon Set needs to be updated.
-		log.Info("Updating controller service Daemon Set")
-		if err = r.Update(ctx, ds); err != nil {
-			return ctrl.Result{}, err
-		}
+	if err = r.applyObject(ctx, log, req, driver, deployed, ds); err != nil {
+		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	lastSuccessfulReconcile.set(req.NamespacedName, time.Now())
+	return ctrl.Result{RequeueAfter: statusRefreshInterval}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -542,9 +539,315 @@ func (r *BeegfsDriverReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&appsv1.DaemonSet{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		// Secrets referenced in fileSystemSecretRefs are not owned, but we must reassemble the connAuth and TLS
+		// certificate Secrets when they change (e.g. when they are rotated).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.findBeegfsDriversForSecret)).
+		// Cluster-scoped objects cannot be owned, but we must restore them when they are modified or deleted.
+		Watches(&rbacv1.ClusterRole{}, handler.EnqueueRequestsFromMapFunc(findBeegfsDriverForClusterScopedObject)).
+		Watches(&rbacv1.ClusterRoleBinding{}, handler.EnqueueRequestsFromMapFunc(findBeegfsDriverForClusterScopedObject)).
+		Watches(&storagev1.CSIDriver{}, handler.EnqueueRequestsFromMapFunc(findBeegfsDriverForClusterScopedObject)).
+		Watches(&storagev1.StorageClass{}, handler.EnqueueRequestsFromMapFunc(findBeegfsDriverForClusterScopedObject)).
 		Complete(r)
 }
 
+// findBeegfsDriversForSecret returns a reconcile.Request for each BeegfsDriver in the namespace of secret that
+// references it in fileSystemSecretRefs.
+func (r *BeegfsDriverReconciler) findBeegfsDriversForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	drivers := new(beegfsv1.BeegfsDriverList)
+	if err := r.List(ctx, drivers, client.InNamespace(secret.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list BeegfsDrivers", "secret", secret.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, driver := range drivers.Items {
+		for _, refs := range driver.Spec.FileSystemSecretRefs {
+			if (refs.ConnAuthSecretRef != nil && refs.ConnAuthSecretRef.Name == secret.GetName()) ||
+				(refs.TLSCertSecretRef != nil && refs.TLSCertSecretRef.Name == secret.GetName()) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Name: driver.Name, Namespace: driver.Namespace}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// findBeegfsDriverForClusterScopedObject returns a reconcile.Request for the BeegfsDriver recorded in the owner
+// annotation of a cluster-scoped object (if any).
+func findBeegfsDriverForClusterScopedObject(ctx context.Context, object client.Object) []reconcile.Request {
+	namespace, name, found := strings.Cut(object.GetAnnotations()[annotationOwner], "/")
+	if !found {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
+
+// setDetailedStatus populates the parts of a BeegfsDriver's status that go beyond the readiness conditions: the file
+// system status reported by the controller service, the nodes on which the node service is not ready, the images in
+// use, and the progress of a rollout of desiredImages. sts and ds are the Stateful Set and Daemon Set from the cluster
+// (or empty objects if they were not found).
+func (r *BeegfsDriverReconciler) setDetailedStatus(ctx context.Context, log logr.Logger, namespace string,
+	driver *beegfsv1.BeegfsDriver, sts *appsv1.StatefulSet, ds *appsv1.DaemonSet,
+	desiredImages []beegfsv1.ContainerImageStatus) error {
+	status := &driver.Status
+	status.Images, status.DriverImage = getImageStatus(sts, ds)
+
+	status.FileSystems = nil
+	status.FileSystemsReachable = ""
+	if sts.Name != "" && sts.Spec.Selector != nil {
+		pods := new(corev1.PodList)
+		if err := r.APIReader.List(ctx, pods, client.InNamespace(namespace),
+			client.MatchingLabels(sts.Spec.Selector.MatchLabels)); err != nil {
+			return err
+		}
+		status.FileSystems = getFileSystemStatus(log, pods.Items)
+		if status.FileSystems != nil {
+			numReachable := 0
+			for _, fsStatus := range status.FileSystems {
+				if fsStatus.Reachable {
+					numReachable++
+				}
+			}
+			status.FileSystemsReachable = fmt.Sprintf("%d/%d", numReachable, len(status.FileSystems))
+		}
+	}
+
+	status.NodesReady = ""
+	status.UnreadyNodes = nil
+	var nodePods []corev1.Pod
+	if ds.Name != "" && ds.Spec.Selector != nil {
+		status.NodesReady = fmt.Sprintf("%d/%d", ds.Status.NumberReady, ds.Status.DesiredNumberScheduled)
+		pods := new(corev1.PodList)
+		if err := r.APIReader.List(ctx, pods, client.InNamespace(namespace),
+			client.MatchingLabels(ds.Spec.Selector.MatchLabels)); err != nil {
+			return err
+		}
+		nodePods = pods.Items
+		status.UnreadyNodes = getUnreadyNodes(nodePods)
+	}
+
+	var upgradeCondition *metav1.Condition
+	status.Upgrade, upgradeCondition = getUpgradeStatus(status.Upgrade, driver.Spec.UpgradeStrategy, desiredImages,
+		status.Images, ds, nodePods, metav1.Now())
+	if upgradeCondition != nil {
+		oldCondition := meta.FindStatusCondition(status.Conditions, beegfsv1.ConditionUpgradeProgressing)
+		if oldCondition == nil || oldCondition.Reason != upgradeCondition.Reason {
+			log.Info("Upgrade progressed", "phase", status.Upgrade.Phase, "message", upgradeCondition.Message)
+		}
+		meta.SetStatusCondition(&status.Conditions, *upgradeCondition)
+	}
+	return nil
+}
+
+// getDesiredImages returns the images the operator deploys according to the spec of driver. During a rollback, the
+// operator deploys the previous images instead.
+func getDesiredImages(log logr.Logger, driver *beegfsv1.BeegfsDriver) []beegfsv1.ContainerImageStatus {
+	// Ignore potential errors because unit testing in the deploy package ensures they will not occur.
+	sts, _ := deploy.GetControllerServiceStatefulSet()
+	ds, _ := deploy.GetNodeServiceDaemonSet()
+	setImages(log, sts.Spec.Template.Spec.Containers, driver.Spec.ContainerImageOverrides)
+	setImages(log, ds.Spec.Template.Spec.Containers, driver.Spec.ContainerImageOverrides)
+	images, _ := getImageStatus(sts, ds)
+	return images
+}
+
+// getUpgradeStatus returns the progress of the rollout of desiredImages and an UpgradeProgressing condition describing
+// it (or nil if the condition should not change). upgrade is the previous progress (or nil), clusterImages are the
+// images specified by the Stateful Set and Daemon Set in the cluster, ds is the Daemon Set from the cluster (or an
+// empty object if it was not found), and nodePods are its Pods. A rollout that is complete or rolled back stays that
+// way until desiredImages changes.
+func getUpgradeStatus(upgrade *beegfsv1.UpgradeStatus, strategy beegfsv1.UpgradeStrategy,
+	desiredImages, clusterImages []beegfsv1.ContainerImageStatus, ds *appsv1.DaemonSet, nodePods []corev1.Pod,
+	now metav1.Time) (*beegfsv1.UpgradeStatus, *metav1.Condition) {
+	if ds.Name == "" {
+		// The node service has not been deployed, so there is nothing to roll out.
+		return upgrade, nil
+	}
+	if upgrade == nil || !equality.Semantic.DeepEqual(upgrade.TargetImages, desiredImages) {
+		if upgrade == nil && equality.Semantic.DeepEqual(clusterImages, desiredImages) {
+			return nil, nil // The images have not changed since the driver was deployed.
+		}
+		upgrade = &beegfsv1.UpgradeStatus{
+			PreviousImages: clusterImages,
+			TargetImages:   desiredImages,
+			StartTime:      now,
+			Phase:          beegfsv1.UpgradePhaseInProgress,
+		}
+	} else if upgrade.Phase == beegfsv1.UpgradePhaseComplete || upgrade.Phase == beegfsv1.UpgradePhaseRolledBack {
+		return upgrade, nil
+	} else {
+		upgrade = upgrade.DeepCopy()
+	}
+
+	deadline := time.Duration(defaultProgressDeadlineSeconds) * time.Second
+	if strategy.ProgressDeadlineSeconds != nil {
+		deadline = time.Duration(*strategy.ProgressDeadlineSeconds) * time.Second
+	}
+	numUpdatedReady := 0
+	var failedPod *corev1.Pod
+	for i := range nodePods {
+		pod := &nodePods[i]
+		if !runsImages(pod, upgrade.TargetImages) {
+			continue
+		}
+		if isPodReady(pod) {
+			numUpdatedReady++
+		} else if failedPod == nil && now.Sub(pod.CreationTimestamp.Time) > deadline {
+			failedPod = pod
+		}
+	}
+	numDesired := int(ds.Status.DesiredNumberScheduled)
+	upgrade.UpdatedNodesReady = fmt.Sprintf("%d/%d", numUpdatedReady, numDesired)
+
+	condition := &metav1.Condition{Type: beegfsv1.ConditionUpgradeProgressing, Status: metav1.ConditionFalse}
+	switch {
+	case failedPod != nil:
+		upgrade.Phase = beegfsv1.UpgradePhasePaused
+		condition.Message = fmt.Sprintf("paused because updated node service pod %s on node %s was not ready "+
+			"within %s", failedPod.Name, failedPod.Spec.NodeName, deadline)
+		if strategy.AutoRollback {
+			upgrade.Phase = beegfsv1.UpgradePhaseRolledBack
+			condition.Message = fmt.Sprintf("rolled back to the previous images because updated node service pod "+
+				"%s on node %s was not ready within %s", failedPod.Name, failedPod.Spec.NodeName, deadline)
+		}
+	case numUpdatedReady >= numDesired && ds.Status.UpdatedNumberScheduled >= ds.Status.DesiredNumberScheduled:
+		upgrade.Phase = beegfsv1.UpgradePhaseComplete
+		condition.Message = fmt.Sprintf("%s node service pods run the target images and are ready",
+			upgrade.UpdatedNodesReady)
+	default:
+		upgrade.Phase = beegfsv1.UpgradePhaseInProgress
+		condition.Status = metav1.ConditionTrue
+		condition.Message = fmt.Sprintf("%s node service pods run the target images and are ready",
+			upgrade.UpdatedNodesReady)
+	}
+	condition.Reason = upgrade.Phase
+	return upgrade, condition
+}
+
+// runsImages returns true if every container of pod that is named in images uses the corresponding image.
+func runsImages(pod *corev1.Pod, images []beegfsv1.ContainerImageStatus) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, image := range images {
+			if container.Name == image.Name && container.Image != image.Image {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// getImageStatus returns the image used by each uniquely named container in sts and ds as well as the image used by
+// the beegfs container.
+func getImageStatus(sts *appsv1.StatefulSet, ds *appsv1.DaemonSet) ([]beegfsv1.ContainerImageStatus, string) {
+	var images []beegfsv1.ContainerImageStatus
+	var driverImage string
+	seen := make(map[string]bool)
+	for _, containers := range [][]corev1.Container{sts.Spec.Template.Spec.Containers, ds.Spec.Template.Spec.Containers} {
+		for _, container := range containers {
+			if seen[container.Name] {
+				continue
+			}
+			seen[container.Name] = true
+			images = append(images, beegfsv1.ContainerImageStatus{Name: container.Name, Image: container.Image})
+			if container.Name == deploy.ContainerNameBeegfsCsiDriver {
+				driverImage = container.Image
+			}
+		}
+	}
+	return images, driverImage
+}
+
+// getFileSystemStatus returns the file system status reported by the controller service in the
+// beegfsv1.AnnotationFileSystemStatus annotation on one of pods. It prefers a ready Pod (there may briefly be more than
+// one controller service Pod during a rollout) and returns nil if no Pod has a valid annotation.
+func getFileSystemStatus(log logr.Logger, pods []corev1.Pod) []beegfsv1.FileSystemStatus {
+	sort.SliceStable(pods, func(i, j int) bool { return isPodReady(&pods[i]) && !isPodReady(&pods[j]) })
+	for _, pod := range pods {
+		annotation, ok := pod.Annotations[beegfsv1.AnnotationFileSystemStatus]
+		if !ok {
+			continue
+		}
+		var statuses []beegfsv1.FileSystemStatus
+		if err := json.Unmarshal([]byte(annotation), &statuses); err != nil {
+			log.Error(err, "Ignoring invalid file system status annotation", "pod", pod.Name)
+			continue
+		}
+		if statuses == nil {
+			statuses = []beegfsv1.FileSystemStatus{} // The driver reported, but it has no file systems to report on.
+		}
+		return statuses
+	}
+	return nil
+}
+
+// getUnreadyNodes returns a NodeStatus for each node with a node service Pod in pods that is not ready. A node service
+// Pod that is not yet scheduled is ignored, since it is not yet associated with a node.
+func getUnreadyNodes(pods []corev1.Pod) []beegfsv1.NodeStatus {
+	var unreadyNodes []beegfsv1.NodeStatus
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || isPodReady(pod) {
+			continue
+		}
+		nodeStatus := beegfsv1.NodeStatus{
+			NodeName: pod.Spec.NodeName,
+			Reason:   beegfsv1.ReasonPodNotReady,
+			Message:  fmt.Sprintf("pod %s is %s", pod.Name, pod.Status.Phase),
+		}
+		// Describe the first container that is not ready unless a container exited because the BeeGFS client kernel
+		// module is missing (the driver writes this to its termination log).
+		describedContainer := false
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.Ready {
+				continue
+			}
+			if terminatedWithMessage(containerStatus, beegfsv1.BeegfsClientModuleMissingMessage) {
+				nodeStatus.Reason = beegfsv1.ReasonBeegfsClientModuleMissing
+				nodeStatus.Message = beegfsv1.BeegfsClientModuleMissingMessage
+				break
+			}
+			if describedContainer {
+				continue
+			}
+			describedContainer = true
+			if waiting := containerStatus.State.Waiting; waiting != nil {
+				nodeStatus.Message = fmt.Sprintf("container %s in pod %s is waiting: %s", containerStatus.Name,
+					pod.Name, waiting.Reason)
+			} else {
+				nodeStatus.Message = fmt.Sprintf("container %s in pod %s is not ready", containerStatus.Name, pod.Name)
+			}
+		}
+		unreadyNodes = append(unreadyNodes, nodeStatus)
+	}
+	sort.Slice(unreadyNodes, func(i, j int) bool { return unreadyNodes[i].NodeName < unreadyNodes[j].NodeName })
+	return unreadyNodes
+}
+
+// terminatedWithMessage returns true if the current or previous instance of a container terminated with a termination
+// message that contains message.
+func terminatedWithMessage(containerStatus corev1.ContainerStatus, message string) bool {
+	for _, terminated := range []*corev1.ContainerStateTerminated{containerStatus.State.Terminated,
+		containerStatus.LastTerminationState.Terminated} {
+		if terminated != nil && strings.Contains(terminated.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPodReady returns true if the Ready condition of pod is true.
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
 // newConfigMap creates a new Config Map containing the configuration specified in the provided BeegfsDriver.
 func newConfigMap(driver *beegfsv1.BeegfsDriver) (*corev1.ConfigMap, error) {
 	const warning = "# This file is managed by the BeeGFS CSI driver operator. Do not modify it directly."
@@ -570,7 +873,7 @@ func newSecret() *corev1.Secret {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: deploy.ResourceNameSecret,
 		},
-		Data: map[string][]byte{deploy.KeyNameSecret: nil},
+		Data: map[string][]byte{deploy.KeyNameSecret: {}},
 	}
 	return s
 }
@@ -580,11 +883,225 @@ func newTLS() *corev1.Secret {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: deploy.ResourceNameTLS,
 		},
-		Data: map[string][]byte{deploy.KeyNameTLS: nil},
+		Data: map[string][]byte{deploy.KeyNameTLS: {}},
 	}
 	return s
 }
 
+// applySecret applies s if managed is true or it does not exist. Otherwise, we expect the Secret to be updated
+// manually (and leave it alone). It returns the Secret as it exists on the cluster (we need the correct
+// resourceVersion later on).
+func (r *BeegfsDriverReconciler) applySecret(ctx context.Context, log logr.Logger, req ctrl.Request,
+	driver *beegfsv1.BeegfsDriver, deployed bool, s *corev1.Secret, managed bool) (*corev1.Secret, error) {
+	if !managed {
+		sFromCluster := new(corev1.Secret)
+		err := r.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: s.Namespace}, sFromCluster)
+		if err == nil {
+			return sFromCluster, nil
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err // Something we aren't prepared for went wrong.
+		}
+	}
+	if err := r.applyObject(ctx, log, req, driver, deployed, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// applyObject server-side applies object (replacing it with the object as it exists on the cluster). Server-side apply
+// reverts modifications made outside of the operator to the fields we specify (and removes fields we specified
+// previously but no longer specify), but it leaves fields added by others alone. If applying object creates or changes
+// it even though its desired state has not changed since we last applied it (or the driver is already deployed and
+// object is missing), applyObject records the drift as an event on the BeegfsDriver and in the
+// beegfs_csi_operator_drift_corrections_total metric. applyObject never modifies a cluster-scoped object that belongs
+// to another BeegfsDriver.
+func (r *BeegfsDriverReconciler) applyObject(ctx context.Context, log logr.Logger, req ctrl.Request,
+	driver *beegfsv1.BeegfsDriver, deployed bool, object client.Object) error {
+	gvk, err := apiutil.GVKForObject(object, r.Scheme)
+	if err != nil {
+		return err
+	}
+	kind := gvk.Kind
+	object.GetObjectKind().SetGroupVersionKind(gvk) // Apply requests must specify apiVersion and kind.
+	object.SetResourceVersion("")
+	object.SetManagedFields(nil)
+	hash, err := getDesiredStateHash(object)
+	if err != nil {
+		return withKind(kind, err)
+	}
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[annotationDesiredStateHash] = hash
+	object.SetAnnotations(annotations)
+
+	// Read directly from the API server. An object from the cache may not reflect our most recent change yet.
+	newObject, err := r.Scheme.New(gvk)
+	if err != nil {
+		return withKind(kind, err)
+	}
+	fromCluster, ok := newObject.(client.Object)
+	if !ok {
+		return fmt.Errorf("expected a client.Object but got a %T", newObject)
+	}
+	found := true
+	if err = r.APIReader.Get(ctx, client.ObjectKeyFromObject(object), fromCluster); err != nil {
+		if !errors.IsNotFound(err) {
+			return withKind(kind, err) // Something we aren't prepared for went wrong.
+		}
+		found = false
+	} else if object.GetNamespace() == "" {
+		if err = checkClusterScopedObjectOwner(req, fromCluster); err != nil {
+			return withKind(kind, err)
+		}
+	}
+
+	if err = r.Patch(ctx, object, client.Apply, client.FieldOwner(fieldOwner), client.ForceOwnership); err != nil {
+		return withKind(kind, err)
+	}
+	switch {
+	case !found && deployed:
+		r.recordDrift(log, driver, kind, object.GetName(), "was deleted and has been recreated")
+	case !found:
+		log.Info("Created object", "kind", kind, "name", object.GetName())
+	case object.GetResourceVersion() == fromCluster.GetResourceVersion():
+		// Applying the object didn't change it.
+	case fromCluster.GetAnnotations()[annotationDesiredStateHash] == hash:
+		r.recordDrift(log, driver, kind, object.GetName(), "was modified and has been restored")
+	default:
+		log.Info("Updated object", "kind", kind, "name", object.GetName())
+	}
+	return nil
+}
+
+// getDesiredStateHash returns a hash of an object we intend to apply (ignoring any existing desired state hash
+// annotation).
+func getDesiredStateHash(object client.Object) (string, error) {
+	object = object.DeepCopyObject().(client.Object)
+	annotations := object.GetAnnotations()
+	delete(annotations, annotationDesiredStateHash)
+	object.SetAnnotations(annotations)
+	data, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// recordDrift reports that an object was modified or deleted outside of the operator and has been restored.
+func (r *BeegfsDriverReconciler) recordDrift(log logr.Logger, driver *beegfsv1.BeegfsDriver, kind, name,
+	message string) {
+	log.Info("Corrected drift", "kind", kind, "name", name)
+	driftCorrectionsTotal.WithLabelValues(driver.Namespace, kind).Inc()
+	if r.Recorder != nil {
+		r.Recorder.Eventf(driver, corev1.EventTypeWarning, reasonDriftCorrected, "%s %s %s", kind, name, message)
+	}
+}
+
+// connAuthFileEntry and tlsCertsFileEntry are used to write the connAuth and TLS certificate files consumed by the
+// driver. The types they are based on (intentionally) redact secrets when they are marshalled.
+type connAuthFileEntry beegfsv1.ConnAuthConfig
+type tlsCertsFileEntry beegfsv1.TLSCertConfig
+
+// assembleConnAuthFile returns the contents of a connAuth file assembled from the Secrets referenced by the
+// connAuthSecretRefs in refs. It returns nil if no connAuthSecretRef is specified. Each referenced value is base64
+// encoded so that the driver writes it to a connAuthFile exactly as it appears in its Secret.
+func (r *BeegfsDriverReconciler) assembleConnAuthFile(ctx context.Context, namespace string,
+	refs []beegfsv1.FileSystemSecretRefs) ([]byte, error) {
+	if !hasConnAuthSecretRef(refs) {
+		return nil, nil
+	}
+	entries := []connAuthFileEntry{} // An empty list is still a valid connAuth file.
+	for _, ref := range refs {
+		if ref.ConnAuthSecretRef == nil {
+			continue
+		}
+		connAuth, found, err := r.getSecretKeyValue(ctx, namespace, ref.ConnAuthSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connAuth for sysMgmtdHost %s: %w", ref.SysMgmtdHost, err)
+		}
+		if !found {
+			continue
+		}
+		entries = append(entries, connAuthFileEntry{
+			SysMgmtdHost: ref.SysMgmtdHost,
+			ConnAuth:     base64.StdEncoding.EncodeToString(connAuth),
+			Encoding:     "base64",
+		})
+	}
+	return yaml.Marshal(entries)
+}
+
+// assembleTLSCertsFile returns the contents of a TLS certificates file assembled from the Secrets referenced by the
+// tlsCertSecretRefs in refs. It returns nil if no tlsCertSecretRef is specified.
+func (r *BeegfsDriverReconciler) assembleTLSCertsFile(ctx context.Context, namespace string,
+	refs []beegfsv1.FileSystemSecretRefs) ([]byte, error) {
+	if !hasTLSCertSecretRef(refs) {
+		return nil, nil
+	}
+	entries := []tlsCertsFileEntry{} // An empty list is still a valid TLS certificates file.
+	for _, ref := range refs {
+		if ref.TLSCertSecretRef == nil {
+			continue
+		}
+		tlsCert, found, err := r.getSecretKeyValue(ctx, namespace, ref.TLSCertSecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TLS certificate for sysMgmtdHost %s: %w", ref.SysMgmtdHost, err)
+		}
+		if !found {
+			continue
+		}
+		entries = append(entries, tlsCertsFileEntry{SysMgmtdHost: ref.SysMgmtdHost, TLSCert: string(tlsCert)})
+	}
+	return yaml.Marshal(entries)
+}
+
+// hasConnAuthSecretRef returns true if any entry in refs includes a connAuthSecretRef.
+func hasConnAuthSecretRef(refs []beegfsv1.FileSystemSecretRefs) bool {
+	for _, ref := range refs {
+		if ref.ConnAuthSecretRef != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTLSCertSecretRef returns true if any entry in refs includes a tlsCertSecretRef.
+func hasTLSCertSecretRef(refs []beegfsv1.FileSystemSecretRefs) bool {
+	for _, ref := range refs {
+		if ref.TLSCertSecretRef != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// getSecretKeyValue returns the value of the key referenced by selector in a Secret in namespace. If the Secret or the
+// key does not exist, getSecretKeyValue returns an error unless selector is optional (in which case it only returns
+// found=false).
+func (r *BeegfsDriverReconciler) getSecretKeyValue(ctx context.Context, namespace string,
+	selector *corev1.SecretKeySelector) (value []byte, found bool, err error) {
+	optional := selector.Optional != nil && *selector.Optional
+	secret := new(corev1.Secret)
+	if err = r.Get(ctx, types.NamespacedName{Name: selector.Name, Namespace: namespace}, secret); err != nil {
+		if errors.IsNotFound(err) && optional {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	data, ok := secret.Data[selector.Key]
+	if !ok {
+		if optional {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("key %s not found in Secret %s", selector.Key, selector.Name)
+	}
+	return data, true, nil
+}
+
 // setCommonObjectMetadata can be used on any namespaced Kubernetes object to ensure that:
 //   - The object exists in the correct namespace (based on the namespace of the request).
 //   - The object is owned by the BeegfsDriver object (for proper garbage collection). setCommonObjectMetadata will NOT
@@ -595,6 +1112,217 @@ func (r *BeegfsDriverReconciler) setCommonObjectMetadata(req ctrl.Request, drive
 	return ctrl.SetControllerReference(driver, object, r.Scheme)
 }
 
+// getDriverName returns the driverName of a BeegfsDriver or the driver name from the deployment manifests if it is not
+// specified.
+func getDriverName(driver *beegfsv1.BeegfsDriver) string {
+	if driver.Spec.DriverName == "" {
+		return deploy.DefaultDriverName
+	}
+	return driver.Spec.DriverName
+}
+
+// getClusterScopedObjectName returns the name of a cluster-scoped object from the deployment manifests for driverName.
+// The names in the deployment manifests are used as is for the default driver name so that the objects created by
+// earlier versions of the operator (or by the Kustomize deployment method) are adopted instead of duplicated.
+func getClusterScopedObjectName(name, driverName string) string {
+	if driverName == deploy.DefaultDriverName {
+		return name
+	}
+	return name + "-" + driverName
+}
+
+// setClusterScopedObjectMetadata renames the Cluster Roles, Cluster Role Bindings, and CSI Driver object from the
+// deployment manifests for driverName (updating any references to the Cluster Roles) and annotates them as owned by the
+// BeegfsDriver being reconciled.
+func setClusterScopedObjectMetadata(req ctrl.Request, driverName string, rbacInterfaces []interface{},
+	d *storagev1.CSIDriver) {
+	owner := req.NamespacedName.String()
+	for _, i := range rbacInterfaces {
+		switch object := i.(type) {
+		case *rbacv1.ClusterRole:
+			object.Name = getClusterScopedObjectName(object.Name, driverName)
+			metav1.SetMetaDataAnnotation(&object.ObjectMeta, annotationOwner, owner)
+		case *rbacv1.ClusterRoleBinding:
+			object.Name = getClusterScopedObjectName(object.Name, driverName)
+			object.RoleRef.Name = getClusterScopedObjectName(object.RoleRef.Name, driverName)
+			metav1.SetMetaDataAnnotation(&object.ObjectMeta, annotationOwner, owner)
+		case *rbacv1.RoleBinding:
+			if object.RoleRef.Kind == "ClusterRole" {
+				object.RoleRef.Name = getClusterScopedObjectName(object.RoleRef.Name, driverName)
+			}
+		}
+	}
+	d.Name = driverName
+	metav1.SetMetaDataAnnotation(&d.ObjectMeta, annotationOwner, owner)
+}
+
+// checkClusterScopedObjectOwner returns an error if a cluster-scoped object from the cluster is owned by a BeegfsDriver
+// other than the one being reconciled (e.g. because two BeegfsDrivers specify the same driverName). An object without
+// an owner annotation was created by an earlier version of the operator and may be claimed.
+func checkClusterScopedObjectOwner(req ctrl.Request, object metav1.Object) error {
+	owner := object.GetAnnotations()[annotationOwner]
+	if owner != "" && owner != req.NamespacedName.String() {
+		return fmt.Errorf("%s is owned by BeegfsDriver %s (each BeegfsDriver requires a unique driverName)",
+			object.GetName(), owner)
+	}
+	return nil
+}
+
+// deleteClusterScopedObject deletes a cluster-scoped object unless it does not exist or it is owned by a different
+// BeegfsDriver.
+func (r *BeegfsDriverReconciler) deleteClusterScopedObject(ctx context.Context, log logr.Logger, req ctrl.Request,
+	object client.Object) error {
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	if err := r.Get(ctx, types.NamespacedName{Name: object.GetName()}, object); err != nil {
+		return withKind(kind, client.IgnoreNotFound(err))
+	}
+	if err := checkClusterScopedObjectOwner(req, object); err != nil {
+		log.Info("Not deleting cluster-scoped object owned by another BeegfsDriver", "name", object.GetName(),
+			"owner", object.GetAnnotations()[annotationOwner])
+		return nil
+	}
+	if err := r.Delete(ctx, object); err != nil && !errors.IsNotFound(err) {
+		return withKind(kind, err)
+	}
+	return nil
+}
+
+// newStorageClass returns the StorageClass for a template with driverName as its provisioner, labeled with driverName
+// and annotated as owned by the BeegfsDriver being reconciled. It returns an error if the driver would reject a
+// CreateVolume request with the parameters of the template. Unspecified fields are set to the defaults the Kubernetes
+// API server would set so that the StorageClass can be compared to the one on the cluster.
+func newStorageClass(req ctrl.Request, driverName string, template beegfsv1.StorageClassTemplate) (
+	*storagev1.StorageClass, error) {
+	if err := beegfsv1.ValidateStorageClassParameters(template.Parameters); err != nil {
+		return nil, err
+	}
+	sc := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        template.Name,
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+		},
+		Provisioner:       driverName,
+		Parameters:        template.Parameters,
+		MountOptions:      template.MountOptions,
+		ReclaimPolicy:     template.ReclaimPolicy,
+		VolumeBindingMode: template.VolumeBindingMode,
+	}
+	for k, v := range template.Labels {
+		sc.Labels[k] = v
+	}
+	for k, v := range template.Annotations {
+		sc.Annotations[k] = v
+	}
+	sc.Labels[labelDriverName] = driverName
+	sc.Annotations[annotationOwner] = req.NamespacedName.String()
+	if sc.ReclaimPolicy == nil {
+		reclaimPolicy := corev1.PersistentVolumeReclaimDelete
+		sc.ReclaimPolicy = &reclaimPolicy
+	}
+	if sc.VolumeBindingMode == nil {
+		volumeBindingMode := storagev1.VolumeBindingImmediate
+		sc.VolumeBindingMode = &volumeBindingMode
+	}
+	allowVolumeExpansion := template.AllowVolumeExpansion != nil && *template.AllowVolumeExpansion
+	sc.AllowVolumeExpansion = &allowVolumeExpansion
+	return sc, nil
+}
+
+// getStorageClasses returns the StorageClasses to create or update for templates and the templates that are invalid or
+// conflict with a StorageClass that the BeegfsDriver being reconciled does not own (e.g. one created by hand).
+func (r *BeegfsDriverReconciler) getStorageClasses(ctx context.Context, req ctrl.Request, driverName string,
+	templates []beegfsv1.StorageClassTemplate) ([]*storagev1.StorageClass, []beegfsv1.StorageClassStatus, error) {
+	var storageClasses []*storagev1.StorageClass
+	var invalid []beegfsv1.StorageClassStatus
+	for _, template := range templates {
+		sc, err := newStorageClass(req, driverName, template)
+		if err != nil {
+			invalid = append(invalid, beegfsv1.StorageClassStatus{Name: template.Name, Message: err.Error()})
+			continue
+		}
+		scFromCluster := new(storagev1.StorageClass)
+		if err = r.Get(ctx, types.NamespacedName{Name: sc.Name}, scFromCluster); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, nil, err
+			}
+		} else if owner := scFromCluster.Annotations[annotationOwner]; owner != req.NamespacedName.String() {
+			message := "a StorageClass with this name already exists and is not managed by the operator"
+			if owner != "" {
+				message = fmt.Sprintf("a StorageClass with this name is managed by BeegfsDriver %s", owner)
+			}
+			invalid = append(invalid, beegfsv1.StorageClassStatus{Name: template.Name, Message: message})
+			continue
+		}
+		storageClasses = append(storageClasses, sc)
+	}
+	return storageClasses, invalid, nil
+}
+
+// setStorageClassesCondition sets the StorageClassesValid condition of a BeegfsDriver based on its
+// storageClassTemplates and invalidStorageClasses. The condition is removed if there are no storageClassTemplates.
+func setStorageClassesCondition(driver *beegfsv1.BeegfsDriver) {
+	numTemplates := len(driver.Spec.StorageClassTemplates)
+	if numTemplates == 0 {
+		meta.RemoveStatusCondition(&driver.Status.Conditions, beegfsv1.ConditionStorageClassesValid)
+		return
+	}
+	numInvalid := len(driver.Status.InvalidStorageClasses)
+	condition := metav1.Condition{
+		Type:    beegfsv1.ConditionStorageClassesValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  beegfsv1.ReasonStorageClassesValid,
+		Message: fmt.Sprintf("%d/%d storage class templates are valid", numTemplates-numInvalid, numTemplates),
+	}
+	if numInvalid > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = beegfsv1.ReasonStorageClassesInvalid
+	}
+	meta.SetStatusCondition(&driver.Status.Conditions, condition)
+}
+
+// storageClassNeedsRecreate returns true if an immutable field of the StorageClass on the cluster differs from the
+// desired StorageClass.
+func storageClassNeedsRecreate(sc, scFromCluster *storagev1.StorageClass) bool {
+	return sc.Provisioner != scFromCluster.Provisioner ||
+		!equality.Semantic.DeepEqual(sc.Parameters, scFromCluster.Parameters) ||
+		!equality.Semantic.DeepEqual(sc.ReclaimPolicy, scFromCluster.ReclaimPolicy) ||
+		!equality.Semantic.DeepEqual(sc.VolumeBindingMode, scFromCluster.VolumeBindingMode)
+}
+
+// storageClassNeedsUpdate returns true if a mutable field of the StorageClass on the cluster differs from the desired
+// StorageClass.
+func storageClassNeedsUpdate(sc, scFromCluster *storagev1.StorageClass) bool {
+	return !equality.Semantic.DeepEqual(sc.Labels, scFromCluster.Labels) ||
+		!equality.Semantic.DeepEqual(sc.Annotations, scFromCluster.Annotations) ||
+		!equality.Semantic.DeepEqual(sc.MountOptions, scFromCluster.MountOptions) ||
+		!equality.Semantic.DeepEqual(sc.AllowVolumeExpansion, scFromCluster.AllowVolumeExpansion)
+}
+
+// deleteStorageClasses deletes the StorageClasses the BeegfsDriver being reconciled created for driverName, except for
+// those named in keep.
+func (r *BeegfsDriverReconciler) deleteStorageClasses(ctx context.Context, log logr.Logger, req ctrl.Request,
+	driverName string, keep map[string]bool) error {
+	storageClasses := new(storagev1.StorageClassList)
+	if err := r.List(ctx, storageClasses, client.MatchingLabels{labelDriverName: driverName}); err != nil {
+		return withKind("StorageClass", err)
+	}
+	for i := range storageClasses.Items {
+		sc := &storageClasses.Items[i]
+		if keep[sc.Name] || sc.Annotations[annotationOwner] != req.NamespacedName.String() {
+			continue
+		}
+		log.Info("Deleting StorageClass", "name", sc.Name)
+		if err := r.Delete(ctx, sc); err != nil && !errors.IsNotFound(err) {
+			return withKind("StorageClass", err)
+		}
+	}
+	return nil
+}
+
+// reasonDriftCorrected is the reason of the events applyObject records.
+const reasonDriftCorrected = "DriftCorrected"
+
 // See setResourceVersionAnnotations for details.
 const (
 	annotationConfigMapVersion      = "beegfs.csi.netapp.com/configMapVersion"
@@ -602,6 +1330,43 @@ const (
 	annotationTLSCertsSecretVersion = "beegfs.csi.netapp.com/tlscertsSecretVersion"
 )
 
+// setRollbackImages sets the image of each container in containers to the image it used before the most recent upgrade
+// if the upgrade was rolled back.
+func setRollbackImages(log logr.Logger, upgrade *beegfsv1.UpgradeStatus, containers []corev1.Container) {
+	if upgrade == nil || upgrade.Phase != beegfsv1.UpgradePhaseRolledBack {
+		return
+	}
+	for i, container := range containers {
+		for _, image := range upgrade.PreviousImages {
+			if container.Name == image.Name {
+				log.V(5).Info("Setting Container image from before upgrade", "containerName", container.Name,
+					"containerImage", image.Image)
+				containers[i].Image = image.Image
+			}
+		}
+	}
+}
+
+// setUpdateStrategy sets the update strategy of the node service Daemon Set. The Daemon Set replaces at most
+// maxUnavailable node service Pods at a time unless the most recent upgrade is paused, in which case it does not
+// replace node service Pods at all (Daemon Sets cannot otherwise be paused).
+func setUpdateStrategy(log logr.Logger, strategy beegfsv1.UpgradeStrategy, upgrade *beegfsv1.UpgradeStatus,
+	spec *appsv1.DaemonSetSpec) {
+	if upgrade != nil && upgrade.Phase == beegfsv1.UpgradePhasePaused {
+		log.V(5).Info("Pausing node service rollout")
+		spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}
+		return
+	}
+	maxUnavailable := intstr.FromInt32(1)
+	if strategy.MaxUnavailable != nil {
+		maxUnavailable = *strategy.MaxUnavailable
+	}
+	spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+		Type:          appsv1.RollingUpdateDaemonSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: &maxUnavailable},
+	}
+}
+
 // setResourceVersionAnnotations is an important part of our overall configuration scheme. It records the current name
 // and resource version of the Config Map and Secret required by our driver in annotations on a Pod Template Spec (for
 // either a Daemon Set or a Stateful Set).
@@ -658,123 +1423,76 @@ func setImages(log logr.Logger, containers []corev1.Container, overrides beegfsv
 // to the relevant container from the provided slice of Container specs (containers). The provided container specs
 // should be the set of containers for the BeeGFS driver's node pod.
 func setNodeResources(log logr.Logger, overrides beegfsv1.ContainerResourceOverrides, containers []corev1.Container) {
-	for i, container := range containers {
-		switch container.Name {
-		case "beegfs":
-			if len(overrides.NodeBeegfsResources.Limits) > 0 {
-				if cpu, ok := overrides.NodeBeegfsResources.Limits["cpu"]; ok {
-					log.Info("Overriding node beegfs cpu limit", "values", cpu)
-					containers[i].Resources.Limits["cpu"] = cpu
-				}
-				if memory, ok := overrides.NodeBeegfsResources.Limits["memory"]; ok {
-					log.Info("Overriding node beegfs memory limit", "values", memory)
-					containers[i].Resources.Limits["memory"] = memory
-				}
-			}
-			if len(overrides.NodeBeegfsResources.Requests) > 0 {
-				if cpu, ok := overrides.NodeBeegfsResources.Requests["cpu"]; ok {
-					log.Info("Overriding node beegfs cpu requests", "values", cpu)
-					containers[i].Resources.Requests["cpu"] = cpu
-				}
-				if memory, ok := overrides.NodeBeegfsResources.Requests["memory"]; ok {
-					log.Info("Overriding node beegfs memory requests", "values", memory)
-					containers[i].Resources.Requests["memory"] = memory
-				}
-			}
-		case "node-driver-registrar":
-			if len(overrides.NodeDriverRegistrarResources.Limits) > 0 {
-				if cpu, ok := overrides.NodeDriverRegistrarResources.Limits["cpu"]; ok {
-					log.Info("Overriding node node-driver-registrar cpu limit", "values", cpu)
-					containers[i].Resources.Limits["cpu"] = cpu
-				}
-				if memory, ok := overrides.NodeDriverRegistrarResources.Limits["memory"]; ok {
-					log.Info("Overriding node node-driver-registrar memory limit", "values", memory)
-					containers[i].Resources.Limits["memory"] = memory
-				}
-			}
-			if len(overrides.NodeDriverRegistrarResources.Requests) > 0 {
-				if cpu, ok := overrides.NodeDriverRegistrarResources.Requests["cpu"]; ok {
-					log.Info("Overriding node node-driver-registrar cpu requests", "values", cpu)
-					containers[i].Resources.Requests["cpu"] = cpu
-				}
-				if memory, ok := overrides.NodeDriverRegistrarResources.Requests["memory"]; ok {
-					log.Info("Overriding node node-driver-registrar memory requests", "values", memory)
-					containers[i].Resources.Requests["memory"] = memory
-				}
-			}
-		case "liveness-probe":
-			if len(overrides.NodeLivenessProbeResources.Limits) > 0 {
-				if cpu, ok := overrides.NodeLivenessProbeResources.Limits["cpu"]; ok {
-					log.Info("Overriding node liveness-probe cpu limit", "values", cpu)
-					containers[i].Resources.Limits["cpu"] = cpu
-				}
-				if memory, ok := overrides.NodeLivenessProbeResources.Limits["memory"]; ok {
-					log.Info("Overriding node liveness-probe memory limit", "values", memory)
-					containers[i].Resources.Limits["memory"] = memory
-				}
-			}
-			if len(overrides.NodeLivenessProbeResources.Requests) > 0 {
-				if cpu, ok := overrides.NodeLivenessProbeResources.Requests["cpu"]; ok {
-					log.Info("Overriding node liveness-probe cpu requests", "values", cpu)
-					containers[i].Resources.Requests["cpu"] = cpu
-				}
-				if memory, ok := overrides.NodeLivenessProbeResources.Requests["memory"]; ok {
-					log.Info("Overriding node liveness-probe memory requests", "values", memory)
-					containers[i].Resources.Requests["memory"] = memory
-				}
-			}
-		}
+	containerNameToResourceOverrideMap := map[string]corev1.ResourceRequirements{
+		deploy.ContainerNameBeegfsCsiDriver:        overrides.NodeBeegfsResources,
+		deploy.ContainerNameCsiNodeDriverRegistrar: overrides.NodeDriverRegistrarResources,
+		deploy.ContainerNameLivenessProbe:          overrides.NodeLivenessProbeResources,
+	}
+	for name, requirements := range overrides.NodeContainers {
+		containerNameToResourceOverrideMap[name] = mergeResourceRequirements(containerNameToResourceOverrideMap[name],
+			requirements)
 	}
+	setResources(log, containerNameToResourceOverrideMap, containers)
 }
 
 // setControllerResources takes a ContainerResourceOverrides (overrides) object and applies any values from the overrides
 // to the relevant container from the provided slice of Container specs (containers). The provided container specs
 // should be the set of containers for the BeeGFS driver's controller pod.
 func setControllerResources(log logr.Logger, overrides beegfsv1.ContainerResourceOverrides, containers []corev1.Container) {
+	containerNameToResourceOverrideMap := map[string]corev1.ResourceRequirements{
+		deploy.ContainerNameBeegfsCsiDriver: overrides.ControllerBeegfsResources,
+		deploy.ContainerNameCsiProvisioner:  overrides.ControllerCsiProvisionerResources,
+	}
+	for name, requirements := range overrides.ControllerContainers {
+		containerNameToResourceOverrideMap[name] = mergeResourceRequirements(containerNameToResourceOverrideMap[name],
+			requirements)
+	}
+	setResources(log, containerNameToResourceOverrideMap, containers)
+}
+
+// mergeResourceRequirements returns a ResourceRequirements that includes every limit and request from base and
+// override. Where both specify the same resource, the value from override wins.
+func mergeResourceRequirements(base, override corev1.ResourceRequirements) corev1.ResourceRequirements {
+	merged := *base.DeepCopy()
+	for name, quantity := range override.Limits {
+		if merged.Limits == nil {
+			merged.Limits = corev1.ResourceList{}
+		}
+		merged.Limits[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range override.Requests {
+		if merged.Requests == nil {
+			merged.Requests = corev1.ResourceList{}
+		}
+		merged.Requests[name] = quantity.DeepCopy()
+	}
+	return merged
+}
+
+// setResources applies every limit and request from the ResourceRequirements in containerNameToResourceOverrideMap to
+// the container with the matching name. Resources that are not overridden keep the values from the manifests.
+func setResources(log logr.Logger, containerNameToResourceOverrideMap map[string]corev1.ResourceRequirements,
+	containers []corev1.Container) {
 	for i, container := range containers {
-		switch container.Name {
-		case "beegfs":
-			if len(overrides.ControllerBeegfsResources.Limits) > 0 {
-				if cpu, ok := overrides.ControllerBeegfsResources.Limits["cpu"]; ok {
-					log.Info("Overriding controller beegfs cpu limit", "values", cpu)
-					containers[i].Resources.Limits["cpu"] = cpu
-				}
-				if memory, ok := overrides.ControllerBeegfsResources.Limits["memory"]; ok {
-					log.Info("Overriding controller beegfs memory limit", "values", memory)
-					containers[i].Resources.Limits["memory"] = memory
-				}
-			}
-			if len(overrides.ControllerBeegfsResources.Requests) > 0 {
-				if cpu, ok := overrides.ControllerBeegfsResources.Requests["cpu"]; ok {
-					log.Info("Overriding controller beegfs cpu requests", "values", cpu)
-					containers[i].Resources.Requests["cpu"] = cpu
-				}
-				if memory, ok := overrides.ControllerBeegfsResources.Requests["memory"]; ok {
-					log.Info("Overriding controller beegfs memory requests", "values", memory)
-					containers[i].Resources.Requests["memory"] = memory
-				}
-			}
-		case "csi-provisioner":
-			if len(overrides.ControllerCsiProvisionerResources.Limits) > 0 {
-				if cpu, ok := overrides.ControllerCsiProvisionerResources.Limits["cpu"]; ok {
-					log.Info("Overriding controller csi-provisioner cpu limit", "values", cpu)
-					containers[i].Resources.Limits["cpu"] = cpu
-				}
-				if memory, ok := overrides.ControllerCsiProvisionerResources.Limits["memory"]; ok {
-					log.Info("Overriding controller csi-provisioner memory limit", "values", memory)
-					containers[i].Resources.Limits["memory"] = memory
-				}
+		override, ok := containerNameToResourceOverrideMap[container.Name]
+		if !ok {
+			continue
+		}
+		for name, quantity := range override.Limits {
+			if containers[i].Resources.Limits == nil {
+				containers[i].Resources.Limits = corev1.ResourceList{}
 			}
-			if len(overrides.ControllerCsiProvisionerResources.Requests) > 0 {
-				if cpu, ok := overrides.ControllerCsiProvisionerResources.Requests["cpu"]; ok {
-					log.Info("Overriding controller csi-provisioner cpu requests", "values", cpu)
-					containers[i].Resources.Requests["cpu"] = cpu
-				}
-				if memory, ok := overrides.ControllerCsiProvisionerResources.Requests["memory"]; ok {
-					log.Info("Overriding controller csi-provisioner memory requests", "values", memory)
-					containers[i].Resources.Requests["memory"] = memory
-				}
+			log.Info("Overriding container limit", "containerName", container.Name, "resource", name,
+				"value", quantity)
+			containers[i].Resources.Limits[name] = quantity
+		}
+		for name, quantity := range override.Requests {
+			if containers[i].Resources.Requests == nil {
+				containers[i].Resources.Requests = corev1.ResourceList{}
 			}
+			log.Info("Overriding container request", "containerName", container.Name, "resource", name,
+				"value", quantity)
+			containers[i].Resources.Requests[name] = quantity
 		}
 	}
 }
@@ -831,6 +1549,66 @@ func setLogLevel(log logr.Logger, level *int, containers []corev1.Container) {
 	}
 }
 
+// setDriverName replaces the driver name from the deployment manifests with driverName in the --driver-name argument of
+// any Container in spec and replaces the kubelet plugin directory of the driver from the deployment manifests in any
+// Container argument, volume mount, or host path volume.
+func setDriverName(log logr.Logger, driverName string, spec *corev1.PodSpec) {
+	if driverName == deploy.DefaultDriverName {
+		return
+	}
+	log.V(5).Info("Setting driver name", "driverName", driverName)
+	defaultPluginDirPath := path.Join(deploy.KubeletPluginsDirPath, deploy.DefaultDriverName)
+	pluginDirPath := path.Join(deploy.KubeletPluginsDirPath, driverName)
+	replacePluginDirPath := func(s string) string {
+		// Replace only whole path elements (e.g. not a hypothetical /var/lib/kubelet/plugins/beegfs.csi.netapp.com2).
+		if s == defaultPluginDirPath || strings.HasPrefix(s, defaultPluginDirPath+"/") {
+			return pluginDirPath + strings.TrimPrefix(s, defaultPluginDirPath)
+		}
+		return s
+	}
+	for i, container := range spec.Containers {
+		for j, arg := range container.Args {
+			if arg == "--driver-name="+deploy.DefaultDriverName {
+				spec.Containers[i].Args[j] = "--driver-name=" + driverName
+			} else if name, value, found := strings.Cut(arg, "="); found {
+				spec.Containers[i].Args[j] = name + "=" + replacePluginDirPath(value)
+			}
+		}
+		for j, volumeMount := range container.VolumeMounts {
+			spec.Containers[i].VolumeMounts[j].MountPath = replacePluginDirPath(volumeMount.MountPath)
+		}
+	}
+	for i, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			spec.Volumes[i].HostPath.Path = replacePluginDirPath(volume.HostPath.Path)
+		}
+	}
+}
+
+// setHealthPort sets the port of any Container port named healthz in spec (and the matching host port) and the
+// --health-port argument of any Container in spec to port. If port is nil, setHealthPort does nothing.
+func setHealthPort(log logr.Logger, port *int32, spec *corev1.PodSpec) {
+	if port == nil {
+		return
+	}
+	log.V(5).Info("Setting health port", "port", *port)
+	for i, container := range spec.Containers {
+		for j, containerPort := range container.Ports {
+			if containerPort.Name == "healthz" {
+				spec.Containers[i].Ports[j].ContainerPort = *port
+				if containerPort.HostPort != 0 {
+					spec.Containers[i].Ports[j].HostPort = *port
+				}
+			}
+		}
+		for j, arg := range container.Args {
+			if strings.HasPrefix(arg, "--health-port=") {
+				spec.Containers[i].Args[j] = "--health-port=" + strconv.Itoa(int(*port))
+			}
+		}
+	}
+}
+
 // setNodeAffinity adds the passed NodeAffinity to the passed PodSpec (or replaces the PodSpec's existing NodeAffinity
 // if it has one).
 func setNodeAffinity(log logr.Logger, affinity *corev1.NodeAffinity, spec *corev1.PodSpec) {
@@ -842,3 +1620,56 @@ func setNodeAffinity(log logr.Logger, affinity *corev1.NodeAffinity, spec *corev
 		spec.Affinity.NodeAffinity = affinity
 	}
 }
+
+// setPodTemplateOverrides applies the passed PodTemplateOverride to the passed PodTemplateSpec using strategic merge
+// patch semantics (e.g. env and volumes are merged by name, but tolerations are replaced).
+func setPodTemplateOverrides(log logr.Logger, override beegfsv1.PodTemplateOverride,
+	template *corev1.PodTemplateSpec) error {
+	spec := make(map[string]interface{})
+	if len(override.Containers) > 0 {
+		// A ContainerTemplateOverride serializes to a partial Container.
+		spec["containers"] = override.Containers
+	}
+	if len(override.NodeSelector) > 0 {
+		spec["nodeSelector"] = override.NodeSelector
+	}
+	if len(override.PriorityClassName) > 0 {
+		spec["priorityClassName"] = override.PriorityClassName
+	}
+	if len(override.Tolerations) > 0 {
+		spec["tolerations"] = override.Tolerations
+	}
+	if len(override.Volumes) > 0 {
+		spec["volumes"] = override.Volumes
+	}
+	patch := make(map[string]interface{})
+	if len(override.Annotations) > 0 {
+		patch["metadata"] = map[string]interface{}{"annotations": override.Annotations}
+	}
+	if len(spec) > 0 {
+		patch["spec"] = spec
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	templateBytes, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	log.V(5).Info("Applying Pod template overrides", "patch", string(patchBytes))
+	patchedBytes, err := strategicpatch.StrategicMergePatch(templateBytes, patchBytes, corev1.PodTemplateSpec{})
+	if err != nil {
+		return fmt.Errorf("failed to apply Pod template overrides: %w", err)
+	}
+	patched := corev1.PodTemplateSpec{}
+	if err = json.Unmarshal(patchedBytes, &patched); err != nil {
+		return err
+	}
+	*template = patched
+	return nil
+}
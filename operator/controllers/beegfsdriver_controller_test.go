@@ -10,15 +10,21 @@ import (
 	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/rand"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("Integration tests using envtest", func() {
@@ -59,6 +65,12 @@ var _ = Describe("Integration tests using envtest", func() {
 			}, timeout).Should(Succeed())
 			Expect(sts.OwnerReferences[0].Name).To(Equal("csi-beegfs-cr"))
 			Expect(sts.Spec.Template.Annotations).To(HaveKey(annotationConfigMapVersion))
+			for _, container := range sts.Spec.Template.Spec.Containers {
+				if container.Name == deploy.ContainerNameBeegfsCsiDriver {
+					Expect(container.Args).To(ContainElements("--driver-name="+cr.Spec.DriverName,
+						"--cs-data-dir=/var/lib/kubelet/plugins/"+cr.Spec.DriverName))
+				}
+			}
 			Expect(sts.Spec.Template.Annotations).To(HaveKey(annotationConnauthSecretVersion))
 			Expect(sts.Spec.Template.Annotations).To(HaveKey(annotationTLSCertsSecretVersion))
 		})
@@ -74,6 +86,17 @@ var _ = Describe("Integration tests using envtest", func() {
 			Expect(ds.Spec.Template.Annotations).To(HaveKey(annotationConfigMapVersion))
 			Expect(ds.Spec.Template.Annotations).To(HaveKey(annotationConnauthSecretVersion))
 			Expect(ds.Spec.Template.Annotations).To(HaveKey(annotationTLSCertsSecretVersion))
+			Expect(ds.Spec.Template.Annotations).To(HaveKeyWithValue("key", "value"))
+			Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"key": "value"}))
+			Expect(ds.Spec.Template.Spec.PriorityClassName).To(Equal("system-node-critical"))
+			Expect(ds.Spec.Template.Spec.Tolerations).To(Equal(cr.Spec.PodTemplateOverrides.NodeService.Tolerations))
+			var hostPaths []string
+			for _, volume := range ds.Spec.Template.Spec.Volumes {
+				if volume.HostPath != nil {
+					hostPaths = append(hostPaths, volume.HostPath.Path)
+				}
+			}
+			Expect(hostPaths).To(ContainElement("/var/lib/kubelet/plugins/" + cr.Spec.DriverName))
 		})
 
 		It("should create a correct Config Map", func() {
@@ -135,21 +158,25 @@ var _ = Describe("Integration tests using envtest", func() {
 
 				case *rbacv1.ClusterRole:
 					clusterRole := new(rbacv1.ClusterRole)
-					// Cluster-scoped resources have no namespace.
-					namespacedName := types.NamespacedName{Name: object.Name}
+					// Cluster-scoped resources have no namespace and are named for the driver.
+					name := getClusterScopedObjectName(object.Name, cr.Spec.DriverName)
 					Eventually(func() error {
-						return k8sClient.Get(ctx, namespacedName, clusterRole)
-					}, timeout).Should(Succeed(), "failed to find Cluster Role %s", object.Name)
-					// Cluster-scoped resources can't be owned by our CR.
+						return k8sClient.Get(ctx, types.NamespacedName{Name: name}, clusterRole)
+					}, timeout).Should(Succeed(), "failed to find Cluster Role %s", name)
+					// Cluster-scoped resources can't be owned by our CR, but they are annotated with it.
+					Expect(clusterRole.Annotations).To(HaveKeyWithValue(annotationOwner, cr.Namespace+"/"+cr.Name))
 
 				case *rbacv1.ClusterRoleBinding:
 					crb := new(rbacv1.ClusterRoleBinding)
-					// Cluster-scoped resources have no namespace.
-					namespacedName := types.NamespacedName{Name: object.Name}
+					// Cluster-scoped resources have no namespace and are named for the driver.
+					name := getClusterScopedObjectName(object.Name, cr.Spec.DriverName)
 					Eventually(func() error {
-						return k8sClient.Get(ctx, namespacedName, crb)
-					}, timeout).Should(Succeed(), "failed to find Cluster Role Binding %s", object.Name)
-					// Cluster-scoped resources can't be owned by our CR.
+						return k8sClient.Get(ctx, types.NamespacedName{Name: name}, crb)
+					}, timeout).Should(Succeed(), "failed to find Cluster Role Binding %s", name)
+					// Cluster-scoped resources can't be owned by our CR, but they are annotated with it.
+					Expect(crb.Annotations).To(HaveKeyWithValue(annotationOwner, cr.Namespace+"/"+cr.Name))
+					Expect(crb.RoleRef.Name).To(Equal(getClusterScopedObjectName(object.RoleRef.Name,
+						cr.Spec.DriverName)))
 
 				default:
 					Fail(fmt.Sprintf("Encountered unexpected type %T in RBAC manifests", object))
@@ -158,12 +185,12 @@ var _ = Describe("Integration tests using envtest", func() {
 		})
 
 		It("should create a correct CSI Driver", func() {
-			driver, err := deploy.GetCSIDriver()
-			Expect(err).NotTo(HaveOccurred())
-			namespacedName := types.NamespacedName{Name: driver.Name}
+			driver := new(storagev1.CSIDriver)
+			namespacedName := types.NamespacedName{Name: cr.Spec.DriverName}
 			Eventually(func() error {
 				return k8sClient.Get(ctx, namespacedName, driver)
-			}, timeout).ShouldNot(HaveOccurred(), "failed to find CSI Driver %s", driver.Name)
+			}, timeout).ShouldNot(HaveOccurred(), "failed to find CSI Driver %s", cr.Spec.DriverName)
+			Expect(driver.Annotations).To(HaveKeyWithValue(annotationOwner, cr.Namespace+"/"+cr.Name))
 		})
 
 		It("should apply a finalizer", func() {
@@ -187,6 +214,10 @@ var _ = Describe("Integration tests using envtest", func() {
 				nsCondition := meta.FindStatusCondition(cr.Status.Conditions, beegfsv1.ConditionNodeServiceReady)
 				g.Expect(nsCondition).ToNot(BeNil())
 				g.Expect(nsCondition.Reason).To(Equal(beegfsv1.ReasonPodsNotScheduled))
+				g.Expect(cr.Status.NodesReady).To(Equal("0/0"))
+				g.Expect(cr.Status.DriverImage).To(Equal("some.registry/some/image:some-tag"))
+				g.Expect(cr.Status.Images).To(ContainElement(beegfsv1.ContainerImageStatus{
+					Name: deploy.ContainerNameCsiProvisioner, Image: "some.registry/some/image:some-tag"}))
 			}, timeout).Should(Succeed())
 		})
 	})
@@ -209,26 +240,261 @@ var _ = Describe("Integration tests using envtest", func() {
 			for _, resource := range resources {
 				switch object := resource.(type) {
 				case *rbacv1.ClusterRole:
+					name := getClusterScopedObjectName(object.Name, cr.Spec.DriverName)
 					Eventually(func() error {
-						return k8sClient.Get(ctx, types.NamespacedName{Name: object.Name}, &rbacv1.ClusterRole{})
-					}, timeout).ShouldNot(Succeed(), "expected Cluster Role %s to be deleted", object.Name)
+						return k8sClient.Get(ctx, types.NamespacedName{Name: name}, &rbacv1.ClusterRole{})
+					}, timeout).ShouldNot(Succeed(), "expected Cluster Role %s to be deleted", name)
 				case rbacv1.ClusterRoleBinding:
+					name := getClusterScopedObjectName(object.Name, cr.Spec.DriverName)
 					Eventually(func() error {
-						return k8sClient.Get(ctx, types.NamespacedName{Name: object.Name}, &rbacv1.ClusterRoleBinding{})
-					}, timeout).ShouldNot(Succeed(), "expected Cluster Role Binding %s to be deleted", object.Name)
+						return k8sClient.Get(ctx, types.NamespacedName{Name: name}, &rbacv1.ClusterRoleBinding{})
+					}, timeout).ShouldNot(Succeed(), "expected Cluster Role Binding %s to be deleted", name)
 				}
 			}
 
-			var driver *storagev1.CSIDriver
-			driver, err = deploy.GetCSIDriver()
-			Expect(err).NotTo(HaveOccurred())
-			namespacedName := types.NamespacedName{Name: driver.Name}
+			namespacedName := types.NamespacedName{Name: cr.Spec.DriverName}
 			Eventually(func() error {
-				return k8sClient.Get(ctx, namespacedName, driver)
+				return k8sClient.Get(ctx, namespacedName, &storagev1.CSIDriver{})
 			}, timeout).ShouldNot(Succeed(), "expected CSI Driver object to be deleted")
 		})
 	})
 
+	Context("When a BeegfsDriver CR with the same driverName is submitted in another namespace", func() {
+		var otherCR *beegfsv1.BeegfsDriver
+
+		BeforeEach(func() {
+			By("Waiting for the first CR to create its CSI Driver object")
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: cr.Spec.DriverName}, &storagev1.CSIDriver{})
+			}, timeout).Should(Succeed())
+
+			By("Submitting a second BeegfsDriver CR with the same driverName")
+			otherCR = getValidCRWithNoFields()
+			for otherCR.Namespace == cr.Namespace {
+				otherCR = getValidCRWithNoFields()
+			}
+			otherCR.Spec.DriverName = cr.Spec.DriverName
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: otherCR.Namespace}}
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			Expect(k8sClient.Create(ctx, otherCR)).To(Succeed())
+		})
+
+		It("should neither claim the cluster-scoped objects nor deploy the driver", func() {
+			sts, err := deploy.GetControllerServiceStatefulSet()
+			Expect(err).NotTo(HaveOccurred())
+			Consistently(func(g Gomega) {
+				driver := new(storagev1.CSIDriver)
+				g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: cr.Spec.DriverName}, driver)).To(Succeed())
+				g.Expect(driver.Annotations).To(HaveKeyWithValue(annotationOwner, cr.Namespace+"/"+cr.Name))
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: sts.Name, Namespace: otherCR.Namespace},
+					&appsv1.StatefulSet{})
+				g.Expect(errors.IsNotFound(err)).To(BeTrue())
+			}, "2s").Should(Succeed())
+		})
+	})
+
+	Context("When objects are modified or deleted outside of the operator", func() {
+		var cr0 *rbacv1.ClusterRole
+
+		BeforeEach(func() {
+			By("Waiting for the driver to be deployed")
+			Eventually(func() ([]string, error) {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, cr)
+				return cr.Finalizers, err
+			}, timeout).Should(ContainElement(finalizerClusterResourceDeletion))
+			ds, err := deploy.GetNodeServiceDaemonSet()
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: ds.Name, Namespace: cr.Namespace}, ds)
+			}, timeout).Should(Succeed())
+
+			rbacInterfaces, err := deploy.GetRBAC()
+			Expect(err).NotTo(HaveOccurred())
+			for _, i := range rbacInterfaces {
+				if object, ok := i.(*rbacv1.ClusterRole); ok {
+					cr0 = object
+					break
+				}
+			}
+			Expect(cr0).NotTo(BeNil())
+			cr0.Name = getClusterScopedObjectName(cr0.Name, cr.Spec.DriverName)
+		})
+
+		It("should restore a modified Cluster Role", func() {
+			Eventually(func() error {
+				clusterRole := new(rbacv1.ClusterRole)
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: cr0.Name}, clusterRole); err != nil {
+					return err
+				}
+				clusterRole.Rules = []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"},
+					Verbs: []string{"get"}}}
+				return k8sClient.Update(ctx, clusterRole)
+			}, timeout).Should(Succeed())
+			Eventually(func() ([]rbacv1.PolicyRule, error) {
+				clusterRole := new(rbacv1.ClusterRole)
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: cr0.Name}, clusterRole)
+				return clusterRole.Rules, err
+			}, timeout).Should(Equal(cr0.Rules))
+		})
+
+		It("should restore a modified Daemon Set and record an event", func() {
+			ds, err := deploy.GetNodeServiceDaemonSet()
+			Expect(err).NotTo(HaveOccurred())
+			namespacedName := types.NamespacedName{Name: ds.Name, Namespace: cr.Namespace}
+			var image string
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, namespacedName, ds); err != nil {
+					return err
+				}
+				image = ds.Spec.Template.Spec.Containers[0].Image
+				ds.Spec.Template.Spec.Containers[0].Image = "some.registry/tampered:latest"
+				return k8sClient.Update(ctx, ds)
+			}, timeout).Should(Succeed())
+			Eventually(func() (string, error) {
+				err := k8sClient.Get(ctx, namespacedName, ds)
+				return ds.Spec.Template.Spec.Containers[0].Image, err
+			}, timeout).Should(Equal(image))
+			Eventually(func() ([]string, error) {
+				events := new(corev1.EventList)
+				err := k8sClient.List(ctx, events, client.InNamespace(cr.Namespace))
+				var messages []string
+				for _, event := range events.Items {
+					if event.Reason == reasonDriftCorrected {
+						messages = append(messages, event.Message)
+					}
+				}
+				return messages, err
+			}, timeout).Should(ContainElement(ContainSubstring("DaemonSet " + ds.Name + " was modified")))
+		})
+
+		It("should recreate a deleted Config Map, Secret, and Service Account", func() {
+			objects := []client.Object{
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: deploy.ResourceNameConfigMap}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: deploy.ResourceNameSecret}},
+			}
+			rbacInterfaces, err := deploy.GetRBAC()
+			Expect(err).NotTo(HaveOccurred())
+			for _, i := range rbacInterfaces {
+				if object, ok := i.(*corev1.ServiceAccount); ok {
+					objects = append(objects, object)
+				}
+			}
+			for _, object := range objects {
+				object.SetNamespace(cr.Namespace)
+				Expect(k8sClient.Delete(ctx, object)).To(Succeed())
+			}
+			for _, object := range objects {
+				Eventually(func() error {
+					return k8sClient.Get(ctx, client.ObjectKeyFromObject(object), object)
+				}, timeout).Should(Succeed(), "expected %s to be recreated", object.GetName())
+			}
+		})
+
+		It("should restore a modified Config Map", func() {
+			namespacedName := types.NamespacedName{Name: deploy.ResourceNameConfigMap, Namespace: cr.Namespace}
+			cm := new(corev1.ConfigMap)
+			var data map[string]string
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, namespacedName, cm); err != nil {
+					return err
+				}
+				data = cm.Data
+				cm.Data = map[string]string{deploy.KeyNameConfigMap: "tampered"}
+				return k8sClient.Update(ctx, cm)
+			}, timeout).Should(Succeed())
+			Eventually(func() (map[string]string, error) {
+				err := k8sClient.Get(ctx, namespacedName, cm)
+				return cm.Data, err
+			}, timeout).Should(Equal(data))
+		})
+	})
+
+	Context("When storageClassTemplates are specified", func() {
+		var validName, invalidName string
+
+		BeforeEach(func() {
+			validName = cr.Namespace + "-valid"
+			invalidName = cr.Namespace + "-invalid"
+			By("Adding a valid and an invalid StorageClass template to the CR")
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+					cr); err != nil {
+					return err
+				}
+				cr.Spec.StorageClassTemplates = []beegfsv1.StorageClassTemplate{
+					{
+						Name:       validName,
+						Parameters: map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s"},
+					},
+					{
+						Name:       invalidName,
+						Parameters: map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasepath": "/k8s"},
+					},
+				}
+				return k8sClient.Update(ctx, cr)
+			}, timeout).Should(Succeed())
+		})
+
+		It("should create valid StorageClasses and report invalid ones", func() {
+			Eventually(func(g Gomega) {
+				sc := new(storagev1.StorageClass)
+				g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: validName}, sc)).To(Succeed())
+				g.Expect(sc.Provisioner).To(Equal(cr.Spec.DriverName))
+				g.Expect(sc.Parameters).To(HaveKeyWithValue("volDirBasePath", "/k8s"))
+				g.Expect(sc.Labels).To(HaveKeyWithValue(labelDriverName, cr.Spec.DriverName))
+				g.Expect(sc.Annotations).To(HaveKeyWithValue(annotationOwner, cr.Namespace+"/"+cr.Name))
+			}, timeout).Should(Succeed())
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+					cr)).To(Succeed())
+				g.Expect(cr.Status.InvalidStorageClasses).To(HaveLen(1))
+				g.Expect(cr.Status.InvalidStorageClasses[0].Name).To(Equal(invalidName))
+				condition := meta.FindStatusCondition(cr.Status.Conditions, beegfsv1.ConditionStorageClassesValid)
+				g.Expect(condition).NotTo(BeNil())
+				g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			}, timeout).Should(Succeed())
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: invalidName}, &storagev1.StorageClass{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should recreate a StorageClass whose parameters change", func() {
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: validName}, &storagev1.StorageClass{})
+			}, timeout).Should(Succeed())
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+					cr); err != nil {
+					return err
+				}
+				cr.Spec.StorageClassTemplates[0].Parameters["stripePattern/chunkSize"] = "2m"
+				return k8sClient.Update(ctx, cr)
+			}, timeout).Should(Succeed())
+			Eventually(func() (map[string]string, error) {
+				sc := new(storagev1.StorageClass)
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: validName}, sc)
+				return sc.Parameters, err
+			}, timeout).Should(HaveKeyWithValue("stripePattern/chunkSize", "2m"))
+		})
+
+		It("should delete a StorageClass whose template is removed", func() {
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: validName}, &storagev1.StorageClass{})
+			}, timeout).Should(Succeed())
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+					cr); err != nil {
+					return err
+				}
+				cr.Spec.StorageClassTemplates = nil
+				return k8sClient.Update(ctx, cr)
+			}, timeout).Should(Succeed())
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: validName}, &storagev1.StorageClass{})
+				return errors.IsNotFound(err)
+			}, timeout).Should(BeTrue())
+		})
+	})
+
 	Context("When a resource is modified", func() {
 		var (
 			cm  *corev1.ConfigMap
@@ -381,6 +647,33 @@ var _ = Describe("Integration tests using envtest", func() {
 		})
 	})
 
+	Context("When the images are changed", func() {
+		It("should record the upgrade", func() {
+			namespacedName := types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, namespacedName, cr)).To(Succeed())
+				g.Expect(cr.Status.DriverImage).To(Equal("some.registry/some/image:some-tag"))
+			}, timeout).Should(Succeed())
+
+			cr.Spec.ContainerImageOverrides.BeegfsCsiDriver.Tag = "some-other-tag"
+			Expect(k8sClient.Update(ctx, cr)).To(Succeed())
+
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, namespacedName, cr)).To(Succeed())
+				g.Expect(cr.Status.Upgrade).NotTo(BeNil())
+				g.Expect(cr.Status.Upgrade.PreviousImages).To(ContainElement(beegfsv1.ContainerImageStatus{
+					Name: deploy.ContainerNameBeegfsCsiDriver, Image: "some.registry/some/image:some-tag"}))
+				g.Expect(cr.Status.Upgrade.TargetImages).To(ContainElement(beegfsv1.ContainerImageStatus{
+					Name: deploy.ContainerNameBeegfsCsiDriver, Image: "some.registry/some/image:some-other-tag"}))
+				// There are no nodes, so there is nothing to roll out.
+				g.Expect(cr.Status.Upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseComplete))
+				condition := meta.FindStatusCondition(cr.Status.Conditions, beegfsv1.ConditionUpgradeProgressing)
+				g.Expect(condition).NotTo(BeNil())
+				g.Expect(condition.Reason).To(Equal(beegfsv1.ReasonUpgradeComplete))
+			}, timeout).Should(Succeed())
+		})
+	})
+
 	Context("When an invalid BeegfsDriver CR is submitted", func() {
 		// We can NOT test the inability to create a BeegfsDriver CR that is NOT named csi-beegfs-cr because we
 		// bootstrap the test environment from the config/crd/bases/ directory, but the restriction is created in the
@@ -409,6 +702,118 @@ var _ = Describe("Integration tests using envtest", func() {
 	})
 })
 
+var _ = Describe("Integration tests of fileSystemSecretRefs using envtest", func() {
+	const (
+		connAuthSecretName = "connauth-source"
+		tlsCertSecretName  = "tlscert-source"
+		sysMgmtdHost       = "10.10.10.10"
+	)
+	var (
+		ctx     context.Context
+		cr      *beegfsv1.BeegfsDriver
+		timeout = "5s"
+	)
+
+	BeforeEach(func() {
+		cr = getValidCRWithAllFields()
+		ctx = context.Background()
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: cr.Namespace}})).To(Succeed())
+
+		By("Submitting the referenced Secrets")
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: connAuthSecretName, Namespace: cr.Namespace},
+			StringData: map[string]string{"connAuthFile": "connAuthSecret1\n"},
+		})).To(Succeed())
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: tlsCertSecretName, Namespace: cr.Namespace},
+			StringData: map[string]string{"tls.crt": "certificate1"},
+		})).To(Succeed())
+
+		By("Submitting a BeegfsDriver CR that references them")
+		cr.Spec.FileSystemSecretRefs = []beegfsv1.FileSystemSecretRefs{
+			{
+				SysMgmtdHost: sysMgmtdHost,
+				ConnAuthSecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: connAuthSecretName},
+					Key:                  "connAuthFile",
+				},
+				TLSCertSecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: tlsCertSecretName},
+					Key:                  "tls.crt",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+	})
+
+	It("should assemble the ConnAuth and TLSCerts Secrets", func() {
+		s := new(corev1.Secret)
+		namespacedName := types.NamespacedName{Name: deploy.ResourceNameSecret, Namespace: cr.Namespace}
+		Eventually(func() (string, error) {
+			err := k8sClient.Get(ctx, namespacedName, s)
+			return string(s.Data[deploy.KeyNameSecret]), err
+		}, timeout).Should(SatisfyAll(
+			ContainSubstring("sysMgmtdHost: "+sysMgmtdHost),
+			// base64 encoding of "connAuthSecret1\n"
+			ContainSubstring("connAuth: Y29ubkF1dGhTZWNyZXQxCg=="),
+			ContainSubstring("encoding: base64")))
+
+		t := new(corev1.Secret)
+		namespacedName = types.NamespacedName{Name: deploy.ResourceNameTLS, Namespace: cr.Namespace}
+		Eventually(func() (string, error) {
+			err := k8sClient.Get(ctx, namespacedName, t)
+			return string(t.Data[deploy.KeyNameTLS]), err
+		}, timeout).Should(SatisfyAll(
+			ContainSubstring("sysMgmtdHost: "+sysMgmtdHost),
+			ContainSubstring("tlsCert: certificate1")))
+	})
+
+	Context("When a referenced Secret is rotated", func() {
+		It("should update the ConnAuth Secret and the Stateful Set and Daemon Set annotations", func() {
+			s := new(corev1.Secret)
+			namespacedName := types.NamespacedName{Name: deploy.ResourceNameSecret, Namespace: cr.Namespace}
+			Eventually(func() (string, error) {
+				err := k8sClient.Get(ctx, namespacedName, s)
+				return string(s.Data[deploy.KeyNameSecret]), err
+			}, timeout).Should(ContainSubstring("connAuth:"))
+			oldResourceVersion := s.ResourceVersion
+
+			By("Modifying the referenced connAuth Secret")
+			source := new(corev1.Secret)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: connAuthSecretName, Namespace: cr.Namespace},
+					source)
+				if err == nil {
+					source.StringData = map[string]string{"connAuthFile": "connAuthSecret2\n"}
+					return k8sClient.Update(ctx, source)
+				}
+				return err
+			}, timeout).Should(Succeed())
+
+			Eventually(func() (string, error) {
+				err := k8sClient.Get(ctx, namespacedName, s)
+				return s.ResourceVersion, err
+			}, timeout).ShouldNot(Equal(oldResourceVersion))
+			// base64 encoding of "connAuthSecret2\n"
+			Expect(string(s.Data[deploy.KeyNameSecret])).To(ContainSubstring("connAuth: Y29ubkF1dGhTZWNyZXQyCg=="))
+
+			sts, err := deploy.GetControllerServiceStatefulSet()
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() (string, error) {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: sts.Name, Namespace: cr.Namespace}, sts)
+				return sts.Spec.Template.Annotations[annotationConnauthSecretVersion], err
+			}, timeout).Should(ContainSubstring(s.ResourceVersion))
+
+			ds, err := deploy.GetNodeServiceDaemonSet()
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() (string, error) {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: ds.Name, Namespace: cr.Namespace}, ds)
+				return ds.Spec.Template.Annotations[annotationConnauthSecretVersion], err
+			}, timeout).Should(ContainSubstring(s.ResourceVersion))
+		})
+	})
+})
+
 var _ = Describe("Unit tests of helper functions", func() {
 	Describe("setImages", func() {
 		var containers []corev1.Container
@@ -568,6 +973,788 @@ var _ = Describe("Unit tests of helper functions", func() {
 			})
 		})
 	})
+
+	Describe("setControllerResources and setNodeResources", func() {
+		var controllerContainers, nodeContainers []corev1.Container
+
+		BeforeEach(func() {
+			sts, err := deploy.GetControllerServiceStatefulSet()
+			Expect(err).NotTo(HaveOccurred())
+			controllerContainers = sts.Spec.Template.Spec.Containers
+			ds, err := deploy.GetNodeServiceDaemonSet()
+			Expect(err).NotTo(HaveOccurred())
+			nodeContainers = ds.Spec.Template.Spec.Containers
+		})
+
+		getContainer := func(containers []corev1.Container, name string) corev1.Container {
+			for _, container := range containers {
+				if container.Name == name {
+					return container
+				}
+			}
+			Fail("container " + name + " not found")
+			return corev1.Container{}
+		}
+
+		Context("When only the container specific fields are set", func() {
+			It("should apply every resource they specify", func() {
+				overrides := beegfsv1.ContainerResourceOverrides{
+					ControllerCsiProvisionerResources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+					},
+					NodeBeegfsResources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+					},
+				}
+				setControllerResources(ctrl.Log, overrides, controllerContainers)
+				setNodeResources(ctrl.Log, overrides, nodeContainers)
+
+				provisioner := getContainer(controllerContainers, deploy.ContainerNameCsiProvisioner)
+				Expect(provisioner.Resources.Limits.Memory().String()).To(Equal("512Mi"))
+				beegfs := getContainer(nodeContainers, deploy.ContainerNameBeegfsCsiDriver)
+				Expect(beegfs.Resources.Requests.StorageEphemeral().String()).To(Equal("1Gi"))
+				// Resources that are not overridden keep the values from the manifests.
+				Expect(beegfs.Resources.Requests.Memory().IsZero()).To(BeFalse())
+			})
+		})
+
+		Context("When the container maps are set", func() {
+			It("should apply them to any container", func() {
+				overrides := beegfsv1.ContainerResourceOverrides{
+					ControllerContainers: map[string]corev1.ResourceRequirements{
+						deploy.ContainerNameCsiResizer: {
+							Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+						},
+					},
+					NodeContainers: map[string]corev1.ResourceRequirements{
+						deploy.ContainerNameLivenessProbe: {
+							Limits: corev1.ResourceList{"hugepages-2Mi": resource.MustParse("128Mi")},
+						},
+					},
+				}
+				setControllerResources(ctrl.Log, overrides, controllerContainers)
+				setNodeResources(ctrl.Log, overrides, nodeContainers)
+
+				resizer := getContainer(controllerContainers, deploy.ContainerNameCsiResizer)
+				Expect(resizer.Resources.Limits.Cpu().String()).To(Equal("1"))
+				livenessProbe := getContainer(nodeContainers, deploy.ContainerNameLivenessProbe)
+				Expect(livenessProbe.Resources.Limits.Name("hugepages-2Mi", resource.BinarySI).String()).To(
+					Equal("128Mi"))
+			})
+		})
+
+		Context("When both a container specific field and a container map set the same resource", func() {
+			It("should prefer the container map", func() {
+				overrides := beegfsv1.ContainerResourceOverrides{
+					ControllerBeegfsResources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("100m"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+					ControllerContainers: map[string]corev1.ResourceRequirements{
+						deploy.ContainerNameBeegfsCsiDriver: {
+							Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+						},
+					},
+				}
+				setControllerResources(ctrl.Log, overrides, controllerContainers)
+
+				beegfs := getContainer(controllerContainers, deploy.ContainerNameBeegfsCsiDriver)
+				Expect(beegfs.Resources.Limits.Cpu().String()).To(Equal("100m"))
+				Expect(beegfs.Resources.Limits.Memory().String()).To(Equal("2Gi"))
+			})
+		})
+	})
+
+	Describe("setPodTemplateOverrides", func() {
+		var template *corev1.PodTemplateSpec
+
+		BeforeEach(func() {
+			sts, err := deploy.GetControllerServiceStatefulSet()
+			Expect(err).NotTo(HaveOccurred())
+			template = &sts.Spec.Template
+		})
+
+		Context("When override is empty", func() {
+			It("should override nothing", func() {
+				original := template.DeepCopy()
+				Expect(setPodTemplateOverrides(ctrl.Log, beegfsv1.PodTemplateOverride{}, template)).To(Succeed())
+				Expect(template).To(Equal(original))
+			})
+		})
+
+		Context("When every field is overridden", func() {
+			It("should merge maps and named lists and replace everything else", func() {
+				original := template.DeepCopy()
+				override := beegfsv1.PodTemplateOverride{
+					Annotations: map[string]string{"key": "value"},
+					Containers: []beegfsv1.ContainerTemplateOverride{
+						{
+							Name: deploy.ContainerNameBeegfsCsiDriver,
+							Env: []corev1.EnvVar{
+								{Name: "LOG_LEVEL", Value: "5"},
+								{Name: "KEY", Value: "value"},
+							},
+							VolumeMounts: []corev1.VolumeMount{{Name: "extra", MountPath: "/extra"}},
+						},
+					},
+					NodeSelector:      map[string]string{"key": "value"},
+					PriorityClassName: "system-cluster-critical",
+					Tolerations:       []corev1.Toleration{{Key: "key", Operator: corev1.TolerationOpExists}},
+					Volumes: []corev1.Volume{
+						{Name: "extra", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					},
+				}
+				Expect(setPodTemplateOverrides(ctrl.Log, override, template)).To(Succeed())
+
+				for key, value := range original.Annotations {
+					Expect(template.Annotations).To(HaveKeyWithValue(key, value))
+				}
+				Expect(template.Annotations).To(HaveKeyWithValue("key", "value"))
+				Expect(template.Spec.NodeSelector).To(Equal(override.NodeSelector))
+				Expect(template.Spec.PriorityClassName).To(Equal(override.PriorityClassName))
+				Expect(template.Spec.Tolerations).To(Equal(override.Tolerations))
+				Expect(template.Spec.Volumes).To(HaveLen(len(original.Spec.Volumes) + 1))
+				Expect(template.Spec.Volumes).To(ContainElement(override.Volumes[0]))
+				Expect(template.Spec.Containers).To(HaveLen(len(original.Spec.Containers)))
+
+				for i, container := range template.Spec.Containers {
+					originalContainer := original.Spec.Containers[i]
+					Expect(container.Name).To(Equal(originalContainer.Name))
+					Expect(container.Image).To(Equal(originalContainer.Image))
+					if container.Name != deploy.ContainerNameBeegfsCsiDriver {
+						Expect(container).To(Equal(originalContainer))
+						continue
+					}
+					Expect(container.Env).To(HaveLen(len(originalContainer.Env) + 1))
+					Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "LOG_LEVEL", Value: "5"}))
+					Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "KEY", Value: "value"}))
+					Expect(container.VolumeMounts).To(HaveLen(len(originalContainer.VolumeMounts) + 1))
+					Expect(container.VolumeMounts).To(ContainElement(override.Containers[0].VolumeMounts[0]))
+				}
+			})
+		})
+	})
+
+	Describe("getUpgradeStatus", func() {
+		const (
+			oldImage = "some.registry/some/image:old-tag"
+			newImage = "some.registry/some/image:new-tag"
+		)
+		var (
+			now           metav1.Time
+			ds            *appsv1.DaemonSet
+			oldImages     []beegfsv1.ContainerImageStatus
+			newImages     []beegfsv1.ContainerImageStatus
+			strategy      beegfsv1.UpgradeStrategy
+			inProgress    *beegfsv1.UpgradeStatus
+			readyPod      func(name, image string) corev1.Pod
+			unreadyPod    func(name, image string, age time.Duration) corev1.Pod
+			readyCond     = corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue}
+			unreadyCond   = corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse}
+			deadlineInSec = int32(60)
+		)
+
+		BeforeEach(func() {
+			now = metav1.Now()
+			ds = &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "csi-beegfs-node"},
+				Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2},
+			}
+			oldImages = []beegfsv1.ContainerImageStatus{{Name: deploy.ContainerNameBeegfsCsiDriver, Image: oldImage}}
+			newImages = []beegfsv1.ContainerImageStatus{{Name: deploy.ContainerNameBeegfsCsiDriver, Image: newImage}}
+			strategy = beegfsv1.UpgradeStrategy{ProgressDeadlineSeconds: &deadlineInSec}
+			inProgress = &beegfsv1.UpgradeStatus{
+				PreviousImages: oldImages,
+				TargetImages:   newImages,
+				StartTime:      now,
+				Phase:          beegfsv1.UpgradePhaseInProgress,
+			}
+			newPod := func(name, image string, age time.Duration, condition corev1.PodCondition) corev1.Pod {
+				return corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              name,
+						CreationTimestamp: metav1.NewTime(now.Add(-age)),
+					},
+					Spec: corev1.PodSpec{
+						NodeName:   name + "-node",
+						Containers: []corev1.Container{{Name: deploy.ContainerNameBeegfsCsiDriver, Image: image}},
+					},
+					Status: corev1.PodStatus{Conditions: []corev1.PodCondition{condition}},
+				}
+			}
+			readyPod = func(name, image string) corev1.Pod { return newPod(name, image, time.Hour, readyCond) }
+			unreadyPod = func(name, image string, age time.Duration) corev1.Pod {
+				return newPod(name, image, age, unreadyCond)
+			}
+		})
+
+		Context("When the driver has not been deployed", func() {
+			It("should not report an upgrade", func() {
+				upgrade, condition := getUpgradeStatus(nil, strategy, newImages, nil, &appsv1.DaemonSet{}, nil, now)
+				Expect(upgrade).To(BeNil())
+				Expect(condition).To(BeNil())
+			})
+		})
+
+		Context("When the images have not changed since the driver was deployed", func() {
+			It("should not report an upgrade", func() {
+				pods := []corev1.Pod{readyPod("a", oldImage), readyPod("b", oldImage)}
+				upgrade, condition := getUpgradeStatus(nil, strategy, oldImages, oldImages, ds, pods, now)
+				Expect(upgrade).To(BeNil())
+				Expect(condition).To(BeNil())
+			})
+		})
+
+		Context("When the images change", func() {
+			It("should start an upgrade from the images in the cluster", func() {
+				pods := []corev1.Pod{readyPod("a", oldImage), readyPod("b", oldImage)}
+				upgrade, condition := getUpgradeStatus(nil, strategy, newImages, oldImages, ds, pods, now)
+				Expect(upgrade).NotTo(BeNil())
+				Expect(upgrade.PreviousImages).To(Equal(oldImages))
+				Expect(upgrade.TargetImages).To(Equal(newImages))
+				Expect(upgrade.StartTime).To(Equal(now))
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseInProgress))
+				Expect(upgrade.UpdatedNodesReady).To(Equal("0/2"))
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal(beegfsv1.ReasonUpgradeInProgress))
+			})
+		})
+
+		Context("When an updated pod is not ready within the deadline", func() {
+			It("should pause the upgrade", func() {
+				pods := []corev1.Pod{readyPod("a", newImage), unreadyPod("b", newImage, 2*time.Minute)}
+				upgrade, condition := getUpgradeStatus(inProgress, strategy, newImages, newImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhasePaused))
+				Expect(upgrade.UpdatedNodesReady).To(Equal("1/2"))
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal(beegfsv1.ReasonUpgradePaused))
+				Expect(condition.Message).To(ContainSubstring("b-node"))
+				Expect(inProgress.Phase).To(Equal(beegfsv1.UpgradePhaseInProgress)) // Don't modify the argument.
+			})
+
+			It("should roll back the upgrade if autoRollback is true", func() {
+				strategy.AutoRollback = true
+				pods := []corev1.Pod{readyPod("a", newImage), unreadyPod("b", newImage, 2*time.Minute)}
+				upgrade, condition := getUpgradeStatus(inProgress, strategy, newImages, newImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseRolledBack))
+				Expect(condition.Reason).To(Equal(beegfsv1.ReasonUpgradeRolledBack))
+
+				// The rollback sticks even though the Pods now run the previous images.
+				pods = []corev1.Pod{readyPod("a", oldImage), readyPod("b", oldImage)}
+				upgrade, condition = getUpgradeStatus(upgrade, strategy, newImages, oldImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseRolledBack))
+				Expect(condition).To(BeNil())
+			})
+		})
+
+		Context("When an updated pod is not ready but the deadline has not passed", func() {
+			It("should continue the upgrade", func() {
+				pods := []corev1.Pod{readyPod("a", newImage), unreadyPod("b", newImage, 30*time.Second)}
+				upgrade, condition := getUpgradeStatus(inProgress, strategy, newImages, newImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseInProgress))
+				Expect(condition.Reason).To(Equal(beegfsv1.ReasonUpgradeInProgress))
+			})
+		})
+
+		Context("When a paused upgrade's failed pod becomes ready", func() {
+			It("should resume the upgrade", func() {
+				paused := inProgress.DeepCopy()
+				paused.Phase = beegfsv1.UpgradePhasePaused
+				pods := []corev1.Pod{readyPod("a", newImage), readyPod("b", oldImage)}
+				upgrade, _ := getUpgradeStatus(paused, strategy, newImages, newImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseInProgress))
+			})
+		})
+
+		Context("When every pod runs the new images and is ready", func() {
+			It("should complete the upgrade and keep it complete", func() {
+				pods := []corev1.Pod{readyPod("a", newImage), readyPod("b", newImage)}
+				upgrade, condition := getUpgradeStatus(inProgress, strategy, newImages, newImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseComplete))
+				Expect(upgrade.UpdatedNodesReady).To(Equal("2/2"))
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal(beegfsv1.ReasonUpgradeComplete))
+
+				// A pod that fails after the upgrade completes has nothing to do with the upgrade.
+				pods = []corev1.Pod{readyPod("a", newImage), unreadyPod("b", newImage, time.Hour)}
+				upgrade, condition = getUpgradeStatus(upgrade, strategy, newImages, newImages, ds, pods, now)
+				Expect(upgrade.Phase).To(Equal(beegfsv1.UpgradePhaseComplete))
+				Expect(condition).To(BeNil())
+			})
+		})
+	})
+
+	Describe("setUpdateStrategy and setRollbackImages", func() {
+		var ds *appsv1.DaemonSet
+
+		BeforeEach(func() {
+			var err error
+			ds, err = deploy.GetNodeServiceDaemonSet()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("When there is no upgrade", func() {
+			It("should roll out updates to one pod at a time and not change images", func() {
+				original := ds.DeepCopy()
+				setUpdateStrategy(ctrl.Log, beegfsv1.UpgradeStrategy{}, nil, &ds.Spec)
+				setRollbackImages(ctrl.Log, nil, ds.Spec.Template.Spec.Containers)
+				Expect(ds.Spec.UpdateStrategy.Type).To(Equal(appsv1.RollingUpdateDaemonSetStrategyType))
+				Expect(*ds.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable).To(Equal(intstr.FromInt32(1)))
+				Expect(ds.Spec.Template.Spec.Containers).To(Equal(original.Spec.Template.Spec.Containers))
+			})
+		})
+
+		Context("When maxUnavailable is set", func() {
+			It("should use it", func() {
+				maxUnavailable := intstr.FromString("25%")
+				setUpdateStrategy(ctrl.Log, beegfsv1.UpgradeStrategy{MaxUnavailable: &maxUnavailable}, nil, &ds.Spec)
+				Expect(*ds.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable).To(Equal(maxUnavailable))
+			})
+		})
+
+		Context("When the upgrade is paused", func() {
+			It("should stop replacing pods", func() {
+				upgrade := &beegfsv1.UpgradeStatus{Phase: beegfsv1.UpgradePhasePaused}
+				setUpdateStrategy(ctrl.Log, beegfsv1.UpgradeStrategy{}, upgrade, &ds.Spec)
+				Expect(ds.Spec.UpdateStrategy.Type).To(Equal(appsv1.OnDeleteDaemonSetStrategyType))
+				Expect(ds.Spec.UpdateStrategy.RollingUpdate).To(BeNil())
+			})
+		})
+
+		Context("When the upgrade is rolled back", func() {
+			It("should use the previous images", func() {
+				const previousImage = "some.registry/some/image:previous-tag"
+				upgrade := &beegfsv1.UpgradeStatus{
+					Phase: beegfsv1.UpgradePhaseRolledBack,
+					PreviousImages: []beegfsv1.ContainerImageStatus{
+						{Name: deploy.ContainerNameBeegfsCsiDriver, Image: previousImage},
+					},
+				}
+				setRollbackImages(ctrl.Log, upgrade, ds.Spec.Template.Spec.Containers)
+				for _, container := range ds.Spec.Template.Spec.Containers {
+					if container.Name == deploy.ContainerNameBeegfsCsiDriver {
+						Expect(container.Image).To(Equal(previousImage))
+					} else {
+						Expect(container.Image).NotTo(Equal(previousImage))
+					}
+				}
+			})
+		})
+	})
+
+	Describe("setClusterScopedObjectMetadata", func() {
+		var (
+			rbacInterfaces []interface{}
+			d              *storagev1.CSIDriver
+			req            = ctrl.Request{NamespacedName: types.NamespacedName{Name: "csi-beegfs-cr", Namespace: "tenant-a"}}
+		)
+
+		BeforeEach(func() {
+			var err error
+			rbacInterfaces, err = deploy.GetRBAC()
+			Expect(err).NotTo(HaveOccurred())
+			d, err = deploy.GetCSIDriver()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("When the driver name is the default", func() {
+			It("should keep the names from the deployment manifests", func() {
+				original, err := deploy.GetRBAC()
+				Expect(err).NotTo(HaveOccurred())
+				setClusterScopedObjectMetadata(req, deploy.DefaultDriverName, rbacInterfaces, d)
+				Expect(d.Name).To(Equal(deploy.DefaultDriverName))
+				for i, object := range rbacInterfaces {
+					Expect(object.(metav1.Object).GetName()).To(Equal(original[i].(metav1.Object).GetName()))
+				}
+				Expect(d.Annotations).To(HaveKeyWithValue(annotationOwner, "tenant-a/csi-beegfs-cr"))
+			})
+		})
+
+		Context("When the driver name is not the default", func() {
+			It("should derive the names of cluster-scoped objects and references to them", func() {
+				const driverName = "tenant-a.beegfs.csi.netapp.com"
+				setClusterScopedObjectMetadata(req, driverName, rbacInterfaces, d)
+				Expect(d.Name).To(Equal(driverName))
+				for _, i := range rbacInterfaces {
+					switch object := i.(type) {
+					case *rbacv1.ClusterRole:
+						Expect(object.Name).To(HaveSuffix("-" + driverName))
+						Expect(object.Annotations).To(HaveKeyWithValue(annotationOwner, "tenant-a/csi-beegfs-cr"))
+					case *rbacv1.ClusterRoleBinding:
+						Expect(object.Name).To(HaveSuffix("-" + driverName))
+						Expect(object.RoleRef.Name).To(HaveSuffix("-" + driverName))
+					case *rbacv1.RoleBinding:
+						Expect(object.Name).NotTo(HaveSuffix("-" + driverName))
+					}
+				}
+			})
+		})
+	})
+
+	Describe("checkClusterScopedObjectOwner", func() {
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "csi-beegfs-cr", Namespace: "tenant-a"}}
+
+		It("should allow objects without an owner or owned by the BeegfsDriver", func() {
+			object := &storagev1.CSIDriver{}
+			Expect(checkClusterScopedObjectOwner(req, object)).To(Succeed())
+			metav1.SetMetaDataAnnotation(&object.ObjectMeta, annotationOwner, "tenant-a/csi-beegfs-cr")
+			Expect(checkClusterScopedObjectOwner(req, object)).To(Succeed())
+		})
+
+		It("should reject objects owned by another BeegfsDriver", func() {
+			object := &storagev1.CSIDriver{}
+			metav1.SetMetaDataAnnotation(&object.ObjectMeta, annotationOwner, "tenant-b/csi-beegfs-cr")
+			err := checkClusterScopedObjectOwner(req, object)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("owned by BeegfsDriver tenant-b/csi-beegfs-cr"))
+		})
+	})
+
+	Describe("getDesiredStateHash", func() {
+		It("should ignore an existing hash and detect changes", func() {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "cm"},
+				Data:       map[string]string{"key": "value"},
+			}
+			hash, err := getDesiredStateHash(cm)
+			Expect(err).NotTo(HaveOccurred())
+			metav1.SetMetaDataAnnotation(&cm.ObjectMeta, annotationDesiredStateHash, hash)
+			Expect(getDesiredStateHash(cm)).To(Equal(hash))
+			Expect(cm.Annotations).To(HaveKeyWithValue(annotationDesiredStateHash, hash))
+			cm.Data["key"] = "otherValue"
+			Expect(getDesiredStateHash(cm)).NotTo(Equal(hash))
+		})
+	})
+
+	Describe("newStorageClass", func() {
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "csi-beegfs-cr", Namespace: "tenant-a"}}
+		const driverName = "tenant-a.beegfs.csi.netapp.com"
+
+		Context("When the template is minimal", func() {
+			It("should fill in the defaults the API server would set", func() {
+				template := beegfsv1.StorageClassTemplate{
+					Name:       "beegfs",
+					Parameters: map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s"},
+				}
+				sc, err := newStorageClass(req, driverName, template)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sc.Provisioner).To(Equal(driverName))
+				Expect(*sc.ReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+				Expect(*sc.VolumeBindingMode).To(Equal(storagev1.VolumeBindingImmediate))
+				Expect(*sc.AllowVolumeExpansion).To(BeFalse())
+				Expect(sc.Labels).To(HaveKeyWithValue(labelDriverName, driverName))
+				Expect(sc.Annotations).To(HaveKeyWithValue(annotationOwner, "tenant-a/csi-beegfs-cr"))
+			})
+		})
+
+		Context("When the template sets labels that collide with the operator's", func() {
+			It("should keep the operator's labels and annotations", func() {
+				template := beegfsv1.StorageClassTemplate{
+					Name:        "beegfs",
+					Labels:      map[string]string{labelDriverName: "other", "team": "a"},
+					Annotations: map[string]string{annotationOwner: "other/other"},
+					Parameters:  map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s"},
+				}
+				sc, err := newStorageClass(req, driverName, template)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sc.Labels).To(HaveKeyWithValue(labelDriverName, driverName))
+				Expect(sc.Labels).To(HaveKeyWithValue("team", "a"))
+				Expect(sc.Annotations).To(HaveKeyWithValue(annotationOwner, "tenant-a/csi-beegfs-cr"))
+				Expect(template.Labels).To(HaveKeyWithValue(labelDriverName, "other"))
+			})
+		})
+
+		Context("When the parameters are invalid", func() {
+			It("should fail", func() {
+				for _, params := range []map[string]string{
+					{"volDirBasePath": "/k8s"},
+					{"sysMgmtdHost": "127.0.0.1"},
+					{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s", "stripePattern/chunkSize": "2"},
+					{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s", "permissions/mode": "0999"},
+					{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s", "volDirBasepath": "/k8s"},
+				} {
+					_, err := newStorageClass(req, driverName, beegfsv1.StorageClassTemplate{Name: "beegfs",
+						Parameters: params})
+					Expect(err).To(HaveOccurred(), "expected parameters %v to be invalid", params)
+				}
+			})
+		})
+	})
+
+	Describe("storageClassNeedsRecreate and storageClassNeedsUpdate", func() {
+		var sc *storagev1.StorageClass
+
+		BeforeEach(func() {
+			var err error
+			sc, err = newStorageClass(ctrl.Request{}, deploy.DefaultDriverName, beegfsv1.StorageClassTemplate{
+				Name:       "beegfs",
+				Parameters: map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/k8s"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should do nothing if the StorageClass is unchanged", func() {
+			scFromCluster := sc.DeepCopy()
+			scFromCluster.ResourceVersion = "1"
+			Expect(storageClassNeedsRecreate(sc, scFromCluster)).To(BeFalse())
+			Expect(storageClassNeedsUpdate(sc, scFromCluster)).To(BeFalse())
+		})
+
+		It("should recreate a StorageClass whose immutable fields changed", func() {
+			scFromCluster := sc.DeepCopy()
+			scFromCluster.Parameters = map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/other"}
+			Expect(storageClassNeedsRecreate(sc, scFromCluster)).To(BeTrue())
+			scFromCluster = sc.DeepCopy()
+			reclaimPolicy := corev1.PersistentVolumeReclaimRetain
+			scFromCluster.ReclaimPolicy = &reclaimPolicy
+			Expect(storageClassNeedsRecreate(sc, scFromCluster)).To(BeTrue())
+		})
+
+		It("should update a StorageClass whose mutable fields changed", func() {
+			scFromCluster := sc.DeepCopy()
+			scFromCluster.MountOptions = []string{"ro"}
+			Expect(storageClassNeedsRecreate(sc, scFromCluster)).To(BeFalse())
+			Expect(storageClassNeedsUpdate(sc, scFromCluster)).To(BeTrue())
+		})
+	})
+
+	Describe("setStorageClassesCondition", func() {
+		It("should only set the condition if there are storageClassTemplates", func() {
+			driver := getValidCRWithNoFields()
+			setStorageClassesCondition(driver)
+			Expect(meta.FindStatusCondition(driver.Status.Conditions, beegfsv1.ConditionStorageClassesValid)).To(BeNil())
+
+			driver.Spec.StorageClassTemplates = []beegfsv1.StorageClassTemplate{{Name: "a"}, {Name: "b"}}
+			driver.Status.InvalidStorageClasses = []beegfsv1.StorageClassStatus{{Name: "b", Message: "invalid"}}
+			setStorageClassesCondition(driver)
+			condition := meta.FindStatusCondition(driver.Status.Conditions, beegfsv1.ConditionStorageClassesValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(Equal("1/2 storage class templates are valid"))
+
+			driver.Spec.StorageClassTemplates = nil
+			setStorageClassesCondition(driver)
+			Expect(meta.FindStatusCondition(driver.Status.Conditions, beegfsv1.ConditionStorageClassesValid)).To(BeNil())
+		})
+	})
+
+	Describe("findBeegfsDriverForClusterScopedObject", func() {
+		It("should map an object to the BeegfsDriver in its owner annotation", func() {
+			sc := new(storagev1.StorageClass)
+			Expect(findBeegfsDriverForClusterScopedObject(context.Background(), sc)).To(BeEmpty())
+			metav1.SetMetaDataAnnotation(&sc.ObjectMeta, annotationOwner, "tenant-a/csi-beegfs-cr")
+			Expect(findBeegfsDriverForClusterScopedObject(context.Background(), sc)).To(ConsistOf(ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: "csi-beegfs-cr", Namespace: "tenant-a"}}))
+		})
+	})
+
+	Describe("setDriverName and setHealthPort", func() {
+		var ds *appsv1.DaemonSet
+
+		BeforeEach(func() {
+			var err error
+			ds, err = deploy.GetNodeServiceDaemonSet()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("When the driver name is the default and the health port is not set", func() {
+			It("should change nothing", func() {
+				original := ds.DeepCopy()
+				setDriverName(ctrl.Log, deploy.DefaultDriverName, &ds.Spec.Template.Spec)
+				setHealthPort(ctrl.Log, nil, &ds.Spec.Template.Spec)
+				Expect(ds.Spec.Template.Spec).To(Equal(original.Spec.Template.Spec))
+			})
+		})
+
+		Context("When the driver name is not the default", func() {
+			It("should replace the driver name and plugin directory", func() {
+				const driverName = "tenant-a.beegfs.csi.netapp.com"
+				setDriverName(ctrl.Log, driverName, &ds.Spec.Template.Spec)
+				var args, paths []string
+				for _, container := range ds.Spec.Template.Spec.Containers {
+					args = append(args, container.Args...)
+					for _, volumeMount := range container.VolumeMounts {
+						paths = append(paths, volumeMount.MountPath)
+					}
+				}
+				for _, volume := range ds.Spec.Template.Spec.Volumes {
+					if volume.HostPath != nil {
+						paths = append(paths, volume.HostPath.Path)
+					}
+				}
+				Expect(args).To(ContainElements("--driver-name="+driverName,
+					"--kubelet-registration-path=/var/lib/kubelet/plugins/"+driverName+"/csi.sock"))
+				Expect(paths).To(ContainElements("/var/lib/kubelet/plugins/"+driverName,
+					"/var/lib/kubelet/plugins/kubernetes.io/csi"))
+				for _, s := range append(args, paths...) {
+					Expect(s).NotTo(ContainSubstring("/" + deploy.DefaultDriverName))
+				}
+			})
+		})
+
+		Context("When the health port is set", func() {
+			It("should replace the container port, host port, and --health-port argument", func() {
+				port := int32(9899)
+				setHealthPort(ctrl.Log, &port, &ds.Spec.Template.Spec)
+				for _, container := range ds.Spec.Template.Spec.Containers {
+					for _, containerPort := range container.Ports {
+						if containerPort.Name == "healthz" {
+							Expect(containerPort.ContainerPort).To(Equal(port))
+							Expect(containerPort.HostPort).To(Equal(port))
+						}
+					}
+					if container.Name == deploy.ContainerNameLivenessProbe {
+						Expect(container.Args).To(ContainElement("--health-port=9899"))
+					}
+				}
+			})
+		})
+	})
+
+	Describe("getUnreadyNodes", func() {
+		readyCondition := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue}
+		unreadyCondition := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse}
+
+		It("should report only nodes with an unready node service Pod", func() {
+			pods := []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "ready"},
+					Spec:       corev1.PodSpec{NodeName: "node1"},
+					Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{readyCondition}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "unscheduled"},
+					Status:     corev1.PodStatus{Phase: corev1.PodPending},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "module-missing"},
+					Spec:       corev1.PodSpec{NodeName: "node3"},
+					Status: corev1.PodStatus{
+						Conditions: []corev1.PodCondition{unreadyCondition},
+						ContainerStatuses: []corev1.ContainerStatus{
+							{Name: deploy.ContainerNameCsiNodeDriverRegistrar, Ready: true},
+							{
+								Name:  deploy.ContainerNameBeegfsCsiDriver,
+								State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+								LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+									Message: "failed to initialize driver: " + beegfsv1.BeegfsClientModuleMissingMessage,
+								}},
+							},
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "image-pull"},
+					Spec:       corev1.PodSpec{NodeName: "node2"},
+					Status: corev1.PodStatus{
+						Conditions: []corev1.PodCondition{unreadyCondition},
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name:  deploy.ContainerNameBeegfsCsiDriver,
+								State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+							},
+						},
+					},
+				},
+			}
+			unreadyNodes := getUnreadyNodes(pods)
+			Expect(unreadyNodes).To(HaveLen(2))
+			Expect(unreadyNodes[0].NodeName).To(Equal("node2"))
+			Expect(unreadyNodes[0].Reason).To(Equal(beegfsv1.ReasonPodNotReady))
+			Expect(unreadyNodes[0].Message).To(ContainSubstring("ImagePullBackOff"))
+			Expect(unreadyNodes[1].NodeName).To(Equal("node3"))
+			Expect(unreadyNodes[1].Reason).To(Equal(beegfsv1.ReasonBeegfsClientModuleMissing))
+		})
+	})
+
+	Describe("getFileSystemStatus", func() {
+		Context("When a controller service Pod has reported file system status", func() {
+			It("should prefer the status reported by a ready Pod", func() {
+				pods := []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "old", Annotations: map[string]string{
+							beegfsv1.AnnotationFileSystemStatus: `[{"sysMgmtdHost":"1.1.1.1","reachable":false}]`,
+						}},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "new", Annotations: map[string]string{
+							beegfsv1.AnnotationFileSystemStatus: `[{"sysMgmtdHost":"1.1.1.1","reachable":true,"beegfsVersion":"8"}]`,
+						}},
+						Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+							{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+						}},
+					},
+				}
+				statuses := getFileSystemStatus(ctrl.Log, pods)
+				Expect(statuses).To(HaveLen(1))
+				Expect(statuses[0].Reachable).To(BeTrue())
+				Expect(statuses[0].BeegfsVersion).To(Equal("8"))
+			})
+		})
+
+		Context("When no controller service Pod has reported valid file system status", func() {
+			It("should return nil", func() {
+				pods := []corev1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Name: "unannotated"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "invalid", Annotations: map[string]string{
+						beegfsv1.AnnotationFileSystemStatus: "invalid",
+					}}},
+				}
+				Expect(getFileSystemStatus(ctrl.Log, pods)).To(BeNil())
+			})
+		})
+	})
+
+	Describe("countConfiguredFileSystems", func() {
+		It("should count each sysMgmtdHost once", func() {
+			Expect(countConfiguredFileSystems(beegfsv1.PluginConfigFromFile{})).To(Equal(0))
+			// getValidCRWithAllFields configures the same sysMgmtdHost at the top level and for node1.
+			Expect(countConfiguredFileSystems(getValidCRWithAllFields().Spec.PluginConfigFromFile)).To(Equal(1))
+			pluginConfig := getValidCRWithAllFields().Spec.PluginConfigFromFile
+			pluginConfig.NodeSpecificConfigs[0].FileSystemSpecificConfigs = []beegfsv1.FileSystemSpecificConfig{
+				{SysMgmtdHost: "1.1.1.1"},
+			}
+			Expect(countConfiguredFileSystems(pluginConfig)).To(Equal(2))
+		})
+	})
+
+	Describe("withKind and getErrorKind", func() {
+		It("should associate an error with the first kind it is wrapped with", func() {
+			Expect(withKind("ConfigMap", nil)).To(BeNil())
+			Expect(getErrorKind(fmt.Errorf("error"))).To(Equal("BeegfsDriver"))
+			err := withKind("DaemonSet", withKind("ConfigMap", errors.NewNotFound(schema.GroupResource{}, "name")))
+			Expect(getErrorKind(err)).To(Equal("ConfigMap"))
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("setDriverMetrics and deleteDriverMetrics", func() {
+		It("should export and remove the gauges for a BeegfsDriver", func() {
+			driver := getValidCRWithAllFields()
+			driver.Generation = 3
+			nn := types.NamespacedName{Namespace: driver.Namespace, Name: driver.Name}
+			sts := &appsv1.StatefulSet{Status: appsv1.StatefulSetStatus{ReadyReplicas: 1}}
+			ds := &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{NumberReady: 2, DesiredNumberScheduled: 3}}
+			setDriverMetrics(driver, sts, ds)
+			reconcileErrorsTotal.WithLabelValues(nn.Namespace, nn.Name, "ConfigMap").Inc()
+			lastSuccessfulReconcile.set(nn, time.Now())
+			Expect(testutil.ToFloat64(nodePodsReady.WithLabelValues(nn.Namespace, nn.Name))).To(Equal(2.0))
+			Expect(testutil.ToFloat64(nodePodsDesired.WithLabelValues(nn.Namespace, nn.Name))).To(Equal(3.0))
+			Expect(testutil.ToFloat64(controllerReady.WithLabelValues(nn.Namespace, nn.Name))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(fileSystemsConfigured.WithLabelValues(nn.Namespace, nn.Name))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(configGeneration.WithLabelValues(nn.Namespace, nn.Name))).To(Equal(3.0))
+			Expect(testutil.CollectAndCount(lastSuccessfulReconcile)).To(BeNumerically(">=", 1))
+
+			deleteDriverMetrics(nn)
+			Expect(nodePodsReady.DeleteLabelValues(nn.Namespace, nn.Name)).To(BeFalse())
+			Expect(reconcileErrorsTotal.DeleteLabelValues(nn.Namespace, nn.Name, "ConfigMap")).To(BeFalse())
+			lastSuccessfulReconcile.mutex.Lock()
+			Expect(lastSuccessfulReconcile.times).NotTo(HaveKey(nn))
+			lastSuccessfulReconcile.mutex.Unlock()
+		})
+	})
 })
 
 // getContainerImageForName is a helper function used only in tests. It returns the image field of a Container in a
@@ -583,14 +1770,17 @@ func getContainerImageForName(name string, containers []corev1.Container) string
 }
 
 // getValidCRWithNoFields is a helper function that returns a pointer to a BeegfsDriver CR in a random namespace with
-// no configuration.
+// no configuration other than a driver name derived from the namespace. Tests do not delete the cluster-scoped objects
+// a CR creates, so every CR needs a unique driver name.
 func getValidCRWithNoFields() *beegfsv1.BeegfsDriver {
 	rand.Seed(time.Now().UnixMilli())
+	namespace := fmt.Sprintf("test-%s", strconv.Itoa(rand.Intn(100000))) // Use a random namespace.
 	return &beegfsv1.BeegfsDriver{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "csi-beegfs-cr",
-			Namespace: fmt.Sprintf("test-%s", strconv.Itoa(rand.Intn(100000))), // Use a random namespace.
+			Namespace: namespace,
 		},
+		Spec: beegfsv1.BeegfsDriverSpec{DriverName: namespace + "." + deploy.DefaultDriverName},
 	}
 }
 
@@ -683,5 +1873,24 @@ func getValidCRWithAllFields() *beegfsv1.BeegfsDriver {
 	logLevel := 3
 	cr.Spec.LogLevel = &logLevel
 
+	cr.Spec.PodTemplateOverrides = beegfsv1.PodTemplateOverrides{
+		NodeService: beegfsv1.PodTemplateOverride{
+			Annotations: map[string]string{"key": "value"},
+			Containers: []beegfsv1.ContainerTemplateOverride{
+				{
+					Name:         deploy.ContainerNameBeegfsCsiDriver,
+					Env:          []corev1.EnvVar{{Name: "KEY", Value: "value"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "extra", MountPath: "/extra"}},
+				},
+			},
+			NodeSelector:      map[string]string{"key": "value"},
+			PriorityClassName: "system-node-critical",
+			Tolerations:       []corev1.Toleration{{Key: "key", Operator: corev1.TolerationOpExists}},
+			Volumes: []corev1.Volume{
+				{Name: "extra", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+
 	return cr
 }
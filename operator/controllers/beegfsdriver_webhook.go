@@ -0,0 +1,282 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	deploy "github.com/netapp/beegfs-csi-driver/deploy/k8s"
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// defaultLogLevel matches the LOG_LEVEL set in the deployment manifests and documented in BeegfsDriverSpec.
+const defaultLogLevel = 3
+
+// SetupBeegfsDriverWebhookWithManager registers the BeegfsDriver defaulting and validating webhooks with the Manager's
+// webhook server.
+func SetupBeegfsDriverWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&beegfsv1.BeegfsDriver{}).
+		WithDefaulter(&BeegfsDriverDefaulter{}).
+		WithValidator(&BeegfsDriverValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-beegfs-csi-netapp-com-v1-beegfsdriver,mutating=true,failurePolicy=fail,sideEffects=None,groups=beegfs.csi.netapp.com,resources=beegfsdrivers,verbs=create;update,versions=v1,name=mbeegfsdriver.csi.netapp.com,admissionReviewVersions=v1
+
+// BeegfsDriverDefaulter fills in defaults for a BeegfsDriver when it is created or updated.
+type BeegfsDriverDefaulter struct{}
+
+var _ admission.CustomDefaulter = &BeegfsDriverDefaulter{}
+
+// Default sets the log level if it is not specified and completes partially specified container image overrides with
+// the default image or tag from the deployment manifests. Container image overrides that specify neither an image nor
+// a tag are intentionally left empty so that upgrading the operator continues to upgrade the images it deploys.
+func (d *BeegfsDriverDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	driver, ok := obj.(*beegfsv1.BeegfsDriver)
+	if !ok {
+		return fmt.Errorf("expected a BeegfsDriver but got a %T", obj)
+	}
+
+	if driver.Spec.LogLevel == nil {
+		logLevel := defaultLogLevel
+		driver.Spec.LogLevel = &logLevel
+	}
+
+	defaultImages, err := getDefaultImages()
+	if err != nil {
+		return err
+	}
+	overrides := &driver.Spec.ContainerImageOverrides
+	for containerName, override := range map[string]*beegfsv1.ContainerImageOverride{
+		deploy.ContainerNameBeegfsCsiDriver:        &overrides.BeegfsCsiDriver,
+		deploy.ContainerNameCsiNodeDriverRegistrar: &overrides.CsiNodeDriverRegistrar,
+		deploy.ContainerNameCsiProvisioner:         &overrides.CsiProvisioner,
+		deploy.ContainerNameCsiResizer:             &overrides.CsiResizer,
+		deploy.ContainerNameLivenessProbe:          &overrides.LivenessProbe,
+	} {
+		if len(override.Image) == 0 && len(override.Tag) == 0 {
+			continue
+		}
+		// Split the image from the tag in the same way getImageStringWithOverride does.
+		defaultImageSlice := strings.SplitN(defaultImages[containerName], ":", 2)
+		if len(override.Image) == 0 {
+			override.Image = defaultImageSlice[0]
+		}
+		if len(override.Tag) == 0 && len(defaultImageSlice) > 1 {
+			override.Tag = defaultImageSlice[1]
+		}
+	}
+	return nil
+}
+
+// getDefaultImages returns a map of container name to the image (including tag) specified for that container in the
+// deployment manifests.
+func getDefaultImages() (map[string]string, error) {
+	sts, err := deploy.GetControllerServiceStatefulSet()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := deploy.GetNodeServiceDaemonSet()
+	if err != nil {
+		return nil, err
+	}
+	defaultImages := make(map[string]string)
+	for _, containers := range [][]corev1.Container{sts.Spec.Template.Spec.Containers, ds.Spec.Template.Spec.Containers} {
+		for _, container := range containers {
+			defaultImages[container.Name] = container.Image
+		}
+	}
+	return defaultImages, nil
+}
+
+//+kubebuilder:webhook:path=/validate-beegfs-csi-netapp-com-v1-beegfsdriver,mutating=false,failurePolicy=fail,sideEffects=None,groups=beegfs.csi.netapp.com,resources=beegfsdrivers,verbs=create;update,versions=v1,name=vbeegfsdriver.csi.netapp.com,admissionReviewVersions=v1
+
+// BeegfsDriverValidator rejects a BeegfsDriver with a pluginConfig the driver would refuse to start with.
+type BeegfsDriverValidator struct{}
+
+var _ admission.CustomValidator = &BeegfsDriverValidator{}
+
+// ValidateCreate validates a new BeegfsDriver.
+func (v *BeegfsDriverValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return validateBeegfsDriver(obj)
+}
+
+// ValidateUpdate validates an updated BeegfsDriver. The driverName of a BeegfsDriver cannot be changed because existing
+// Persistent Volumes reference it.
+func (v *BeegfsDriverValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (
+	admission.Warnings, error) {
+	oldDriver, ok := oldObj.(*beegfsv1.BeegfsDriver)
+	if !ok {
+		return nil, fmt.Errorf("expected a BeegfsDriver but got a %T", oldObj)
+	}
+	newDriver, ok := newObj.(*beegfsv1.BeegfsDriver)
+	if !ok {
+		return nil, fmt.Errorf("expected a BeegfsDriver but got a %T", newObj)
+	}
+	if getDriverName(oldDriver) != getDriverName(newDriver) {
+		return nil, fmt.Errorf("driverName cannot be changed from %s to %s", getDriverName(oldDriver),
+			getDriverName(newDriver))
+	}
+	return validateBeegfsDriver(newObj)
+}
+
+// ValidateDelete does nothing. A BeegfsDriver can always be deleted.
+func (v *BeegfsDriverValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateBeegfsDriver applies the same rules the driver applies to its configuration file to the pluginConfig of a
+// BeegfsDriver. Invalid configuration is rejected. No-effect and unsupported beegfsClientConf options are returned as
+// warnings (the driver removes no-effect options itself, so they are not removed here), as are storageClassTemplates
+// with parameters the driver would reject. It also validates fileSystemSecretRefs, containerResourceOverrides,
+// podTemplateOverrides, and upgradeStrategy.
+func validateBeegfsDriver(obj runtime.Object) (admission.Warnings, error) {
+	driver, ok := obj.(*beegfsv1.BeegfsDriver)
+	if !ok {
+		return nil, fmt.Errorf("expected a BeegfsDriver but got a %T", obj)
+	}
+	if err := beegfsv1.ValidatePluginConfigFromFile(&driver.Spec.PluginConfigFromFile); err != nil {
+		return nil, fmt.Errorf("invalid pluginConfig: %v", err)
+	}
+	if err := validateFileSystemSecretRefs(driver.Spec.FileSystemSecretRefs); err != nil {
+		return nil, fmt.Errorf("invalid fileSystemSecretRefs: %v", err)
+	}
+	if err := validateContainerResourceOverrides(driver.Spec.ContainerResourceOverrides); err != nil {
+		return nil, fmt.Errorf("invalid containerResourceOverrides: %v", err)
+	}
+	if err := validatePodTemplateOverrides(driver.Spec.PodTemplateOverrides); err != nil {
+		return nil, fmt.Errorf("invalid podTemplateOverrides: %v", err)
+	}
+	if err := validateUpgradeStrategy(driver.Spec.UpgradeStrategy); err != nil {
+		return nil, fmt.Errorf("invalid upgradeStrategy: %v", err)
+	}
+	// StripPluginConfigFromFile modifies its argument, so give it a copy.
+	pluginConfig := driver.Spec.PluginConfigFromFile.DeepCopy()
+	var warnings admission.Warnings
+	for _, warning := range beegfsv1.StripPluginConfigFromFile(pluginConfig) {
+		warnings = append(warnings, "pluginConfig: "+warning)
+	}
+	// Invalid storageClassTemplates are not rejected. The operator reports them in status instead of creating them.
+	for _, template := range driver.Spec.StorageClassTemplates {
+		if err := beegfsv1.ValidateStorageClassParameters(template.Parameters); err != nil {
+			warnings = append(warnings, fmt.Sprintf("storageClassTemplates: %s will not be created: %v", template.Name,
+				err))
+		}
+	}
+	return warnings, nil
+}
+
+// validateFileSystemSecretRefs ensures each entry in refs applies to a unique sysMgmtdHost and references at least one
+// Secret by name.
+func validateFileSystemSecretRefs(refs []beegfsv1.FileSystemSecretRefs) error {
+	sysMgmtdHosts := make(map[string]bool)
+	for i, ref := range refs {
+		if ref.SysMgmtdHost == "" {
+			return fmt.Errorf("sysMgmtdHost is required in fileSystemSecretRefs[%d]", i)
+		}
+		if sysMgmtdHosts[ref.SysMgmtdHost] {
+			return fmt.Errorf("duplicate sysMgmtdHost %s in fileSystemSecretRefs[%d]", ref.SysMgmtdHost, i)
+		}
+		sysMgmtdHosts[ref.SysMgmtdHost] = true
+		if ref.ConnAuthSecretRef == nil && ref.TLSCertSecretRef == nil {
+			return fmt.Errorf("neither connAuthSecretRef nor tlsCertSecretRef is specified in fileSystemSecretRefs[%d]",
+				i)
+		}
+		for _, selector := range []*corev1.SecretKeySelector{ref.ConnAuthSecretRef, ref.TLSCertSecretRef} {
+			if selector != nil && selector.Name == "" {
+				return fmt.Errorf("a Secret name is required in fileSystemSecretRefs[%d]", i)
+			}
+		}
+	}
+	return nil
+}
+
+// validateContainerResourceOverrides ensures the controllerContainers and nodeContainers maps only reference containers
+// that exist in the controller service and node service pods respectively.
+func validateContainerResourceOverrides(overrides beegfsv1.ContainerResourceOverrides) error {
+	controllerContainerNames, nodeContainerNames, err := getContainerNames()
+	if err != nil {
+		return err
+	}
+	for name := range overrides.ControllerContainers {
+		if !controllerContainerNames[name] {
+			return fmt.Errorf("unknown container %s in controllerContainers", name)
+		}
+	}
+	for name := range overrides.NodeContainers {
+		if !nodeContainerNames[name] {
+			return fmt.Errorf("unknown container %s in nodeContainers", name)
+		}
+	}
+	return nil
+}
+
+// validatePodTemplateOverrides ensures the container overrides for the controller service and node service only
+// reference containers that exist in the respective pods. A strategic merge patch would otherwise add an incomplete
+// container.
+func validatePodTemplateOverrides(overrides beegfsv1.PodTemplateOverrides) error {
+	controllerContainerNames, nodeContainerNames, err := getContainerNames()
+	if err != nil {
+		return err
+	}
+	for i, container := range overrides.ControllerService.Containers {
+		if !controllerContainerNames[container.Name] {
+			return fmt.Errorf("unknown container %s in controllerService.containers[%d]", container.Name, i)
+		}
+	}
+	for i, container := range overrides.NodeService.Containers {
+		if !nodeContainerNames[container.Name] {
+			return fmt.Errorf("unknown container %s in nodeService.containers[%d]", container.Name, i)
+		}
+	}
+	return nil
+}
+
+// validateUpgradeStrategy ensures maxUnavailable is a positive number or percentage.
+func validateUpgradeStrategy(strategy beegfsv1.UpgradeStrategy) error {
+	if strategy.MaxUnavailable == nil {
+		return nil
+	}
+	// Scale a percentage against 100 nodes and round up, so any positive percentage is valid.
+	value, err := intstr.GetScaledValueFromIntOrPercent(strategy.MaxUnavailable, 100, true)
+	if err != nil {
+		return fmt.Errorf("invalid maxUnavailable %s: %v", strategy.MaxUnavailable.String(), err)
+	}
+	if value < 1 {
+		return fmt.Errorf("maxUnavailable %s must be greater than 0", strategy.MaxUnavailable.String())
+	}
+	return nil
+}
+
+// getContainerNames returns the sets of container names in the controller service and node service pods as specified
+// in the deployment manifests.
+func getContainerNames() (controllerContainerNames, nodeContainerNames map[string]bool, err error) {
+	sts, err := deploy.GetControllerServiceStatefulSet()
+	if err != nil {
+		return nil, nil, err
+	}
+	ds, err := deploy.GetNodeServiceDaemonSet()
+	if err != nil {
+		return nil, nil, err
+	}
+	controllerContainerNames = make(map[string]bool)
+	for _, container := range sts.Spec.Template.Spec.Containers {
+		controllerContainerNames[container.Name] = true
+	}
+	nodeContainerNames = make(map[string]bool)
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		nodeContainerNames[container.Name] = true
+	}
+	return controllerContainerNames, nodeContainerNames, nil
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	deploy "github.com/netapp/beegfs-csi-driver/deploy/k8s"
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Webhook integration tests using envtest", func() {
+	var (
+		ctx context.Context
+		cr  *beegfsv1.BeegfsDriver
+	)
+
+	BeforeEach(func() {
+		cr = getValidCRWithAllFields()
+		ctx = context.Background()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: cr.Namespace}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+	})
+
+	Context("When a BeegfsDriver CR with an invalid pluginConfig is submitted", func() {
+		It("should reject an invalid sysMgmtdHost", func() {
+			cr.Spec.PluginConfigFromFile.FileSystemSpecificConfigs[0].SysMgmtdHost = "testinvalid"
+			err := k8sClient.Create(ctx, cr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid SysMgmtdHost testinvalid"))
+		})
+
+		It("should reject a malformed connNetFilter", func() {
+			cr.Spec.PluginConfigFromFile.DefaultConfig.ConnNetFilter = []string{"10.10.10.10/99"}
+			err := k8sClient.Create(ctx, cr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid ConnNetFilter 10.10.10.10/99"))
+		})
+
+		It("should reject an out of range grpcPort in a nodeSpecificConfig", func() {
+			cr.Spec.PluginConfigFromFile.NodeSpecificConfigs[0].DefaultConfig.GrpcPort = "65536"
+			err := k8sClient.Create(ctx, cr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid GrpcPort 65536 in nodeSpecificConfigs[0]"))
+		})
+
+		It("should reject an invalid pluginConfig on update", func() {
+			Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+			cr.Spec.PluginConfigFromFile.DefaultConfig.ConnTcpOnlyFilter = []string{"testinvalid"}
+			err := k8sClient.Update(ctx, cr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid ConnTCPOnlyFilter testinvalid"))
+		})
+	})
+
+	Context("When a BeegfsDriver CR with no-effect beegfsClientConf options is submitted", func() {
+		It("should accept it", func() {
+			cr.Spec.PluginConfigFromFile.DefaultConfig.BeegfsClientConf = map[string]string{"connClientPort": "8004"}
+			Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+		})
+	})
+
+	Context("When a BeegfsDriver CR without defaults is submitted", func() {
+		It("should fill in defaults", func() {
+			cr.Spec.LogLevel = nil
+			cr.Spec.ContainerImageOverrides = beegfsv1.ContainerImageOverrides{
+				BeegfsCsiDriver: beegfsv1.ContainerImageOverride{Tag: "some-tag"},
+			}
+			Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+
+			fromCluster := new(beegfsv1.BeegfsDriver)
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+				fromCluster)).To(Succeed())
+			Expect(fromCluster.Spec.LogLevel).NotTo(BeNil())
+			Expect(*fromCluster.Spec.LogLevel).To(Equal(defaultLogLevel))
+			Expect(fromCluster.Spec.ContainerImageOverrides.BeegfsCsiDriver.Image).NotTo(BeEmpty())
+			Expect(fromCluster.Spec.ContainerImageOverrides.BeegfsCsiDriver.Tag).To(Equal("some-tag"))
+			Expect(fromCluster.Spec.ContainerImageOverrides.CsiProvisioner).To(
+				Equal(beegfsv1.ContainerImageOverride{}))
+		})
+	})
+})
+
+var _ = Describe("Unit tests of webhook functions", func() {
+	Describe("BeegfsDriverDefaulter.Default", func() {
+		Context("When only an image or only a tag is overridden", func() {
+			It("should fill in the other from the deployment manifests", func() {
+				defaultImages, err := getDefaultImages()
+				Expect(err).NotTo(HaveOccurred())
+				defaultProvisionerImage := strings.SplitN(defaultImages[deploy.ContainerNameCsiProvisioner], ":", 2)[0]
+
+				cr := getValidCRWithNoFields()
+				cr.Spec.ContainerImageOverrides.CsiProvisioner = beegfsv1.ContainerImageOverride{Tag: "some-tag"}
+				cr.Spec.ContainerImageOverrides.LivenessProbe = beegfsv1.ContainerImageOverride{Image: "some/image"}
+				Expect((&BeegfsDriverDefaulter{}).Default(context.Background(), cr)).To(Succeed())
+
+				overrides := cr.Spec.ContainerImageOverrides
+				Expect(overrides.CsiProvisioner.Image).To(Equal(defaultProvisionerImage))
+				Expect(overrides.CsiProvisioner.Tag).To(Equal("some-tag"))
+				Expect(overrides.LivenessProbe.Image).To(Equal("some/image"))
+				Expect(overrides.LivenessProbe.Tag).NotTo(BeEmpty())
+				Expect(overrides.BeegfsCsiDriver).To(Equal(beegfsv1.ContainerImageOverride{}))
+				Expect(*cr.Spec.LogLevel).To(Equal(defaultLogLevel))
+			})
+		})
+	})
+
+	Describe("validateBeegfsDriver", func() {
+		Context("When the pluginConfig contains no-effect and unsupported beegfsClientConf options", func() {
+			It("should warn without modifying the CR", func() {
+				cr := getValidCRWithAllFields()
+				cr.Spec.PluginConfigFromFile.DefaultConfig.BeegfsClientConf = map[string]string{
+					"connClientPort": "8004",
+					"connAuthFile":   "/etc/beegfs/connauthfile",
+				}
+				warnings, err := validateBeegfsDriver(cr)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(warnings).To(HaveLen(2))
+				Expect(cr.Spec.PluginConfigFromFile.DefaultConfig.BeegfsClientConf).To(HaveKey("connClientPort"))
+			})
+		})
+
+		Context("When storageClassTemplates are invalid", func() {
+			It("should warn without failing", func() {
+				cr := getValidCRWithAllFields()
+				cr.Spec.StorageClassTemplates = []beegfsv1.StorageClassTemplate{
+					{Name: "valid", Parameters: map[string]string{"sysMgmtdHost": "127.0.0.1", "volDirBasePath": "/"}},
+					{Name: "invalid", Parameters: map[string]string{"sysMgmtdHost": "127.0.0.1"}},
+				}
+				warnings, err := validateBeegfsDriver(cr)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(warnings).To(ConsistOf(ContainSubstring("invalid will not be created")))
+			})
+		})
+
+		Context("When fileSystemSecretRefs are invalid", func() {
+			It("should fail", func() {
+				ref := beegfsv1.FileSystemSecretRefs{
+					SysMgmtdHost: "10.10.10.10",
+					ConnAuthSecretRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "connauth"},
+						Key:                  "connAuthFile",
+					},
+				}
+				cr := getValidCRWithAllFields()
+				cr.Spec.FileSystemSecretRefs = []beegfsv1.FileSystemSecretRefs{ref}
+				_, err := validateBeegfsDriver(cr)
+				Expect(err).NotTo(HaveOccurred())
+
+				cr.Spec.FileSystemSecretRefs = []beegfsv1.FileSystemSecretRefs{ref, ref}
+				_, err = validateBeegfsDriver(cr)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("duplicate sysMgmtdHost 10.10.10.10"))
+
+				cr.Spec.FileSystemSecretRefs = []beegfsv1.FileSystemSecretRefs{{SysMgmtdHost: "10.10.10.10"}}
+				_, err = validateBeegfsDriver(cr)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("neither connAuthSecretRef nor tlsCertSecretRef"))
+			})
+		})
+
+		Context("When containerResourceOverrides reference an unknown container", func() {
+			It("should fail", func() {
+				cr := getValidCRWithAllFields()
+				cr.Spec.ContainerResourceOverrides.ControllerContainers = map[string]corev1.ResourceRequirements{
+					"csi-resizer": {},
+				}
+				cr.Spec.ContainerResourceOverrides.NodeContainers = map[string]corev1.ResourceRequirements{
+					"liveness-probe": {},
+				}
+				_, err := validateBeegfsDriver(cr)
+				Expect(err).NotTo(HaveOccurred())
+
+				cr.Spec.ContainerResourceOverrides.NodeContainers["csi-resizer"] = corev1.ResourceRequirements{}
+				_, err = validateBeegfsDriver(cr)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unknown container csi-resizer in nodeContainers"))
+			})
+		})
+
+		Context("When podTemplateOverrides reference an unknown container", func() {
+			It("should fail", func() {
+				cr := getValidCRWithAllFields()
+				_, err := validateBeegfsDriver(cr)
+				Expect(err).NotTo(HaveOccurred())
+
+				cr.Spec.PodTemplateOverrides.ControllerService.Containers = []beegfsv1.ContainerTemplateOverride{
+					{Name: deploy.ContainerNameLivenessProbe},
+				}
+				_, err = validateBeegfsDriver(cr)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(
+					"unknown container liveness-probe in controllerService.containers[0]"))
+			})
+		})
+
+		Context("When maxUnavailable is invalid", func() {
+			It("should fail", func() {
+				cr := getValidCRWithAllFields()
+				for _, valid := range []intstr.IntOrString{intstr.FromInt32(2), intstr.FromString("10%")} {
+					cr.Spec.UpgradeStrategy.MaxUnavailable = &valid
+					_, err := validateBeegfsDriver(cr)
+					Expect(err).NotTo(HaveOccurred())
+				}
+				for _, invalid := range []intstr.IntOrString{intstr.FromInt32(0), intstr.FromString("0%"),
+					intstr.FromString("ten")} {
+					cr.Spec.UpgradeStrategy.MaxUnavailable = &invalid
+					_, err := validateBeegfsDriver(cr)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("invalid upgradeStrategy"))
+				}
+			})
+		})
+
+		Context("When a nodeSelector is invalid", func() {
+			It("should fail", func() {
+				cr := getValidCRWithAllFields()
+				cr.Spec.PluginConfigFromFile.NodeSpecificConfigs[0].NodeSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "key", Operator: "Invalid"}},
+				}
+				_, err := validateBeegfsDriver(cr)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid NodeSelector in nodeSpecificConfigs[0]"))
+			})
+		})
+	})
+
+	Describe("BeegfsDriverValidator.ValidateUpdate", func() {
+		Context("When the driverName is changed", func() {
+			It("should fail", func() {
+				oldCR := getValidCRWithAllFields()
+				newCR := oldCR.DeepCopy()
+				newCR.Spec.DriverName = "tenant-a.beegfs.csi.netapp.com"
+				_, err := (&BeegfsDriverValidator{}).ValidateUpdate(context.TODO(), oldCR, newCR)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("driverName cannot be changed"))
+			})
+		})
+
+		Context("When the default driverName is specified explicitly", func() {
+			It("should succeed", func() {
+				oldCR := getValidCRWithAllFields()
+				oldCR.Spec.DriverName = ""
+				newCR := oldCR.DeepCopy()
+				newCR.Spec.DriverName = deploy.DefaultDriverName
+				_, err := (&BeegfsDriverValidator{}).ValidateUpdate(context.TODO(), oldCR, newCR)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+})
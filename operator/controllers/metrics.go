@@ -0,0 +1,179 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Metrics are served by the controller-runtime metrics endpoint (--metrics-bind-address) alongside the metrics
+// controller-runtime exposes about the controller itself. Metrics about a BeegfsDriver are labeled with its namespace
+// and name and removed when it is deleted.
+
+// driftCorrectionsTotal counts the objects the operator restored after they were modified or deleted outside of the
+// operator.
+var driftCorrectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "beegfs_csi_operator_drift_corrections_total",
+	Help: "Number of times the operator restored an object that was modified or deleted outside of the operator.",
+}, []string{"namespace", "kind"})
+
+// reconcileErrorsTotal counts failed reconciliations by the kind of object the operator was reconciling when it failed.
+var reconcileErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "beegfs_csi_operator_reconcile_errors_total",
+	Help: "Number of failed reconciliations of a BeegfsDriver by the kind of object that caused the failure.",
+}, []string{"namespace", "name", "kind"})
+
+var (
+	nodePodsReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "beegfs_csi_operator_node_pods_ready",
+		Help: "Number of ready node service Pods.",
+	}, []string{"namespace", "name"})
+	nodePodsDesired = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "beegfs_csi_operator_node_pods_desired",
+		Help: "Number of nodes that should run a node service Pod.",
+	}, []string{"namespace", "name"})
+	controllerReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "beegfs_csi_operator_controller_ready",
+		Help: "Whether the controller service Pod is ready (1) or not (0).",
+	}, []string{"namespace", "name"})
+	fileSystemsConfigured = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "beegfs_csi_operator_file_systems_configured",
+		Help: "Number of file systems (distinct sysMgmtdHosts) with a fileSystemSpecificConfig in the pluginConfig.",
+	}, []string{"namespace", "name"})
+	configGeneration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "beegfs_csi_operator_config_generation",
+		Help: "The generation of the BeegfsDriver spec most recently reconciled.",
+	}, []string{"namespace", "name"})
+)
+
+// lastSuccessfulReconcile exports the time since each BeegfsDriver was last reconciled successfully. The value is
+// computed when the metrics are scraped, so it keeps growing while reconciliation fails (or stops).
+var lastSuccessfulReconcile = &reconcileTimeCollector{
+	desc: prometheus.NewDesc("beegfs_csi_operator_seconds_since_last_successful_reconcile",
+		"Seconds since the BeegfsDriver was last reconciled successfully.", []string{"namespace", "name"}, nil),
+	times: make(map[types.NamespacedName]time.Time),
+}
+
+func init() {
+	metrics.Registry.MustRegister(driftCorrectionsTotal, reconcileErrorsTotal, nodePodsReady, nodePodsDesired,
+		controllerReady, fileSystemsConfigured, configGeneration, lastSuccessfulReconcile)
+}
+
+// reconcileTimeCollector is a prometheus.Collector that reports the seconds since a recorded time for each
+// BeegfsDriver.
+type reconcileTimeCollector struct {
+	desc  *prometheus.Desc
+	mutex sync.Mutex
+	times map[types.NamespacedName]time.Time
+}
+
+var _ prometheus.Collector = &reconcileTimeCollector{}
+
+// Describe implements prometheus.Collector.
+func (c *reconcileTimeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *reconcileTimeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for driver, t := range c.times {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, time.Since(t).Seconds(), driver.Namespace,
+			driver.Name)
+	}
+}
+
+// set records t as the time driver was last reconciled successfully.
+func (c *reconcileTimeCollector) set(driver types.NamespacedName, t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.times[driver] = t
+}
+
+// delete stops reporting driver.
+func (c *reconcileTimeCollector) delete(driver types.NamespacedName) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.times, driver)
+}
+
+// setDriverMetrics updates the gauges for driver from the Stateful Set and Daemon Set on the cluster (or empty objects
+// if they were not found).
+func setDriverMetrics(driver *beegfsv1.BeegfsDriver, sts *appsv1.StatefulSet, ds *appsv1.DaemonSet) {
+	nodePodsReady.WithLabelValues(driver.Namespace, driver.Name).Set(float64(ds.Status.NumberReady))
+	nodePodsDesired.WithLabelValues(driver.Namespace, driver.Name).Set(float64(ds.Status.DesiredNumberScheduled))
+	ready := 0.0
+	if sts.Status.ReadyReplicas > 0 {
+		ready = 1.0
+	}
+	controllerReady.WithLabelValues(driver.Namespace, driver.Name).Set(ready)
+	fileSystemsConfigured.WithLabelValues(driver.Namespace, driver.Name).Set(
+		float64(countConfiguredFileSystems(driver.Spec.PluginConfigFromFile)))
+	configGeneration.WithLabelValues(driver.Namespace, driver.Name).Set(float64(driver.Generation))
+}
+
+// deleteDriverMetrics removes every metric about a BeegfsDriver that no longer exists.
+func deleteDriverMetrics(driver types.NamespacedName) {
+	for _, gauge := range []*prometheus.GaugeVec{nodePodsReady, nodePodsDesired, controllerReady,
+		fileSystemsConfigured, configGeneration} {
+		gauge.DeleteLabelValues(driver.Namespace, driver.Name)
+	}
+	reconcileErrorsTotal.DeletePartialMatch(prometheus.Labels{"namespace": driver.Namespace, "name": driver.Name})
+	lastSuccessfulReconcile.delete(driver)
+}
+
+// countConfiguredFileSystems returns the number of distinct sysMgmtdHosts with a fileSystemSpecificConfig in
+// pluginConfig (including those in nodeSpecificConfigs).
+func countConfiguredFileSystems(pluginConfig beegfsv1.PluginConfigFromFile) int {
+	sysMgmtdHosts := make(map[string]bool)
+	for _, config := range pluginConfig.FileSystemSpecificConfigs {
+		sysMgmtdHosts[config.SysMgmtdHost] = true
+	}
+	for _, nodeConfig := range pluginConfig.NodeSpecificConfigs {
+		for _, config := range nodeConfig.FileSystemSpecificConfigs {
+			sysMgmtdHosts[config.SysMgmtdHost] = true
+		}
+	}
+	return len(sysMgmtdHosts)
+}
+
+// kindError records the kind of object the operator was reconciling when an error occurred so that
+// reconcileErrorsTotal can be labeled with it.
+type kindError struct {
+	kind string
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+func (e *kindError) Unwrap() error { return e.err }
+
+// withKind associates err with kind unless it is nil or already associated with a kind.
+func withKind(kind string, err error) error {
+	var ke *kindError
+	if err == nil || errors.As(err, &ke) {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// getErrorKind returns the kind associated with err by withKind. Errors that are not associated with a kind occurred
+// while reconciling the BeegfsDriver itself.
+func getErrorKind(err error) string {
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	return "BeegfsDriver"
+}
@@ -127,13 +127,23 @@ func main() {
 	}
 
 	if err = (&controllers.BeegfsDriverReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("BeegfsDriver"),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Log:       ctrl.Log.WithName("controllers").WithName("BeegfsDriver"),
+		Recorder:  mgr.GetEventRecorderFor("beegfs-csi-driver-operator"),
+		Scheme:    mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BeegfsDriver")
 		os.Exit(1)
 	}
+	// The webhook server requires a serving certificate (provided automatically by OLM). Set ENABLE_WEBHOOKS=false to
+	// run the operator without one (e.g. locally or when installing from manifests without cert-manager).
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = controllers.SetupBeegfsDriverWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BeegfsDriver")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
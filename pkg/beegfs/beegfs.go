@@ -24,23 +24,41 @@ package beegfs
 import (
 	"context"
 	"os"
+	"os/signal"
 	"path"
+	"sync"
+	"syscall"
+	"time"
 
 	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
 	"github.com/pkg/errors"
 )
 
 const (
-	volDirBasePathKey             = "volDirBasePath"
-	sysMgmtdHostKey               = "sysMgmtdHost"
-	stripePatternStoragePoolIDKey = "stripePattern/storagePoolID"
-	stripePatternChunkSizeKey     = "stripePattern/chunkSize"
-	stripePatternNumTargetsKey    = "stripePattern/numTargets"
-	permissionsUIDKey             = "permissions/uid"
-	permissionsGIDKey             = "permissions/gid"
-	permissionsModeKey            = "permissions/mode"
+	volDirBasePathKey             = beegfsv1.ParameterVolDirBasePath
+	sysMgmtdHostKey               = beegfsv1.ParameterSysMgmtdHost
+	stripePatternStoragePoolIDKey = beegfsv1.ParameterStripePatternStoragePoolID
+	stripePatternChunkSizeKey     = beegfsv1.ParameterStripePatternChunkSize
+	stripePatternNumTargetsKey    = beegfsv1.ParameterStripePatternNumTargets
+	permissionsUIDKey             = beegfsv1.ParameterPermissionsUID
+	permissionsGIDKey             = beegfsv1.ParameterPermissionsGID
+	permissionsModeKey            = beegfsv1.ParameterPermissionsMode
+	retentionPeriodKey            = beegfsv1.ParameterRetentionPeriod
+	volDirTemplateKey             = beegfsv1.ParameterVolDirTemplate
+	basePathParamPrefix           = beegfsv1.BasePathParameterPrefix
+	pvcNameKey                    = "csi.storage.k8s.io/pvc/name"      // added by csi-provisioner --extra-create-metadata
+	pvcNamespaceKey               = "csi.storage.k8s.io/pvc/namespace" // added by csi-provisioner --extra-create-metadata
+	pvNameKey                     = "csi.storage.k8s.io/pv/name"       // added by csi-provisioner --extra-create-metadata
+	connAuthSecretKey             = "connAuth"
+	connAuthEncodingSecretKey     = "connAuthEncoding"
+	tlsCertSecretKey              = "tlsCert"
 	defaultPermissionsMode        = 0o0777
 
+	// DefaultDriverName is the name of the CSI driver in the deployment manifests. The kubelet plugin directory on
+	// each node (kubeletPluginsDirPath/<driver name>) is derived from the driver name.
+	DefaultDriverName     = "beegfs.csi.netapp.com"
+	kubeletPluginsDirPath = "/var/lib/kubelet/plugins"
+
 	logLevelDebug   = 3 // This log level is used for most informational logs in RPCs and GRPC calls
 	logLevelVerbose = 5 // This log level is used for only very repetitive logs such as the Probe GRPC call
 )
@@ -50,9 +68,16 @@ type beegfs struct {
 	nodeID                 string
 	version                string
 	endpoint               string
+	diagnosticsEndpoint    string // optional endpoint for the volume diagnostics HTTP server
+	diagnosticsService     string // the service (controller or node) the diagnostics endpoint serves diagnostics of
+	metricsEndpoint        string // optional endpoint for the metrics HTTP server
 	pluginConfig           beegfsv1.PluginConfig
 	clientConfTemplatePath string
 	csDataDir              string // directory controller service uses to create BeeGFS config files and mount file systems
+	// The controller service reports file system status on this Pod every statusReportInterval if it is set.
+	statusReportPodNamespace string
+	statusReportPodName      string
+	statusReportInterval     time.Duration
 
 	ids *identityServer
 	ns  *nodeServer
@@ -136,6 +161,14 @@ type permissionsConfig struct {
 	mode uint16 // A full access mode consists of four base-8 digits (12 bits).
 }
 
+// parentDirConfig contains our internal representation of all CreateVolume parameters (StorageClass parameters in
+// K8s) that should be prefaced with basePath/. CreateVolume creates the missing parent directories of a volume with
+// them instead of with the volume's permissionsConfig.
+type parentDirConfig struct {
+	stripePatternConfig stripePatternConfig
+	permissionsConfig   permissionsConfig
+}
+
 // reqParameters contains all possible parameters from CreateVolumeRequest.parameters or ValidateVolumeCapabilitiesRequest.parameters.
 // We utilize the reqParameters struct with ValidateReqParameters to validate all parameters passed, ensuring correct parameters
 // when called by by CreateVolume and ValidateVolumeCapabilities.
@@ -144,6 +177,21 @@ type reqParameters struct {
 	volDirBasePathBeegfsRoot string
 	volStripePatternConfig   stripePatternConfig
 	volPermissionsConfig     permissionsConfig
+	parentDirConfig          *parentDirConfig // nil if no basePath/ parameters were provided
+	retentionPeriod          time.Duration
+	volDirTemplate           string
+	pvcName                  string
+	pvcNamespace             string
+	pvName                   string
+}
+
+// volDirTemplateValues returns the values of the variables a volDirTemplate may reference.
+func (params reqParameters) volDirTemplateValues() map[string]string {
+	return map[string]string{
+		beegfsv1.VolDirTemplateVarPVCNamespace: params.pvcNamespace,
+		beegfsv1.VolDirTemplateVarPVCName:      params.pvcName,
+		beegfsv1.VolDirTemplateVarPVName:       params.pvName,
+	}
 }
 
 // hasNonDefaultOwnerOrGroup returns true if either uid or gid are not 0 and false otherwise.
@@ -182,15 +230,15 @@ var (
 )
 
 // NewBeegfsDriver initializes a working BeegfsDriver.
-func NewBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, nodeID, clientConfTemplatePath,
-	version string, nodeUnstageTimeout uint64) (*beegfs, error) {
+func NewBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, diagnosticsEndpoint, nodeID,
+	clientConfTemplatePath, version string, nodeUnstageTimeout uint64) (*beegfs, error) {
 
 	if err := verifyBeegfsClientModuleIsAvailable(); err != nil {
 		return nil, err
 	}
 
-	driver, err := newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, nodeID,
-		clientConfTemplatePath, version, nodeUnstageTimeout)
+	driver, err := newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint,
+		diagnosticsEndpoint, nodeID, clientConfTemplatePath, version, nodeUnstageTimeout)
 	if err != nil {
 		return nil, err
 	}
@@ -203,16 +251,17 @@ func NewBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverNa
 		driver.csDataDir, nodeUnstageTimeout); err != nil {
 		return nil, err
 	}
+	driver.cs.driverName, driver.cs.driverVersion = driver.driverName, driver.version
 
 	return driver, nil
 }
 
 // NewBeegfsDriverSanity initializes a BeegfsDriver that doesn't have a working mounter or beegfs-ctl execution
 // capabilities. This BeegfsDriver can be used for sanity testing on any machine.
-func NewBeegfsDriverSanity(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, nodeID, clientConfTemplatePath,
-	version string, nodeUnstageTimeout uint64) (*beegfs, error) {
-	driver, err := newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, nodeID,
-		clientConfTemplatePath, version, nodeUnstageTimeout)
+func NewBeegfsDriverSanity(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, diagnosticsEndpoint, nodeID,
+	clientConfTemplatePath, version string, nodeUnstageTimeout uint64) (*beegfs, error) {
+	driver, err := newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint,
+		diagnosticsEndpoint, nodeID, clientConfTemplatePath, version, nodeUnstageTimeout)
 	if err != nil {
 		return nil, err
 	}
@@ -221,13 +270,14 @@ func NewBeegfsDriverSanity(connAuthPath, tlsCertsPath, configPath, csDataDir, dr
 	driver.ns = newNodeServerSanity(driver.nodeID, driver.pluginConfig, driver.clientConfTemplatePath)
 	driver.cs = newControllerServerSanity(driver.nodeID, driver.pluginConfig, driver.clientConfTemplatePath,
 		driver.csDataDir, nodeUnstageTimeout)
+	driver.cs.driverName, driver.cs.driverVersion = driver.driverName, driver.version
 
 	return driver, nil
 }
 
 // newBeegfsDriver is used by both NewBeegfsDriver and NewBeegfsDriverSanity for common initialization.
-func newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, nodeID, clientConfTemplatePath,
-	version string, nodeUnstageTimeout uint64) (*beegfs, error) {
+func newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverName, endpoint, diagnosticsEndpoint, nodeID,
+	clientConfTemplatePath, version string, nodeUnstageTimeout uint64) (*beegfs, error) {
 	if driverName == "" {
 		return nil, errors.New("no driver name provided")
 	}
@@ -240,6 +290,12 @@ func newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverNa
 		return nil, errors.New("no driver endpoint provided")
 	}
 
+	if diagnosticsEndpoint != "" {
+		if _, _, err := parseEndpoint(diagnosticsEndpoint); err != nil {
+			return nil, errors.WithMessage(err, "invalid diagnostics endpoint")
+		}
+	}
+
 	if version != "" {
 		vendorVersion = version
 	}
@@ -249,7 +305,7 @@ func newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverNa
 		if _, err := fsutil.ReadFile(clientConfTemplatePath); err != nil {
 			return nil, errors.WithMessage(err, "failed to read client configuration template file")
 		}
-	} else if clientConfTemplatePath = getDefaultClientConfTemplatePath(); clientConfTemplatePath == "" {
+	} else if clientConfTemplatePath = getDefaultClientConfTemplatePath(driverName); clientConfTemplatePath == "" {
 		return nil, errors.New("failed to get valid default client configuration template file")
 	}
 
@@ -285,6 +341,8 @@ func newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverNa
 		version:                vendorVersion,
 		nodeID:                 nodeID,
 		endpoint:               endpoint,
+		diagnosticsEndpoint:    diagnosticsEndpoint,
+		diagnosticsService:     DiagnosticsServiceNode,
 		pluginConfig:           pluginConfig,
 		clientConfTemplatePath: clientConfTemplatePath,
 		csDataDir:              csDataDir,
@@ -297,9 +355,58 @@ func newBeegfsDriver(connAuthPath, tlsCertsPath, configPath, csDataDir, driverNa
 }
 
 func (b *beegfs) Run() {
+	// Background work that uses the mount cache must stop before we clean it up on shutdown.
+	stop := make(chan struct{})
+	var background sync.WaitGroup
+	runInBackground := func(f func(stop <-chan struct{})) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			f(stop)
+		}()
+	}
+
+	if b.diagnosticsEndpoint != "" {
+		// Only serve the diagnostics of the service this process is deployed as. The other one is never called.
+		cs, ns := (*controllerServer)(nil), b.ns
+		if b.diagnosticsService == DiagnosticsServiceController {
+			cs, ns = b.cs, nil
+		}
+		runInBackground(func(stop <-chan struct{}) { serveDiagnostics(b.diagnosticsEndpoint, cs, ns, stop) })
+	}
+	if b.metricsEndpoint != "" {
+		runInBackground(func(stop <-chan struct{}) { serveMetrics(b.metricsEndpoint, stop) })
+	}
+	if b.statusReportInterval > 0 {
+		runInBackground(b.reportStatusPeriodically)
+	}
+	// Unmount anything a previous instance of the controller service left in its mount cache.
+	b.cs.mountCache.unmountAll(context.TODO())
+	runInBackground(b.cs.mountCache.checkMountsPeriodically)
+	b.cs.volumeDeleter.start(context.TODO())
+	runInBackground(b.cs.volumeRetainer.purgeExpiredPeriodically)
+	if b.cs.orphanScanInterval > 0 {
+		runInBackground(b.cs.scanOrphansPeriodically)
+	}
+
 	s := newNonBlockingGRPCServer()
 	s.Start(b.endpoint, b.ids, b.cs, b.ns)
+
+	// Stop accepting requests, wait for in-flight requests and background work to complete, stop removing volumes from
+	// the trash (we resume when we restart), and clean up the mount cache when we are asked to shut down. The node
+	// service's mounts (shared or not) belong to staged volumes and must remain.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		LogDebug(context.TODO(), "Shutting down", "signal", sig.String())
+		s.Stop()
+	}()
 	s.Wait()
+	close(stop)
+	background.Wait()
+	b.cs.volumeDeleter.stop()
+	b.cs.mountCache.unmountAll(context.TODO())
 }
 
 // newBeeGFSVolume creates a beegfsVolume from parameters.
@@ -336,18 +443,19 @@ func newBeegfsVolumeFromID(mountDirPath, volumeID string, pluginConfig beegfsv1.
 	return newBeegfsVolume(mountDirPath, sysMgmtdHost, volDirPathBeegfsRoot, pluginConfig), nil
 }
 
-// getDefaultClientConfTemplatePath looks for a beegfs-client.conf file in an ordered slice of default paths. It
-// returns the first valid path it finds or an empty string if none of the default paths are valid.
-func getDefaultClientConfTemplatePath() string {
+// getDefaultClientConfTemplatePath looks for a beegfs-client.conf file in an ordered slice of default paths (including
+// the client files subdirectory of the plugin data directory of driverName). It returns the first valid path it finds
+// or an empty string if none of the default paths are valid.
+func getDefaultClientConfTemplatePath(driverName string) string {
 	defaultPaths := []string{
 		// Default beegfs-client.conf install location.
 		"/etc/beegfs/beegfs-client.conf",
 		// Default beegfs-client.conf install location inside CSI container.
 		"/host/etc/beegfs/beegfs-client.conf",
 		// Client files subdirectory of plugin data directory.
-		"/var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/beegfs-client.conf",
+		path.Join(kubeletPluginsDirPath, driverName, "client", "beegfs-client.conf"),
 		// Client files subdirectory of plugin data directory inside CSI container.
-		"/host/var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/beegfs-client.conf",
+		path.Join("/host", kubeletPluginsDirPath, driverName, "client", "beegfs-client.conf"),
 	}
 	for _, path := range defaultPaths {
 		if _, err := fsutil.ReadFile(path); err == nil {
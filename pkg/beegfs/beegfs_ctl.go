@@ -21,8 +21,13 @@ import (
 // file system.
 type beegfsCtlExecutorInterface interface {
 	createDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string, cfg permissionsConfig) error
+	// createDirectory creates the single directory dirPath (whose parent must exist). Unlike createDirectoryForVolume,
+	// it returns a ctlExistError if dirPath already exists, so the caller knows whether it created the directory.
+	createDirectory(ctx context.Context, vol beegfsVolume, dirPath string, cfg permissionsConfig) error
 	statDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string) (string, error)
 	setPatternForVolume(ctx context.Context, vol beegfsVolume, cfg stripePatternConfig) error
+	// getBeegfsVersion returns the major version of BeeGFS (e.g. "8") running on the file system referenced by vol.
+	getBeegfsVersion(ctx context.Context, vol beegfsVolume) (string, error)
 }
 
 // beegfsCtlDispatcher handles calling either the v7 or v8 CTL depending on the beegfsVolume.
@@ -111,6 +116,14 @@ func (d beegfsCtlDispatcher) createDirectoryForVolume(ctx context.Context, vol b
 	}
 }
 
+func (d beegfsCtlDispatcher) createDirectory(ctx context.Context, vol beegfsVolume, dirPath string, cfg permissionsConfig) error {
+	if ctl, err := d.detectCTLVersion(ctx, vol); err != nil {
+		return err
+	} else {
+		return ctl.createDirectory(ctx, vol, dirPath, cfg)
+	}
+}
+
 func (d beegfsCtlDispatcher) statDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string) (string, error) {
 	if ctl, err := d.detectCTLVersion(ctx, vol); err != nil {
 		return "", err
@@ -126,6 +139,14 @@ func (d beegfsCtlDispatcher) setPatternForVolume(ctx context.Context, vol beegfs
 	}
 }
 
+func (d beegfsCtlDispatcher) getBeegfsVersion(ctx context.Context, vol beegfsVolume) (string, error) {
+	if ctl, err := d.detectCTLVersion(ctx, vol); err != nil {
+		return "", err
+	} else {
+		return ctl.getBeegfsVersion(ctx, vol)
+	}
+}
+
 // execBeeGFSCmd provides a common approach to handling output from v7/v8 BeeGFS CTL commands.
 func execBeeGFSCmd(ctx context.Context, cmd *exec.Cmd, isHelpCommand bool) (stdOut string, err error) {
 	var stdoutBuffer bytes.Buffer
@@ -201,6 +222,15 @@ func (ctl beegfsCtlExecutorV8) createDirectoryForVolume(ctx context.Context, vol
 
 	return nil
 }
+
+func (ctl beegfsCtlExecutorV8) createDirectory(ctx context.Context, vol beegfsVolume, dirPath string, cfg permissionsConfig) error {
+	LogDebug(ctx, "Creating BeeGFS directory", "path", dirPath, "volumeID", vol.volumeID)
+	if _, err := ctl.execute(ctx, vol, append(constructCreateDirForVolumeArgs(cfg, true), dirPath)); err != nil {
+		return errors.WithMessagef(err, "cannot create BeeGFS directory %s for %s", dirPath, vol.volumeID)
+	}
+	return nil
+}
+
 func (ctl beegfsCtlExecutorV8) statDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string) (string, error) {
 	return ctl.execute(ctx, vol, []string{"--mount=none", "entry", "info", dirPath})
 }
@@ -217,6 +247,11 @@ func (ctl beegfsCtlExecutorV8) setPatternForVolume(ctx context.Context, vol beeg
 	return nil
 }
 
+// getBeegfsVersion always returns "8". The beegfsCtlDispatcher only selects the v8 CTL for BeeGFS 8 file systems.
+func (ctl beegfsCtlExecutorV8) getBeegfsVersion(ctx context.Context, vol beegfsVolume) (string, error) {
+	return "8", nil
+}
+
 func (*beegfsCtlExecutorV8) execute(ctx context.Context, vol beegfsVolume, args []string) (stdOut string, err error) {
 	if len(args) > 0 && args[0] == "--help" {
 		// We want to log differently if this is just a --help command. There is also no reason to
@@ -284,6 +319,18 @@ func (ctlExec *beegfsCtlExecutorV7) createDirectoryForVolume(ctx context.Context
 	return nil
 }
 
+// createDirectory uses a "beegfs-ctl --createdir" command to create the directory specified by dirPath (whose parent
+// must exist) on the BeeGFS file system specified by vol.sysMgmtdHost. createDirectory returns a ctlExistError if the
+// directory already exists.
+func (ctlExec *beegfsCtlExecutorV7) createDirectory(ctx context.Context, vol beegfsVolume, dirPath string, cfg permissionsConfig) error {
+	LogDebug(ctx, "Creating BeeGFS directory", "path", dirPath, "volumeID", vol.volumeID)
+	args := append(constructCreateDirForVolumeArgs(cfg, false), dirPath)
+	if _, err := ctlExec.execute(ctx, vol.clientConfPath, args); err != nil {
+		return errors.WithMessagef(err, "cannot create BeeGFS directory %s for %s", dirPath, vol.volumeID)
+	}
+	return nil
+}
+
 // statDirectoryForVolume returns the information output by "beegfs-ctl --getentryinfo dirPath" as a string, or an empty
 // string and an error if the stat fails.
 func (ctlExec *beegfsCtlExecutorV7) statDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string) (string, error) {
@@ -376,6 +423,11 @@ func (ctlExec *beegfsCtlExecutorV7) setPatternForVolume(ctx context.Context, vol
 	return nil
 }
 
+// getBeegfsVersion always returns "7". The beegfsCtlDispatcher only selects the v7 CTL for BeeGFS 7 file systems.
+func (ctlExec *beegfsCtlExecutorV7) getBeegfsVersion(ctx context.Context, vol beegfsVolume) (string, error) {
+	return "7", nil
+}
+
 // execute runs arbitrary beegfs-ctl commands like "beegfs-ctl --arg1 --arg2=value". It logs the stdout and stderr
 // when running at a high verbosity and returns stdout as a string (as well as any potential errors). execute fails if
 // beegfs-ctl is not on the PATH.
@@ -435,6 +487,10 @@ func (*fakeBeegfsCtlExecutor) createDirectoryForVolume(ctx context.Context, vol
 	return nil
 }
 
+func (*fakeBeegfsCtlExecutor) createDirectory(ctx context.Context, vol beegfsVolume, path string, cfg permissionsConfig) error {
+	return nil
+}
+
 func (*fakeBeegfsCtlExecutor) statDirectoryForVolume(ctx context.Context, vol beegfsVolume, path string) (string, error) {
 	return "", nil
 }
@@ -442,3 +498,7 @@ func (*fakeBeegfsCtlExecutor) statDirectoryForVolume(ctx context.Context, vol be
 func (*fakeBeegfsCtlExecutor) setPatternForVolume(ctx context.Context, vol beegfsVolume, cfg stripePatternConfig) error {
 	return nil
 }
+
+func (*fakeBeegfsCtlExecutor) getBeegfsVersion(ctx context.Context, vol beegfsVolume) (string, error) {
+	return "8", nil
+}
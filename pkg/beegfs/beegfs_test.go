@@ -106,7 +106,7 @@ func TestNewBeegfsDriver(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			tc := tcFunc()
 			_, err := NewBeegfsDriver(tc.connAuthPath, tc.tlsCertsPath, tc.configPath, tc.csDataDir, tc.driverName, tc.endpoint,
-				tc.nodeID, tc.clientConfTemplatePath, tc.version, tc.nodeUnstageTimeout)
+				"", tc.nodeID, tc.clientConfTemplatePath, tc.version, tc.nodeUnstageTimeout)
 			if err == nil {
 				t.Fatal("expected error but got none")
 			}
@@ -250,14 +250,21 @@ func TestGetDefaultClientConfTemplatePath(t *testing.T) {
 	fsutil = afero.Afero{Fs: fs}
 
 	// Should return an empty string if a file doesn't exist at any default path.
-	if path := getDefaultClientConfTemplatePath(); path != "" {
+	if path := getDefaultClientConfTemplatePath(DefaultDriverName); path != "" {
 		t.Fatalf("expected no valid default path; got %s", path)
 	}
 
 	// Should return a default path a file exists at one.
 	const defaultPath = "/host/var/lib/kubelet/plugins/beegfs.csi.netapp.com/client/beegfs-client.conf"
 	_ = fsutil.WriteFile(defaultPath, []byte{}, 0644)
-	if path := getDefaultClientConfTemplatePath(); path != defaultPath {
+	if path := getDefaultClientConfTemplatePath(DefaultDriverName); path != defaultPath {
 		t.Fatalf("expected default path %s; got %s", defaultPath, path)
 	}
+
+	// Should search the plugin data directory of a non-default driver name.
+	const tenantPath = "/var/lib/kubelet/plugins/tenant-a.beegfs.csi.netapp.com/client/beegfs-client.conf"
+	_ = fsutil.WriteFile(tenantPath, []byte{}, 0644)
+	if path := getDefaultClientConfTemplatePath("tenant-a.beegfs.csi.netapp.com"); path != tenantPath {
+		t.Fatalf("expected default path %s; got %s", tenantPath, path)
+	}
 }
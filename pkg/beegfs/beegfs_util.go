@@ -62,6 +62,56 @@ func parseBeegfsURL(rawURL string) (sysMgmtdHost string, path string, err error)
 // beegfsVolume's config. writeClientFiles assumes an empty directory has already been created at mountDirPath.
 func writeClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath string) (err error) {
 	LogDebug(ctx, "Writing client files", "volumeID", vol.volumeID, "path", vol.mountDirPath)
+
+	// The BeeGFS client must bind to and listen on a UDP port. Each BeeGFS mount requires a
+	// different port. Though the client is free to define and use its own port, BeeGFS does not
+	// support binding to port 0 to obtain an OS assigned ephemeral port. In BeeGFS 8 this was
+	// renamed to connClientPort as part of simplifying the configuration but inside the driver is
+	// still referenced in places as connClientPortUDP.
+	var connClientPort string
+	port, err := getEphemeralPortUDP()
+	if err != nil {
+		return errors.WithMessage(err, "error selecting connClientPort")
+	}
+	connClientPort = strconv.Itoa(port)
+
+	clientConfINI, auxFiles, err := generateClientFiles(ctx, vol, confTemplatePath, connClientPort)
+	if err != nil {
+		return err
+	}
+
+	for _, auxFile := range auxFiles {
+		if err = fsutil.WriteFile(auxFile.path, auxFile.contents, auxFile.perm); err != nil {
+			return errors.Wrapf(err, "error writing %s file", auxFile.name)
+		}
+	}
+
+	var clientConfFileHandle afero.File
+	if clientConfFileHandle, err = fs.Create(vol.clientConfPath); err != nil {
+		return errors.Wrap(err, "error creating beegfs-client.conf file")
+	}
+	if _, err = clientConfINI.WriteTo(clientConfFileHandle); err != nil {
+		return errors.Wrap(err, "error writing beegfs-client.conf file")
+	}
+
+	return nil
+}
+
+// clientFile describes one of the auxiliary files (e.g. connInterfacesFile) that must be written alongside a
+// beegfs-client.conf file.
+type clientFile struct {
+	name     string
+	path     string
+	contents []byte
+	perm     os.FileMode
+}
+
+// generateClientFiles does all of the work of writeClientFiles except for actually writing files. It returns the
+// beegfs-client.conf file generated from the template at confTemplatePath and the auxiliary files it references (in
+// the order they should be written). It returns an error if the beegfsVolume's config can not be applied to the
+// template (e.g. because a beegfsClientConf key does not exist in the template).
+func generateClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath, connClientPort string) (
+	clientConfINI *ini.File, auxFiles []clientFile, err error) {
 	connInterfacesFilePath := path.Join(vol.mountDirPath, "connInterfacesFile")
 	connNetFilterFilePath := path.Join(vol.mountDirPath, "connNetFilterFile")
 	connTcpOnlyFilterFilePath := path.Join(vol.mountDirPath, "connTcpOnlyFilterFile")
@@ -79,28 +129,15 @@ func writeClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath st
 		return nil
 	}
 
-	// The BeeGFS client must bind to and listen on a UDP port. Each BeeGFS mount requires a
-	// different port. Though the client is free to define and use its own port, BeeGFS does not
-	// support binding to port 0 to obtain an OS assigned ephemeral port. In BeeGFS 8 this was
-	// renamed to connClientPort as part of simplifying the configuration but inside the driver is
-	// still referenced in places as connClientPortUDP.
-	var connClientPort string
-	port, err := getEphemeralPortUDP()
-	if err != nil {
-		return errors.WithMessage(err, "error selecting connClientPort")
-	}
-	connClientPort = strconv.Itoa(port)
-
 	var clientConfBytes []byte
-	var clientConfINI *ini.File
 	if clientConfBytes, err = fsutil.ReadFile(confTemplatePath); err != nil {
-		return errors.Wrapf(err, "error loading beegfs-client.conf file at %s", confTemplatePath)
+		return nil, nil, errors.Wrapf(err, "error loading beegfs-client.conf file at %s", confTemplatePath)
 	}
 	if clientConfINI, err = ini.Load(clientConfBytes); err != nil {
-		return errors.Wrap(err, "error parsing template beegfs-client.conf file")
+		return nil, nil, errors.Wrap(err, "error parsing template beegfs-client.conf file")
 	}
 	if err = setConfigValueIfKeyExists(clientConfINI, "sysMgmtdHost", vol.sysMgmtdHost); err != nil {
-		return err
+		return nil, nil, err
 	}
 	// In BeeGFS 8 the name of the config option in the default config file changed from
 	// connClientPortUDP to connClientPort. A template beegfs-client.conf file from BeeGFS 7 will
@@ -108,7 +145,7 @@ func writeClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath st
 	if err = setConfigValueIfKeyExists(clientConfINI, "connClientPort", connClientPort); err != nil {
 		// Fallback to BeeGFS 7 parameter name:
 		if err = setConfigValueIfKeyExists(clientConfINI, "connClientPortUDP", connClientPort); err != nil {
-			return fmt.Errorf("unable to set connClientPort (BeeGFS 8) or connClientPortUDP (BeeGFS 7): %w", err)
+			return nil, nil, fmt.Errorf("unable to set connClientPort (BeeGFS 8) or connClientPortUDP (BeeGFS 7): %w", err)
 		}
 	}
 
@@ -130,18 +167,18 @@ func writeClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath st
 					if connMgmtdPort == "" {
 						connMgmtdPort = v
 					} else if connMgmtdPort != v {
-						return fmt.Errorf("the template beegfs-client.conf file appears to be for BeeGFS 8 but connMgmtdPortTCP and connMgmtdPortUDP appear to be set to different values in the driver configuration (they must be the same in BeeGFS 8+)")
+						return nil, nil, fmt.Errorf("the template beegfs-client.conf file appears to be for BeeGFS 8 but connMgmtdPortTCP and connMgmtdPortUDP appear to be set to different values in the driver configuration (they must be the same in BeeGFS 8+)")
 					}
 					LogDebug(ctx, "driver configuration contains a deprecated configuration key that does not exist in the template beegfs-client.conf file", "deprecatedKey", k, "replacedWith", "connMgmtdPort")
 					if err = setConfigValueIfKeyExists(clientConfINI, "connMgmtdPort", v); err != nil {
-						return err
+						return nil, nil, err
 					}
 				} else {
 					// If the template doesn't contain connMgmtdPort then don't perform the replacement.
-					return err
+					return nil, nil, err
 				}
 			} else {
-				return err
+				return nil, nil, err
 			}
 		}
 	}
@@ -149,11 +186,10 @@ func writeClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath st
 	if len(vol.config.ConnInterfaces) != 0 {
 		connInterfacesFileContents := strings.Join(vol.config.ConnInterfaces, "\n") + "\n"
 		if err := setConfigValueIfKeyExists(clientConfINI, "connInterfacesFile", connInterfacesFilePath); err != nil {
-			return err
-		}
-		if err = fsutil.WriteFile(connInterfacesFilePath, []byte(connInterfacesFileContents), 0644); err != nil {
-			return errors.Wrap(err, "error writing connInterfaces file")
+			return nil, nil, err
 		}
+		auxFiles = append(auxFiles, clientFile{name: "connInterfaces", path: connInterfacesFilePath,
+			contents: []byte(connInterfacesFileContents), perm: 0644})
 	}
 
 	if len(vol.config.ConnAuth) != 0 {
@@ -161,60 +197,47 @@ func writeClientFiles(ctx context.Context, vol beegfsVolume, confTemplatePath st
 		// of connAuthFile. For raw (not base64) encoded connAuth secrets, see additional behavior in parseConnAuthFromFile.
 		connAuthFileContents := vol.config.ConnAuth
 		if err := setConfigValueIfKeyExists(clientConfINI, "connAuthFile", vol.getConnAuthPath()); err != nil {
-			return err
-		}
-		if err = fsutil.WriteFile(vol.getConnAuthPath(), []byte(connAuthFileContents), 0400); err != nil {
-			return errors.Wrap(err, "error writing connAuth file")
+			return nil, nil, err
 		}
+		auxFiles = append(auxFiles, clientFile{name: "connAuth", path: vol.getConnAuthPath(),
+			contents: []byte(connAuthFileContents), perm: 0400})
 	}
 
 	if len(vol.config.TLSCert) != 0 {
 		tlsCertFileContents := vol.config.TLSCert
 		// The TLS cert should not be set in the client config file.
-		if err = fsutil.WriteFile(vol.getTLSCertPath(), []byte(tlsCertFileContents), 0400); err != nil {
-			return errors.Wrap(err, "error writing tlsCert file")
-		}
+		auxFiles = append(auxFiles, clientFile{name: "tlsCert", path: vol.getTLSCertPath(),
+			contents: []byte(tlsCertFileContents), perm: 0400})
 	}
 
 	if len(vol.config.ConnNetFilter) != 0 {
 		connNetFilterFileContents := strings.Join(vol.config.ConnNetFilter, "\n") + "\n"
 		if err := setConfigValueIfKeyExists(clientConfINI, "connNetFilterFile", connNetFilterFilePath); err != nil {
-			return err
-		}
-		if err = fsutil.WriteFile(connNetFilterFilePath, []byte(connNetFilterFileContents), 0644); err != nil {
-			return errors.Wrap(err, "error writing connNetFilter file")
+			return nil, nil, err
 		}
+		auxFiles = append(auxFiles, clientFile{name: "connNetFilter", path: connNetFilterFilePath,
+			contents: []byte(connNetFilterFileContents), perm: 0644})
 	}
 
 	if len(vol.config.ConnTcpOnlyFilter) != 0 {
 		connTcpOnlyFilterFileContents := strings.Join(vol.config.ConnTcpOnlyFilter, "\n") + "\n"
 		if err := setConfigValueIfKeyExists(clientConfINI, "connTcpOnlyFilterFile", connTcpOnlyFilterFilePath); err != nil {
-			return err
-		}
-		if err = fsutil.WriteFile(connTcpOnlyFilterFilePath, []byte(connTcpOnlyFilterFileContents), 0644); err != nil {
-			return errors.Wrap(err, "error writing connTcpOnlyFilter file")
+			return nil, nil, err
 		}
+		auxFiles = append(auxFiles, clientFile{name: "connTcpOnlyFilter", path: connTcpOnlyFilterFilePath,
+			contents: []byte(connTcpOnlyFilterFileContents), perm: 0644})
 	}
 
 	if len(vol.config.ConnRDMAInterfaces) != 0 {
 		connRDMAInterfacesContents := strings.Join(vol.config.ConnRDMAInterfaces, "\n") + "\n"
 		if err := setConfigValueIfKeyExists(clientConfINI, "connRDMAInterfacesFile", connRDMAInterfacesFilePath); err != nil {
-			return err
-		}
-		if err = fsutil.WriteFile(connRDMAInterfacesFilePath, []byte(connRDMAInterfacesContents), 0644); err != nil {
-			return errors.Wrap(err, "error writing connRDMAInterfaces file")
+			return nil, nil, err
 		}
+		auxFiles = append(auxFiles, clientFile{name: "connRDMAInterfaces", path: connRDMAInterfacesFilePath,
+			contents: []byte(connRDMAInterfacesContents), perm: 0644})
 	}
 
-	var clientConfFileHandle afero.File
-	if clientConfFileHandle, err = fs.Create(vol.clientConfPath); err != nil {
-		return errors.Wrap(err, "error creating beegfs-client.conf file")
-	}
-	if _, err = clientConfINI.WriteTo(clientConfFileHandle); err != nil {
-		return errors.Wrap(err, "error writing beegfs-client.conf file")
-	}
-
-	return nil
+	return clientConfINI, auxFiles, nil
 }
 
 // squashConfigForSysMgmtdHost takes a sysMgmtdHost and PluginConfig, which MAY have FileSystemSpecificConfigs. If
@@ -232,6 +255,85 @@ func squashConfigForSysMgmtdHost(sysMgmtdHost string, config beegfsv1.PluginConf
 	return returnConfig
 }
 
+// allowsUnmarkedVolumeDeletion reports whether the FileSystemSpecificConfig for sysMgmtdHost in config allows the
+// controller service to delete volumes without an ownership marker. Unlike the fields of BeegfsConfig, this setting
+// has no default and must be enabled for each file system explicitly.
+func allowsUnmarkedVolumeDeletion(sysMgmtdHost string, config beegfsv1.PluginConfig) bool {
+	for _, fsConfig := range config.FileSystemSpecificConfigs {
+		if sysMgmtdHost == fsConfig.SysMgmtdHost && fsConfig.AllowUnmarkedVolumeDeletion {
+			return true
+		}
+	}
+	return false
+}
+
+// setsVolumeMetadataXAttrs reports whether the FileSystemSpecificConfig for sysMgmtdHost in config makes the
+// controller service record volume metadata as extended attributes of volume directories. Like
+// allowUnmarkedVolumeDeletion, this setting must be enabled for each file system explicitly.
+func setsVolumeMetadataXAttrs(sysMgmtdHost string, config beegfsv1.PluginConfig) bool {
+	for _, fsConfig := range config.FileSystemSpecificConfigs {
+		if sysMgmtdHost == fsConfig.SysMgmtdHost && fsConfig.SetVolumeMetadataXAttrs {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVolDirPathPermitted returns an error if config does not permit a volume with the directory volDirPathBeegfsRoot
+// on the file system at sysMgmtdHost. The volume's volDirBasePath must be (or lie below) one of the
+// allowedVolDirBasePaths (if there are any) and the volume's directory must not be (or lie below) any of the
+// deniedVolDirBasePaths. Checking the volume's directory against the deniedVolDirBasePaths ensures that a volumeID
+// that refers to a denied path itself (e.g. a statically provisioned volume in "/home") is denied. The top-level lists
+// of config apply to every file system. The lists of the FileSystemSpecificConfigs for sysMgmtdHost apply in addition,
+// but only when a volume refers to its file system by that exact sysMgmtdHost (and not, e.g., by an alias).
+func checkVolDirPathPermitted(sysMgmtdHost, volDirPathBeegfsRoot string, config beegfsv1.PluginConfig) error {
+	volDirPathBeegfsRoot = path.Clean(path.Join("/", volDirPathBeegfsRoot))
+	if err := checkVolDirPathInLists(volDirPathBeegfsRoot, config.AllowedVolDirBasePaths,
+		config.DeniedVolDirBasePaths); err != nil {
+		return errors.WithMessage(err, "volume is not permitted by the top-level configuration")
+	}
+	var allowedBasePaths, deniedBasePaths []string
+	for _, fsConfig := range config.FileSystemSpecificConfigs {
+		if sysMgmtdHost == fsConfig.SysMgmtdHost {
+			allowedBasePaths = append(allowedBasePaths, fsConfig.AllowedVolDirBasePaths...)
+			deniedBasePaths = append(deniedBasePaths, fsConfig.DeniedVolDirBasePaths...)
+		}
+	}
+	if err := checkVolDirPathInLists(volDirPathBeegfsRoot, allowedBasePaths, deniedBasePaths); err != nil {
+		return errors.WithMessagef(err, "volume is not permitted for sysMgmtdHost %s", sysMgmtdHost)
+	}
+	return nil
+}
+
+// checkVolDirPathInLists returns an error if volDirPathBeegfsRoot (an absolute, clean path) is (or lies below) one of
+// deniedBasePaths or its parent directory is not (and does not lie below) one of allowedBasePaths (if there are any).
+func checkVolDirPathInLists(volDirPathBeegfsRoot string, allowedBasePaths, deniedBasePaths []string) error {
+	for _, deniedBasePath := range deniedBasePaths {
+		if isPathWithin(volDirPathBeegfsRoot, deniedBasePath) {
+			return errors.Errorf("volume directory %s is in %s, which is denied by deniedVolDirBasePaths",
+				volDirPathBeegfsRoot, deniedBasePath)
+		}
+	}
+	if len(allowedBasePaths) == 0 {
+		return nil
+	}
+	volDirBasePathBeegfsRoot := path.Dir(volDirPathBeegfsRoot)
+	for _, allowedBasePath := range allowedBasePaths {
+		if isPathWithin(volDirBasePathBeegfsRoot, allowedBasePath) {
+			return nil
+		}
+	}
+	return errors.Errorf("volDirBasePath %s is not in allowedVolDirBasePaths %v", volDirBasePathBeegfsRoot,
+		allowedBasePaths)
+}
+
+// isPathWithin reports whether p (an absolute, clean path) is basePath or lies below it. Paths are compared element by
+// element, so "/k8s-old" does not lie below "/k8s".
+func isPathWithin(p, basePath string) bool {
+	basePath = path.Clean(path.Join("/", basePath))
+	return basePath == "/" || p == basePath || strings.HasPrefix(p, basePath+"/")
+}
+
 // mountIfNecessary mounts a BeeGFS file system to vol.mountPath assuming configuration files have been written to
 // vol.mountDirPath by writeClientFiles.
 func mountIfNecessary(ctx context.Context, vol beegfsVolume, desiredMountOpts []string, mounter mount.Interface) (err error) {
@@ -539,5 +641,5 @@ func verifyBeegfsClientModuleIsAvailable() error {
 		LogDebug(context.TODO(), "The BeeGFS client module is installed but not loaded")
 		return nil
 	}
-	return errors.New("the BeeGFS client kernel module is not installed")
+	return errors.New(beegfsv1.BeegfsClientModuleMissingMessage)
 }
@@ -425,6 +425,144 @@ func TestSquashConfigForSysMgmtdHost(t *testing.T) {
 	}
 }
 
+func TestCheckVolDirPathPermitted(t *testing.T) {
+	testConfig := beegfsv1.PluginConfig{
+		FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+			{
+				SysMgmtdHost:           "127.0.0.1",
+				AllowedVolDirBasePaths: []string{"/k8s", "scratch/k8s/"},
+				DeniedVolDirBasePaths:  []string{"/k8s/denied"},
+			},
+			{
+				SysMgmtdHost:          "127.0.0.2",
+				DeniedVolDirBasePaths: []string{"/home"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		sysMgmtdHost         string
+		volDirPathBeegfsRoot string
+		wantErr              bool
+	}{
+		"allowed base path": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/pvc-1",
+		},
+		"below allowed base path": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/team-a/pvc-1",
+		},
+		"below unclean allowed base path": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/scratch/k8s/pvc-1",
+		},
+		"allowed base path itself": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s",
+			wantErr:              true,
+		},
+		"sibling with allowed base path prefix": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s-old/pvc-1",
+			wantErr:              true,
+		},
+		"escapes allowed base path": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/../home/pvc-1",
+			wantErr:              true,
+		},
+		"denied below allowed base path": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/denied/pvc-1",
+			wantErr:              true,
+		},
+		"denied base path itself": {
+			sysMgmtdHost:         "127.0.0.2",
+			volDirPathBeegfsRoot: "/home",
+			wantErr:              true,
+		},
+		"not denied": {
+			sysMgmtdHost:         "127.0.0.2",
+			volDirPathBeegfsRoot: "/projects/pvc-1",
+		},
+		"no fileSystemSpecificConfig": {
+			sysMgmtdHost:         "127.0.0.3",
+			volDirPathBeegfsRoot: "/home",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkVolDirPathPermitted(tc.sysMgmtdHost, tc.volDirPathBeegfsRoot, testConfig)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected error for %s", tc.volDirPathBeegfsRoot)
+			} else if !tc.wantErr && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckVolDirPathPermittedAllFileSystems(t *testing.T) {
+	testConfig := beegfsv1.PluginConfig{
+		AllowedVolDirBasePaths: []string{"/k8s"},
+		DeniedVolDirBasePaths:  []string{"/k8s/system"},
+		FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+			{
+				SysMgmtdHost:           "127.0.0.1",
+				AllowedVolDirBasePaths: []string{"/k8s/team-a"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		sysMgmtdHost         string
+		volDirPathBeegfsRoot string
+		wantErr              bool
+	}{
+		"allowed by both": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/team-a/pvc-1",
+		},
+		"not allowed for sysMgmtdHost": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/team-b/pvc-1",
+			wantErr:              true,
+		},
+		"allowed for alias": {
+			sysMgmtdHost:         "beegfs.example.com",
+			volDirPathBeegfsRoot: "/k8s/team-b/pvc-1",
+		},
+		"not allowed for alias": {
+			sysMgmtdHost:         "beegfs.example.com",
+			volDirPathBeegfsRoot: "/home/pvc-1",
+			wantErr:              true,
+		},
+		"denied for alias": {
+			sysMgmtdHost:         "beegfs.example.com",
+			volDirPathBeegfsRoot: "/k8s/system/pvc-1",
+			wantErr:              true,
+		},
+		"denied for sysMgmtdHost": {
+			sysMgmtdHost:         "127.0.0.1",
+			volDirPathBeegfsRoot: "/k8s/system",
+			wantErr:              true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkVolDirPathPermitted(tc.sysMgmtdHost, tc.volDirPathBeegfsRoot, testConfig)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected error for %s", tc.volDirPathBeegfsRoot)
+			} else if !tc.wantErr && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
 func TestGetEphemeralPortUDP(t *testing.T) {
 	_, err := getEphemeralPortUDP()
 	if err != nil {
@@ -12,36 +12,40 @@ package beegfs
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
-	"net"
+	"encoding/pem"
 	"regexp"
-	"strconv"
+	"strings"
+	"time"
 
 	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/yaml"
 )
 
-// These parameters have no effect when specified in the beeGFSClientConf configuration section.
-var noEffectBeegfsConfOptions = []string{
-	"sysMgmtdHost",
-	"connClientPortUDP",
-	"connClientPort",
-	"connPortShift",
-}
-
-// These parameters are unsupported when specified in the beeGFSClientConf configuration section.
-var unsupportedBeegfsConfOptions = []string{
-	"connInterfacesFile",
-	"connNetFilterFile",
-	"connTcpOnlyFilterFile",
-	"connAuthFile",
-	"connRDMAInterfacesFile",
-}
+const (
+	// minConnAuthLength is the minimum length (in bytes) of the contents of a connAuthFile written by the driver
+	// (including the newline appended to raw connAuth secrets). It is intended to catch empty or truncated secrets.
+	minConnAuthLength = 8
+	// tlsCertExpiryWarningPeriod is how long before a TLS certificate expires the driver begins to warn about it.
+	tlsCertExpiryWarningPeriod = 30 * 24 * time.Hour
+)
 
 // parseConfigFromFile reads the file at the specified path, unmarshalls it into a PluginConfigFromFile, and constructs
-// a PluginConfig. It uses nodeID to determine if any node specific configuration applies to the node the plugin is
-// running on. If it does, the final PluginConfig contains node specific overrides.
+// a PluginConfig. It uses nodeID (and, if any NodeSpecificConfig includes a nodeSelector, the labels of the Kubernetes
+// Node named nodeID) to determine if any node specific configuration applies to the node the plugin is running on. If
+// it does, the final PluginConfig contains node specific overrides.
+//
+// Multiple NodeSpecificConfigs may apply to the same node. They are merged with the following precedence (lowest
+// first):
+//   - NodeSpecificConfigs that apply because of their nodeSelector, in the order they appear in the file.
+//   - NodeSpecificConfigs that apply because their nodeList contains nodeID, in the order they appear in the file.
+//
+// In other words, an explicitly listed node name is considered more specific than a label selector, and configuration
+// set lower in the file takes precedence over configuration set higher in the file.
 func parseConfigFromFile(path, nodeID string) (beegfsv1.PluginConfig, error) {
 	var rawConfig beegfsv1.PluginConfigFromFile
 	var newPluginConfig beegfsv1.PluginConfig
@@ -57,7 +61,9 @@ func parseConfigFromFile(path, nodeID string) (beegfsv1.PluginConfig, error) {
 		// missing quotes in the beegfsClientConf field. It is generally bad practice to base program logic on
 		// "reading" and error string, but here we only add to the error message we write and fall back to simply
 		// logging the error as is if anything goes wrong.
-		re := ".*cannot unmarshal .* into Go struct field .*\\.beegfsClientConf of type string.*"
+		// Newer versions of encoding/json include the offending key in the field path (e.g.
+		// "beegfsClientConf.connMgmtdPort"), so allow for an optional suffix.
+		re := ".*cannot unmarshal .* into Go struct field .*\\.beegfsClientConf(\\.\\S+)? of type string.*"
 		if matched, regexErr := regexp.MatchString(re, err.Error()); regexErr == nil && matched {
 			return beegfsv1.PluginConfig{}, errors.Wrap(err, "likely missing quotes around an integer or "+
 				"boolean beegfsClientConf value")
@@ -70,10 +76,42 @@ func parseConfigFromFile(path, nodeID string) (beegfsv1.PluginConfig, error) {
 	newPluginConfig = beegfsv1.PluginConfig{
 		DefaultConfig:             rawConfig.DefaultConfig,
 		FileSystemSpecificConfigs: rawConfig.FileSystemSpecificConfigs,
+		AllowedVolDirBasePaths:    rawConfig.AllowedVolDirBasePaths,
+		DeniedVolDirBasePaths:     rawConfig.DeniedVolDirBasePaths,
 	}
 
 	// overwrite newPluginConfig with anything found in NodeSpecificConfigs pertaining to this node
-	for _, nodeConfig := range rawConfig.NodeSpecificConfigs {
+	nodeConfigs, err := getNodeSpecificConfigsForNode(rawConfig.NodeSpecificConfigs, nodeID)
+	if err != nil {
+		return beegfsv1.PluginConfig{}, err
+	}
+	for _, nodeConfig := range nodeConfigs {
+		overWriteBeegfsConfig(&newPluginConfig.DefaultConfig, nodeConfig.DefaultConfig)
+		newPluginConfig.FileSystemSpecificConfigs = overwriteFileSystemSpecificConfigs(
+			newPluginConfig.FileSystemSpecificConfigs, nodeConfig.FileSystemSpecificConfigs)
+	}
+
+	if err := validateConfig(&newPluginConfig); err != nil {
+		return newPluginConfig, errors.WithMessage(err, "config validation failed")
+	}
+	stripConfig(&newPluginConfig)
+	LogDebug(context.TODO(), "Actual configuration to be applied", "PluginConfig", newPluginConfig)
+
+	return newPluginConfig, nil
+}
+
+// getNodeSpecificConfigsForNode returns the NodeSpecificConfigs that apply to the node identified by nodeID, ordered
+// from lowest to highest precedence (see parseConfigFromFile). The labels of the node are only retrieved (using
+// getNodeLabels) if at least one NodeSpecificConfig includes a nodeSelector.
+func getNodeSpecificConfigsForNode(nodeConfigs []beegfsv1.NodeSpecificConfig, nodeID string) (
+	[]beegfsv1.NodeSpecificConfig, error) {
+	var nodeLabels labels.Set
+	var matchedBySelector, matchedByName []beegfsv1.NodeSpecificConfig
+	for _, nodeConfig := range nodeConfigs {
+		if len(nodeConfig.NodeList) == 0 && nodeConfig.NodeSelector == nil {
+			return nil, errors.New("node specific configuration must include a nodeList or a nodeSelector")
+		}
+
 		appliesToNode := false
 		for _, nodeName := range nodeConfig.NodeList {
 			if nodeID == nodeName {
@@ -82,19 +120,33 @@ func parseConfigFromFile(path, nodeID string) (beegfsv1.PluginConfig, error) {
 			}
 		}
 		if appliesToNode {
-			overWriteBeegfsConfig(&newPluginConfig.DefaultConfig, nodeConfig.DefaultConfig)
-			newPluginConfig.FileSystemSpecificConfigs = overwriteFileSystemSpecificConfigs(
-				newPluginConfig.FileSystemSpecificConfigs, nodeConfig.FileSystemSpecificConfigs)
+			matchedByName = append(matchedByName, nodeConfig)
+			continue
 		}
-	}
 
-	if err := validateConfig(&newPluginConfig); err != nil {
-		return newPluginConfig, errors.WithMessage(err, "config validation failed")
+		if nodeConfig.NodeSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(nodeConfig.NodeSelector)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid nodeSelector in node specific configuration")
+			}
+			if nodeLabels == nil {
+				rawLabels, err := getNodeLabels(context.TODO(), nodeID)
+				if err != nil {
+					return nil, errors.WithMessage(err, "failed to get node labels required to evaluate nodeSelector")
+				}
+				LogDebug(context.TODO(), "Retrieved node labels to evaluate nodeSelector", "nodeID", nodeID,
+					"labels", rawLabels)
+				nodeLabels = labels.Set(rawLabels)
+				if nodeLabels == nil {
+					nodeLabels = labels.Set{} // Avoid retrieving the labels of a node with no labels again.
+				}
+			}
+			if selector.Matches(nodeLabels) {
+				matchedBySelector = append(matchedBySelector, nodeConfig)
+			}
+		}
 	}
-	stripConfig(&newPluginConfig)
-	LogDebug(context.TODO(), "Actual configuration to be applied", "PluginConfig", newPluginConfig)
-
-	return newPluginConfig, nil
+	return append(matchedBySelector, matchedByName...), nil
 }
 
 // parseConnAuthFromFile reads the file at the specified path and modifies the provided PluginConfig so that it
@@ -114,22 +166,8 @@ func parseConnAuthFromFile(path string, newPluginConfig *beegfsv1.PluginConfig)
 
 	for _, connAuth := range connAuthConfigs {
 		foundMatchingConfig := false
-		switch connAuth.Encoding {
-		case "raw", "":
-			// We previously added this newline to the connAuth in writeClientFiles because
-			// users' connAuthFiles typically contained a final newline (though they
-			// may not have realized it). When adding support for base64 encoding, we had to
-			// move the newline here to ensure we wrote out base64 encoded secrets exactly as
-			// expected.
-			connAuth.ConnAuth += "\n"
-		case "base64":
-			connAuthDecoded, err := base64.StdEncoding.DecodeString(connAuth.ConnAuth)
-			if err != nil {
-				return errors.Wrap(err, "failed to decode base64 connAuth")
-			}
-			connAuth.ConnAuth = string(connAuthDecoded)
-		default:
-			return errors.Errorf("invalid ConnAuthFile encoding %s", connAuth.Encoding)
+		if connAuth.ConnAuth, err = decodeConnAuth(connAuth.ConnAuth, connAuth.Encoding); err != nil {
+			return err
 		}
 		for i, specificConfig := range newPluginConfig.FileSystemSpecificConfigs {
 			if connAuth.SysMgmtdHost == specificConfig.SysMgmtdHost {
@@ -156,6 +194,86 @@ func parseConnAuthFromFile(path string, newPluginConfig *beegfsv1.PluginConfig)
 	return nil
 }
 
+// decodeConnAuth returns the exact contents of the connAuthFile that should be written for a connAuth secret with the
+// provided encoding ("raw", "base64", or "" for raw). It returns an error if the contents would be shorter than
+// minConnAuthLength.
+func decodeConnAuth(connAuth, encoding string) (string, error) {
+	var connAuthDecoded string
+	switch encoding {
+	case "raw", "":
+		// We previously added this newline to the connAuth in writeClientFiles because
+		// users' connAuthFiles typically contained a final newline (though they
+		// may not have realized it). When adding support for base64 encoding, we had to
+		// move the newline here to ensure we wrote out base64 encoded secrets exactly as
+		// expected.
+		connAuthDecoded = connAuth + "\n"
+	case "base64":
+		connAuthBytes, err := base64.StdEncoding.DecodeString(connAuth)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to decode base64 connAuth")
+		}
+		connAuthDecoded = string(connAuthBytes)
+	default:
+		return "", errors.Errorf("invalid ConnAuthFile encoding %s", encoding)
+	}
+	if len(connAuthDecoded) < minConnAuthLength {
+		return "", errors.Errorf("connAuth is %d bytes but must be at least %d bytes", len(connAuthDecoded),
+			minConnAuthLength)
+	}
+	return connAuthDecoded, nil
+}
+
+// validateTLSCert parses the PEM encoded certificate chain in tlsCert. It returns an error if tlsCert contains no
+// certificates, contains anything other than certificates, or contains a certificate that is expired or not yet
+// valid. It logs the expiry of each certificate (and a warning for any certificate that expires within
+// tlsCertExpiryWarningPeriod) so administrators can track rotation for each sysMgmtdHost. Certificate subjects are
+// not logged for the same reasons TLSCertConfig.MarshalJSON redacts certificates.
+func validateTLSCert(ctx context.Context, sysMgmtdHost, tlsCert string) error {
+	now := time.Now()
+	rest := []byte(tlsCert)
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return errors.Errorf("tlsCert for sysMgmtdHost %s contains an unexpected %s PEM block", sysMgmtdHost,
+				block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse tlsCert for sysMgmtdHost %s", sysMgmtdHost)
+		}
+		certs = append(certs, cert)
+	}
+	if len(strings.TrimSpace(string(rest))) != 0 {
+		return errors.Errorf("tlsCert for sysMgmtdHost %s contains data that is not PEM encoded", sysMgmtdHost)
+	}
+	if len(certs) == 0 {
+		return errors.Errorf("tlsCert for sysMgmtdHost %s does not contain a PEM encoded certificate", sysMgmtdHost)
+	}
+
+	for i, cert := range certs {
+		if now.After(cert.NotAfter) {
+			return errors.Errorf("tlsCert for sysMgmtdHost %s contains a certificate (position %d in chain) that "+
+				"expired at %s", sysMgmtdHost, i, cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+		if now.Before(cert.NotBefore) {
+			return errors.Errorf("tlsCert for sysMgmtdHost %s contains a certificate (position %d in chain) that "+
+				"is not valid until %s", sysMgmtdHost, i, cert.NotBefore.UTC().Format(time.RFC3339))
+		}
+		logger(ctx).Info("TLS certificate loaded", "sysMgmtdHost", sysMgmtdHost, "chainPosition", i,
+			"notAfter", cert.NotAfter.UTC().Format(time.RFC3339))
+		if cert.NotAfter.Sub(now) < tlsCertExpiryWarningPeriod {
+			logger(ctx).Info("WARNING: TLS certificate expires soon", "sysMgmtdHost", sysMgmtdHost,
+				"chainPosition", i, "notAfter", cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
 func parseTLSCertsFromFile(path string, newPluginConfig *beegfsv1.PluginConfig) error {
 	tlsCertConfigs := make([]beegfsv1.TLSCertConfig, 0)
 	rawTlsCertConfigBytes, err := fsutil.ReadFile(path)
@@ -171,6 +289,9 @@ func parseTLSCertsFromFile(path string, newPluginConfig *beegfsv1.PluginConfig)
 
 	for _, tlsCert := range tlsCertConfigs {
 		foundMatchingConfig := false
+		if err := validateTLSCert(context.TODO(), tlsCert.SysMgmtdHost, tlsCert.TLSCert); err != nil {
+			return err
+		}
 		// newline added for consistency with how connAuthFiles are written out.
 		tlsCert.TLSCert += "\n"
 		for i, specificConfig := range newPluginConfig.FileSystemSpecificConfigs {
@@ -194,65 +315,56 @@ func parseTLSCertsFromFile(path string, newPluginConfig *beegfsv1.PluginConfig)
 	return nil
 }
 
-// validateConfig checks the basic syntax of assorted fields in a PluginConfig and returns an error if it finds
-// something incorrect.
-func validateConfig(plConfig *beegfsv1.PluginConfig) error {
-	beegfsConfigs := []beegfsv1.BeegfsConfig{plConfig.DefaultConfig}
-	// this regex is used to determine whether a given string is a domain name
-	domainRegex := regexp.MustCompile(`^(?:[_a-z0-9](?:[_a-z0-9-]{0,61}[a-z0-9]\.)|(?:[0-9]+/[0-9]{2})\.)+(?:[a-z](?:[a-z0-9-]{0,61}[a-z0-9])?)?$`)
-	for _, config := range plConfig.FileSystemSpecificConfigs {
-		// sysMgmtdHost can be localhost, an IP address, or a domain name. if it is none of these, return an error
-		if config.SysMgmtdHost != "localhost" && net.ParseIP(config.SysMgmtdHost) == nil &&
-			!domainRegex.MatchString(config.SysMgmtdHost) {
-			return errors.Errorf("invalid SysMgmtdHost %s", config.SysMgmtdHost)
+// overrideConfigWithSecrets overwrites the ConnAuth and TLSCert in config with any supplied in the secrets of a CSI
+// request. In Kubernetes, these secrets come from the Secrets referenced by the csi.storage.k8s.io/*-secret-name
+// StorageClass parameters, allowing each StorageClass to use its own connAuth and TLS certificate for a sysMgmtdHost.
+// Secret values are never included in returned errors.
+func overrideConfigWithSecrets(ctx context.Context, sysMgmtdHost string, config *beegfsv1.BeegfsConfig,
+	secrets map[string]string) error {
+	for key, value := range secrets {
+		switch key {
+		case connAuthSecretKey, connAuthEncodingSecretKey, tlsCertSecretKey:
+			if value == "" {
+				return errors.Errorf("secret key %s is empty", key)
+			}
+		default:
+			return errors.Errorf("invalid secret key %s", key)
 		}
-		beegfsConfigs = append(beegfsConfigs, config.Config)
 	}
 
-	for _, config := range beegfsConfigs {
-		// Validate optional BeeGFS 8 mgmtd gRPC port, if provided.
-		if config.GrpcPort != "" {
-			if p, err := strconv.Atoi(config.GrpcPort); err != nil || p < 1 || p > 65535 {
-				return errors.Errorf("invalid GrpcPort %s", config.GrpcPort)
-			}
-		}
-		for _, filter := range config.ConnNetFilter {
-			if _, _, err := net.ParseCIDR(filter); err != nil && net.ParseIP(filter) == nil {
-				return errors.Errorf("invalid ConnNetFilter %s", filter)
-			}
-		}
-		for _, filter := range config.ConnTcpOnlyFilter {
-			if _, _, err := net.ParseCIDR(filter); err != nil && net.ParseIP(filter) == nil {
-				return errors.Errorf("invalid ConnTCPOnlyFilter %s", filter)
-			}
+	if connAuth, ok := secrets[connAuthSecretKey]; ok {
+		connAuthDecoded, err := decodeConnAuth(connAuth, secrets[connAuthEncodingSecretKey])
+		if err != nil {
+			return err
 		}
+		config.ConnAuth = connAuthDecoded
+	} else if _, ok := secrets[connAuthEncodingSecretKey]; ok {
+		return errors.Errorf("secret key %s provided without secret key %s", connAuthEncodingSecretKey,
+			connAuthSecretKey)
 	}
 
+	if tlsCert, ok := secrets[tlsCertSecretKey]; ok {
+		if err := validateTLSCert(ctx, sysMgmtdHost, tlsCert); err != nil {
+			return err
+		}
+		// newline added for consistency with parseTLSCertsFromFile.
+		config.TLSCert = tlsCert + "\n"
+	}
 	return nil
 }
 
+// validateConfig checks the basic syntax of assorted fields in a PluginConfig and returns an error if it finds
+// something incorrect. The rules are shared with the operator's validating webhook.
+func validateConfig(plConfig *beegfsv1.PluginConfig) error {
+	return beegfsv1.ValidatePluginConfig(plConfig)
+}
+
 // stripConfig removes any no-effect beegfsConf options from the plugin configuration, logging a warning if any are
 // found. It also logs a warning (but does not remove) any unsupported options it finds. See deployment.md for the list
 // of no-effect options.
 func stripConfig(plConfig *beegfsv1.PluginConfig) {
-	beegfsConfigs := []beegfsv1.BeegfsConfig{plConfig.DefaultConfig}
-	for _, config := range plConfig.FileSystemSpecificConfigs {
-		beegfsConfigs = append(beegfsConfigs, config.Config)
-	}
-	for _, config := range beegfsConfigs {
-		for _, noEffectOption := range noEffectBeegfsConfOptions {
-			if val, present := config.BeegfsClientConf[noEffectOption]; present {
-				LogDebug(context.TODO(), "WARNING: No-effect beegfs configuration option found and removed from config",
-					"noEffectOption", noEffectOption, "noEffectValue", val)
-				delete(config.BeegfsClientConf, noEffectOption)
-			}
-		}
-		for _, unsupportedOption := range unsupportedBeegfsConfOptions {
-			if val, present := config.BeegfsClientConf[unsupportedOption]; present {
-				LogDebug(context.TODO(), "WARNING: Unsupported beegfs configuration option found and left in config",
-					"unsupportedOption", unsupportedOption, "unsupportedValue", val)
-			}
-		}
+	for _, warning := range beegfsv1.StripPluginConfig(plConfig) {
+		LogDebug(context.TODO(), "WARNING: Problematic beegfs configuration option found", "warning", warning)
 	}
 }
 
@@ -266,6 +378,20 @@ func overwriteFileSystemSpecificConfigs(writeTo, writeFrom []beegfsv1.FileSystem
 			if writeToConfig.SysMgmtdHost == writeFromConfig.SysMgmtdHost {
 				writeToHadConfig = true
 				overWriteBeegfsConfig(&writeTo[i].Config, writeFromConfig.Config)
+				if writeFromConfig.AllowUnmarkedVolumeDeletion {
+					writeTo[i].AllowUnmarkedVolumeDeletion = true
+				}
+				if writeFromConfig.SetVolumeMetadataXAttrs {
+					writeTo[i].SetVolumeMetadataXAttrs = true
+				}
+				if len(writeFromConfig.AllowedVolDirBasePaths) != 0 {
+					writeTo[i].AllowedVolDirBasePaths = make([]string, len(writeFromConfig.AllowedVolDirBasePaths))
+					copy(writeTo[i].AllowedVolDirBasePaths, writeFromConfig.AllowedVolDirBasePaths)
+				}
+				if len(writeFromConfig.DeniedVolDirBasePaths) != 0 {
+					writeTo[i].DeniedVolDirBasePaths = make([]string, len(writeFromConfig.DeniedVolDirBasePaths))
+					copy(writeTo[i].DeniedVolDirBasePaths, writeFromConfig.DeniedVolDirBasePaths)
+				}
 			}
 		}
 		if !writeToHadConfig {
@@ -6,10 +6,20 @@ Licensed under the Apache License, Version 2.0.
 package beegfs
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"os"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
 	"github.com/pkg/errors"
@@ -22,6 +32,15 @@ import (
 func TestParseConfigFromFile(t *testing.T) {
 	fs = afero.NewOsFs()
 	fsutil = afero.Afero{Fs: fs}
+	defer func() { getNodeLabels = getNodeLabelsFromAPIServer }()
+	getNodeLabels = func(ctx context.Context, nodeName string) (map[string]string, error) {
+		nodeLabels := map[string]map[string]string{
+			"testnode":  {"example.com/rdma": "true"},
+			"rdmanode":  {"example.com/rdma": "true"},
+			"plainnode": {"example.com/rdma": "false"},
+		}
+		return nodeLabels[nodeName], nil
+	}
 	tests := map[string]struct {
 		configFile string
 		nodeID     string
@@ -107,6 +126,49 @@ func TestParseConfigFromFile(t *testing.T) {
 				},
 			},
 		},
+		"node selector override (matching labels)": {
+			// because "rdmanode" has a matching label, default values should be overridden
+			configFile: "testdata/node-selector-override.yaml",
+			nodeID:     "rdmanode",
+			want: beegfsv1.PluginConfig{
+				DefaultConfig: beegfsv1.BeegfsConfig{
+					GrpcPort:          "8011",
+					ConnInterfaces:    []string{"ib1"},
+					ConnNetFilter:     []string{"127.0.0.1/24"},
+					ConnTcpOnlyFilter: []string{"127.0.0.0"},
+					BeegfsClientConf:  map[string]string{"connMgmtdPort": "8001"},
+				},
+			},
+		},
+		"node selector override (not matching labels)": {
+			// because "plainnode" does not have a matching label, default values should NOT be overridden
+			configFile: "testdata/node-selector-override.yaml",
+			nodeID:     "plainnode",
+			want: beegfsv1.PluginConfig{
+				DefaultConfig: beegfsv1.BeegfsConfig{
+					GrpcPort:          "8010",
+					ConnInterfaces:    []string{"ib0"},
+					ConnNetFilter:     []string{"127.0.0.0/24"},
+					ConnTcpOnlyFilter: []string{"127.0.0.0"},
+					BeegfsClientConf:  map[string]string{"connMgmtdPort": "8000"},
+				},
+			},
+		},
+		"node selector and node list override (matching both)": {
+			// because "testnode" matches both, the nodeList configuration should take precedence over the nodeSelector
+			// configuration
+			configFile: "testdata/node-selector-override.yaml",
+			nodeID:     "testnode",
+			want: beegfsv1.PluginConfig{
+				DefaultConfig: beegfsv1.BeegfsConfig{
+					GrpcPort:          "8012",
+					ConnInterfaces:    []string{"ib2"},
+					ConnNetFilter:     []string{"127.0.0.1/24"},
+					ConnTcpOnlyFilter: []string{"127.0.0.0"},
+					BeegfsClientConf:  map[string]string{"connMgmtdPort": "8001"},
+				},
+			},
+		},
 		"node specific filesystem specific override": {
 			// because "testnode" is in nodeList, file system specific values should be overridden
 			configFile: "testdata/node-filesystem-override.yaml",
@@ -132,6 +194,58 @@ func TestParseConfigFromFile(t *testing.T) {
 				},
 			},
 		},
+		"node specific filesystem specific allowUnmarkedVolumeDeletion": {
+			// because "testnode" is in nodeList, deletion of unmarked volumes should be allowed on both file systems
+			configFile: "testdata/allow-unmarked-volume-deletion.yaml",
+			nodeID:     "testnode",
+			want: beegfsv1.PluginConfig{
+				FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+					{
+						SysMgmtdHost:                "127.0.0.0",
+						Config:                      beegfsv1.BeegfsConfig{ConnInterfaces: []string{"ib0"}},
+						AllowUnmarkedVolumeDeletion: true,
+					},
+					{
+						SysMgmtdHost:                "127.0.0.1",
+						Config:                      beegfsv1.BeegfsConfig{ConnInterfaces: []string{"ib1"}},
+						AllowUnmarkedVolumeDeletion: true,
+					},
+				},
+			},
+		},
+		"node specific filesystem specific allowUnmarkedVolumeDeletion (not matching nodeid)": {
+			// because "testnode" is NOT in nodeList, deletion of unmarked volumes should only be allowed on 127.0.0.0
+			configFile: "testdata/allow-unmarked-volume-deletion.yaml",
+			nodeID:     "nottestnode",
+			want: beegfsv1.PluginConfig{
+				FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+					{
+						SysMgmtdHost:                "127.0.0.0",
+						Config:                      beegfsv1.BeegfsConfig{ConnInterfaces: []string{"ib0"}},
+						AllowUnmarkedVolumeDeletion: true,
+					},
+					{
+						SysMgmtdHost: "127.0.0.1",
+						Config:       beegfsv1.BeegfsConfig{ConnInterfaces: []string{"ib0"}},
+					},
+				},
+			},
+		},
+		"top-level volDirBasePaths": {
+			configFile: "testdata/vol-dir-base-paths.yaml",
+			nodeID:     "testnode",
+			want: beegfsv1.PluginConfig{
+				FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+					{
+						SysMgmtdHost:           "127.0.0.0",
+						Config:                 beegfsv1.BeegfsConfig{ConnInterfaces: []string{"ib0"}},
+						AllowedVolDirBasePaths: []string{"/k8s/team-a"},
+					},
+				},
+				AllowedVolDirBasePaths: []string{"/k8s"},
+				DeniedVolDirBasePaths:  []string{"/k8s/system"},
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -164,6 +278,11 @@ func TestParseConnAuthAndTLSCertsFromFiles(t *testing.T) {
 	if err != nil {
 		t.Fatal("failed to read binary connAuthFile")
 	}
+	// Read in the PEM encoded certificate referenced by testdata/tlscerts.yaml.
+	tlsCertBytes, err := os.ReadFile("testdata/tlscert.pem")
+	if err != nil {
+		t.Fatal("failed to read TLS certificate")
+	}
 
 	tests := map[string]struct {
 		authPath    string
@@ -196,7 +315,7 @@ func TestParseConnAuthAndTLSCertsFromFiles(t *testing.T) {
 						SysMgmtdHost: "127.0.0.0",
 						Config: beegfsv1.BeegfsConfig{
 							ConnAuth: "secret1\n",
-							TLSCert:  "cert1\n",
+							TLSCert:  string(tlsCertBytes),
 						},
 					},
 				},
@@ -222,7 +341,7 @@ func TestParseConnAuthAndTLSCertsFromFiles(t *testing.T) {
 						Config: beegfsv1.BeegfsConfig{
 							BeegfsClientConf: map[string]string{"testkey": "testvalue"},
 							ConnAuth:         "secret1\n",
-							TLSCert:          "cert1\n",
+							TLSCert:          string(tlsCertBytes),
 						},
 					},
 				},
@@ -252,7 +371,7 @@ func TestParseConnAuthAndTLSCertsFromFiles(t *testing.T) {
 						Config: beegfsv1.BeegfsConfig{
 							BeegfsClientConf: map[string]string{"testkey": "testvalue"},
 							ConnAuth:         "secret1\n",
-							TLSCert:          "cert1\n",
+							TLSCert:          string(tlsCertBytes),
 						},
 					},
 				},
@@ -267,7 +386,7 @@ func TestParseConnAuthAndTLSCertsFromFiles(t *testing.T) {
 						SysMgmtdHost: "127.0.0.0",
 						Config: beegfsv1.BeegfsConfig{
 							ConnAuth: "secret1\n",
-							TLSCert:  "cert1\n",
+							TLSCert:  string(tlsCertBytes),
 						},
 					},
 				},
@@ -382,6 +501,40 @@ func TestValidateConfig(t *testing.T) {
 				},
 			},
 		},
+		"empty allowedVolDirBasePaths entry": {
+			errors.New("empty AllowedVolDirBasePaths entry for SysMgmtdHost 127.0.0.0"),
+			beegfsv1.PluginConfig{
+				FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+					{
+						SysMgmtdHost:           "127.0.0.0",
+						AllowedVolDirBasePaths: []string{"/k8s", ""},
+					},
+				},
+			},
+		},
+		"empty deniedVolDirBasePaths entry": {
+			errors.New("empty DeniedVolDirBasePaths entry for SysMgmtdHost 127.0.0.0"),
+			beegfsv1.PluginConfig{
+				FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+					{
+						SysMgmtdHost:          "127.0.0.0",
+						DeniedVolDirBasePaths: []string{""},
+					},
+				},
+			},
+		},
+		"empty top-level allowedVolDirBasePaths entry": {
+			errors.New("empty AllowedVolDirBasePaths entry"),
+			beegfsv1.PluginConfig{
+				AllowedVolDirBasePaths: []string{""},
+			},
+		},
+		"empty top-level deniedVolDirBasePaths entry": {
+			errors.New("empty DeniedVolDirBasePaths entry"),
+			beegfsv1.PluginConfig{
+				DeniedVolDirBasePaths: []string{"/home", ""},
+			},
+		},
 		"invalid ConnTCPOnlyFilter": {
 			errors.New("invalid ConnTCPOnlyFilter testinvalid"),
 			beegfsv1.PluginConfig{
@@ -418,7 +571,7 @@ func TestStripNoEffectConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 	// introduce no-effect options in the default and filesystem configs
-	for _, noEffectOption := range noEffectBeegfsConfOptions {
+	for _, noEffectOption := range beegfsv1.NoEffectBeegfsConfOptions {
 		modifiedConfig.DefaultConfig.BeegfsClientConf[noEffectOption] = "noeffectdefaultkey"
 		modifiedConfig.FileSystemSpecificConfigs[0].Config.BeegfsClientConf[noEffectOption] = "noeffectfskey"
 	}
@@ -457,7 +610,7 @@ func TestStripUnsupportedConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 	// introduce unsupported options in the default and filesystem configs
-	for _, unsupportedOption := range unsupportedBeegfsConfOptions {
+	for _, unsupportedOption := range beegfsv1.UnsupportedBeegfsConfOptions {
 		originalConfig.DefaultConfig.BeegfsClientConf[unsupportedOption] = "unsupporteddefaultkey"
 		originalConfig.FileSystemSpecificConfigs[0].Config.BeegfsClientConf[unsupportedOption] = "unsupportedfskey"
 		modifiedConfig.DefaultConfig.BeegfsClientConf[unsupportedOption] = "unsupporteddefaultkey"
@@ -491,3 +644,162 @@ func TestOverwriteFromBeegfsClientConfEmptyValue(t *testing.T) {
 		t.Fatalf("expected: %v, got: %v", want, writeTo)
 	}
 }
+
+func TestOverrideConfigWithSecrets(t *testing.T) {
+	fileConfig := beegfsv1.BeegfsConfig{
+		ConnAuth:       "fileSecret\n",
+		TLSCert:        "fileCert\n",
+		ConnInterfaces: []string{"ib0"},
+	}
+	validCert := newTestCertPEM(t, time.Now().Add(-time.Hour), time.Now().Add(365*24*time.Hour))
+
+	tests := map[string]struct {
+		secrets       map[string]string
+		want          beegfsv1.BeegfsConfig
+		expectedError error
+	}{
+		"no secrets": {
+			secrets: nil,
+			want:    fileConfig,
+		},
+		"raw connAuth and tlsCert": {
+			secrets: map[string]string{"connAuth": "scSecret", "tlsCert": validCert},
+			want: beegfsv1.BeegfsConfig{
+				ConnAuth:       "scSecret\n",
+				TLSCert:        validCert + "\n",
+				ConnInterfaces: []string{"ib0"},
+			},
+		},
+		"base64 connAuth": {
+			secrets: map[string]string{"connAuth": "c2NTZWNyZXQ=", "connAuthEncoding": "base64"},
+			want: beegfsv1.BeegfsConfig{
+				ConnAuth:       "scSecret",
+				TLSCert:        "fileCert\n",
+				ConnInterfaces: []string{"ib0"},
+			},
+		},
+		"invalid key": {
+			secrets:       map[string]string{"connauth": "scSecret"},
+			expectedError: errors.New("invalid secret key connauth"),
+		},
+		"empty value": {
+			secrets:       map[string]string{"connAuth": ""},
+			expectedError: errors.New("secret key connAuth is empty"),
+		},
+		"encoding without connAuth": {
+			secrets:       map[string]string{"connAuthEncoding": "base64"},
+			expectedError: errors.New("secret key connAuthEncoding provided without secret key connAuth"),
+		},
+		"invalid encoding": {
+			secrets:       map[string]string{"connAuth": "scSecret", "connAuthEncoding": "hex"},
+			expectedError: errors.New("invalid ConnAuthFile encoding hex"),
+		},
+		"short connAuth": {
+			secrets:       map[string]string{"connAuth": "scSecr"},
+			expectedError: errors.New("connAuth is 7 bytes but must be at least 8 bytes"),
+		},
+		"malformed tlsCert": {
+			secrets:       map[string]string{"tlsCert": "scCert"},
+			expectedError: errors.New("tlsCert for sysMgmtdHost 127.0.0.1 contains data that is not PEM encoded"),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := fileConfig
+			err := overrideConfigWithSecrets(context.Background(), "127.0.0.1", &got, tc.secrets)
+			if (err != nil && tc.expectedError == nil) || (err == nil && tc.expectedError != nil) ||
+				(err != nil && tc.expectedError != nil && err.Error() != tc.expectedError.Error()) {
+				t.Fatalf("expected error: %v, got: %v", tc.expectedError, err)
+			}
+			if err == nil && !reflect.DeepEqual(tc.want, got) {
+				t.Fatalf("expected: %v, got: %v", tc.want, got)
+			}
+			if err != nil && strings.Contains(err.Error(), "scSecret") {
+				t.Fatalf("expected error not to contain secret, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTLSCert(t *testing.T) {
+	now := time.Now()
+	validCert := newTestCertPEM(t, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	expiringCert := newTestCertPEM(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	expiredCert := newTestCertPEM(t, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	futureCert := newTestCertPEM(t, now.Add(24*time.Hour), now.Add(48*time.Hour))
+	keyBlock := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}))
+	badCertBlock := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}))
+
+	tests := map[string]struct {
+		tlsCert  string
+		errRegex string // A regular expression expected to be in err.Error(). Empty if validation should succeed.
+	}{
+		"valid certificate": {
+			tlsCert: validCert,
+		},
+		"valid chain": {
+			tlsCert: validCert + expiringCert,
+		},
+		"certificate expiring soon": {
+			tlsCert: expiringCert,
+		},
+		"expired certificate in chain": {
+			tlsCert:  validCert + expiredCert,
+			errRegex: "certificate \\(position 1 in chain\\) that expired",
+		},
+		"certificate not yet valid": {
+			tlsCert:  futureCert,
+			errRegex: "is not valid until",
+		},
+		"private key": {
+			tlsCert:  validCert + keyBlock,
+			errRegex: "unexpected PRIVATE KEY PEM block",
+		},
+		"malformed certificate": {
+			tlsCert:  badCertBlock,
+			errRegex: "failed to parse tlsCert",
+		},
+		"trailing data": {
+			tlsCert:  validCert + "garbage",
+			errRegex: "contains data that is not PEM encoded",
+		},
+		"empty": {
+			tlsCert:  "",
+			errRegex: "does not contain a PEM encoded certificate",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateTLSCert(context.Background(), "127.0.0.1", tc.tlsCert)
+			if tc.errRegex == "" && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			} else if tc.errRegex != "" {
+				if err == nil {
+					t.Fatalf("expected error matching %s, got none", tc.errRegex)
+				} else if !regexp.MustCompile(tc.errRegex).MatchString(err.Error()) {
+					t.Fatalf("expected error matching %s, got: %v", tc.errRegex, err)
+				}
+			}
+		})
+	}
+}
+
+// newTestCertPEM returns a PEM encoded self-signed certificate with the provided validity period.
+func newTestCertPEM(t *testing.T, notBefore, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "beegfs-csi-driver-test"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}))
+}
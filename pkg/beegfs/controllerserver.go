@@ -25,8 +25,6 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +34,7 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/mount-utils"
 )
 
@@ -44,6 +43,7 @@ var (
 	controllerCaps = []csi.ControllerServiceCapability_RPC_Type{
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
 	}
 )
 
@@ -57,6 +57,16 @@ type controllerServer struct {
 	volumeIDsInFlight      *threadSafeStringLock
 	volumeStatusMap        *threadSafeStatusMap
 	nodeUnstageTimeout     uint64
+	mountCache             *mountCache
+	volumeDeleter          *volumeDeleter
+	volumeRetainer         *volumeRetainer
+	// driverName, driverVersion, and clusterID are recorded in the metadata of each volume. ListVolumes lists the
+	// volumes under the volDirBasePaths of the StorageClasses of driverName.
+	driverName    string
+	driverVersion string
+	clusterID     string
+	// orphanScanInterval is how often the controller service scans for orphaned volumes (never if it is zero).
+	orphanScanInterval time.Duration
 	csi.UnimplementedControllerServer
 }
 
@@ -66,38 +76,54 @@ func newControllerServer(nodeID string, pluginConfig beegfsv1.PluginConfig, clie
 	if err != nil {
 		return nil, err
 	}
+	mounter := mount.New("")
+	mountCache := newMountCache(path.Join(csDataDir, mountCacheDirName), clientConfTemplatePath, mounter)
+	volumeDeleter := newVolumeDeleter(path.Join(csDataDir, deletionRecordDirName), pluginConfig, mountCache)
+	volumeRetainer := newVolumeRetainer(path.Join(csDataDir, retentionRecordDirName), pluginConfig, mountCache,
+		volumeDeleter)
 	return &controllerServer{
 		ctlExec:                executor,
 		nodeID:                 nodeID,
 		pluginConfig:           pluginConfig,
 		clientConfTemplatePath: clientConfTemplatePath,
 		csDataDir:              csDataDir,
-		mounter:                mount.New(""),
+		mounter:                mounter,
 		volumeIDsInFlight:      newThreadSafeStringLock(),
 		volumeStatusMap:        newThreadSafeStatusMap(),
 		nodeUnstageTimeout:     nodeUnstageTimeout,
+		mountCache:             mountCache,
+		volumeDeleter:          volumeDeleter,
+		volumeRetainer:         volumeRetainer,
 	}, err
 }
 
 func newControllerServerSanity(nodeID string, pluginConfig beegfsv1.PluginConfig, clientConfTemplatePath, csDataDir string,
 	nodeUnstageTimeout uint64) *controllerServer {
+	mounter := mount.NewFakeMounter([]mount.MountPoint{})
+	mountCache := newMountCache(path.Join(csDataDir, mountCacheDirName), clientConfTemplatePath, mounter)
+	volumeDeleter := newVolumeDeleter(path.Join(csDataDir, deletionRecordDirName), pluginConfig, mountCache)
+	volumeRetainer := newVolumeRetainer(path.Join(csDataDir, retentionRecordDirName), pluginConfig, mountCache,
+		volumeDeleter)
 	return &controllerServer{
 		ctlExec:                &fakeBeegfsCtlExecutor{},
 		nodeID:                 nodeID,
 		pluginConfig:           pluginConfig,
 		clientConfTemplatePath: clientConfTemplatePath,
 		csDataDir:              csDataDir,
-		mounter:                mount.NewFakeMounter([]mount.MountPoint{}),
+		mounter:                mounter,
 		volumeIDsInFlight:      newThreadSafeStringLock(),
 		volumeStatusMap:        newThreadSafeStatusMap(),
 		nodeUnstageTimeout:     nodeUnstageTimeout,
+		mountCache:             mountCache,
+		volumeDeleter:          volumeDeleter,
+		volumeRetainer:         volumeRetainer,
 	}
 }
 
 // CreateVolume generates a new volumeID and uses beegfs-ctl to create an associated directory at the proper location
-// on the referenced BeeGFS file system. CreateVolume will not mount the filesystem unless the volume configuration
-// requires the use of special permissions (sticky bit, setuid, setgid), in which case the filesystem will be mounted
-// to properly set those permissions.
+// on the referenced BeeGFS file system. Like every controller service operation, it runs against the controller
+// service's cached mount of the file system, which it also uses to apply special permissions (sticky bit, setuid,
+// setgid) beegfs-ctl cannot handle on its own.
 func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
 	// Check arguments.
 	volName := req.GetName()
@@ -120,11 +146,28 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, newGrpcErrorFromCause(codes.InvalidArgument, err)
 	}
 
-	// Construct an internal representation of the volume.
+	// Construct an internal representation of the volume. The directory of a volume laid out by a volDirTemplate is
+	// only known for sure once BeeGFS is mounted (see resolveTemplatedVolume).
 	vol := cs.newBeegfsVolume(params.sysMgmtdHost, params.volDirBasePathBeegfsRoot, volName)
+	if params.volDirTemplate != "" {
+		volDirPathRel, err := beegfsv1.ExpandVolDirTemplate(params.volDirTemplate, params.volDirTemplateValues())
+		if err != nil {
+			return nil, newGrpcErrorFromCause(codes.InvalidArgument, errors.WithStack(err))
+		}
+		vol = cs.newBeegfsVolume(params.sysMgmtdHost, path.Join(params.volDirBasePathBeegfsRoot,
+			path.Dir(volDirPathRel)), path.Base(volDirPathRel))
+	}
+	if err := checkVolDirPathPermitted(vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, cs.pluginConfig); err != nil {
+		return nil, newGrpcErrorFromCause(codes.PermissionDenied, err)
+	}
+	if err := overrideConfigWithSecrets(ctx, vol.sysMgmtdHost, &vol.config, req.GetSecrets()); err != nil {
+		return nil, newGrpcErrorFromCause(codes.InvalidArgument, err)
+	}
 
-	// Return success if we don't need to do anything.
-	if status, ok := cs.volumeStatusMap.readStatus(vol.volumeID); ok && status == statusCreated {
+	// Return success if we don't need to do anything. The volumeID of a templated volume is not derived from its
+	// name, so it is never in the volumeStatusMap.
+	if status, ok := cs.volumeStatusMap.readStatus(vol.volumeID); ok && status == statusCreated &&
+		params.volDirTemplate == "" {
 		return &csi.CreateVolumeResponse{
 			Volume: &csi.Volume{
 				VolumeId: vol.volumeID,
@@ -139,24 +182,71 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	}
 	defer cs.volumeIDsInFlight.releaseLockOnString(vol.volumeID)
 
-	// Write configuration files but do not mount BeeGFS.
-	defer func() {
-		// Failure to clean up is an internal problem. The CO only cares whether or not we created the volume.
-		// If CreateVolume exits with an error, the deferred cleanup will pass on the error regardless of the outcome
-		// of the cleanup.
-		// In theory an error unmounting the filesystem could result in an orphaned mount on the host. We don't think
-		// returning an error is appropriate if the mount failed to clean if the volume was successfully created.
-		// We may consider alternate behavior if we observe cases where orphaned mounts occur and are believed
-		// to cause a problem. We could retry the unmount, but until then we will leave the behavior as is.
-		if err := unmountAndCleanUpIfNecessary(ctx, vol, true, cs.mounter); err != nil {
-			LogError(ctx, err, "Failed to clean up path for volume", "path", vol.mountDirPath, "volumeID", vol.volumeID)
-		}
-	}()
-	if err := fs.MkdirAll(vol.mountDirPath, 0750); err != nil {
-		err = errors.WithStack(err)
-		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	// Mount BeeGFS (or reuse the existing mount).
+	vol, release, err := cs.mountCache.acquire(ctx, vol)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Find the directory of a templated volume. The template may render the same directory for different volumes
+	// (e.g. for a PVC that is recreated while the volume of the previous one is retained).
+	if params.volDirTemplate != "" {
+		if vol, err = resolveTemplatedVolume(ctx, vol, volName, params.volDirBasePathBeegfsRoot); err != nil {
+			return nil, err
+		}
+		if err := checkVolDirPathPermitted(vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, cs.pluginConfig); err != nil {
+			return nil, newGrpcErrorFromCause(codes.PermissionDenied, err)
+		}
+	}
+
+	// Create missing parent directories with the basePath/ parameters. Otherwise, beegfs-ctl creates them with the
+	// volume's permissions.
+	if params.parentDirConfig != nil {
+		if err := cs.createParentDirectories(ctx, vol, *params.parentDirConfig); err != nil {
+			return nil, newGrpcErrorFromCause(codes.Internal, err)
+		}
 	}
-	if err := writeClientFiles(ctx, vol, cs.clientConfTemplatePath); err != nil {
+
+	// The volumeMetadata lives in volDirBasePath, so create it first (the way beegfs-ctl would create it with the
+	// volume's directory).
+	if exists, err := fsutil.DirExists(vol.volDirBasePath); err != nil {
+		return nil, newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	} else if !exists {
+		err := cs.ctlExec.createDirectoryForVolume(ctx, vol, vol.volDirBasePathBeegfsRoot, params.volPermissionsConfig)
+		if err != nil {
+			return nil, newGrpcErrorFromCause(codes.Internal, err)
+		}
+	}
+
+	// Record what later requests need to know about the volume (e.g. for how long DeleteVolume retains it) and what
+	// BeeGFS administrators need to know about it (e.g. which cluster and PVC it belongs to), and mark the volume's
+	// directory as ours so DeleteVolume may delete it. Keep the CreationToken and CreationTime of a previous attempt.
+	// Do this before we create the directory, so a retry of a request that fails in between still recognizes it.
+	creationToken := newCreationToken()
+	creationTime := time.Now().UTC().Format(time.RFC3339)
+	if existing, err := readVolumeMetadata(vol); err == nil && existing.ownsVolume(vol.volumeID) {
+		creationToken = existing.CreationToken
+		if existing.CreationTime != "" {
+			creationTime = existing.CreationTime
+		}
+	}
+	metadata := volumeMetadata{
+		VolumeID:         vol.volumeID,
+		CreationToken:    creationToken,
+		Name:             volName,
+		ClusterID:        cs.clusterID,
+		DriverName:       cs.driverName,
+		DriverVersion:    cs.driverVersion,
+		PVCNamespace:     params.pvcNamespace,
+		PVCName:          params.pvcName,
+		PVName:           params.pvName,
+		StorageClassName: lookUpStorageClassName(ctx, params.pvcNamespace, params.pvcName),
+		CreationTime:     creationTime,
+		CapacityBytes:    req.GetCapacityRange().GetRequiredBytes(),
+		RetentionPeriod:  metav1.Duration{Duration: params.retentionPeriod},
+	}
+	if err := writeVolumeMetadata(vol, metadata); err != nil {
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
 
@@ -168,13 +258,10 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
 
-	// Mount BeeGFS and use OS tools to change the access mode only if beegfs-ctl could not handle the access mode
-	// on its own. beegfs-ctl cannot handle access modes with special permissions (e.g. the set gid bit). These are
-	// governed by the first three bits of a 12 bit access mode (i.e. the first digit in four digit octal notation).
+	// Use OS tools to change the access mode only if beegfs-ctl could not handle the access mode on its own.
+	// beegfs-ctl cannot handle access modes with special permissions (e.g. the set gid bit). These are governed by the
+	// first three bits of a 12 bit access mode (i.e. the first digit in four digit octal notation).
 	if params.volPermissionsConfig.hasSpecialPermissions() {
-		if err := mountIfNecessary(ctx, vol, []string{}, cs.mounter); err != nil {
-			return nil, newGrpcErrorFromCause(codes.Internal, err)
-		}
 		LogDebug(ctx, "Applying permissions", "permissions", fmt.Sprintf("%4o", params.volPermissionsConfig.mode),
 			"volDirPath", vol.volDirPath, "volumeID", vol.volumeID)
 		if err := os.Chmod(vol.volDirPath, params.volPermissionsConfig.goFileMode()); err != nil {
@@ -182,8 +269,7 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 	}
 
-	// Use beegfs-ctl to create the directory we will use to track the nodes that mount this volume. Don't mount and
-	// use mkdir in case there is no other need to mount.
+	// Use beegfs-ctl to create the directory we will use to track the nodes that mount this volume.
 	if cs.nodeUnstageTimeout > 0 {
 		nodesDirPath := path.Join(vol.csiDirPathBeegfsRoot, "nodes")
 		nodesDirPermissions := permissionsConfig{mode: 0750}
@@ -195,6 +281,12 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		LogVerbose(ctx, "Node tracking not enabled", "volumeID", vol.volumeID)
 	}
 
+	if setsVolumeMetadataXAttrs(vol.sysMgmtdHost, cs.pluginConfig) {
+		if err := setVolumeMetadataXAttrs(vol, metadata); err != nil {
+			return nil, newGrpcErrorFromCause(codes.Internal, err)
+		}
+	}
+
 	// Update status and return.
 	cs.volumeStatusMap.writeStatus(vol.volumeID, statusCreated)
 	return &csi.CreateVolumeResponse{
@@ -205,8 +297,11 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 }
 
 // DeleteVolume deletes the directory referenced in the volumeID from the BeeGFS file system referenced in the
-// volumeID.
-func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (resp *csi.DeleteVolumeResponse, err error) {
+// volumeID if CreateVolume marked it as created by the driver (or the file system's configuration allows deleting
+// unmarked volumes). It moves the directory into a trash directory and leaves removing its contents to the
+// volumeDeleter (or, if the volume's StorageClass set a retentionPeriod, into a retention directory for the
+// volumeRetainer).
+func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	// Check arguments.
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
@@ -219,6 +314,12 @@ func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 		LogError(ctx, err, "Beegfs volume not found for deletion", "volumeID", volumeID)
 		return &csi.DeleteVolumeResponse{}, nil
 	}
+	if err := checkVolDirPathPermitted(vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, cs.pluginConfig); err != nil {
+		return nil, newGrpcErrorFromCause(codes.PermissionDenied, err)
+	}
+	if err := overrideConfigWithSecrets(ctx, vol.sysMgmtdHost, &vol.config, req.GetSecrets()); err != nil {
+		return nil, newGrpcErrorFromCause(codes.InvalidArgument, err)
+	}
 
 	// Return success if we don't need to do anything.
 	if status, ok := cs.volumeStatusMap.readStatus(vol.volumeID); ok && status == statusDeleted {
@@ -232,46 +333,68 @@ func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 	}
 	defer cs.volumeIDsInFlight.releaseLockOnString(vol.volumeID)
 
-	// Prepare to clean up.
-	defer func() {
-		// Clean up no matter what and return an error if cleanup fails. Ignoring cleanup failure might lead to silent
-		// orphaned mounts. One occasional cause of cleanup failure is a mount reporting "busy" on attempted unmount
-		// immediately after a directory or file deletion. Another DeleteVolume call resolves the issue.
-		if cleanupErr := unmountAndCleanUpIfNecessary(ctx, vol, true, cs.mounter); cleanupErr != nil {
-			// err and resp are named values that were being returned by DeleteVolume. We can modify them here to
-			// return something different.
-			resp = nil
-			if err != nil {
-				// Instead of overwriting the returned GrpcError, let's just log a separate error here.
-				LogError(ctx, err, "Failed to clean up path for volume", "path", vol.mountDirPath, "volumeID", vol.volumeID)
-			} else {
-				err = newGrpcErrorFromCause(codes.Internal, cleanupErr)
-			}
-		}
-		if err == nil {
-			// Only record success if both deletion and cleanup are successful. This allows a subsequent DeleteVolume
-			// request to attempt a failed cleanup again.
-			cs.volumeStatusMap.writeStatus(vol.volumeID, statusDeleted)
-		}
-	}()
-
-	// Write configuration files and mount BeeGFS.
-	if err = fs.MkdirAll(vol.mountDirPath, 0750); err != nil {
-		err = errors.WithStack(err)
-		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	// Mount BeeGFS (or reuse the existing mount).
+	vol, release, err := cs.mountCache.acquire(ctx, vol)
+	if err != nil {
+		return nil, err
 	}
-	if err = writeClientFiles(ctx, vol, cs.clientConfTemplatePath); err != nil {
+	defer release()
+
+	// Read the volume's metadata before deleting it with the rest of the volume's CSI metadata directory. Refuse to
+	// delete a directory we did not create (e.g. one a statically provisioned volume with a Delete reclaim policy
+	// points at). We only delete the CSI metadata directory after the volume's directory is gone, so a retry of a
+	// request that failed part way through still finds the metadata.
+	metadata, err := readVolumeMetadata(vol)
+	if err != nil && !os.IsNotExist(err) {
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
-	if err = mountIfNecessary(ctx, vol, []string{}, cs.mounter); err != nil {
-		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	if !metadata.ownsVolume(vol.volumeID) {
+		if exists, err := fsutil.DirExists(vol.volDirPath); err != nil {
+			return nil, newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+		} else if !exists {
+			// A previous DeleteVolume request may have deleted both the volume's directory and its CSI metadata
+			// directory. Either way, there is nothing left to delete.
+			LogDebug(ctx, "BeeGFS directory already deleted", "path", vol.volDirPathBeegfsRoot,
+				"volumeID", vol.volumeID)
+			cs.volumeStatusMap.writeStatus(vol.volumeID, statusDeleted)
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+		if !allowsUnmarkedVolumeDeletion(vol.sysMgmtdHost, cs.pluginConfig) {
+			return nil, status.Errorf(codes.FailedPrecondition, "refusing to delete %s: it has no ownership "+
+				"marker at %s, so the driver did not create it; set allowUnmarkedVolumeDeletion in the "+
+				"fileSystemSpecificConfig for %s to delete volumes created by older versions of the driver",
+				vol.volumeID, path.Join(vol.csiDirPathBeegfsRoot, volumeMetadataFileName), vol.sysMgmtdHost)
+		}
+		LogDebug(ctx, "Deleting volume without ownership marker", "volumeID", vol.volumeID)
 	}
 
-	// Delete volume from mounted BeeGFS.
-	if err = deleteVolumeUntilWait(ctx, vol, cs.nodeUnstageTimeout); err != nil {
+	// Delete (or retain) volume from mounted BeeGFS.
+	// Record a moved volume directory even if we failed to delete the CSI metadata directory afterwards.
+	if metadata.RetentionPeriod.Duration > 0 {
+		var retainedVolumeID string
+		retainedVolumeID, err = retainVolumeUntilWait(ctx, vol, metadata, cs.nodeUnstageTimeout)
+		if retainedVolumeID != "" {
+			cs.volumeRetainer.add(ctx, retainedVolumeID)
+		}
+	} else {
+		var trashPathBeegfsRoot string
+		trashPathBeegfsRoot, err = deleteVolumeUntilWait(ctx, vol, cs.nodeUnstageTimeout)
+		if trashPathBeegfsRoot != "" {
+			cs.volumeDeleter.add(ctx, newTrashVolume(vol.sysMgmtdHost, trashPathBeegfsRoot, vol.config))
+		}
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, newGrpcErrorFromCause(status.FromContextError(ctxErr).Code(), err)
+		}
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
+	// Pick up anything left in the trash by a controller service that couldn't finish and any retained volumes a
+	// previous controller service recorded elsewhere.
+	cs.volumeDeleter.addTrash(ctx, vol)
+	cs.volumeRetainer.addRetained(ctx, vol)
 
+	cs.volumeStatusMap.writeStatus(vol.volumeID, statusDeleted)
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
@@ -305,27 +428,24 @@ func (cs *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req
 		err = errors.WithMessage(err, "volume ID is invalid or the volume does not exist")
 		return nil, newGrpcErrorFromCause(codes.NotFound, err)
 	}
+	if err := checkVolDirPathPermitted(vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, cs.pluginConfig); err != nil {
+		return nil, newGrpcErrorFromCause(codes.PermissionDenied, err)
+	}
+	if err := overrideConfigWithSecrets(ctx, vol.sysMgmtdHost, &vol.config, req.GetSecrets()); err != nil {
+		return nil, newGrpcErrorFromCause(codes.InvalidArgument, err)
+	}
 	if !cs.volumeIDsInFlight.obtainLockOnString(vol.volumeID) {
 		return nil, status.Errorf(codes.Aborted, "volumeID %s is in use by another request; check BeeGFS network "+
 			"configuration if this problem persists", vol.volumeID)
 	}
 	defer cs.volumeIDsInFlight.releaseLockOnString(vol.volumeID)
 
-	// Write configuration files but do not mount BeeGFS.
-	defer func() {
-		// Failure to clean up is an internal problem. The CO only cares whether or not the volume exists.
-		// Cleanup failures may leave directories and/or files but no orphaned mounts because no mounts are involved.
-		if err := cleanUpIfNecessary(ctx, vol, true); err != nil {
-			LogError(ctx, err, "Failed to clean up path for volume", "path", vol.mountDirPath, "volumeID", vol.volumeID)
-		}
-	}()
-	if err := fs.MkdirAll(vol.mountDirPath, 0750); err != nil {
-		err = errors.WithStack(err)
-		return nil, newGrpcErrorFromCause(codes.Internal, err)
-	}
-	if err := writeClientFiles(ctx, vol, cs.clientConfTemplatePath); err != nil {
-		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	// Mount BeeGFS (or reuse the existing mount).
+	vol, release, err := cs.mountCache.acquire(ctx, vol)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	if _, err := cs.ctlExec.statDirectoryForVolume(ctx, vol, vol.volDirPathBeegfsRoot); err != nil {
 		if errors.As(err, &ctlNotExistError{}) {
@@ -363,8 +483,42 @@ func (cs *controllerServer) GetCapacity(ctx context.Context, req *csi.GetCapacit
 	return nil, status.Error(codes.Unimplemented, "")
 }
 
+// ListVolumes lists the volumes listVolumeMetadata finds in the order of their volumeIDs. The volume context of each
+// entry contains the volume's metadata (e.g. the PVC it was created for). A next token is the volumeID of the last
+// volume listed so a volume deleted between requests does not cause others to be skipped.
 func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	startingToken := req.GetStartingToken()
+	if startingToken != "" {
+		if _, _, err := parseBeegfsURL(startingToken); err != nil {
+			return nil, status.Errorf(codes.Aborted, "invalid starting token %s", startingToken)
+		}
+	}
+	if req.GetMaxEntries() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid max entries %d", req.GetMaxEntries())
+	}
+
+	volumes, err := cs.listVolumeMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &csi.ListVolumesResponse{}
+	for _, metadata := range volumes {
+		if startingToken != "" && metadata.VolumeID <= startingToken {
+			continue
+		}
+		if req.GetMaxEntries() > 0 && len(resp.Entries) == int(req.GetMaxEntries()) {
+			resp.NextToken = resp.Entries[len(resp.Entries)-1].GetVolume().GetVolumeId()
+			break
+		}
+		resp.Entries = append(resp.Entries, &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      metadata.VolumeID,
+				CapacityBytes: metadata.CapacityBytes,
+				VolumeContext: metadata.attributes(),
+			},
+		})
+	}
+	return resp, nil
 }
 
 func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
@@ -427,41 +581,19 @@ func getStripePatternConfigFromParams(reqParams map[string]string) (stripePatter
 	cfg := stripePatternConfig{}
 	for param := range reqParams {
 		if strings.Contains(param, "stripePattern/") {
+			// The rules are shared with the operator, which validates the StorageClasses it creates.
+			if err := beegfsv1.ValidateStripePatternParameter(param, reqParams[param]); err != nil {
+				return cfg, nil, errors.WithStack(err)
+			}
 			switch param {
 			case stripePatternStoragePoolIDKey:
 				cfg.storagePoolID = reqParams[stripePatternStoragePoolIDKey]
-				// Validate storagePoolID is an integer only.
-				if cfg.storagePoolID != "" {
-					_, err := strconv.ParseUint(cfg.storagePoolID, 10, 16)
-					if err != nil {
-						return cfg, nil, errors.Wrap(err, "could not parse provided StoragePoolID")
-					}
-				}
-				delete(reqParams, stripePatternStoragePoolIDKey)
 			case stripePatternChunkSizeKey:
 				cfg.stripePatternChunkSize = reqParams[stripePatternChunkSizeKey]
-				// Validate StripePatternChunkSize has only digits followed by a single upper or lowercase letter.
-				if cfg.stripePatternChunkSize != "" {
-					r := regexp.MustCompile("(^[0-9]+[a-zA-Z]$)")
-					matched := r.MatchString(cfg.stripePatternChunkSize)
-					if !matched {
-						return cfg, nil, errors.New("could not parse provided chunkSize")
-					}
-				}
-				delete(reqParams, stripePatternChunkSizeKey)
 			case stripePatternNumTargetsKey:
 				cfg.stripePatternNumTargets = reqParams[stripePatternNumTargetsKey]
-				// Validate stripePatternNumTargets value is an integer.
-				if cfg.stripePatternNumTargets != "" {
-					_, err := strconv.ParseUint(cfg.stripePatternNumTargets, 10, 16)
-					if err != nil {
-						return cfg, nil, errors.Wrap(err, "could not parse provided numTargets")
-					}
-				}
-				delete(reqParams, stripePatternNumTargetsKey)
-			default:
-				return cfg, nil, errors.Errorf("CreateVolume parameter invalid: %s", param)
 			}
+			delete(reqParams, param)
 		}
 	}
 	return cfg, reqParams, nil
@@ -475,39 +607,96 @@ func getPermissionsConfigFromParams(reqParams map[string]string) (permissionsCon
 	cfg := permissionsConfig{mode: defaultPermissionsMode}
 	for param := range reqParams {
 		if strings.HasPrefix(param, "permissions/") {
+			// The rules are shared with the operator, which validates the StorageClasses it creates.
+			val, err := beegfsv1.ParsePermissionsParameter(param, reqParams[param])
+			if err != nil {
+				return cfg, nil, errors.WithStack(err)
+			}
 			switch param {
 			case permissionsUIDKey:
-				val := reqParams[permissionsUIDKey]
-				uid, err := strconv.ParseUint(val, 10, 32) // UIDs are <= 32 bits.
-				if err != nil {
-					return cfg, nil, errors.Wrap(err, "could not parse provided UID")
-				}
-				cfg.uid = uint32(uid)
-				delete(reqParams, permissionsUIDKey)
+				cfg.uid = uint32(val)
 			case permissionsGIDKey:
-				val := reqParams[permissionsGIDKey]
-				gid, err := strconv.ParseUint(val, 10, 32) // GIDs are <= 32 bits.
-				if err != nil {
-					return cfg, nil, errors.Wrap(err, "could not parse provided GID")
-				}
-				cfg.gid = uint32(gid)
-				delete(reqParams, permissionsGIDKey)
+				cfg.gid = uint32(val)
 			case permissionsModeKey:
-				val := reqParams[permissionsModeKey]
-				mode, err := strconv.ParseUint(val, 8, 12) // Full modes are 12 bits.
-				if err != nil {
-					return cfg, nil, errors.Wrap(err, "could not parse provided mode")
-				}
-				cfg.mode = uint16(mode)
-				delete(reqParams, permissionsModeKey)
-			default:
-				return cfg, nil, errors.Errorf("CreateVolume parameter invalid: %s", param)
+				cfg.mode = uint16(val)
 			}
+			delete(reqParams, param)
 		}
 	}
 	return cfg, reqParams, nil
 }
 
+// getParentDirConfigFromParams parses a map of parameters and sets parentDirConfig variables from the basePath/
+// parameters, if provided. The basePath/ parameters are deleted from the original map, which is returned.
+// getParentDirConfigFromParams returns a nil parentDirConfig if there are no basePath/ parameters. Otherwise, the
+// permissions of the parent directories default to those of volumes without permissions/ parameters.
+func getParentDirConfigFromParams(reqParams map[string]string) (*parentDirConfig, map[string]string, error) {
+	parentParams := make(map[string]string)
+	for param := range reqParams {
+		if strings.HasPrefix(param, basePathParamPrefix) {
+			// The rules are shared with the operator, which validates the StorageClasses it creates.
+			if err := beegfsv1.ValidateBasePathParameter(param, reqParams[param]); err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+			parentParams[strings.TrimPrefix(param, basePathParamPrefix)] = reqParams[param]
+			delete(reqParams, param)
+		}
+	}
+	if len(parentParams) == 0 {
+		return nil, reqParams, nil
+	}
+	stripePatternConfig, parentParams, err := getStripePatternConfigFromParams(parentParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	permissionsConfig, _, err := getPermissionsConfigFromParams(parentParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &parentDirConfig{stripePatternConfig: stripePatternConfig, permissionsConfig: permissionsConfig}, reqParams,
+		nil
+}
+
+// createParentDirectories creates each parent directory of vol's directory that does not exist (on a mounted file
+// system) with the permissions and stripe pattern of cfg. Existing directories (including those a concurrent request
+// creates first) are never changed.
+func (cs *controllerServer) createParentDirectories(ctx context.Context, vol beegfsVolume, cfg parentDirConfig) error {
+	var dirsToMake []string
+	for dir := path.Dir(vol.volDirPathBeegfsRoot); dir != "/"; dir = path.Dir(dir) {
+		exists, err := fsutil.DirExists(path.Join(vol.mountPath, dir))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if exists {
+			break // Every directory above an existing one exists too.
+		}
+		dirsToMake = append([]string{dir}, dirsToMake...) // Prepend so the more general path comes first.
+	}
+	for _, dir := range dirsToMake {
+		if err := cs.ctlExec.createDirectory(ctx, vol, dir, cfg.permissionsConfig); errors.As(err, &ctlExistError{}) {
+			LogDebug(ctx, "BeeGFS directory already exists", "path", dir, "volumeID", vol.volumeID)
+			continue
+		} else if err != nil {
+			return err
+		}
+		// setPatternForVolume sets the pattern of a volume's directory, so describe the parent directory as one.
+		parentVol := newBeegfsVolume(vol.mountDirPath, vol.sysMgmtdHost, dir, cs.pluginConfig)
+		parentVol.config = vol.config
+		if err := cs.ctlExec.setPatternForVolume(ctx, parentVol, cfg.stripePatternConfig); err != nil {
+			return err
+		}
+		// As for volumes, beegfs-ctl cannot handle access modes with special permissions.
+		if cfg.permissionsConfig.hasSpecialPermissions() {
+			LogDebug(ctx, "Applying permissions", "permissions", fmt.Sprintf("%4o", cfg.permissionsConfig.mode),
+				"path", dir, "volumeID", vol.volumeID)
+			if err := os.Chmod(parentVol.volDirPath, cfg.permissionsConfig.goFileMode()); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
 // (*controllerServer) newBeegfsVolume is a wrapper around newBeegfsVolume that makes it easier to call in the context
 // of the controller service. (*controllerServer) newBeegfsVolume selects the mountDirPath and passes the controller
 // service's PluginConfig.
@@ -520,6 +709,58 @@ func (cs *controllerServer) newBeegfsVolume(sysMgmtdHost, volDirBasePathBeegfsRo
 	return newBeegfsVolume(mountDirPath, sysMgmtdHost, volDirPathBeegfsRoot, cs.pluginConfig)
 }
 
+// resolveTemplatedVolume returns the volume CreateVolume should create for the volume named volName when a
+// volDirTemplate rendered vol (on a mounted file system). It tries vol's directory and then the fallback directory
+// <vol's directory>-<volName>. If one of them already belongs to volName (i.e. a previous CreateVolume request for
+// volName created it), it returns that volume so retried requests are idempotent. Otherwise, it returns the first
+// that does not exist. A directory that belongs to another volume (or that the driver did not create) is never used.
+func resolveTemplatedVolume(ctx context.Context, vol beegfsVolume, volName, volDirBasePathBeegfsRoot string) (
+	beegfsVolume, error) {
+	newCandidate := func(volDirPathBeegfsRoot string) beegfsVolume {
+		candidate := newBeegfsVolume(vol.mountDirPath, vol.sysMgmtdHost, volDirPathBeegfsRoot, beegfsv1.PluginConfig{})
+		candidate.config = vol.config
+		return candidate
+	}
+
+	// Templates of different StorageClasses with the same volDirBasePath may render paths inside each other's
+	// volumes. Don't create a volume inside another volume.
+	for dirPath := vol.volDirBasePathBeegfsRoot; dirPath != volDirBasePathBeegfsRoot && dirPath != "/"; dirPath =
+		path.Dir(dirPath) {
+		if _, err := readVolumeMetadata(newCandidate(dirPath)); err == nil {
+			return beegfsVolume{}, status.Errorf(codes.AlreadyExists, "volume directory %s would be inside the "+
+				"volume directory %s", vol.volDirPathBeegfsRoot, dirPath)
+		} else if !os.IsNotExist(err) {
+			return beegfsVolume{}, newGrpcErrorFromCause(codes.Internal, err)
+		}
+	}
+
+	candidates := []beegfsVolume{newCandidate(vol.volDirPathBeegfsRoot),
+		newCandidate(vol.volDirPathBeegfsRoot + "-" + volName)}
+	for _, candidate := range candidates {
+		metadata, err := readVolumeMetadata(candidate)
+		if err == nil {
+			if metadata.ownsVolume(candidate.volumeID) && metadata.Name == volName {
+				return candidate, nil
+			}
+			LogDebug(ctx, "Volume directory belongs to another volume", "path", candidate.volDirPathBeegfsRoot,
+				"owner", metadata.Name, "volumeName", volName)
+			continue
+		} else if !os.IsNotExist(err) {
+			return beegfsVolume{}, newGrpcErrorFromCause(codes.Internal, err)
+		}
+		if exists, err := fsutil.Exists(candidate.volDirPath); err != nil {
+			return beegfsVolume{}, newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+		} else if exists {
+			LogDebug(ctx, "Volume directory exists without ownership marker", "path",
+				candidate.volDirPathBeegfsRoot, "volumeName", volName)
+			continue
+		}
+		return candidate, nil
+	}
+	return beegfsVolume{}, status.Errorf(codes.AlreadyExists, "volume directories %s and %s already exist and do "+
+		"not belong to volume %s", candidates[0].volDirPathBeegfsRoot, candidates[1].volDirPathBeegfsRoot, volName)
+}
+
 // (*controllerServer) newBeegfsVolumeFromID is a wrapper around newBeegfsVolumeFromID that makes it easier to call in
 // the context of the controller service. (*controllerServer) newBeegfsVolumeFromID selects the mountDirPath and passes
 // the controller service's PluginConfig.
@@ -528,7 +769,42 @@ func (cs *controllerServer) newBeegfsVolumeFromID(volumeID string) (beegfsVolume
 	return newBeegfsVolumeFromID(mountDirPath, volumeID, cs.pluginConfig)
 }
 
-func deleteVolumeUntilWait(ctx context.Context, vol beegfsVolume, waitTime uint64) error {
+// deleteVolumeUntilWait waits up to waitTime seconds (or until ctx is done) for all nodes to unstage vol, moves its
+// directory into the trash, and deletes its CSI metadata directory. It returns the path of the volume's directory in
+// the trash from the BeeGFS root (or an empty string if the directory no longer exists), even if it then fails to
+// delete the CSI metadata directory.
+func deleteVolumeUntilWait(ctx context.Context, vol beegfsVolume, waitTime uint64) (string, error) {
+	if err := waitForUnstageUntilWait(ctx, vol, waitTime); err != nil {
+		return "", err
+	}
+	// Now it's time to delete the volume itself. Removing a large directory can take hours, so move it into the trash
+	// and let the volumeDeleter remove it in the background.
+	trashPathBeegfsRoot, err := moveVolumeToTrash(ctx, vol)
+	if err != nil {
+		return "", err
+	}
+	return trashPathBeegfsRoot, removeCSIDir(ctx, vol)
+}
+
+// retainVolumeUntilWait waits up to waitTime seconds (or until ctx is done) for all nodes to unstage vol, moves its
+// directory into a new retention directory (see retainVolume), and deletes its CSI metadata directory. It returns a
+// volumeID that refers to the new retention directory (or an empty string if the volume's directory no longer
+// exists), even if it then fails to delete the CSI metadata directory.
+func retainVolumeUntilWait(ctx context.Context, vol beegfsVolume, metadata volumeMetadata, waitTime uint64) (string,
+	error) {
+	if err := waitForUnstageUntilWait(ctx, vol, waitTime); err != nil {
+		return "", err
+	}
+	retainedVolumeID, err := retainVolume(ctx, vol, metadata)
+	if err != nil {
+		return "", err
+	}
+	return retainedVolumeID, removeCSIDir(ctx, vol)
+}
+
+// waitForUnstageUntilWait waits up to waitTime seconds (or until ctx is done) for all nodes to unstage vol. It returns
+// an error only if it cannot read vol's node tracking information or ctx is done.
+func waitForUnstageUntilWait(ctx context.Context, vol beegfsVolume, waitTime uint64) error {
 	start := time.Now()
 	nodesPath := path.Join(vol.csiDirPath, "nodes")
 	for {
@@ -547,7 +823,11 @@ func deleteVolumeUntilWait(ctx context.Context, vol beegfsVolume, waitTime uint6
 					secondsLeft := int64((time.Duration(waitTime)*time.Second - time.Since(start)).Seconds())
 					LogVerbose(ctx, "Waiting for volume to unstage from all nodes",
 						"secondsLeft", secondsLeft, "volumeID", vol.volumeID)
-					time.Sleep(time.Duration(2) * time.Second)
+					select {
+					case <-ctx.Done():
+						return errors.WithMessage(ctx.Err(), "stopped waiting for volume to unstage from all nodes")
+					case <-time.After(time.Duration(2) * time.Second):
+					}
 					continue // Wait for the next loop to do anything else.
 				} else {
 					// The .csi/volumes/volume/nodes directory is not empty, but we're no longer willing to wait.
@@ -561,22 +841,23 @@ func deleteVolumeUntilWait(ctx context.Context, vol beegfsVolume, waitTime uint6
 						"remainingNodes", remainingNodeNames, "volumeID", vol.volumeID)
 				}
 			}
-			// Whether the .csi/volumes/volume/nodes/ directory is empty or we're done waiting, we should delete it.
-			LogDebug(ctx, "Deleting BeeGFS directory", "path", vol.csiDirPathBeegfsRoot, "volumeID", vol.volumeID)
-			if err = fsutil.RemoveAll(vol.csiDirPath); err != nil {
-				return errors.WithStack(err)
-			}
-			break // Go on to delete the volume.
+			// Whether the .csi/volumes/volume/nodes/ directory is empty or we're done waiting, we can delete the volume.
+			break
 		} else {
 			// It's fine if the .csi/volumes/volume/nodes directory does not exist. It was likely never created in the
 			// first place. We'll just fall back to naive deletion behavior.
 			LogVerbose(ctx, "No node tracking information found", "path", vol.csiDirPathBeegfsRoot, "volumeID", vol.volumeID)
-			break // Go on to delete the volume.
+			break
 		}
 	}
-	// Now it's time to delete the volume itself.
-	LogDebug(ctx, "Deleting BeeGFS directory", "path", vol.volDirPathBeegfsRoot, "volumeID", vol.volumeID)
-	if err := fs.RemoveAll(vol.volDirPath); err != nil {
+	return nil
+}
+
+// removeCSIDir deletes vol's CSI metadata directory (including its node tracking information and volumeMetadata).
+func removeCSIDir(ctx context.Context, vol beegfsVolume) error {
+	// The CSI metadata directory may exist even without node tracking information (e.g. to hold volumeMetadata).
+	LogDebug(ctx, "Deleting BeeGFS directory", "path", vol.csiDirPathBeegfsRoot, "volumeID", vol.volumeID)
+	if err := fsutil.RemoveAll(vol.csiDirPath); err != nil {
 		return errors.WithStack(err)
 	}
 	return nil
@@ -603,6 +884,14 @@ func validateReqParams(params map[string]string) (reqParams reqParameters, err e
 	reqParams.volDirBasePathBeegfsRoot = path.Clean(path.Join("/", volDirBasePathBeegfsRoot))
 	delete(params, volDirBasePathKey)
 
+	// Parse basePath/ parameters first, as getStripePatternConfigFromParams would mistake basePath/stripePattern/ ones
+	// for its own.
+	parentDirConfig, params, err := getParentDirConfigFromParams(params)
+	if err != nil {
+		return reqParameters{}, err
+	}
+	reqParams.parentDirConfig = parentDirConfig
+
 	stripePatternConfig, params, err := getStripePatternConfigFromParams(params)
 	if err != nil {
 		return reqParameters{}, err
@@ -615,6 +904,31 @@ func validateReqParams(params map[string]string) (reqParams reqParameters, err e
 	}
 	reqParams.volPermissionsConfig = volPermissionsConfig
 
+	if retentionPeriod, ok := params[retentionPeriodKey]; ok {
+		if reqParams.retentionPeriod, err = beegfsv1.ParseRetentionPeriodParameter(retentionPeriod); err != nil {
+			return reqParameters{}, errors.WithStack(err)
+		}
+		delete(params, retentionPeriodKey)
+	}
+
+	// ValidateVolumeCapabilities requests do not include PVC and PV metadata, so only check that the template is
+	// valid here. CreateVolume expands it.
+	if volDirTemplate, ok := params[volDirTemplateKey]; ok {
+		if err := beegfsv1.ValidateVolDirTemplateParameter(volDirTemplate); err != nil {
+			return reqParameters{}, errors.WithStack(err)
+		}
+		reqParams.volDirTemplate = volDirTemplate
+		delete(params, volDirTemplateKey)
+	}
+
+	// The external-provisioner only passes PVC and PV metadata if it runs with --extra-create-metadata.
+	reqParams.pvcName = params[pvcNameKey]
+	reqParams.pvcNamespace = params[pvcNamespaceKey]
+	reqParams.pvName = params[pvNameKey]
+	delete(params, pvcNameKey)
+	delete(params, pvcNamespaceKey)
+	delete(params, pvNameKey)
+
 	// If extra parameters remain in params, return error and the parameters that remain.
 	if len(params) != 0 {
 		return reqParameters{}, errors.Errorf("CreateVolume parameter invalid: %s", params)
@@ -7,13 +7,18 @@ package beegfs
 
 import (
 	"context"
+	"os"
 	"path"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	v1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestGetStripePatternConfigFromParams(t *testing.T) {
@@ -263,7 +268,7 @@ func TestDeleteVolumeUntilWaitEmptyNodesDir(t *testing.T) {
 		t.Fatal("error in setup")
 	}
 
-	if err := deleteVolumeUntilWait(context.TODO(), vol, 0); err != nil {
+	if _, err := deleteVolumeUntilWait(context.TODO(), vol, 0); err != nil {
 		t.Fatal("expected no error deleting volume")
 	}
 	if _, err := fs.Stat(vol.csiDirPath); err == nil {
@@ -282,7 +287,7 @@ func TestDeleteVolumeUntilWaitNoCSIDir(t *testing.T) {
 		t.Fatal("error in setup")
 	}
 
-	if err := deleteVolumeUntilWait(context.TODO(), vol, 0); err != nil {
+	if _, err := deleteVolumeUntilWait(context.TODO(), vol, 0); err != nil {
 		t.Fatal("expected no error deleting volume")
 	}
 	if _, err := fs.Stat(vol.volDirPath); err == nil {
@@ -302,7 +307,7 @@ func TestDeleteVolumeUntilWaitNodesDirNeverEmpties(t *testing.T) {
 		t.Fatal("error in setup")
 	}
 
-	if err := deleteVolumeUntilWait(context.TODO(), vol, 0); err != nil {
+	if _, err := deleteVolumeUntilWait(context.TODO(), vol, 0); err != nil {
 		t.Fatal("expected no error deleting volume")
 	}
 	if _, err := fs.Stat(vol.csiDirPath); err == nil {
@@ -338,7 +343,7 @@ func TestDeleteVolumeUntilWaitNodesDirEmptiesEventually(t *testing.T) {
 
 	start := time.Now()
 	const waitTime = 10
-	if err := deleteVolumeUntilWait(context.TODO(), vol, uint64(waitTime)); err != nil {
+	if _, err := deleteVolumeUntilWait(context.TODO(), vol, uint64(waitTime)); err != nil {
 		t.Fatal("expected no error deleting volume")
 	}
 	if _, err := fs.Stat(vol.csiDirPath); err == nil {
@@ -352,6 +357,406 @@ func TestDeleteVolumeUntilWaitNodesDirEmptiesEventually(t *testing.T) {
 	}
 }
 
+func TestDeleteVolumeOwnershipMarker(t *testing.T) {
+	tests := map[string]struct {
+		createVolume bool   // whether CreateVolume marks the volume
+		markerID     string // a volumeID to write into the volume's metadata instead
+		noVolDir     bool
+		allow        bool
+		wantCode     codes.Code
+		wantDeleted  bool
+	}{
+		"marked volume": {
+			createVolume: true,
+			wantDeleted:  true,
+		},
+		"unmarked volume": {
+			wantCode: codes.FailedPrecondition,
+		},
+		"marker for another volume": {
+			markerID: "beegfs://127.0.0.2/scratch/pvc-1",
+			wantCode: codes.FailedPrecondition,
+		},
+		"unmarked volume with override": {
+			allow:       true,
+			wantDeleted: true,
+		},
+		"unmarked volume already deleted": {
+			noVolDir:    true,
+			wantDeleted: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs = afero.NewOsFs()
+			fsutil = afero.Afero{Fs: fs}
+			tempDir := t.TempDir()
+			confTemplatePath := path.Join(tempDir, "beegfs-client.conf")
+			if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+				t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+			}
+			pluginConfig := v1.PluginConfig{FileSystemSpecificConfigs: []v1.FileSystemSpecificConfig{
+				{SysMgmtdHost: "127.0.0.1", AllowUnmarkedVolumeDeletion: tc.allow},
+			}}
+			cs := newControllerServerSanity("node", pluginConfig, confTemplatePath, tempDir, 0)
+			ctx := context.Background()
+
+			if tc.createVolume {
+				_, err := cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+					Name: "pvc-1",
+					VolumeCapabilities: []*csi.VolumeCapability{{
+						AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+						AccessMode: &csi.VolumeCapability_AccessMode{
+							Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+					}},
+					Parameters: map[string]string{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"},
+				})
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+			}
+			vol, release, err := cs.mountCache.acquire(ctx, cs.newBeegfsVolume("127.0.0.1", "/scratch", "pvc-1"))
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			defer release()
+			// The fake beegfs-ctl doesn't create directories.
+			if !tc.noVolDir {
+				if err := fs.MkdirAll(vol.volDirPath, 0750); err != nil {
+					t.Fatalf("failed to create volume directory: %v", err)
+				}
+			}
+			if tc.markerID != "" {
+				marker := volumeMetadata{VolumeID: tc.markerID, CreationToken: newCreationToken()}
+				if err := writeVolumeMetadata(vol, marker); err != nil {
+					t.Fatalf("failed to write volume metadata: %v", err)
+				}
+			}
+
+			_, err = cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.volumeID})
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("expected code %s, got: %v", tc.wantCode, err)
+			}
+			exists, _ := fsutil.Exists(vol.volDirPath)
+			if tc.wantDeleted && exists {
+				t.Errorf("expected %s to be deleted", vol.volDirPath)
+			} else if !tc.wantDeleted && !exists {
+				t.Errorf("expected %s to remain", vol.volDirPath)
+			}
+		})
+	}
+}
+
+func TestControllerServerDeniesVolDirBasePath(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	fsutil = afero.Afero{Fs: fs}
+	pluginConfig := v1.PluginConfig{FileSystemSpecificConfigs: []v1.FileSystemSpecificConfig{
+		{SysMgmtdHost: "127.0.0.1", AllowedVolDirBasePaths: []string{"/k8s"}},
+	}}
+	cs := newControllerServerSanity("node", pluginConfig, "", t.TempDir(), 0)
+	ctx := context.Background()
+	volCaps := []*csi.VolumeCapability{{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+	}}
+	volumeID := NewBeegfsURL("127.0.0.1", "/home/pvc-1")
+
+	_, err := cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		VolumeCapabilities: volCaps,
+		Parameters:         map[string]string{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/home"},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected CreateVolume to return PermissionDenied, got: %v", err)
+	}
+	_, err = cs.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{
+		VolumeId:           volumeID,
+		VolumeCapabilities: volCaps,
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected ValidateVolumeCapabilities to return PermissionDenied, got: %v", err)
+	}
+	_, err = cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected DeleteVolume to return PermissionDenied, got: %v", err)
+	}
+}
+
+func TestCreateVolumeKeepsCreationToken(t *testing.T) {
+	cs, vol := newTestRetentionControllerServer(t)
+	ctx := context.Background()
+	first, err := readVolumeMetadata(vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !first.ownsVolume(vol.volumeID) {
+		t.Fatalf("expected CreateVolume to mark %s, got metadata: %+v", vol.volumeID, first)
+	}
+
+	// Repeat the CreateVolume request after the controller service forgot about the volume (e.g. after a restart).
+	cs.volumeStatusMap.writeStatus(vol.volumeID, statusDeleted)
+	_, err = cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: "pvc-1",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		}},
+		Parameters: map[string]string{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	second, err := readVolumeMetadata(vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if second.CreationToken != first.CreationToken {
+		t.Errorf("expected CreationToken %s to be kept, got: %s", first.CreationToken, second.CreationToken)
+	}
+}
+
+func TestCreateVolumeVolDirTemplate(t *testing.T) {
+	cs, _ := newTestRetentionControllerServer(t)
+	ctx := context.Background()
+	createVolume := func(volName, template, pvcName string) (string, error) {
+		resp, err := cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name: volName,
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+			}},
+			Parameters: map[string]string{
+				sysMgmtdHostKey:   "127.0.0.1",
+				volDirBasePathKey: "/scratch",
+				volDirTemplateKey: template,
+				pvcNameKey:        pvcName,
+				pvcNamespaceKey:   "default",
+				pvNameKey:         volName,
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		// The fake beegfs-ctl doesn't create directories.
+		vol, _ := cs.newBeegfsVolumeFromID(resp.GetVolume().GetVolumeId())
+		vol, release, err := cs.mountCache.acquire(ctx, vol)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		defer release()
+		if err := fs.MkdirAll(vol.volDirPath, 0750); err != nil {
+			t.Fatalf("failed to create volume directory: %v", err)
+		}
+		return vol.volumeID, nil
+	}
+	template := "${pvc.namespace}/${pvc.name}"
+
+	volumeID, err := createVolume("pvc-2", template, "data")
+	if err != nil || volumeID != "beegfs://127.0.0.1/scratch/default/data" {
+		t.Fatalf("expected volume beegfs://127.0.0.1/scratch/default/data, got: %s, %v", volumeID, err)
+	}
+	// A repeated request returns the same volume.
+	if volumeID, err = createVolume("pvc-2", template, "data"); err != nil ||
+		volumeID != "beegfs://127.0.0.1/scratch/default/data" {
+		t.Fatalf("expected volume beegfs://127.0.0.1/scratch/default/data, got: %s, %v", volumeID, err)
+	}
+	// Another volume for a PVC with the same name (e.g. a recreated PVC) gets the fallback directory.
+	if volumeID, err = createVolume("pvc-3", template, "data"); err != nil ||
+		volumeID != "beegfs://127.0.0.1/scratch/default/data-pvc-3" {
+		t.Fatalf("expected volume beegfs://127.0.0.1/scratch/default/data-pvc-3, got: %s, %v", volumeID, err)
+	}
+	if volumeID, err = createVolume("pvc-3", template, "data"); err != nil ||
+		volumeID != "beegfs://127.0.0.1/scratch/default/data-pvc-3" {
+		t.Fatalf("expected volume beegfs://127.0.0.1/scratch/default/data-pvc-3, got: %s, %v", volumeID, err)
+	}
+	// Neither directory may be taken over if both exist.
+	if _, err = createVolume("pvc-4", template, "data"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	vol, release, err := cs.mountCache.acquire(ctx, cs.newBeegfsVolume("127.0.0.1", "/scratch/default", "data-pvc-5"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+	if err := fs.MkdirAll(path.Join(path.Dir(vol.volDirPath), "unmarked"), 0750); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := fs.MkdirAll(path.Join(path.Dir(vol.volDirPath), "unmarked-pvc-5"), 0750); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if _, err = createVolume("pvc-5", template, "unmarked"); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists, got: %v", err)
+	}
+	// A volume is never created inside another volume.
+	if _, err = createVolume("pvc-6", "${pvc.namespace}/data/${pvc.name}", "nested"); status.Code(err) !=
+		codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists, got: %v", err)
+	}
+	// The template needs the PVC metadata.
+	if _, err = createVolume("pvc-7", template, ""); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got: %v", err)
+	}
+	// A retry of a request that failed after creating its directory gets the same directory.
+	cs.ctlExec = &failingBeegfsCtlExecutor{}
+	if _, err = createVolume("pvc-8", template, "retried"); err == nil {
+		t.Fatal("expected error when the stripe pattern cannot be set")
+	}
+	cs.ctlExec = &fakeBeegfsCtlExecutor{}
+	if volumeID, err = createVolume("pvc-8", template, "retried"); err != nil ||
+		volumeID != "beegfs://127.0.0.1/scratch/default/retried" {
+		t.Errorf("expected volume beegfs://127.0.0.1/scratch/default/retried, got: %s, %v", volumeID, err)
+	}
+}
+
+// failingBeegfsCtlExecutor creates directories (on a controller service's cached mount) but fails to stripe them.
+type failingBeegfsCtlExecutor struct {
+	fakeBeegfsCtlExecutor
+}
+
+func (*failingBeegfsCtlExecutor) createDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string,
+	cfg permissionsConfig) error {
+	return errors.WithStack(fs.MkdirAll(path.Join(vol.mountPath, dirPath), cfg.goFileMode()))
+}
+
+func (*failingBeegfsCtlExecutor) setPatternForVolume(ctx context.Context, vol beegfsVolume,
+	cfg stripePatternConfig) error {
+	return errors.Errorf("cannot set stripe pattern for %s", vol.volumeID)
+}
+
+// recordingBeegfsCtlExecutor creates directories on a controller service's cached mount (like beegfs-ctl, it creates
+// missing parents with the same permissions) and records the permissions and stripe patterns it applies.
+type recordingBeegfsCtlExecutor struct {
+	fakeBeegfsCtlExecutor
+	permissions map[string]permissionsConfig   // keyed by path from the BeeGFS root
+	patterns    map[string]stripePatternConfig // keyed by path from the BeeGFS root
+	concurrent  map[string]bool                // directories another request creates just before createDirectory
+}
+
+func (e *recordingBeegfsCtlExecutor) createDirectoryForVolume(ctx context.Context, vol beegfsVolume, dirPath string,
+	cfg permissionsConfig) error {
+	var dirsToMake []string
+	for dir := dirPath; dir != "/"; dir = path.Dir(dir) {
+		if exists, _ := fsutil.DirExists(path.Join(vol.mountPath, dir)); exists {
+			break
+		}
+		dirsToMake = append([]string{dir}, dirsToMake...)
+	}
+	for _, dir := range dirsToMake {
+		if err := fs.Mkdir(path.Join(vol.mountPath, dir), 0755); err != nil {
+			return err
+		}
+		e.permissions[dir] = cfg
+	}
+	return nil
+}
+
+func (e *recordingBeegfsCtlExecutor) createDirectory(ctx context.Context, vol beegfsVolume, dirPath string,
+	cfg permissionsConfig) error {
+	if e.concurrent[dirPath] {
+		if err := fs.Mkdir(path.Join(vol.mountPath, dirPath), 0755); err != nil {
+			return err
+		}
+	}
+	if exists, _ := fsutil.DirExists(path.Join(vol.mountPath, dirPath)); exists {
+		return newCtlExistError("", "exists already")
+	}
+	if err := fs.Mkdir(path.Join(vol.mountPath, dirPath), 0755); err != nil {
+		return err
+	}
+	e.permissions[dirPath] = cfg
+	return nil
+}
+
+func (e *recordingBeegfsCtlExecutor) setPatternForVolume(ctx context.Context, vol beegfsVolume,
+	cfg stripePatternConfig) error {
+	if cfg != (stripePatternConfig{}) {
+		e.patterns[vol.volDirPathBeegfsRoot] = cfg
+	}
+	return nil
+}
+
+func TestCreateVolumeParentDirectories(t *testing.T) {
+	cs, _ := newTestRetentionControllerServer(t)
+	ctl := &recordingBeegfsCtlExecutor{
+		permissions: make(map[string]permissionsConfig),
+		patterns:    make(map[string]stripePatternConfig),
+		concurrent:  make(map[string]bool),
+	}
+	cs.ctlExec = ctl
+	ctx := context.Background()
+	createVolume := func(volName, pvcNamespace, pvcName, parentMode string) {
+		_, err := cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name: volName,
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+			}},
+			Parameters: map[string]string{
+				sysMgmtdHostKey:                                  "127.0.0.1",
+				volDirBasePathKey:                                "/projects/team",
+				volDirTemplateKey:                                "${pvc.namespace}/${pvc.name}",
+				permissionsUIDKey:                                "1000",
+				permissionsModeKey:                               "0700",
+				basePathParamPrefix + permissionsModeKey:         parentMode,
+				basePathParamPrefix + stripePatternNumTargetsKey: "4",
+				pvcNameKey:                                       pvcName,
+				pvcNamespaceKey:                                  pvcNamespace,
+				pvNameKey:                                        volName,
+			},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	createVolume("pvc-2", "default", "data", "2755")
+	wantParentPermissions := permissionsConfig{mode: 0o2755}
+	wantParentPattern := stripePatternConfig{stripePatternNumTargets: "4"}
+	for _, dir := range []string{"/projects", "/projects/team", "/projects/team/default"} {
+		if ctl.permissions[dir] != wantParentPermissions || ctl.patterns[dir] != wantParentPattern {
+			t.Errorf("expected %s to be created with %+v and %+v, got: %+v and %+v", dir, wantParentPermissions,
+				wantParentPattern, ctl.permissions[dir], ctl.patterns[dir])
+		}
+	}
+	if got := ctl.permissions["/projects/team/default/data"]; got != (permissionsConfig{uid: 1000, mode: 0o700}) {
+		t.Errorf("expected the volume's directory to be created with its own permissions, got: %+v", got)
+	}
+	// beegfs-ctl cannot set special permissions, so the controller service sets them.
+	vol, release, err := cs.mountCache.acquire(ctx, cs.newBeegfsVolume("127.0.0.1", "/projects/team", "default"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+	if info, err := fs.Stat(vol.volDirPath); err != nil || info.Mode()&os.ModeSetgid == 0 {
+		t.Errorf("expected %s to have the setgid bit, got: %v, %v", vol.volDirPath, info, err)
+	}
+
+	// Existing parent directories are never changed.
+	delete(ctl.permissions, "/projects/team/default")
+	createVolume("pvc-3", "default", "logs", "0700")
+	if _, ok := ctl.permissions["/projects/team/default"]; ok {
+		t.Errorf("expected the existing parent directory not to be recreated")
+	}
+	if got := ctl.permissions["/projects/team/default/logs"]; got != (permissionsConfig{uid: 1000, mode: 0o700}) {
+		t.Errorf("expected the volume's directory to be created with its own permissions, got: %+v", got)
+	}
+
+	// Neither are parent directories a concurrent request creates first.
+	ctl.concurrent["/projects/team/other"] = true
+	createVolume("pvc-4", "other", "data", "3755")
+	if _, ok := ctl.patterns["/projects/team/other"]; ok {
+		t.Errorf("expected the stripe pattern of a concurrently created parent directory not to be changed")
+	}
+	other, release, err := cs.mountCache.acquire(ctx, cs.newBeegfsVolume("127.0.0.1", "/projects/team", "other"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+	if info, err := fs.Stat(other.volDirPath); err != nil || info.Mode()&os.ModeSticky != 0 {
+		t.Errorf("expected the permissions of %s not to be changed, got: %v, %v", other.volDirPath, info, err)
+	}
+}
+
 // This test is to check sysMgmtdHost, VolDirBasePathBeefsRoot, and the number of parameters going into
 // the ValidateReqParams function. The stripePatternConfig and permissionsConfig parameters are not tested here
 // as they are already tested above.
@@ -415,6 +820,66 @@ func TestValidateReqParams(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		"retention and PVC metadata example": {
+			reqParams: map[string]string{
+				sysMgmtdHostKey:    "localhost",
+				volDirBasePathKey:  "/testDir",
+				retentionPeriodKey: "168h",
+				pvcNameKey:         "data",
+				pvcNamespaceKey:    "default",
+				pvNameKey:          "pvc-12345678",
+			},
+			want: reqParameters{
+				sysMgmtdHost:             "localhost",
+				volDirBasePathBeegfsRoot: "/testDir",
+				retentionPeriod:          168 * time.Hour,
+				pvcName:                  "data",
+				pvcNamespace:             "default",
+				pvName:                   "pvc-12345678",
+			},
+			wantErr: false,
+		},
+		"volDirTemplate example": {
+			reqParams: map[string]string{
+				sysMgmtdHostKey:   "localhost",
+				volDirBasePathKey: "/testDir",
+				volDirTemplateKey: "${pvc.namespace}/${pvc.name}",
+			},
+			want: reqParameters{
+				sysMgmtdHost:             "localhost",
+				volDirBasePathBeegfsRoot: "/testDir",
+				volDirTemplate:           "${pvc.namespace}/${pvc.name}",
+			},
+			wantErr: false,
+		},
+		"basePath example": {
+			reqParams: map[string]string{
+				sysMgmtdHostKey:                     "localhost",
+				volDirBasePathKey:                   "/testDir",
+				stripePatternNumTargetsKey:          "2",
+				permissionsUIDKey:                   "1000",
+				"basePath/stripePattern/numTargets": "4",
+				"basePath/permissions/mode":         "0755",
+			},
+			want: reqParameters{
+				sysMgmtdHost:             "localhost",
+				volDirBasePathBeegfsRoot: "/testDir",
+				parentDirConfig: &parentDirConfig{
+					stripePatternConfig: stripePatternConfig{stripePatternNumTargets: "4"},
+					permissionsConfig:   permissionsConfig{mode: 0o755},
+				},
+			},
+			wantErr: false,
+		},
+		"invalid basePath example": {
+			reqParams: map[string]string{
+				sysMgmtdHostKey:           "localhost",
+				volDirBasePathKey:         "/testDir",
+				"basePath/volDirTemplate": "${pvc.name}",
+			},
+			want:    reqParameters{},
+			wantErr: true,
+		},
 		"Extra pair in map example": {
 			reqParams: map[string]string{
 				sysMgmtdHostKey:               "localhost",
@@ -447,7 +912,11 @@ func TestValidateReqParams(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			got, err := validateReqParams(tc.reqParams)
 			if !reflect.DeepEqual(tc.want.sysMgmtdHost, got.sysMgmtdHost) ||
-				!reflect.DeepEqual(tc.want.volDirBasePathBeegfsRoot, got.volDirBasePathBeegfsRoot) {
+				!reflect.DeepEqual(tc.want.volDirBasePathBeegfsRoot, got.volDirBasePathBeegfsRoot) ||
+				tc.want.retentionPeriod != got.retentionPeriod || tc.want.pvcName != got.pvcName ||
+				tc.want.pvcNamespace != got.pvcNamespace || tc.want.pvName != got.pvName ||
+				tc.want.volDirTemplate != got.volDirTemplate ||
+				!reflect.DeepEqual(tc.want.parentDirConfig, got.parentDirConfig) {
 				t.Fatalf("expected: %v, got: %v", tc.want, got)
 			}
 			if !tc.wantErr && err != nil {
@@ -459,3 +928,167 @@ func TestValidateReqParams(t *testing.T) {
 		})
 	}
 }
+
+// The operator validates StorageClasses with v1.ValidateStorageClassParameters. It must accept exactly the
+// parameters validateReqParams accepts (except for the parameters the external-provisioner removes).
+func TestValidateStorageClassParameters(t *testing.T) {
+	tests := map[string]struct {
+		params  map[string]string
+		wantErr bool
+	}{
+		"minimal": {
+			params: map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/"},
+		},
+		"everything": {
+			params: map[string]string{
+				sysMgmtdHostKey:               "localhost",
+				volDirBasePathKey:             "/testDir",
+				stripePatternStoragePoolIDKey: "2",
+				stripePatternChunkSizeKey:     "2m",
+				stripePatternNumTargetsKey:    "4",
+				permissionsUIDKey:             "1500",
+				permissionsGIDKey:             "1500",
+				permissionsModeKey:            "0755",
+			},
+		},
+		"empty stripePattern": {
+			params: map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", stripePatternChunkSizeKey: ""},
+		},
+		"missing sysMgmtdHost": {
+			params:  map[string]string{volDirBasePathKey: "/"},
+			wantErr: true,
+		},
+		"missing volDirBasePath": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost"},
+			wantErr: true,
+		},
+		"invalid chunkSize": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", stripePatternChunkSizeKey: "2"},
+			wantErr: true,
+		},
+		"unknown stripePattern": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", "stripePattern/foo": "1"},
+			wantErr: true,
+		},
+		"invalid mode": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", permissionsModeKey: "0999"},
+			wantErr: true,
+		},
+		"retentionPeriod": {
+			params: map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", retentionPeriodKey: "168h"},
+		},
+		"invalid retentionPeriod": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", retentionPeriodKey: "7d"},
+			wantErr: true,
+		},
+		"negative retentionPeriod": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", retentionPeriodKey: "-1h"},
+			wantErr: true,
+		},
+		"volDirTemplate": {
+			params: map[string]string{
+				sysMgmtdHostKey:   "localhost",
+				volDirBasePathKey: "/",
+				volDirTemplateKey: "${pvc.namespace}/${pvc.name}",
+			},
+		},
+		"invalid volDirTemplate": {
+			params: map[string]string{
+				sysMgmtdHostKey:   "localhost",
+				volDirBasePathKey: "/",
+				volDirTemplateKey: "../${pvc.name}",
+			},
+			wantErr: true,
+		},
+		"basePath": {
+			params: map[string]string{
+				sysMgmtdHostKey:                    "localhost",
+				volDirBasePathKey:                  "/",
+				"basePath/stripePattern/chunkSize": "1m",
+				"basePath/permissions/gid":         "1500",
+				"basePath/permissions/mode":        "2775",
+			},
+		},
+		"invalid basePath mode": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", "basePath/permissions/mode": "0999"},
+			wantErr: true,
+		},
+		"unknown basePath parameter": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", "basePath/retentionPeriod": "1h"},
+			wantErr: true,
+		},
+		"unknown parameter": {
+			params:  map[string]string{sysMgmtdHostKey: "localhost", volDirBasePathKey: "/", "volDirBasepath": "/"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := v1.ValidateStorageClassParameters(tc.params)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t; got: %v", tc.wantErr, err)
+			}
+			// validateReqParams modifies its argument, so give it a copy.
+			params := make(map[string]string)
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			if _, reqErr := validateReqParams(params); (reqErr != nil) != (err != nil) {
+				t.Fatalf("ValidateStorageClassParameters returned %v but validateReqParams returned %v", err, reqErr)
+			}
+		})
+	}
+
+	// Parameters the external-provisioner removes are ignored.
+	params := map[string]string{
+		sysMgmtdHostKey:   "localhost",
+		volDirBasePathKey: "/",
+		"csi.storage.k8s.io/provisioner-secret-name": "some-secret",
+	}
+	if err := v1.ValidateStorageClassParameters(params); err != nil {
+		t.Fatalf("expected no error; got %v", err)
+	}
+}
+
+func TestExpandVolDirTemplate(t *testing.T) {
+	values := map[string]string{
+		v1.VolDirTemplateVarPVCNamespace: "default",
+		v1.VolDirTemplateVarPVCName:      "data",
+		v1.VolDirTemplateVarPVName:       "pvc-12345678",
+	}
+	tests := map[string]struct {
+		template string
+		values   map[string]string
+		want     string
+		wantErr  bool
+	}{
+		"namespace and name":    {template: "${pvc.namespace}/${pvc.name}", values: values, want: "default/data"},
+		"fixed prefix":          {template: "k8s/${pv.name}", values: values, want: "k8s/pvc-12345678"},
+		"no variables":          {template: "shared", values: values, want: "shared"},
+		"unknown variable":      {template: "${pvc.uid}", values: values, wantErr: true},
+		"missing value":         {template: "${pvc.namespace}/${pvc.name}", values: map[string]string{}, wantErr: true},
+		"unterminated variable": {template: "${pvc.name", values: values, wantErr: true},
+		"empty":                 {template: "", values: values, wantErr: true},
+		"absolute":              {template: "/${pvc.name}", values: values, wantErr: true},
+		"trailing slash":        {template: "${pvc.name}/", values: values, wantErr: true},
+		"double slash":          {template: "${pvc.namespace}//${pvc.name}", values: values, wantErr: true},
+		"parent directory":      {template: "../${pvc.name}", values: values, wantErr: true},
+		"CSI metadata":          {template: ".csi/${pvc.name}", values: values, wantErr: true},
+		"slash in value": {
+			template: "${pvc.name}",
+			values:   map[string]string{v1.VolDirTemplateVarPVCName: "a/../../b"},
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := v1.ExpandVolDirTemplate(tc.template, tc.values)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t; got: %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,626 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/mount-utils"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	diagnosticsVolumesPath       = "/volumes"
+	diagnosticsVolumeListPath    = "/volumes/list"
+	diagnosticsDeletionsPath     = "/deletions"
+	diagnosticsMetricsPath       = "/metrics"
+	diagnosticsRetainedPath      = "/retained"
+	diagnosticsRestorePath       = "/retained/restore"
+	diagnosticsOrphansPath       = "/orphans"
+	diagnosticsOrphanCleanupPath = "/orphans/cleanup"
+	diagnosticsRetainedIDKey     = "retainedVolumeID"
+	diagnosticsVolumeNameKey     = "volumeName"
+	diagnosticsVolumeIDKey       = "volumeID"
+	diagnosticsStagingTargetKey  = "stagingTargetPath"
+	diagnosticsOtherClustersKey  = "allowOtherClusters"
+	redactedFileContents         = "******"
+
+	// DiagnosticsServiceController and DiagnosticsServiceNode select the service whose diagnostics the diagnostics
+	// endpoint serves. Every driver process runs both services, but only one of them is in use in a given deployment.
+	DiagnosticsServiceController = "controller"
+	DiagnosticsServiceNode       = "node"
+)
+
+// auxClientFileNames contains the names of all auxiliary files writeClientFiles may write to a mountDirPath. The
+// contents of files in secretClientFileNames are always redacted in volume diagnostics.
+var (
+	auxClientFileNames = []string{"connInterfacesFile", "connNetFilterFile", "connTcpOnlyFilterFile",
+		"connRDMAInterfacesFile", "connAuthFile", "cert.pem"}
+	secretClientFileNames = []string{"connAuthFile", "cert.pem"}
+)
+
+// volumeDiagnostics describes the BeeGFS client configuration a service uses (or would use) for a volume. Secrets
+// (connAuth and TLS certificates) are always redacted.
+type volumeDiagnostics struct {
+	VolumeID     string                `json:"volumeID"`
+	Config       beegfsv1.BeegfsConfig `json:"config"` // BeegfsConfig.MarshalJSON redacts secrets.
+	Mounted      bool                  `json:"mounted"`
+	MountDirPath string                `json:"mountDirPath,omitempty"`
+	MountPath    string                `json:"mountPath,omitempty"`
+	MountOptions []string              `json:"mountOptions,omitempty"`
+	// ClientConf and ClientFiles are read from mountDirPath if the volume is mounted. Otherwise, they are generated
+	// from the template beegfs-client.conf file (with a placeholder mountDirPath and connClientPort).
+	ClientConf  string            `json:"clientConf,omitempty"`
+	ClientFiles map[string]string `json:"clientFiles,omitempty"` // file name -> file contents
+	Error       string            `json:"error,omitempty"`
+}
+
+// volumeDiagnosticsResponse is returned by the diagnostics HTTP server. Each service is only included if the driver
+// runs it.
+type volumeDiagnosticsResponse struct {
+	Controller *volumeDiagnostics `json:"controller,omitempty"`
+	Node       *volumeDiagnostics `json:"node,omitempty"`
+}
+
+// getVolumeDiagnostics returns diagnostics for a volume from the perspective of the controller service. The
+// controller service only keeps a volume's file system mounted while it is in its mount cache.
+func (cs *controllerServer) getVolumeDiagnostics(ctx context.Context, volumeID string) (volumeDiagnostics, error) {
+	vol, err := cs.newBeegfsVolumeFromID(volumeID)
+	if err != nil {
+		return volumeDiagnostics{}, err
+	}
+	if mountDirPath, ok := cs.mountCache.findMountDirPath(vol.sysMgmtdHost); ok {
+		vol = newBeegfsVolume(mountDirPath, vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, cs.pluginConfig)
+	}
+	mountPoint, err := findMountPoint(cs.mounter, vol.mountPath)
+	if err != nil {
+		return volumeDiagnostics{}, err
+	}
+	return newVolumeDiagnostics(ctx, vol, mountPoint, cs.clientConfTemplatePath), nil
+}
+
+// getVolumeDiagnostics returns diagnostics for a volume from the perspective of the node service. If
+// stagingTargetPath is empty, getVolumeDiagnostics looks for a BeeGFS file system staged by the kubelet for volumeID.
+func (ns *nodeServer) getVolumeDiagnostics(ctx context.Context, volumeID, stagingTargetPath string) (volumeDiagnostics,
+	error) {
+	var mountPoint *mount.MountPoint
+	var err error
+	if stagingTargetPath == "" {
+		if mountPoint, err = findStagedMountPoint(ns.mounter, volumeID); err != nil {
+			return volumeDiagnostics{}, err
+		}
+		if mountPoint != nil {
+			stagingTargetPath = trimHostRootPath(path.Dir(mountPoint.Path))
+		}
+	}
+	if stagingTargetPath == "" {
+		// The volume is not staged on this node. Use a placeholder to generate the client files it would use.
+		stagingTargetPath = placeholderMountDirPath
+	}
+	mountDirPath := stagingTargetPath
+	if key, ok := ns.sharedMounts.findKey(stagingTargetPath); ok {
+		// The volume is staged in shared mount mode. Report the shared mount it uses.
+		mountDirPath = ns.sharedMounts.getMountDirPath(key)
+		mountPoint = nil
+	}
+	vol, err := newBeegfsVolumeFromID(mountDirPath, volumeID, ns.pluginConfig)
+	if err != nil {
+		return volumeDiagnostics{}, err
+	}
+	if mountPoint == nil && stagingTargetPath != placeholderMountDirPath {
+		if mountPoint, err = findMountPoint(ns.mounter, vol.mountPath); err != nil {
+			return volumeDiagnostics{}, err
+		}
+	}
+	return newVolumeDiagnostics(ctx, vol, mountPoint, ns.clientConfTemplatePath), nil
+}
+
+// newVolumeDiagnostics assembles volumeDiagnostics for vol. If mountPoint is not nil, vol's file system is mounted and
+// the client files are read from its mountDirPath. Otherwise, the client files are generated from the template at
+// confTemplatePath. Problems reading or generating client files are reported in volumeDiagnostics.Error instead of
+// failing the request, as they are often exactly what the caller is trying to debug.
+func newVolumeDiagnostics(ctx context.Context, vol beegfsVolume, mountPoint *mount.MountPoint,
+	confTemplatePath string) volumeDiagnostics {
+	diag := volumeDiagnostics{
+		VolumeID:    vol.volumeID,
+		Config:      vol.config,
+		ClientFiles: make(map[string]string),
+	}
+
+	if mountPoint != nil {
+		diag.Mounted = true
+		diag.MountDirPath = vol.mountDirPath
+		diag.MountPath = mountPoint.Path
+		diag.MountOptions = mountPoint.Opts
+
+		clientConfBytes, err := fsutil.ReadFile(vol.clientConfPath)
+		if err != nil {
+			diag.Error = errors.Wrap(err, "failed to read beegfs-client.conf file").Error()
+			return diag
+		}
+		diag.ClientConf = string(clientConfBytes)
+		for _, name := range auxClientFileNames {
+			filePath := path.Join(vol.mountDirPath, name)
+			contents, err := fsutil.ReadFile(filePath)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				diag.Error = errors.Wrapf(err, "failed to read %s file", name).Error()
+				return diag
+			}
+			diag.ClientFiles[name] = redactClientFile(name, string(contents))
+		}
+		return diag
+	}
+
+	// The connClientPort is chosen at mount time, so any value works here.
+	clientConfINI, auxFiles, err := generateClientFiles(ctx, vol, confTemplatePath, "0")
+	if err != nil {
+		diag.Error = err.Error()
+		return diag
+	}
+	var clientConf bytes.Buffer
+	if _, err = clientConfINI.WriteTo(&clientConf); err != nil {
+		diag.Error = errors.Wrap(err, "failed to generate beegfs-client.conf file").Error()
+		return diag
+	}
+	diag.ClientConf = clientConf.String()
+	for _, auxFile := range auxFiles {
+		name := path.Base(auxFile.path)
+		diag.ClientFiles[name] = redactClientFile(name, string(auxFile.contents))
+	}
+	return diag
+}
+
+// redactClientFile returns contents unless name is the name of a file that contains secrets.
+func redactClientFile(name, contents string) string {
+	for _, secretName := range secretClientFileNames {
+		if name == secretName {
+			return redactedFileContents
+		}
+	}
+	return contents
+}
+
+// findMountPoint returns the MountPoint mounted at mountPath or nil if nothing is mounted there.
+func findMountPoint(mounter mount.Interface, mountPath string) (*mount.MountPoint, error) {
+	mountPoints, err := mounter.List()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range mountPoints {
+		if mountPoints[i].Path == mountPath {
+			return &mountPoints[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// findStagedMountPoint returns the BeeGFS MountPoint the kubelet staged for volumeID or nil if the volume is not
+// staged. The node service mounts BeeGFS to the "mount" subdirectory of the staging target path (or bind mounts the
+// volume to the sharedStagingDirName subdirectory in shared mount mode). Current kubelets use the SHA-256 hash of the
+// volume ID as a directory name in the staging target path (e.g.
+// /var/lib/kubelet/plugins/kubernetes.io/csi/<driver>/<hash>/globalmount). Older kubelets use the PV name instead (e.g.
+// /var/lib/kubelet/plugins/kubernetes.io/csi/pv/<pv>/globalmount), which matches the volume name for dynamically
+// provisioned volumes.
+func findStagedMountPoint(mounter mount.Interface, volumeID string) (*mount.MountPoint, error) {
+	_, volDirPathBeegfsRoot, err := parseBeegfsURL(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	stagingPathElements := []string{
+		fmt.Sprintf("/%x/", sha256.Sum256([]byte(volumeID))),
+		"/pv/" + path.Base(volDirPathBeegfsRoot) + "/",
+	}
+	mountPoints, err := mounter.List()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, mountPoint := range mountPoints {
+		if mountPoint.Type != "beegfs" ||
+			(path.Base(mountPoint.Path) != "mount" && path.Base(mountPoint.Path) != sharedStagingDirName) {
+			continue
+		}
+		for _, element := range stagingPathElements {
+			if strings.Contains(mountPoint.Path, element) {
+				return &mountPoints[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// SetDiagnosticsService configures whether the diagnostics endpoint serves the diagnostics of the controller service
+// (DiagnosticsServiceController) or the node service (DiagnosticsServiceNode, the default). It must be called before
+// Run.
+func (b *beegfs) SetDiagnosticsService(service string) error {
+	if service != DiagnosticsServiceController && service != DiagnosticsServiceNode {
+		return errors.Errorf("invalid diagnostics service %s (must be %s or %s)", service,
+			DiagnosticsServiceController, DiagnosticsServiceNode)
+	}
+	b.diagnosticsService = service
+	return nil
+}
+
+// newDiagnosticsHandler returns an http.Handler that serves volume diagnostics from cs and ns (either of which may be
+// nil), the metadata of cs's volumes, the progress of cs's pending volume deletions, cs's retained and orphaned volumes,
+// and the driver's metrics. It also handles requests to restore retained volumes and clean up orphaned volumes, but
+// rejects them unless allowChanges is set. The diagnostics endpoint has no authentication, so only requests that reach
+// it through a Unix socket (which only the driver's own containers can access) may change volumes.
+func newDiagnosticsHandler(cs *controllerServer, ns *nodeServer, allowChanges bool) http.Handler {
+	mux := http.NewServeMux()
+	// rejectChanges rejects a request to change volumes if allowChanges is not set. It returns true if it did.
+	rejectChanges := func(w http.ResponseWriter) bool {
+		if !allowChanges {
+			http.Error(w, "requests that change volumes are only accepted on a unix:// diagnostics endpoint",
+				http.StatusForbidden)
+		}
+		return !allowChanges
+	}
+	mux.HandleFunc(diagnosticsVolumesPath, func(w http.ResponseWriter, r *http.Request) {
+		ctx := generateRequestContext(r.Context())
+		volumeID := r.URL.Query().Get(diagnosticsVolumeIDKey)
+		if volumeID == "" {
+			http.Error(w, "volumeID not provided", http.StatusBadRequest)
+			return
+		}
+		if _, _, err := parseBeegfsURL(volumeID); err != nil {
+			http.Error(w, fmt.Sprintf("invalid volumeID %s: %v", volumeID, err), http.StatusBadRequest)
+			return
+		}
+		LogDebug(ctx, "Diagnostics request", "volumeID", volumeID)
+
+		var resp volumeDiagnosticsResponse
+		if cs != nil {
+			diag, err := cs.getVolumeDiagnostics(ctx, volumeID)
+			if err != nil {
+				LogError(ctx, err, "Failed to get controller volume diagnostics", "volumeID", volumeID)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.Controller = &diag
+		}
+		if ns != nil {
+			diag, err := ns.getVolumeDiagnostics(ctx, volumeID, r.URL.Query().Get(diagnosticsStagingTargetKey))
+			if err != nil {
+				LogError(ctx, err, "Failed to get node volume diagnostics", "volumeID", volumeID)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.Node = &diag
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(resp); err != nil {
+			LogError(ctx, errors.WithStack(err), "Failed to write diagnostics response", "volumeID", volumeID)
+		}
+	})
+	if cs != nil {
+		mux.HandleFunc(diagnosticsDeletionsPath, func(w http.ResponseWriter, r *http.Request) {
+			writeDiagnosticsJSON(r.Context(), w, cs.volumeDeleter.progress())
+		})
+		mux.HandleFunc(diagnosticsVolumeListPath, func(w http.ResponseWriter, r *http.Request) {
+			ctx := generateRequestContext(r.Context())
+			volumes, err := cs.listVolumeMetadata(ctx)
+			if err != nil {
+				LogError(ctx, err, "Failed to list volumes")
+				http.Error(w, err.Error(), httpStatusFromGrpcError(err))
+				return
+			}
+			writeDiagnosticsJSON(ctx, w, volumes)
+		})
+		mux.HandleFunc(diagnosticsRetainedPath, func(w http.ResponseWriter, r *http.Request) {
+			writeDiagnosticsJSON(r.Context(), w, cs.volumeRetainer.list(generateRequestContext(r.Context())))
+		})
+		mux.HandleFunc(diagnosticsRestorePath, func(w http.ResponseWriter, r *http.Request) {
+			ctx := generateRequestContext(r.Context())
+			if r.Method != http.MethodPost {
+				http.Error(w, "restoring a volume requires a POST request", http.StatusMethodNotAllowed)
+				return
+			}
+			if rejectChanges(w) {
+				return
+			}
+			retainedVolumeID := r.URL.Query().Get(diagnosticsRetainedIDKey)
+			if retainedVolumeID == "" {
+				http.Error(w, "retainedVolumeID not provided", http.StatusBadRequest)
+				return
+			}
+			LogDebug(ctx, "Restore request", "retainedVolumeID", retainedVolumeID)
+			metadata, err := cs.restoreVolume(ctx, retainedVolumeID, r.URL.Query().Get(diagnosticsVolumeNameKey))
+			if err != nil {
+				LogError(ctx, err, "Failed to restore retained volume", "retainedVolumeID", retainedVolumeID)
+				http.Error(w, err.Error(), httpStatusFromGrpcError(err))
+				return
+			}
+			writeDiagnosticsJSON(ctx, w, metadata)
+		})
+		mux.HandleFunc(diagnosticsOrphansPath, func(w http.ResponseWriter, r *http.Request) {
+			ctx := generateRequestContext(r.Context())
+			report, err := cs.scanOrphans(ctx, time.Now())
+			if err != nil {
+				LogError(ctx, err, "Failed to scan for orphaned volumes")
+				http.Error(w, err.Error(), httpStatusFromGrpcError(err))
+				return
+			}
+			writeDiagnosticsJSON(ctx, w, report)
+		})
+		mux.HandleFunc(diagnosticsOrphanCleanupPath, func(w http.ResponseWriter, r *http.Request) {
+			ctx := generateRequestContext(r.Context())
+			if r.Method != http.MethodPost {
+				http.Error(w, "cleaning up orphaned volumes requires a POST request", http.StatusMethodNotAllowed)
+				return
+			}
+			if rejectChanges(w) {
+				return
+			}
+			volumeIDs := r.URL.Query()[diagnosticsVolumeIDKey]
+			if len(volumeIDs) == 0 {
+				http.Error(w, "volumeID not provided", http.StatusBadRequest)
+				return
+			}
+			allowOtherClusters := r.URL.Query().Get(diagnosticsOtherClustersKey) == "true"
+			LogDebug(ctx, "Orphaned volume cleanup request", "volumeIDs", volumeIDs,
+				"allowOtherClusters", allowOtherClusters)
+			results, err := cs.cleanUpOrphans(ctx, volumeIDs, allowOtherClusters, time.Now())
+			if err != nil {
+				LogError(ctx, err, "Failed to clean up orphaned volumes")
+				http.Error(w, err.Error(), httpStatusFromGrpcError(err))
+				return
+			}
+			writeDiagnosticsJSON(ctx, w, results)
+		})
+	}
+	mux.Handle(diagnosticsMetricsPath, promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// writeDiagnosticsJSON writes the indented JSON encoding of v to w.
+func writeDiagnosticsJSON(ctx context.Context, w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		LogError(ctx, errors.WithStack(err), "Failed to write diagnostics response")
+	}
+}
+
+// httpStatusFromGrpcError returns the HTTP status code that best describes the gRPC error err.
+func httpStatusFromGrpcError(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// serveDiagnostics serves volume diagnostics over HTTP at endpoint (e.g. unix://csi/diagnostics.sock) until stop is
+// closed and then waits for in-flight requests to complete. Diagnostics are best effort, so serveDiagnostics logs (but
+// does not exit on) errors. Requests that change volumes are only accepted if endpoint is a Unix socket.
+func serveDiagnostics(endpoint string, cs *controllerServer, ns *nodeServer, stop <-chan struct{}) {
+	proto, _, err := parseEndpoint(endpoint)
+	if err != nil {
+		LogError(context.TODO(), err, "Error parsing diagnostics endpoint")
+		return
+	}
+	allowChanges := proto == "unix"
+	if !allowChanges && cs != nil {
+		logger(context.TODO()).Info("Rejecting requests to restore or clean up volumes on a diagnostics endpoint "+
+			"that is not a Unix socket", "endpoint", endpoint)
+	}
+	serveHTTP(endpoint, "diagnostics", newDiagnosticsHandler(cs, ns, allowChanges), stop)
+}
+
+// serveHTTP serves handler over HTTP at endpoint until stop is closed and then waits for in-flight requests to
+// complete. It logs (but does not exit on) errors. name describes what is served in log messages.
+func serveHTTP(endpoint, name string, handler http.Handler, stop <-chan struct{}) {
+	proto, addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		LogError(context.TODO(), err, "Error parsing endpoint", "name", name)
+		return
+	}
+	if proto == "unix" {
+		addr = "/" + addr
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			LogError(context.TODO(), errors.WithStack(err), "Failed to remove address", "address", addr)
+			return
+		}
+	}
+	listener, err := net.Listen(proto, addr)
+	if err != nil {
+		LogError(context.TODO(), errors.WithStack(err), "Failed to listen", "name", name)
+		return
+	}
+	logger(context.TODO()).Info("Serving "+name, "address", listener.Addr())
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			LogError(context.TODO(), errors.WithStack(err), "Server stopped", "name", name)
+		}
+	}()
+	<-stop
+	if err := server.Shutdown(context.TODO()); err != nil {
+		LogError(context.TODO(), errors.WithStack(err), "Failed to shut down server", "name", name)
+	}
+}
+
+// QueryVolumeDiagnostics requests diagnostics for volumeID from the diagnostics HTTP server listening at endpoint
+// and writes the JSON response to w. stagingTargetPath is optional. QueryVolumeDiagnostics is exported for use by the
+// volume-info subcommand in cmd/beegfs-csi-driver.
+func QueryVolumeDiagnostics(w io.Writer, endpoint, volumeID, stagingTargetPath string) error {
+	query := url.Values{}
+	query.Set(diagnosticsVolumeIDKey, volumeID)
+	if stagingTargetPath != "" {
+		query.Set(diagnosticsStagingTargetKey, stagingTargetPath)
+	}
+	return queryDiagnostics(w, endpoint, diagnosticsVolumesPath, query)
+}
+
+// QueryDeletionProgress requests the progress of pending volume deletions from the diagnostics HTTP server listening
+// at endpoint and writes the JSON response to w. QueryDeletionProgress is exported for use by the deletion-status
+// subcommand in cmd/beegfs-csi-driver.
+func QueryDeletionProgress(w io.Writer, endpoint string) error {
+	return queryDiagnostics(w, endpoint, diagnosticsDeletionsPath, nil)
+}
+
+// QueryVolumeList requests the metadata of the volumes the controller service finds under the volDirBasePaths of the
+// driver's StorageClasses from the diagnostics HTTP server listening at endpoint and writes the JSON response to w.
+// QueryVolumeList is exported for use by the list-volumes subcommand in cmd/beegfs-csi-driver.
+func QueryVolumeList(w io.Writer, endpoint string) error {
+	return queryDiagnostics(w, endpoint, diagnosticsVolumeListPath, nil)
+}
+
+// QueryRetainedVolumes requests the list of retained volumes from the diagnostics HTTP server listening at endpoint
+// and writes the JSON response to w. QueryRetainedVolumes is exported for use by the retained-volumes subcommand in
+// cmd/beegfs-csi-driver.
+func QueryRetainedVolumes(w io.Writer, endpoint string) error {
+	return queryDiagnostics(w, endpoint, diagnosticsRetainedPath, nil)
+}
+
+// QueryOrphanedVolumes asks the diagnostics HTTP server listening at endpoint to scan for orphaned volumes and writes
+// the JSON report to w. QueryOrphanedVolumes is exported for use by the orphaned-volumes subcommand in
+// cmd/beegfs-csi-driver.
+func QueryOrphanedVolumes(w io.Writer, endpoint string) error {
+	return queryDiagnostics(w, endpoint, diagnosticsOrphansPath, nil)
+}
+
+// CleanUpOrphanedVolumes asks the diagnostics HTTP server listening at endpoint to delete the orphaned volumes with
+// volumeIDs (including those of other clusters if allowOtherClusters is set) and writes the JSON results to w. It
+// returns an error if any volume was not deleted. CleanUpOrphanedVolumes is exported for use by the
+// clean-up-orphaned-volumes subcommand in cmd/beegfs-csi-driver.
+func CleanUpOrphanedVolumes(w io.Writer, endpoint string, volumeIDs []string, allowOtherClusters bool) error {
+	query := url.Values{diagnosticsVolumeIDKey: volumeIDs}
+	if allowOtherClusters {
+		query.Set(diagnosticsOtherClustersKey, "true")
+	}
+	body, err := requestDiagnostics(http.MethodPost, endpoint, diagnosticsOrphanCleanupPath, query)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return errors.WithStack(err)
+	}
+	var results []orphanCleanupResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		return errors.Wrap(err, "failed to parse diagnostics response")
+	}
+	for _, result := range results {
+		if !result.Deleted {
+			return errors.Errorf("failed to clean up %s: %s", result.VolumeID, result.Error)
+		}
+	}
+	return nil
+}
+
+// RestoreRetainedVolume asks the diagnostics HTTP server listening at endpoint to restore the retained volume
+// retainedVolumeID to the directory volumeName in its volDirBasePath (or to its original directory if volumeName is
+// empty). It writes a manifest for a PersistentVolume of the driver driverName that refers to the restored volume to
+// w. RestoreRetainedVolume is exported for use by the restore-volume subcommand in cmd/beegfs-csi-driver.
+func RestoreRetainedVolume(w io.Writer, endpoint, driverName, retainedVolumeID, volumeName string) error {
+	query := url.Values{}
+	query.Set(diagnosticsRetainedIDKey, retainedVolumeID)
+	if volumeName != "" {
+		query.Set(diagnosticsVolumeNameKey, volumeName)
+	}
+	body, err := requestDiagnostics(http.MethodPost, endpoint, diagnosticsRestorePath, query)
+	if err != nil {
+		return err
+	}
+	var metadata volumeMetadata
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return errors.Wrap(err, "failed to parse diagnostics response")
+	}
+	pv, err := newRestoredPersistentVolume(driverName, metadata)
+	if err != nil {
+		return err
+	}
+	manifest, err := yaml.Marshal(pv)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.Write(manifest)
+	return errors.WithStack(err)
+}
+
+// queryDiagnostics makes a GET request for urlPath with query to the diagnostics HTTP server listening at endpoint and
+// writes the response to w.
+func queryDiagnostics(w io.Writer, endpoint, urlPath string, query url.Values) error {
+	body, err := requestDiagnostics(http.MethodGet, endpoint, urlPath, query)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return errors.WithStack(err)
+}
+
+// requestDiagnostics makes a method request for urlPath with query to the diagnostics HTTP server listening at
+// endpoint and returns the response body.
+func requestDiagnostics(method, endpoint, urlPath string, query url.Values) ([]byte, error) {
+	proto, addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	host := addr
+	if proto == "unix" {
+		addr = "/" + addr
+		host = "localhost" // The host is irrelevant when dialing a unix socket, but it must be valid in a URL.
+	}
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, proto, addr)
+			},
+		},
+	}
+
+	reqURL := url.URL{Scheme: "http", Host: host, Path: urlPath, RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, reqURL.String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query diagnostics endpoint")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read diagnostics response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("diagnostics request failed with status %s: %s", resp.Status,
+			strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/spf13/afero"
+	"k8s.io/mount-utils"
+)
+
+func TestGetVolumeDiagnostics(t *testing.T) {
+	fs = afero.NewMemMapFs() // Set up a new memory-mapped file system.
+	fsutil = afero.Afero{Fs: fs}
+	const (
+		sysMgmtdHost     = "127.0.0.1"
+		volumeID         = "beegfs://127.0.0.1/scratch/pvc-12345678"
+		confTemplatePath = "/etc/beegfs/beegfs-client.conf"
+	)
+	pluginConfig := beegfsv1.PluginConfig{
+		DefaultConfig: beegfsv1.BeegfsConfig{
+			ConnInterfaces: []string{"ib0"},
+		},
+		FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+			{
+				SysMgmtdHost: sysMgmtdHost,
+				Config: beegfsv1.BeegfsConfig{
+					ConnAuth: "secret1",
+				},
+			},
+		},
+	}
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+
+	// Set up a volume staged the way the kubelet and NodeStageVolume would stage it.
+	stagingTargetPath := path.Join("/var/lib/kubelet/plugins/kubernetes.io/csi/beegfs.csi.netapp.com",
+		fmt.Sprintf("%x", sha256.Sum256([]byte(volumeID))), "globalmount")
+	stagedMountPath := path.Join(stagingTargetPath, "mount")
+	stagedMountOpts := []string{"rw", "relatime", "cfgFile=" + path.Join(stagingTargetPath, "beegfs-client.conf"), "nosuid"}
+	stagedFiles := map[string]string{
+		"beegfs-client.conf": "sysMgmtdHost = 127.0.0.1\n",
+		"connInterfacesFile": "ib1\n",
+		"connAuthFile":       "secret1\n",
+	}
+	for name, contents := range stagedFiles {
+		if err := fsutil.WriteFile(path.Join(stagingTargetPath, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write staged client file: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		mountPoints []mount.MountPoint
+		controller  bool
+		want        volumeDiagnostics
+	}{
+		"node volume staged": {
+			mountPoints: []mount.MountPoint{
+				{Device: "beegfs_nodev", Path: "/some/other/mount", Type: "beegfs"},
+				{Device: "beegfs_nodev", Path: stagedMountPath, Type: "beegfs", Opts: stagedMountOpts},
+			},
+			want: volumeDiagnostics{
+				VolumeID:     volumeID,
+				Config:       squashConfigForSysMgmtdHost(sysMgmtdHost, pluginConfig),
+				Mounted:      true,
+				MountDirPath: stagingTargetPath,
+				MountPath:    stagedMountPath,
+				MountOptions: stagedMountOpts,
+				ClientConf:   "sysMgmtdHost = 127.0.0.1\n",
+				ClientFiles: map[string]string{
+					"connInterfacesFile": "ib1\n",
+					"connAuthFile":       redactedFileContents,
+				},
+			},
+		},
+		"node volume not staged": {
+			want: volumeDiagnostics{
+				VolumeID: volumeID,
+				Config:   squashConfigForSysMgmtdHost(sysMgmtdHost, pluginConfig),
+				ClientFiles: map[string]string{
+					"connInterfacesFile": "ib0\n",
+					"connAuthFile":       redactedFileContents,
+				},
+			},
+		},
+		"controller volume not mounted": {
+			controller: true,
+			want: volumeDiagnostics{
+				VolumeID: volumeID,
+				Config:   squashConfigForSysMgmtdHost(sysMgmtdHost, pluginConfig),
+				ClientFiles: map[string]string{
+					"connInterfacesFile": "ib0\n",
+					"connAuthFile":       redactedFileContents,
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got volumeDiagnostics
+			var err error
+			if tc.controller {
+				cs := newControllerServerSanity("testID", pluginConfig, confTemplatePath, "/csDataDir", 0)
+				cs.mounter = mount.NewFakeMounter(tc.mountPoints)
+				got, err = cs.getVolumeDiagnostics(context.Background(), volumeID)
+			} else {
+				ns := newNodeServerSanity("testID", pluginConfig, confTemplatePath)
+				ns.mounter = mount.NewFakeMounter(tc.mountPoints)
+				got, err = ns.getVolumeDiagnostics(context.Background(), volumeID, "")
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !tc.want.Mounted {
+				// Generated beegfs-client.conf contents are tested elsewhere. Only verify they were generated.
+				if !strings.Contains(got.ClientConf, "sysMgmtdHost") {
+					t.Errorf("expected generated beegfs-client.conf, got: %s", got.ClientConf)
+				}
+				got.ClientConf = ""
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Fatalf("expected: %+v, got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	fs = afero.NewMemMapFs() // Set up a new memory-mapped file system.
+	fsutil = afero.Afero{Fs: fs}
+	confTemplatePath := "/etc/beegfs/beegfs-client.conf"
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	pluginConfig := beegfsv1.PluginConfig{
+		FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+			{SysMgmtdHost: "127.0.0.1", Config: beegfsv1.BeegfsConfig{ConnAuth: "secret1"}},
+		},
+	}
+	cs := newControllerServerSanity("testID", pluginConfig, confTemplatePath, "/csDataDir", 0)
+	handler := newDiagnosticsHandler(cs, newNodeServerSanity("testID", pluginConfig, confTemplatePath), true)
+
+	tests := map[string]struct {
+		query      string
+		wantStatus int
+	}{
+		"valid volumeID":   {query: "?volumeID=beegfs://127.0.0.1/scratch/pvc-12345678", wantStatus: http.StatusOK},
+		"missing volumeID": {query: "", wantStatus: http.StatusBadRequest},
+		"invalid volumeID": {query: "?volumeID=nfs://127.0.0.1/scratch", wantStatus: http.StatusBadRequest},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, diagnosticsVolumesPath+tc.query, nil))
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			if strings.Contains(recorder.Body.String(), "secret1") {
+				t.Fatalf("expected secrets to be redacted, got: %s", recorder.Body.String())
+			}
+			var resp volumeDiagnosticsResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Controller == nil || resp.Node == nil {
+				t.Fatalf("expected controller and node diagnostics, got: %s", recorder.Body.String())
+			}
+		})
+	}
+
+	for _, urlPath := range []string{diagnosticsDeletionsPath, diagnosticsMetricsPath, diagnosticsRetainedPath} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, urlPath, nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("expected status %d from %s, got %d: %s", http.StatusOK, urlPath, recorder.Code,
+				recorder.Body.String())
+		}
+	}
+
+	restoreTests := map[string]struct {
+		method     string
+		query      string
+		wantStatus int
+	}{
+		"GET":                      {method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+		"missing retainedVolumeID": {method: http.MethodPost, wantStatus: http.StatusBadRequest},
+		"not a retained volume":    {method: http.MethodPost, query: "?retainedVolumeID=beegfs://127.0.0.1/scratch/pvc-12345678", wantStatus: http.StatusBadRequest},
+	}
+	for name, tc := range restoreTests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(tc.method, diagnosticsRestorePath+tc.query, nil))
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	// Requests that change volumes are rejected unless the diagnostics endpoint is a Unix socket.
+	handler = newDiagnosticsHandler(cs, nil, false)
+	for _, urlPath := range []string{
+		diagnosticsRestorePath + "?retainedVolumeID=beegfs://127.0.0.1/scratch/pvc-12345678",
+		diagnosticsOrphanCleanupPath + "?volumeID=beegfs://127.0.0.1/scratch/pvc-12345678",
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, urlPath, nil))
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("expected status %d from %s, got %d: %s", http.StatusForbidden, urlPath, recorder.Code,
+				recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Most driver functionality is orchestrator agnostic and does not require access to the Kubernetes API server. The
+// functions in this file are only called when optional configuration requires information that only Kubernetes can
+// provide (e.g. the labels of the node the driver is running on).
+
+// getNodeLabels returns the labels of the named node. It is a variable so unit tests can run without access to a
+// Kubernetes API server.
+var getNodeLabels = getNodeLabelsFromAPIServer
+
+// patchPodAnnotation sets a single annotation on the named Pod. It is a variable so unit tests can run without access
+// to a Kubernetes API server.
+var patchPodAnnotation = patchPodAnnotationOnAPIServer
+
+// getPVCStorageClassName returns the name of the StorageClass of the named PersistentVolumeClaim. It is a variable so
+// unit tests can run without access to a Kubernetes API server.
+var getPVCStorageClassName = getPVCStorageClassNameFromAPIServer
+
+// listStorageClassParameters returns the parameters of each StorageClass whose provisioner is driverName. It is a
+// variable so unit tests can run without access to a Kubernetes API server.
+var listStorageClassParameters = listStorageClassParametersFromAPIServer
+
+// listPersistentVolumeHandles returns the volume handles of the PersistentVolumes of driverName. It is a variable so
+// unit tests can run without access to a Kubernetes API server.
+var listPersistentVolumeHandles = listPersistentVolumeHandlesFromAPIServer
+
+// newKubernetesClientset returns a clientset configured from the service account Kubernetes mounts into the driver's
+// Pods. It returns an error if the driver is not running in a Kubernetes Pod.
+func newKubernetesClientset() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load in-cluster Kubernetes configuration")
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Kubernetes clientset")
+	}
+	return clientset, nil
+}
+
+// getNodeLabelsFromAPIServer reads the Node object for nodeName from the Kubernetes API server and returns its labels.
+func getNodeLabelsFromAPIServer(ctx context.Context, nodeName string) (map[string]string, error) {
+	clientset, err := newKubernetesClientset()
+	if err != nil {
+		return nil, err
+	}
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get Node %s", nodeName)
+	}
+	return node.Labels, nil
+}
+
+// patchPodAnnotationOnAPIServer uses a merge patch to set a single annotation on the named Pod without modifying any
+// of its other annotations.
+func patchPodAnnotationOnAPIServer(ctx context.Context, namespace, name, key, value string) error {
+	clientset, err := newKubernetesClientset()
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = clientset.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch,
+		metav1.PatchOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to patch Pod %s/%s", namespace, name)
+	}
+	return nil
+}
+
+// getPVCStorageClassNameFromAPIServer reads the named PersistentVolumeClaim from the Kubernetes API server and returns
+// the name of its StorageClass.
+func getPVCStorageClassNameFromAPIServer(ctx context.Context, namespace, name string) (string, error) {
+	clientset, err := newKubernetesClientset()
+	if err != nil {
+		return "", err
+	}
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get PersistentVolumeClaim %s/%s", namespace, name)
+	}
+	if pvc.Spec.StorageClassName == nil {
+		return "", nil
+	}
+	return *pvc.Spec.StorageClassName, nil
+}
+
+// listStorageClassParametersFromAPIServer lists the StorageClasses on the Kubernetes API server and returns the
+// parameters of each StorageClass whose provisioner is driverName.
+func listStorageClassParametersFromAPIServer(ctx context.Context, driverName string) ([]map[string]string, error) {
+	clientset, err := newKubernetesClientset()
+	if err != nil {
+		return nil, err
+	}
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list StorageClasses")
+	}
+	var parameters []map[string]string
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Provisioner == driverName {
+			parameters = append(parameters, storageClass.Parameters)
+		}
+	}
+	return parameters, nil
+}
+
+// listPersistentVolumeHandlesFromAPIServer lists the PersistentVolumes on the Kubernetes API server and returns the
+// volume handles of those provisioned by (or statically bound to) driverName.
+func listPersistentVolumeHandlesFromAPIServer(ctx context.Context, driverName string) (map[string]bool, error) {
+	clientset, err := newKubernetesClientset()
+	if err != nil {
+		return nil, err
+	}
+	pvs, err := clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list PersistentVolumes")
+	}
+	handles := make(map[string]bool)
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == driverName {
+			handles[pv.Spec.CSI.VolumeHandle] = true
+		}
+	}
+	return handles, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are served at diagnosticsMetricsPath by the metrics endpoint (--metrics-endpoint), which serves nothing else
+// and is meant to be scraped by Prometheus over TCP. The diagnostics endpoint (--diagnostics-endpoint) serves them too.
+
+// metricsRegistry contains every metric the driver exports.
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	volumeDeletionsPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "beegfs_csi_driver_volume_deletions_pending",
+		Help: "Number of deleted volumes whose directories are still being removed from the trash.",
+	})
+	volumeDeletionsCompletedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "beegfs_csi_driver_volume_deletions_completed_total",
+		Help: "Number of deleted volume directories completely removed from the trash.",
+	})
+	volumeDeletionFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "beegfs_csi_driver_volume_deletion_failures_total",
+		Help: "Number of attempts to remove a deleted volume directory from the trash that failed (and will be retried).",
+	})
+	volumeDeletionEntriesRemovedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "beegfs_csi_driver_volume_deletion_entries_removed_total",
+		Help: "Number of files and directories removed from the trash.",
+	})
+	volumesRetained = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "beegfs_csi_driver_volumes_retained",
+		Help: "Number of deleted volumes retained until their retention periods end.",
+	})
+	retainedVolumesPurgedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "beegfs_csi_driver_retained_volumes_purged_total",
+		Help: "Number of retained volumes moved to the trash because their retention periods ended.",
+	})
+	orphanedVolumes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "beegfs_csi_driver_orphaned_volumes",
+		Help: "Number of volumes without a PersistentVolume found by the last orphaned volume scan.",
+	})
+	orphanedVolumeBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "beegfs_csi_driver_orphaned_volume_bytes",
+		Help: "Total size of the files in the volumes without a PersistentVolume found by the last orphaned volume scan.",
+	})
+	orphanedVolumesCleanedUpTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "beegfs_csi_driver_orphaned_volumes_cleaned_up_total",
+		Help: "Number of orphaned volumes deleted (or retained) at an administrator's request.",
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(volumeDeletionsPending, volumeDeletionsCompletedTotal, volumeDeletionFailuresTotal,
+		volumeDeletionEntriesRemovedTotal, volumesRetained, retainedVolumesPurgedTotal, orphanedVolumes,
+		orphanedVolumeBytes, orphanedVolumesCleanedUpTotal)
+}
+
+// EnableMetrics configures the driver to serve its metrics (and nothing else) at endpoint (e.g. tcp://0.0.0.0:9809).
+// It must be called before Run.
+func (b *beegfs) EnableMetrics(endpoint string) error {
+	if _, _, err := parseEndpoint(endpoint); err != nil {
+		return errors.WithMessage(err, "invalid metrics endpoint")
+	}
+	b.metricsEndpoint = endpoint
+	return nil
+}
+
+// newMetricsHandler returns an http.Handler that serves the driver's metrics at diagnosticsMetricsPath.
+func newMetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(diagnosticsMetricsPath, promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// serveMetrics serves the driver's metrics over HTTP at endpoint until stop is closed.
+func serveMetrics(endpoint string, stop <-chan struct{}) {
+	serveHTTP(endpoint, "metrics", newMetricsHandler(), stop)
+}
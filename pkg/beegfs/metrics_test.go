@@ -0,0 +1,35 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	handler := newMetricsHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, diagnosticsMetricsPath, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), "beegfs_csi_driver_volume_deletions_pending") {
+		t.Errorf("expected driver metrics, got: %s", recorder.Body.String())
+	}
+
+	// The metrics endpoint may be reachable over the network, so it must not serve anything else.
+	for _, urlPath := range []string{diagnosticsVolumesPath, diagnosticsRestorePath, diagnosticsOrphanCleanupPath} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, urlPath, nil))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("expected status %d from %s, got %d", http.StatusNotFound, urlPath, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"k8s.io/mount-utils"
+)
+
+// The controller service runs every operation on a file system (beegfs-ctl commands and OS tools alike) against a
+// long-lived mount of that file system instead of writing client files and mounting for every request. It keeps one
+// mount per file system and configuration in a subdirectory of its mount cache directory (csDataDir/.mounts) and
+// unmounts it once it has gone unused for the mount idle timeout (immediately if the timeout is zero). It also
+// periodically checks that idle mounts are still healthy and unmounts those that are not, so the next request that
+// needs the file system mounts it again. Every request verifies that the mount is still there before it uses it, so a
+// request never runs against the bare mount point directory of a file system that was unmounted behind our back.
+
+const (
+	// mountCacheDirName is the directory in csDataDir the controller service keeps its cached mounts in.
+	mountCacheDirName = ".mounts"
+	// defaultMountIdleTimeout is how long a cached mount may go unused before the controller service unmounts it.
+	defaultMountIdleTimeout = 5 * time.Minute
+	// mountCacheCheckInterval is how often the controller service checks its cached mounts.
+	mountCacheCheckInterval = 30 * time.Second
+)
+
+// mountHealthCheckTimeout is how long the controller service waits for a cached mount to respond to a health check. It
+// is a variable so tests can shorten it.
+var mountHealthCheckTimeout = 10 * time.Second
+
+// mountCache tracks the controller service's cached mounts.
+type mountCache struct {
+	dirPath                string
+	clientConfTemplatePath string
+	mounter                mount.Interface
+	idleTimeout            time.Duration
+	mutex                  sync.Mutex // guards mounts and the users and lastUsed fields of every cachedMount
+	mounts                 map[string]*cachedMount
+}
+
+// cachedMount is a single cached mount. Entries are never removed from mountCache.mounts, so a goroutine waiting on
+// a cachedMount's mutex never holds a stale entry.
+type cachedMount struct {
+	vol      beegfsVolume // represents the root of the file system (e.g. vol.volDirPath is vol.mountPath)
+	mutex    sync.Mutex   // serializes mounting, checking, and unmounting; acquire mountCache.mutex only after this
+	mounted  bool         // guarded by mutex
+	users    int          // number of requests using the mount
+	lastUsed time.Time    // time the last request using the mount released it
+}
+
+// SetMountIdleTimeout configures how long the controller service keeps a file system mounted after the last request
+// that used it completes. If timeout is zero, the controller service unmounts a file system as soon as no request is
+// using it. It must be called before Run.
+func (b *beegfs) SetMountIdleTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return errors.Errorf("invalid mount idle timeout %s", timeout)
+	}
+	b.cs.mountCache.idleTimeout = timeout
+	return nil
+}
+
+// newMountCache returns an empty mountCache that keeps its mounts in subdirectories of dirPath. It does not create
+// dirPath until it mounts a file system.
+func newMountCache(dirPath, clientConfTemplatePath string, mounter mount.Interface) *mountCache {
+	return &mountCache{
+		dirPath:                dirPath,
+		clientConfTemplatePath: clientConfTemplatePath,
+		mounter:                mounter,
+		idleTimeout:            defaultMountIdleTimeout,
+		mounts:                 make(map[string]*cachedMount),
+	}
+}
+
+// acquire mounts vol's file system if it is not already mounted and returns a copy of vol (including its config) whose
+// paths refer to the cached mount. The caller must call release once it no longer needs the mount. Like the
+// controller service RPCs, acquire returns a gRPC error.
+func (c *mountCache) acquire(ctx context.Context, vol beegfsVolume) (cachedVol beegfsVolume, release func(),
+	err error) {
+	key, err := getMountKey(vol, nil)
+	if err != nil {
+		return beegfsVolume{}, nil, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	c.mutex.Lock()
+	m, ok := c.mounts[key]
+	if !ok {
+		rootVol := newBeegfsVolume(path.Join(c.dirPath, key), vol.sysMgmtdHost, "/", beegfsv1.PluginConfig{})
+		rootVol.config = vol.config
+		m = &cachedMount{vol: rootVol}
+		c.mounts[key] = m
+	}
+	m.users++
+	c.mutex.Unlock()
+	release = func() { c.release(ctx, m) }
+
+	m.mutex.Lock()
+	err = c.ensureMounted(ctx, m)
+	m.mutex.Unlock()
+	if err != nil {
+		release()
+		return beegfsVolume{}, nil, newGrpcErrorFromCause(codes.Internal, err)
+	}
+
+	cachedVol = newBeegfsVolume(m.vol.mountDirPath, vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, beegfsv1.PluginConfig{})
+	cachedVol.config = vol.config
+	return cachedVol, release, nil
+}
+
+// release records that a request no longer uses m. It unmounts m right away if the mount idle timeout is zero.
+func (c *mountCache) release(ctx context.Context, m *cachedMount) {
+	c.mutex.Lock()
+	m.users--
+	m.lastUsed = time.Now()
+	c.mutex.Unlock()
+	if c.idleTimeout == 0 {
+		c.checkMount(ctx, m)
+	}
+}
+
+// ensureMounted writes client files for and mounts m if it is not already mounted. It mounts m again if it was
+// unmounted by someone else (e.g. an administrator or a reload of the BeeGFS client module) and fails if m does not
+// respond. The caller must hold m.mutex.
+func (c *mountCache) ensureMounted(ctx context.Context, m *cachedMount) error {
+	if m.mounted {
+		notMnt, err := c.probeMount(m)
+		if err != nil && os.IsNotExist(errors.Cause(err)) {
+			notMnt, err = true, nil
+		}
+		if err != nil {
+			// Other requests may still be using m. checkMount unmounts it once they are done.
+			return errors.WithMessagef(err, "cached mount of %s is unhealthy", m.vol.sysMgmtdHost)
+		}
+		if !notMnt {
+			return nil
+		}
+		LogError(ctx, errors.Errorf("%s is not mounted", m.vol.mountPath), "Mounting cached mount again")
+		m.mounted = false
+	}
+	err := fs.MkdirAll(m.vol.mountDirPath, 0750)
+	if err == nil {
+		err = writeClientFiles(ctx, m.vol, c.clientConfTemplatePath)
+	}
+	if err == nil {
+		err = mountIfNecessary(ctx, m.vol, []string{}, c.mounter)
+	}
+	if err != nil {
+		// Don't leave client files (or a partial mount) behind.
+		if cleanupErr := unmountAndCleanUpIfNecessary(ctx, m.vol, true, c.mounter); cleanupErr != nil {
+			LogError(ctx, cleanupErr, "Failed to clean up cached mount", "path", m.vol.mountDirPath)
+		}
+		return errors.WithStack(err)
+	}
+	m.mounted = true
+	return nil
+}
+
+// findMountDirPath returns the mountDirPath of a cached mount of the file system at sysMgmtdHost that is currently
+// mounted. It returns false if there is none.
+func (c *mountCache) findMountDirPath(sysMgmtdHost string) (string, bool) {
+	c.mutex.Lock()
+	mounts := make([]*cachedMount, 0, len(c.mounts))
+	for _, m := range c.mounts {
+		if m.vol.sysMgmtdHost == sysMgmtdHost {
+			mounts = append(mounts, m)
+		}
+	}
+	c.mutex.Unlock()
+	for _, m := range mounts {
+		m.mutex.Lock()
+		mounted := m.mounted
+		m.mutex.Unlock()
+		if mounted {
+			return m.vol.mountDirPath, true
+		}
+	}
+	return "", false
+}
+
+// checkMountsPeriodically calls checkMounts every mountCacheCheckInterval until stop is closed.
+func (c *mountCache) checkMountsPeriodically(stop <-chan struct{}) {
+	ticker := time.NewTicker(mountCacheCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.checkMounts(context.TODO())
+		}
+	}
+}
+
+// checkMounts checks every cached mount in parallel, so a mount that hangs (e.g. while we unmount it) does not hold up
+// the others. It skips mounts that are busy (e.g. still being unmounted since the last check).
+func (c *mountCache) checkMounts(ctx context.Context) {
+	c.mutex.Lock()
+	mounts := make([]*cachedMount, 0, len(c.mounts))
+	for _, m := range c.mounts {
+		mounts = append(mounts, m)
+	}
+	c.mutex.Unlock()
+	var wg sync.WaitGroup
+	for _, m := range mounts {
+		if !m.mutex.TryLock() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer m.mutex.Unlock()
+			c.checkMountLocked(ctx, m)
+		}()
+	}
+	wg.Wait()
+}
+
+// checkMount unmounts m if no request is using it and it has been idle for at least the mount idle timeout or it is
+// no longer healthy.
+func (c *mountCache) checkMount(ctx context.Context, m *cachedMount) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	c.checkMountLocked(ctx, m)
+}
+
+// checkMountLocked is checkMount for a caller that holds m.mutex.
+func (c *mountCache) checkMountLocked(ctx context.Context, m *cachedMount) {
+	if !m.mounted {
+		return
+	}
+	// A request that acquires m after we read users waits for m.mutex and mounts again if we unmount.
+	c.mutex.Lock()
+	users, idle := m.users, time.Since(m.lastUsed)
+	c.mutex.Unlock()
+	if users > 0 {
+		return
+	}
+	if idle >= c.idleTimeout {
+		LogDebug(ctx, "Unmounting idle cached mount", "path", m.vol.mountPath, "idle", idle.Round(time.Second).String())
+	} else if err := c.checkHealth(m); err != nil {
+		LogError(ctx, err, "Unmounting unhealthy cached mount", "path", m.vol.mountPath)
+	} else {
+		return
+	}
+	if err := unmountAndCleanUpIfNecessary(ctx, m.vol, true, c.mounter); err != nil {
+		LogError(ctx, err, "Failed to unmount cached mount", "path", m.vol.mountPath)
+	}
+	// Even if we failed to clean up, the next request to acquire m verifies that it is mounted before using it.
+	m.mounted = false
+}
+
+// checkHealth returns an error if m's mount point is no longer mounted or does not respond within
+// mountHealthCheckTimeout (e.g. because the file system's servers are unreachable).
+func (c *mountCache) checkHealth(m *cachedMount) error {
+	notMnt, err := c.probeMount(m)
+	if err == nil && notMnt {
+		err = errors.Errorf("%s is not mounted", m.vol.mountPath)
+	}
+	return err
+}
+
+// probeMount reports whether m's mount point is (likely) not mounted. It returns an error if the mount point does not
+// respond within mountHealthCheckTimeout.
+func (c *mountCache) probeMount(m *cachedMount) (notMnt bool, err error) {
+	type probeResult struct {
+		notMnt bool
+		err    error
+	}
+	result := make(chan probeResult, 1)
+	go func() {
+		notMnt, err := c.mounter.IsLikelyNotMountPoint(m.vol.mountPath)
+		result <- probeResult{notMnt: notMnt, err: errors.WithStack(err)}
+	}()
+	select {
+	case r := <-result:
+		return r.notMnt, r.err
+	case <-time.After(mountHealthCheckTimeout):
+		return false, errors.Errorf("%s did not respond within %s", m.vol.mountPath, mountHealthCheckTimeout)
+	}
+}
+
+// unmountAll unmounts every mount in the mount cache directory, including any left behind by an instance of the
+// controller service that did not shut down cleanly, and removes their client files. It must not be called while
+// requests are in flight.
+func (c *mountCache) unmountAll(ctx context.Context) {
+	// Lock each cachedMount's mutex only after releasing c.mutex (see cachedMount.mutex).
+	c.mutex.Lock()
+	mounts := make([]*cachedMount, 0, len(c.mounts))
+	for _, m := range c.mounts {
+		mounts = append(mounts, m)
+	}
+	c.mutex.Unlock()
+	for _, m := range mounts {
+		m.mutex.Lock()
+		m.mounted = false
+		m.mutex.Unlock()
+	}
+
+	entries, err := fsutil.ReadDir(c.dirPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			LogError(ctx, errors.WithStack(err), "Failed to read mount cache directory", "path", c.dirPath)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		vol := newBeegfsVolume(path.Join(c.dirPath, entry.Name()), "", "/", beegfsv1.PluginConfig{})
+		LogDebug(ctx, "Unmounting cached mount", "path", vol.mountPath)
+		if err := unmountAndCleanUpIfNecessary(ctx, vol, true, c.mounter); err != nil {
+			LogError(ctx, err, "Failed to unmount cached mount", "path", vol.mountPath)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/spf13/afero"
+	"k8s.io/mount-utils"
+)
+
+// newTestMountCache returns a mountCache in a temporary directory that uses a FakeMounter.
+func newTestMountCache(t *testing.T) (*mountCache, *mount.FakeMounter) {
+	fs = afero.NewOsFs()
+	fsutil = afero.Afero{Fs: fs}
+	tempDir := t.TempDir()
+	confTemplatePath := path.Join(tempDir, "beegfs-client.conf")
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	mounter := mount.NewFakeMounter(nil)
+	return newMountCache(path.Join(tempDir, mountCacheDirName), confTemplatePath, mounter), mounter
+}
+
+// hangingMounter is a FakeMounter whose mount point at hangPath stops responding once hang is called (like a BeeGFS
+// mount whose servers are unreachable) until unhang is called.
+type hangingMounter struct {
+	*mount.FakeMounter
+	hangPath string
+	mutex    sync.Mutex
+	hung     chan struct{}
+	waiting  int // number of calls waiting for the mount point at hangPath to respond
+}
+
+func (m *hangingMounter) hang() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.hung = make(chan struct{})
+}
+
+func (m *hangingMounter) unhang() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	close(m.hung)
+	m.hung = nil
+}
+
+func (m *hangingMounter) getWaiting() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.waiting
+}
+
+func (m *hangingMounter) IsLikelyNotMountPoint(file string) (bool, error) {
+	m.mutex.Lock()
+	hung := m.hung
+	if hung != nil && file == m.hangPath {
+		m.waiting++
+	}
+	m.mutex.Unlock()
+	if hung != nil && file == m.hangPath {
+		<-hung
+	}
+	return m.FakeMounter.IsLikelyNotMountPoint(file)
+}
+
+// countMounts returns the number of file systems mounted in the mount cache directory.
+func countMounts(t *testing.T, c *mountCache, mounter *mount.FakeMounter) int {
+	mountPoints, err := mounter.List()
+	if err != nil {
+		t.Fatalf("failed to list mounts: %v", err)
+	}
+	count := 0
+	for _, mountPoint := range mountPoints {
+		if path.Dir(path.Dir(mountPoint.Path)) == c.dirPath {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMountCacheAcquire(t *testing.T) {
+	c, mounter := newTestMountCache(t)
+	ctx := context.Background()
+	vol1 := newBeegfsVolume("/csDataDir/vol1", "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	vol2 := newBeegfsVolume("/csDataDir/vol2", "127.0.0.1", "/scratch/pvc-2", beegfsv1.PluginConfig{})
+	otherFS := newBeegfsVolume("/csDataDir/vol3", "127.0.0.2", "/scratch/pvc-3", beegfsv1.PluginConfig{})
+
+	cachedVol1, release1, err := c.acquire(ctx, vol1)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	cachedVol2, release2, err := c.acquire(ctx, vol2)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cachedVol1.mountPath != cachedVol2.mountPath {
+		t.Errorf("expected volumes on the same file system to share a mount, got: %s and %s", cachedVol1.mountPath,
+			cachedVol2.mountPath)
+	}
+	if cachedVol1.volumeID != vol1.volumeID || cachedVol1.volDirPath != path.Join(cachedVol1.mountPath, "scratch",
+		"pvc-1") {
+		t.Errorf("expected cached volume to refer to %s, got: %s at %s", vol1.volumeID, cachedVol1.volumeID,
+			cachedVol1.volDirPath)
+	}
+	if exists, _ := fsutil.Exists(cachedVol1.clientConfPath); !exists {
+		t.Errorf("expected client files to be written to %s", cachedVol1.mountDirPath)
+	}
+	_, releaseOther, err := c.acquire(ctx, otherFS)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if count := countMounts(t, c, mounter); count != 2 {
+		t.Fatalf("expected 2 cached mounts, got: %d", count)
+	}
+
+	// With an idle timeout of zero, a mount goes away as soon as the last request releases it.
+	c.idleTimeout = 0
+	release1()
+	releaseOther()
+	if count := countMounts(t, c, mounter); count != 1 {
+		t.Fatalf("expected 1 cached mount, got: %d", count)
+	}
+	release2()
+	if count := countMounts(t, c, mounter); count != 0 {
+		t.Fatalf("expected no cached mounts, got: %d", count)
+	}
+	if exists, _ := fsutil.Exists(cachedVol1.mountDirPath); exists {
+		t.Errorf("expected %s to be removed", cachedVol1.mountDirPath)
+	}
+
+	// The file system is mounted again when it is next needed.
+	if _, release1, err = c.acquire(ctx, vol1); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release1()
+	if count := countMounts(t, c, mounter); count != 1 {
+		t.Fatalf("expected 1 cached mount, got: %d", count)
+	}
+}
+
+func TestMountCacheAcquireVanishedMount(t *testing.T) {
+	c, mounter := newTestMountCache(t)
+	ctx := context.Background()
+	vol := newBeegfsVolume("/csDataDir/vol", "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	cachedVol, release, err := c.acquire(ctx, vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+
+	// Simulate a mount that disappeared out from under a busy controller service. checkMounts leaves mounts that are
+	// in use alone, so the next request must notice on its own.
+	if err := mounter.Unmount(cachedVol.mountPath); err != nil {
+		t.Fatalf("failed to unmount: %v", err)
+	}
+	c.checkMounts(ctx)
+	if _, release, err = c.acquire(ctx, vol); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+	if count := countMounts(t, c, mounter); count != 1 {
+		t.Fatalf("expected 1 cached mount, got: %d", count)
+	}
+}
+
+func TestMountCacheHungMount(t *testing.T) {
+	defer func(timeout time.Duration) { mountHealthCheckTimeout = timeout }(mountHealthCheckTimeout)
+	mountHealthCheckTimeout = 10 * time.Millisecond
+	c, fakeMounter := newTestMountCache(t)
+	ctx := context.Background()
+	hungVol := newBeegfsVolume("/csDataDir/vol1", "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	otherVol := newBeegfsVolume("/csDataDir/vol2", "127.0.0.2", "/scratch/pvc-2", beegfsv1.PluginConfig{})
+	cachedHungVol, releaseHung, err := c.acquire(ctx, hungVol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	cachedOtherVol, releaseOther, err := c.acquire(ctx, otherVol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	releaseOther()
+	mounter := &hangingMounter{FakeMounter: fakeMounter, hangPath: cachedHungVol.mountPath}
+	c.mounter = mounter
+	mounter.hang()
+
+	// A request must not use a mount that does not respond.
+	if _, _, err := c.acquire(ctx, hungVol); err == nil {
+		t.Fatalf("expected an error acquiring a hung mount")
+	}
+
+	// Unmounting the hung mount (which hangs too) must not hold up checking the other mount.
+	releaseHung()
+	if err := fakeMounter.Unmount(cachedOtherVol.mountPath); err != nil {
+		t.Fatalf("failed to unmount: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		c.checkMounts(ctx)
+		close(done)
+	}()
+	// Wait until the failed request's health check, checkMounts' health check, and checkMounts' unmount all hang.
+	deadline := time.Now().Add(5 * time.Second)
+	for mounter.getWaiting() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected checkMounts to try to unmount the hung mount")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for exists, _ := fsutil.Exists(cachedOtherVol.mountDirPath); exists; exists, _ = fsutil.Exists(
+		cachedOtherVol.mountDirPath) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected unhealthy mount %s to be cleaned up while another mount hangs",
+				cachedOtherVol.mountDirPath)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mounter.unhang()
+	<-done
+	if count := countMounts(t, c, fakeMounter); count != 0 {
+		t.Fatalf("expected no cached mounts, got: %d", count)
+	}
+}
+
+func TestMountCacheCheckMounts(t *testing.T) {
+	tests := map[string]struct {
+		inUse       bool
+		idleTimeout time.Duration
+		unhealthy   bool
+		wantMounted bool
+	}{
+		"idle timeout not reached": {idleTimeout: time.Hour, wantMounted: true},
+		"idle timeout reached":     {idleTimeout: time.Nanosecond},
+		"unhealthy":                {idleTimeout: time.Hour, unhealthy: true},
+		"in use":                   {inUse: true, idleTimeout: time.Nanosecond, wantMounted: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, mounter := newTestMountCache(t)
+			ctx := context.Background()
+			vol := newBeegfsVolume("/csDataDir/vol", "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+			cachedVol, release, err := c.acquire(ctx, vol)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !tc.inUse {
+				release()
+			}
+			if tc.unhealthy {
+				// Simulate a mount that disappeared out from under us.
+				if err := mounter.Unmount(cachedVol.mountPath); err != nil {
+					t.Fatalf("failed to unmount: %v", err)
+				}
+			}
+			c.idleTimeout = tc.idleTimeout
+			time.Sleep(time.Millisecond)
+
+			c.checkMounts(ctx)
+			if mounted := countMounts(t, c, mounter) == 1; mounted != tc.wantMounted {
+				t.Fatalf("expected mounted: %t", tc.wantMounted)
+			}
+			if exists, _ := fsutil.Exists(cachedVol.mountDirPath); exists != tc.wantMounted {
+				t.Fatalf("expected %s to exist: %t", cachedVol.mountDirPath, tc.wantMounted)
+			}
+
+			// Whatever happened, the file system is mounted when it is next needed.
+			if _, release, err = c.acquire(ctx, vol); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			defer release()
+			if count := countMounts(t, c, mounter); count != 1 {
+				t.Fatalf("expected 1 cached mount, got: %d", count)
+			}
+		})
+	}
+}
+
+func TestMountCacheUnmountAll(t *testing.T) {
+	c, mounter := newTestMountCache(t)
+	ctx := context.Background()
+	vol := newBeegfsVolume("/csDataDir/vol", "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	_, release, err := c.acquire(ctx, vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	release()
+
+	// Simulate a mount left behind by a previous instance of the controller service.
+	orphan := newBeegfsVolume(path.Join(c.dirPath, "orphan"), "127.0.0.2", "/", beegfsv1.PluginConfig{})
+	if err := fs.MkdirAll(orphan.mountPath, 0750); err != nil {
+		t.Fatalf("failed to create mount point: %v", err)
+	}
+	if err := mounter.Mount("beegfs_nodev", orphan.mountPath, "beegfs",
+		[]string{"cfgFile=" + orphan.clientConfPath}); err != nil {
+		t.Fatalf("failed to mount: %v", err)
+	}
+	if count := countMounts(t, c, mounter); count != 2 {
+		t.Fatalf("expected 2 cached mounts, got: %d", count)
+	}
+
+	c.unmountAll(ctx)
+	if count := countMounts(t, c, mounter); count != 0 {
+		t.Fatalf("expected no cached mounts, got: %d", count)
+	}
+	if entries, _ := fsutil.ReadDir(c.dirPath); len(entries) != 0 {
+		t.Errorf("expected mount cache directory to be empty, got %d entries", len(entries))
+	}
+
+	// The cache still works after unmountAll (e.g. if it is called at startup).
+	if _, release, err = c.acquire(ctx, vol); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	release()
+	if count := countMounts(t, c, mounter); count != 1 {
+		t.Fatalf("expected 1 cached mount, got: %d", count)
+	}
+}
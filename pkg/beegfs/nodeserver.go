@@ -46,6 +46,7 @@ type nodeServer struct {
 	pluginConfig           beegfsv1.PluginConfig
 	clientConfTemplatePath string
 	mounter                mount.Interface
+	sharedMounts           *sharedMountTable // nil unless shared mount mode is enabled
 	csi.UnimplementedNodeServer
 }
 
@@ -100,6 +101,15 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	if err != nil {
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
+	sourcePath := vol.volDirPath
+	if key, ok := ns.sharedMounts.findKey(stagingTargetPath); ok {
+		// The volume was staged in shared mount mode. Its volDirPath is bind mounted into the staging target path from
+		// a shared mount (which has the client files).
+		if vol, err = newBeegfsVolumeFromID(ns.sharedMounts.getMountDirPath(key), volumeID, ns.pluginConfig); err != nil {
+			return nil, newGrpcErrorFromCause(codes.Internal, err)
+		}
+		sourcePath = path.Join(stagingTargetPath, sharedStagingDirName)
+	}
 
 	// Only continue if our target directory exists. Check using beegfs-ctl instead of something more straightforward
 	// (e.g. fs.Stat(vol.volDirPath)) because beegfs-ctl is easier to mock for sanity tests. Client files are already
@@ -146,8 +156,8 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		opts = append(opts, "ro")
 	}
 	opts = removeInvalidMountOptions(ctx, opts)
-	LogDebug(ctx, "Mounting volume", "volDirPath", vol.volDirPath, "targetPath", targetPath, "options", opts)
-	err = ns.mounter.Mount(vol.volDirPath, targetPath, "beegfs", opts)
+	LogDebug(ctx, "Mounting volume", "volDirPath", sourcePath, "targetPath", targetPath, "options", opts)
+	err = ns.mounter.Mount(sourcePath, targetPath, "beegfs", opts)
 	if err != nil {
 		err = errors.WithStack(err)
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
@@ -197,6 +207,9 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	if err != nil {
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
+	if err := overrideConfigWithSecrets(ctx, vol.sysMgmtdHost, &vol.config, req.GetSecrets()); err != nil {
+		return nil, newGrpcErrorFromCause(codes.InvalidArgument, err)
+	}
 
 	// Ensure mountDirPath already exists (CO should have created req.StagingTargetPath).
 	_, err = fs.Stat(vol.mountDirPath)
@@ -205,6 +218,13 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
 
+	if ns.sharedMounts != nil {
+		if err := ns.stageSharedVolume(ctx, vol, volCap.GetMount().MountFlags); err != nil {
+			return nil, err
+		}
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
 	// Write configuration files.
 	if err := writeClientFiles(ctx, vol, ns.clientConfTemplatePath); err != nil {
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
@@ -222,22 +242,8 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
 
-	// At this point, the volume should be mounted. Add our nodeID to the appropriate tracking directory. This is best
-	// effort. Log if something goes wrong, but don't fail the operation.
-	nodesPath := path.Join(vol.csiDirPath, "nodes")
-	nodePath := path.Join(nodesPath, ns.nodeID)
-	dirExists, err := fsutil.DirExists(nodesPath)
-	if err != nil {
-		LogError(ctx, err, "Failed attempting to stat node tracking directory", "path", nodesPath, "volumeID", vol.volumeID)
-	} else if !dirExists {
-		LogVerbose(ctx, "Node tracking directory doesn't exist for volume", "path", nodesPath, "volumeID", vol.volumeID)
-	} else {
-		LogDebug(ctx, "Creating file in node tracking directory", "path", nodePath, "volumeID", vol.volumeID)
-		// Use WriteFile instead of Create to avoid the need for Close.
-		if err = fsutil.WriteFile(nodePath, []byte{}, 0640); err != nil {
-			LogError(ctx, err, "Failed to create file in node tracking directory", "path", nodePath, "volumeID", vol.volumeID)
-		}
-	}
+	// At this point, the volume should be mounted. Add our nodeID to the appropriate tracking directory.
+	ns.createNodeTrackingFile(ctx, vol)
 
 	return &csi.NodeStageVolumeResponse{}, nil
 }
@@ -253,13 +259,52 @@ func (ns *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 		return nil, status.Error(codes.InvalidArgument, "Staging target path not provided")
 	}
 
+	// Volumes staged in shared mount mode are unstaged differently. Volumes staged before shared mount mode was enabled
+	// continue to be unstaged normally.
+	if staged, err := ns.unstageSharedVolume(ctx, volumeID, stagingTargetPath); err != nil {
+		return nil, err
+	} else if staged {
+		return &csi.NodeUnstageVolumeResponse{}, nil
+	}
+
 	vol, err := newBeegfsVolumeFromID(stagingTargetPath, volumeID, ns.pluginConfig)
 	if err != nil {
 		return nil, newGrpcErrorFromCause(codes.Internal, err)
 	}
 
-	// While the volume is still mounted, delete our nodeID from the appropriate tracking directory. This is best
-	// effort. Log if something goes wrong, but don't fail the operation.
+	// While the volume is still mounted, delete our nodeID from the appropriate tracking directory.
+	ns.deleteNodeTrackingFile(ctx, vol)
+
+	err = unmountAndCleanUpIfNecessary(ctx, vol, false, ns.mounter) // The CO will clean up mountDirPath.
+	if err != nil {
+		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	}
+
+	return &csi.NodeUnstageVolumeResponse{}, nil
+}
+
+// createNodeTrackingFile adds our nodeID to the node tracking directory of a mounted volume. This is best effort. It
+// logs if something goes wrong, but doesn't fail.
+func (ns *nodeServer) createNodeTrackingFile(ctx context.Context, vol beegfsVolume) {
+	nodesPath := path.Join(vol.csiDirPath, "nodes")
+	nodePath := path.Join(nodesPath, ns.nodeID)
+	dirExists, err := fsutil.DirExists(nodesPath)
+	if err != nil {
+		LogError(ctx, err, "Failed attempting to stat node tracking directory", "path", nodesPath, "volumeID", vol.volumeID)
+	} else if !dirExists {
+		LogVerbose(ctx, "Node tracking directory doesn't exist for volume", "path", nodesPath, "volumeID", vol.volumeID)
+	} else {
+		LogDebug(ctx, "Creating file in node tracking directory", "path", nodePath, "volumeID", vol.volumeID)
+		// Use WriteFile instead of Create to avoid the need for Close.
+		if err = fsutil.WriteFile(nodePath, []byte{}, 0640); err != nil {
+			LogError(ctx, err, "Failed to create file in node tracking directory", "path", nodePath, "volumeID", vol.volumeID)
+		}
+	}
+}
+
+// deleteNodeTrackingFile deletes our nodeID from the node tracking directory of a mounted volume. This is best effort.
+// It logs if something goes wrong, but doesn't fail.
+func (ns *nodeServer) deleteNodeTrackingFile(ctx context.Context, vol beegfsVolume) {
 	nodesPath := path.Join(vol.csiDirPath, "nodes")
 	nodePath := path.Join(nodesPath, ns.nodeID)
 	fileExists, err := fsutil.Exists(nodePath)
@@ -273,13 +318,6 @@ func (ns *nodeServer) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstag
 			LogError(ctx, err, "Failed to delete node tracking file", "path", nodePath, "volumeID", vol.volumeID)
 		}
 	}
-
-	err = unmountAndCleanUpIfNecessary(ctx, vol, false, ns.mounter) // The CO will clean up mountDirPath.
-	if err != nil {
-		return nil, newGrpcErrorFromCause(codes.Internal, err)
-	}
-
-	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
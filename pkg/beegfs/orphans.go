@@ -0,0 +1,272 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"google.golang.org/grpc/codes"
+)
+
+// A volume is orphaned when its directory outlives its PersistentVolume (e.g. because DeleteVolume failed
+// permanently, the PersistentVolume was force-removed, or the cluster that used it was destroyed). If orphaned volume
+// scanning is enabled, the controller service periodically compares the volumes it finds under the volDirBasePaths of
+// its StorageClasses (see listVolumeMetadata) with the PersistentVolumes of its driver, logs the orphans, and reports
+// them in the driver's metrics. The diagnostics endpoint serves a full report at diagnosticsOrphansPath (whether or
+// not scanning is enabled), and only an explicit request to diagnosticsOrphanCleanupPath deletes orphans.
+//
+// Only volumes with an ownership marker are considered, so statically provisioned volumes and volumes created by
+// older versions of the driver are never reported. Each cluster only knows its own PersistentVolumes, so volumes whose
+// metadata records a different ClusterID than the controller service's (see SetClusterID) are reported separately and
+// are only cleaned up on explicit request. An empty ClusterID (in a volume's metadata or on the controller service)
+// means the cluster is unknown, so such volumes are treated like the volumes of other clusters.
+
+// orphanMinAge is how long a volume must have existed before a scan reports it. The external-provisioner only creates
+// a volume's PersistentVolume after CreateVolume succeeds.
+const orphanMinAge = time.Hour
+
+// orphanedVolume describes a volume without a PersistentVolume. It is served by the diagnostics endpoint at
+// diagnosticsOrphansPath.
+type orphanedVolume struct {
+	volumeMetadata
+	Age       string `json:"age"`       // since volumeMetadata.CreationTime (or the volume directory's modification)
+	SizeBytes int64  `json:"sizeBytes"` // the total size of the files in the volume's directory
+	Error     string `json:"error,omitempty"`
+}
+
+// orphanReport is the result of a scan for orphaned volumes.
+type orphanReport struct {
+	ScanTime time.Time        `json:"scanTime"`
+	Orphans  []orphanedVolume `json:"orphans"`
+	// OtherClusters are the volumes of other clusters that have no PersistentVolume in this cluster. They are not
+	// necessarily orphaned.
+	OtherClusters []orphanedVolume `json:"otherClusters"`
+}
+
+// orphanCleanupResult is the result of a request to clean up an orphaned volume.
+type orphanCleanupResult struct {
+	VolumeID string `json:"volumeID"`
+	Deleted  bool   `json:"deleted"` // the volume was moved to the trash or retained (see DeleteVolume)
+	Error    string `json:"error,omitempty"`
+}
+
+// EnableOrphanScanning makes the controller service scan for orphaned volumes every interval.
+func (b *beegfs) EnableOrphanScanning(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.Errorf("invalid orphaned volume scan interval %s", interval)
+	}
+	b.cs.orphanScanInterval = interval
+	return nil
+}
+
+// scanOrphansPeriodically calls scanOrphans every cs.orphanScanInterval and logs the orphans it finds until stop is
+// closed.
+func (cs *controllerServer) scanOrphansPeriodically(stop <-chan struct{}) {
+	ticker := time.NewTicker(cs.orphanScanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ctx := context.TODO()
+		report, err := cs.scanOrphans(ctx, time.Now())
+		if err != nil {
+			LogError(ctx, err, "Failed to scan for orphaned volumes")
+			continue
+		}
+		for _, orphan := range report.Orphans {
+			logger(ctx).Info("Found orphaned volume", "volumeID", orphan.VolumeID, "age", orphan.Age,
+				"sizeBytes", orphan.SizeBytes, "clusterID", orphan.ClusterID, "pvcNamespace", orphan.PVCNamespace,
+				"pvcName", orphan.PVCName, "pvName", orphan.PVName)
+		}
+		if len(report.OtherClusters) > 0 {
+			LogDebug(ctx, "Found volumes of other clusters without a PersistentVolume",
+				"count", len(report.OtherClusters))
+		}
+	}
+}
+
+// scanOrphans returns an orphanReport of the volumes the controller service finds that have existed for at least
+// orphanMinAge at now and have no PersistentVolume. It updates the orphaned volume metrics (which only count the
+// volumes of this cluster). Like the controller service RPCs, scanOrphans returns a gRPC error.
+func (cs *controllerServer) scanOrphans(ctx context.Context, now time.Time) (orphanReport, error) {
+	volumes, err := cs.listVolumeMetadata(ctx)
+	if err != nil {
+		return orphanReport{}, err
+	}
+	// List PersistentVolumes after volumes, so every volume we found had a chance to get one.
+	handles, err := listPersistentVolumeHandles(ctx, cs.driverName)
+	if err != nil {
+		return orphanReport{}, newGrpcErrorFromCause(codes.Internal, err)
+	}
+
+	report := orphanReport{ScanTime: now, Orphans: make([]orphanedVolume, 0),
+		OtherClusters: make([]orphanedVolume, 0)}
+	var totalBytes int64
+	for _, metadata := range volumes {
+		if handles[metadata.VolumeID] {
+			continue
+		}
+		orphan, ok, err := cs.inspectOrphan(ctx, metadata, now)
+		if err != nil {
+			return orphanReport{}, err
+		}
+		if !ok {
+			continue
+		}
+		if !cs.isOwnClusterVolume(metadata) {
+			report.OtherClusters = append(report.OtherClusters, orphan)
+			continue
+		}
+		report.Orphans = append(report.Orphans, orphan)
+		totalBytes += orphan.SizeBytes
+	}
+	orphanedVolumes.Set(float64(len(report.Orphans)))
+	orphanedVolumeBytes.Set(float64(totalBytes))
+	return report, nil
+}
+
+// inspectOrphan returns an orphanedVolume for the volume metadata describes if it has existed for at least
+// orphanMinAge at now (and false otherwise). It only walks the volume's directory to determine its size if it is old
+// enough, and reports problems doing so in orphanedVolume.Error instead of failing the scan.
+func (cs *controllerServer) inspectOrphan(ctx context.Context, metadata volumeMetadata, now time.Time) (orphanedVolume,
+	bool, error) {
+	vol, err := cs.newBeegfsVolumeFromID(metadata.VolumeID)
+	if err != nil {
+		return orphanedVolume{}, false, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	vol, release, err := cs.mountCache.acquire(ctx, vol)
+	if err != nil {
+		return orphanedVolume{}, false, err
+	}
+	defer release()
+
+	age, err := getVolumeAge(vol, metadata, now)
+	if err != nil {
+		return orphanedVolume{}, false, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	if age < orphanMinAge {
+		return orphanedVolume{}, false, nil
+	}
+	orphan := orphanedVolume{volumeMetadata: metadata, Age: age.Round(time.Second).String()}
+	if orphan.SizeBytes, err = getDirSize(vol.volDirPath); err != nil {
+		orphan.Error = err.Error()
+	}
+	return orphan, true, nil
+}
+
+// getVolumeAge returns the age at now of vol (on a mounted file system), which metadata describes.
+func getVolumeAge(vol beegfsVolume, metadata volumeMetadata, now time.Time) (time.Duration, error) {
+	created, err := time.Parse(time.RFC3339, metadata.CreationTime)
+	if err != nil {
+		// Volumes created by older versions of the driver have no CreationTime.
+		info, err := fs.Stat(vol.csiDirPath)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		created = info.ModTime()
+	}
+	return now.Sub(created), nil
+}
+
+// getDirSize returns the total size of the regular files in the directory at dirPath (or 0 if it does not exist).
+func getDirSize(dirPath string) (int64, error) {
+	var size int64
+	err := afero.Walk(fs, dirPath, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return size, errors.WithStack(err)
+}
+
+// cleanUpOrphans deletes each volume with one of volumeIDs that is orphaned at now the way DeleteVolume would (so
+// volumes with a retentionPeriod are retained). It refuses to delete volumes that are not orphaned and, unless
+// allowOtherClusters is set, volumes of other clusters. It checks only the listed volumes (without a full scan). Like
+// the controller service RPCs, cleanUpOrphans returns a gRPC error.
+func (cs *controllerServer) cleanUpOrphans(ctx context.Context, volumeIDs []string, allowOtherClusters bool,
+	now time.Time) ([]orphanCleanupResult, error) {
+	handles, err := listPersistentVolumeHandles(ctx, cs.driverName)
+	if err != nil {
+		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	results := make([]orphanCleanupResult, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		result := orphanCleanupResult{VolumeID: volumeID}
+		if err := cs.checkOrphan(ctx, volumeID, handles, allowOtherClusters, now); err != nil {
+			result.Error = err.Error()
+		} else if _, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID}); err != nil {
+			LogError(ctx, err, "Failed to clean up orphaned volume", "volumeID", volumeID)
+			result.Error = err.Error()
+		} else {
+			LogDebug(ctx, "Cleaned up orphaned volume", "volumeID", volumeID)
+			orphanedVolumesCleanedUpTotal.Inc()
+			result.Deleted = true
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// checkOrphan returns an error that explains why cleanUpOrphans must not delete the volume with volumeID (or nil if it
+// may). handles are the volumeIDs of the driver's PersistentVolumes.
+func (cs *controllerServer) checkOrphan(ctx context.Context, volumeID string, handles map[string]bool,
+	allowOtherClusters bool, now time.Time) error {
+	if handles[volumeID] {
+		return errors.New("not an orphaned volume: it has a PersistentVolume")
+	}
+	vol, err := cs.newBeegfsVolumeFromID(volumeID)
+	if err != nil {
+		return err
+	}
+	vol, release, err := cs.mountCache.acquire(ctx, vol)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	metadata, err := readVolumeMetadata(vol)
+	if os.IsNotExist(err) || (err == nil && !metadata.ownsVolume(volumeID)) {
+		return errors.New("not an orphaned volume: it was not found or the driver did not create it")
+	} else if err != nil {
+		return err
+	}
+	if age, err := getVolumeAge(vol, metadata, now); err != nil {
+		return err
+	} else if age < orphanMinAge {
+		return errors.Errorf("not an orphaned volume: it is only %s old", age.Round(time.Second))
+	}
+	if !cs.isOwnClusterVolume(metadata) && !allowOtherClusters {
+		if metadata.ClusterID == "" || cs.clusterID == "" {
+			return errors.Errorf("volume may belong to another cluster (cluster IDs of the volume %q and the "+
+				"controller service %q must both be set), which may still use it; allow cleaning up the volumes of "+
+				"other clusters to delete it", metadata.ClusterID, cs.clusterID)
+		}
+		return errors.Errorf("volume belongs to cluster %q (not %q), which may still use it; allow cleaning up the "+
+			"volumes of other clusters to delete it", metadata.ClusterID, cs.clusterID)
+	}
+	return nil
+}
+
+// isOwnClusterVolume reports whether metadata records the controller service's (non-empty) ClusterID. Two clusters
+// that share a volDirBasePath without cluster IDs would otherwise mistake each other's volumes for orphans.
+func (cs *controllerServer) isOwnClusterVolume(metadata volumeMetadata) bool {
+	return cs.clusterID != "" && metadata.ClusterID == cs.clusterID
+}
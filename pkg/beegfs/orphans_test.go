@@ -0,0 +1,141 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestScanAndCleanUpOrphans(t *testing.T) {
+	cs, vol := newTestRetentionControllerServer(t) // pvc-1 has a retentionPeriod
+	cs.clusterID = "cluster-a"
+	metadata, err := readVolumeMetadata(vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	metadata.ClusterID = cs.clusterID
+	if err := writeVolumeMetadata(vol, metadata); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return []map[string]string{{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"}}, nil
+	}
+	defer func() { listStorageClassParameters = listStorageClassParametersFromAPIServer }()
+	listPersistentVolumeHandles = func(ctx context.Context, driverName string) (map[string]bool, error) {
+		return map[string]bool{"beegfs://127.0.0.1/scratch/pvc-2": true}, nil
+	}
+	defer func() { listPersistentVolumeHandles = listPersistentVolumeHandlesFromAPIServer }()
+	ctx := context.Background()
+	_, err = cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: "pvc-2",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		}},
+		Parameters: map[string]string{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// New volumes without a PersistentVolume may still get one.
+	report, err := cs.scanOrphans(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(report.Orphans) != 0 {
+		t.Errorf("expected no orphaned volumes, got: %+v", report.Orphans)
+	}
+
+	later := time.Now().Add(2 * orphanMinAge)
+	report, err = cs.scanOrphans(ctx, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0].VolumeID != vol.volumeID {
+		t.Fatalf("expected orphaned volume %s, got: %+v", vol.volumeID, report.Orphans)
+	}
+	if orphan := report.Orphans[0]; orphan.SizeBytes != int64(len("data")) || orphan.PVCName != "data" ||
+		orphan.Error != "" {
+		t.Errorf("expected orphaned volume to describe its size and PVC, got: %+v", orphan)
+	}
+
+	results, err := cs.cleanUpOrphans(ctx, []string{"beegfs://127.0.0.1/scratch/pvc-2", vol.volumeID}, false, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(results) != 2 || results[0].Deleted || results[0].Error == "" || !results[1].Deleted {
+		t.Errorf("expected only the orphaned volume to be cleaned up, got: %+v", results)
+	}
+	if exists, _ := fsutil.Exists(vol.volDirPath); exists {
+		t.Errorf("expected %s to be retained", vol.volDirPath)
+	}
+	if retained := cs.volumeRetainer.list(ctx); len(retained) != 1 || retained[0].VolumeID != vol.volumeID {
+		t.Errorf("expected the orphaned volume to be retained, got: %+v", retained)
+	}
+}
+
+func TestCleanUpOrphansOfOtherClusters(t *testing.T) {
+	cs, vol := newTestRetentionControllerServer(t) // pvc-1 records no ClusterID
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return []map[string]string{{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"}}, nil
+	}
+	defer func() { listStorageClassParameters = listStorageClassParametersFromAPIServer }()
+	listPersistentVolumeHandles = func(ctx context.Context, driverName string) (map[string]bool, error) {
+		return map[string]bool{}, nil
+	}
+	defer func() { listPersistentVolumeHandles = listPersistentVolumeHandlesFromAPIServer }()
+	ctx := context.Background()
+	later := time.Now().Add(2 * orphanMinAge)
+
+	// Without cluster IDs, the volume may belong to any cluster sharing the volDirBasePath.
+	report, err := cs.scanOrphans(ctx, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(report.Orphans) != 0 || len(report.OtherClusters) != 1 || report.OtherClusters[0].VolumeID != vol.volumeID {
+		t.Fatalf("expected volume %s of an unknown cluster to be reported separately, got: %+v", vol.volumeID, report)
+	}
+	results, err := cs.cleanUpOrphans(ctx, []string{vol.volumeID}, false, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(results) != 1 || results[0].Deleted || results[0].Error == "" {
+		t.Errorf("expected the volume of an unknown cluster not to be cleaned up, got: %+v", results)
+	}
+
+	cs.clusterID = "cluster-b"
+	report, err = cs.scanOrphans(ctx, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(report.Orphans) != 0 || len(report.OtherClusters) != 1 || report.OtherClusters[0].VolumeID != vol.volumeID {
+		t.Fatalf("expected volume %s to be reported for another cluster, got: %+v", vol.volumeID, report)
+	}
+
+	results, err = cs.cleanUpOrphans(ctx, []string{vol.volumeID}, false, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(results) != 1 || results[0].Deleted || results[0].Error == "" {
+		t.Errorf("expected the volume of another cluster not to be cleaned up, got: %+v", results)
+	}
+	if exists, _ := fsutil.Exists(vol.volDirPath); !exists {
+		t.Fatalf("expected %s to remain", vol.volDirPath)
+	}
+
+	results, err = cs.cleanUpOrphans(ctx, []string{vol.volumeID}, true, later)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(results) != 1 || !results[0].Deleted {
+		t.Errorf("expected the volume of another cluster to be cleaned up on request, got: %+v", results)
+	}
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeleteVolume retains the directory of a volume whose StorageClass sets the retentionPeriod parameter instead of
+// moving it into the trash. It moves the directory into a dated retention directory in its volDirBasePath
+// (volDirBasePath/.csi/retained/<date>/<volName>.<timestamp>/volume) next to a metadata file that describes the volume,
+// the PVC it was created for, and when its retention period ends. Until then, an administrator can restore the volume
+// (to its original directory or a new one) with the restore-volume subcommand. Afterwards, the controller service's
+// volumeRetainer moves the retention directory into the trash, and the volumeDeleter removes it.
+//
+// Like the volumeDeleter, the volumeRetainer records every retention directory it knows about in csDataDir/.retained
+// and looks for retention directories it has no record of whenever DeleteVolume runs for a volume in the same
+// volDirBasePath.
+
+const (
+	// retainedDirName is the directory in volDirBasePath/.csi that DeleteVolume moves retained volume directories into.
+	retainedDirName = "retained"
+	// retainedVolumeDirName is the name of a retained volume's directory within its retention directory.
+	retainedVolumeDirName = "volume"
+	// retainedDateFormat is the format of the name of the dated directories in volDirBasePath/.csi/retained.
+	retainedDateFormat = "2006-01-02"
+	// retentionRecordDirName is the directory in csDataDir the volumeRetainer records retention directories in.
+	retentionRecordDirName = ".retained"
+	// retentionCheckInterval is how often the volumeRetainer looks for retention periods that have ended.
+	retentionCheckInterval = time.Hour
+)
+
+// retainedVolume describes a retained volume. It is stored in the volume's retention directory and served by the
+// diagnostics endpoint at diagnosticsRetainedPath.
+type retainedVolume struct {
+	volumeMetadata             // volumeMetadata.VolumeID is the volumeID of the deleted volume
+	RetainedVolumeID string    `json:"retainedVolumeID"` // a volumeID that refers to the retention directory
+	Deleted          time.Time `json:"deleted"`
+	RetainUntil      time.Time `json:"retainUntil"`
+}
+
+// volumeRetainer purges retained volumes once their retention periods end.
+type volumeRetainer struct {
+	recordDirPath string
+	pluginConfig  beegfsv1.PluginConfig
+	mountCache    *mountCache
+	volumeDeleter *volumeDeleter
+	mutex         sync.Mutex // serializes purges and restores
+}
+
+// newVolumeRetainer returns a volumeRetainer that records retention directories in recordDirPath, mounts file systems
+// with mountCache, and hands retention directories to volumeDeleter when their retention periods end.
+func newVolumeRetainer(recordDirPath string, pluginConfig beegfsv1.PluginConfig, mountCache *mountCache,
+	volumeDeleter *volumeDeleter) *volumeRetainer {
+	return &volumeRetainer{
+		recordDirPath: recordDirPath,
+		pluginConfig:  pluginConfig,
+		mountCache:    mountCache,
+		volumeDeleter: volumeDeleter,
+	}
+}
+
+// getRetainedDirPathBeegfsRoot returns the path of the retention directory for vol's volDirBasePath from the BeeGFS
+// root.
+func getRetainedDirPathBeegfsRoot(vol beegfsVolume) string {
+	return path.Join(vol.volDirBasePathBeegfsRoot, ".csi", retainedDirName)
+}
+
+// isRetainedPath returns true if retainedPathBeegfsRoot has the form volDirBasePath/.csi/retained/<date>/<name>.
+func isRetainedPath(retainedPathBeegfsRoot string) bool {
+	retainedDirPathBeegfsRoot := path.Dir(path.Dir(retainedPathBeegfsRoot))
+	return path.Base(retainedDirPathBeegfsRoot) == retainedDirName &&
+		path.Base(path.Dir(retainedDirPathBeegfsRoot)) == ".csi"
+}
+
+// getRetainedVolDirBasePathBeegfsRoot returns the volDirBasePath (from the BeeGFS root) of the retention directory at
+// retainedPathBeegfsRoot.
+func getRetainedVolDirBasePathBeegfsRoot(retainedPathBeegfsRoot string) string {
+	// retainedPathBeegfsRoot is volDirBasePath/.csi/retained/<date>/<volName>.<timestamp>.
+	return path.Dir(path.Dir(path.Dir(path.Dir(retainedPathBeegfsRoot))))
+}
+
+// retainVolume moves vol's volDirPath (on a mounted file system) into a new retention directory for its
+// volDirBasePath and describes it there with metadata. It returns a volumeID that refers to the new retention
+// directory or an empty string if vol's volDirPath does not exist.
+func retainVolume(ctx context.Context, vol beegfsVolume, metadata volumeMetadata) (string, error) {
+	if exists, err := fsutil.DirExists(vol.volDirPath); err != nil {
+		return "", errors.WithStack(err)
+	} else if !exists {
+		LogDebug(ctx, "BeeGFS directory already deleted", "path", vol.volDirPathBeegfsRoot, "volumeID", vol.volumeID)
+		return "", nil
+	}
+	now := time.Now()
+	retainedPathBeegfsRoot := path.Join(getRetainedDirPathBeegfsRoot(vol), now.UTC().Format(retainedDateFormat),
+		fmt.Sprintf("%s.%d", path.Base(vol.volDirPathBeegfsRoot), now.UnixNano()))
+	retainedPath := path.Join(vol.mountPath, retainedPathBeegfsRoot)
+	if err := fs.MkdirAll(retainedPath, 0750); err != nil {
+		return "", errors.WithStack(err)
+	}
+	metadata.VolumeID = vol.volumeID
+	retained := retainedVolume{
+		volumeMetadata:   metadata,
+		RetainedVolumeID: NewBeegfsURL(vol.sysMgmtdHost, retainedPathBeegfsRoot),
+		Deleted:          now,
+		RetainUntil:      now.Add(metadata.RetentionPeriod.Duration),
+	}
+	if err := writeJSONFile(path.Join(retainedPath, volumeMetadataFileName), retained); err != nil {
+		return "", err
+	}
+	if err := fs.Rename(vol.volDirPath, path.Join(retainedPath, retainedVolumeDirName)); err != nil {
+		return "", errors.WithStack(err)
+	}
+	LogDebug(ctx, "Retained BeeGFS directory", "path", vol.volDirPathBeegfsRoot, "retainedPath", retainedPathBeegfsRoot,
+		"retainUntil", retained.RetainUntil.Format(time.RFC3339), "volumeID", vol.volumeID)
+	return retained.RetainedVolumeID, nil
+}
+
+// readRetainedVolume reads the retainedVolume from the retention directory retainedVol refers to (on a mounted file
+// system). It returns an error that satisfies os.IsNotExist if the retention directory does not exist.
+func readRetainedVolume(retainedVol beegfsVolume) (retainedVolume, error) {
+	var retained retainedVolume
+	if err := readJSONFile(path.Join(retainedVol.volDirPath, volumeMetadataFileName), &retained); err != nil {
+		return retainedVolume{}, err
+	}
+	retained.RetainedVolumeID = retainedVol.volumeID
+	return retained, nil
+}
+
+// purgeExpiredPeriodically calls purgeExpired every retentionCheckInterval until stop is closed.
+func (r *volumeRetainer) purgeExpiredPeriodically(stop <-chan struct{}) {
+	r.purgeExpired(context.TODO(), time.Now())
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.purgeExpired(context.TODO(), time.Now())
+		}
+	}
+}
+
+// add records the retention directory retainedVolumeID refers to.
+func (r *volumeRetainer) add(ctx context.Context, retainedVolumeID string) {
+	if err := writeRecord(r.recordDirPath, retainedVolumeID); err != nil {
+		// The retained volume is safe. It just won't be purged until DeleteVolume finds it again.
+		LogError(ctx, err, "Failed to record retained volume", "retainedVolumeID", retainedVolumeID)
+	}
+	r.updateMetrics()
+}
+
+// addRetained records every retention directory for vol's volDirBasePath (on a mounted file system).
+func (r *volumeRetainer) addRetained(ctx context.Context, vol beegfsVolume) {
+	retainedDirPathBeegfsRoot := getRetainedDirPathBeegfsRoot(vol)
+	dateEntries, err := fsutil.ReadDir(path.Join(vol.mountPath, retainedDirPathBeegfsRoot))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			LogError(ctx, errors.WithStack(err), "Failed to read retention directory", "path",
+				retainedDirPathBeegfsRoot)
+		}
+		return
+	}
+	for _, dateEntry := range dateEntries {
+		datePathBeegfsRoot := path.Join(retainedDirPathBeegfsRoot, dateEntry.Name())
+		entries, err := fsutil.ReadDir(path.Join(vol.mountPath, datePathBeegfsRoot))
+		if err != nil {
+			LogError(ctx, errors.WithStack(err), "Failed to read retention directory", "path", datePathBeegfsRoot)
+			continue
+		}
+		for _, entry := range entries {
+			retainedVolumeID := NewBeegfsURL(vol.sysMgmtdHost, path.Join(datePathBeegfsRoot, entry.Name()))
+			if exists, _ := fsutil.Exists(getRecordPath(r.recordDirPath, retainedVolumeID)); !exists {
+				r.add(ctx, retainedVolumeID)
+			}
+		}
+	}
+}
+
+// purgeExpired moves every recorded retention directory whose retention period ended before now into the trash.
+func (r *volumeRetainer) purgeExpired(ctx context.Context, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, retainedVolumeID := range readRecords(ctx, r.recordDirPath) {
+		if err := r.purgeIfExpired(ctx, retainedVolumeID, now); err != nil {
+			LogError(ctx, err, "Failed to purge retained volume", "retainedVolumeID", retainedVolumeID)
+		}
+	}
+	r.updateMetrics()
+}
+
+// purgeIfExpired moves the retention directory retainedVolumeID refers to into the trash if its retention period ended
+// before now. It forgets retention directories that no longer exist (e.g. because they were restored).
+func (r *volumeRetainer) purgeIfExpired(ctx context.Context, retainedVolumeID string, now time.Time) error {
+	sysMgmtdHost, retainedPathBeegfsRoot, err := parseBeegfsURL(retainedVolumeID)
+	if err != nil {
+		return err
+	}
+	retainedVol, release, err := r.mountCache.acquire(ctx, newBeegfsVolume("", sysMgmtdHost, retainedPathBeegfsRoot,
+		r.pluginConfig))
+	if err != nil {
+		return err
+	}
+	defer release()
+	retained, err := readRetainedVolume(retainedVol)
+	if os.IsNotExist(err) {
+		LogDebug(ctx, "Retained volume no longer exists", "retainedVolumeID", retainedVolumeID)
+		return removeRecord(r.recordDirPath, retainedVolumeID)
+	} else if err != nil {
+		return err
+	}
+	if now.Before(retained.RetainUntil) {
+		return nil
+	}
+
+	_, volDirPathBeegfsRoot, err := parseBeegfsURL(retained.VolumeID)
+	if err != nil {
+		return err
+	}
+	trashPathBeegfsRoot, err := moveToTrash(retainedVol.mountPath, retainedPathBeegfsRoot,
+		getRetainedVolDirBasePathBeegfsRoot(retainedPathBeegfsRoot), path.Base(volDirPathBeegfsRoot))
+	if err != nil {
+		return err
+	}
+	LogDebug(ctx, "Retention period ended; moved retained volume to trash", "retainedVolumeID", retainedVolumeID,
+		"trashPath", trashPathBeegfsRoot, "volumeID", retained.VolumeID)
+	retainedVolumesPurgedTotal.Inc()
+	r.volumeDeleter.add(ctx, newTrashVolume(sysMgmtdHost, trashPathBeegfsRoot, retainedVol.config))
+	// The retention directory for the date of the deletion is empty once the last volume deleted that day is purged.
+	_ = fs.Remove(path.Dir(path.Join(retainedVol.mountPath, retainedPathBeegfsRoot)))
+	return removeRecord(r.recordDirPath, retainedVolumeID)
+}
+
+// list returns every recorded retained volume, sorted by deletion time.
+func (r *volumeRetainer) list(ctx context.Context) []retainedVolume {
+	retainedVolumes := make([]retainedVolume, 0)
+	for _, retainedVolumeID := range readRecords(ctx, r.recordDirPath) {
+		sysMgmtdHost, retainedPathBeegfsRoot, err := parseBeegfsURL(retainedVolumeID)
+		if err != nil {
+			LogError(ctx, err, "Invalid retention record", "retainedVolumeID", retainedVolumeID)
+			continue
+		}
+		retainedVol, release, err := r.mountCache.acquire(ctx, newBeegfsVolume("", sysMgmtdHost,
+			retainedPathBeegfsRoot, r.pluginConfig))
+		if err != nil {
+			LogError(ctx, err, "Failed to read retained volume", "retainedVolumeID", retainedVolumeID)
+			continue
+		}
+		retained, err := readRetainedVolume(retainedVol)
+		release()
+		if err != nil {
+			if !os.IsNotExist(err) {
+				LogError(ctx, err, "Failed to read retained volume", "retainedVolumeID", retainedVolumeID)
+			}
+			continue
+		}
+		retainedVolumes = append(retainedVolumes, retained)
+	}
+	sort.Slice(retainedVolumes, func(i, j int) bool {
+		return retainedVolumes[i].Deleted.Before(retainedVolumes[j].Deleted)
+	})
+	return retainedVolumes
+}
+
+// updateMetrics sets the retained volume gauge to the number of recorded retention directories.
+func (r *volumeRetainer) updateMetrics() {
+	entries, err := fsutil.ReadDir(r.recordDirPath)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	volumesRetained.Set(float64(len(entries)))
+}
+
+// restoreVolume moves the volume directory out of the retention directory retainedVolumeID refers to and back into
+// its volDirBasePath. It restores the directory to volName (or to its original name if volName is empty) and returns
+// the volumeMetadata of the restored volume. The returned error is a gRPC error.
+func (cs *controllerServer) restoreVolume(ctx context.Context, retainedVolumeID, volName string) (volumeMetadata,
+	error) {
+	sysMgmtdHost, retainedPathBeegfsRoot, err := parseBeegfsURL(retainedVolumeID)
+	if err != nil {
+		return volumeMetadata{}, newGrpcErrorFromCause(codes.InvalidArgument, err)
+	}
+	if !isRetainedPath(retainedPathBeegfsRoot) {
+		return volumeMetadata{}, status.Errorf(codes.InvalidArgument, "%s does not refer to a retained volume",
+			retainedVolumeID)
+	}
+	if volName != "" && (path.Base(volName) != volName || volName == "." || volName == "..") {
+		return volumeMetadata{}, status.Errorf(codes.InvalidArgument, "invalid volume name %s", volName)
+	}
+
+	// Don't let the volumeRetainer purge the volume out from under us.
+	cs.volumeRetainer.mutex.Lock()
+	defer cs.volumeRetainer.mutex.Unlock()
+
+	retainedVol, release, err := cs.mountCache.acquire(ctx, newBeegfsVolume("", sysMgmtdHost, retainedPathBeegfsRoot,
+		cs.pluginConfig))
+	if err != nil {
+		return volumeMetadata{}, err
+	}
+	defer release()
+	retained, err := readRetainedVolume(retainedVol)
+	if os.IsNotExist(err) {
+		return volumeMetadata{}, status.Errorf(codes.NotFound, "retained volume %s not found", retainedVolumeID)
+	} else if err != nil {
+		return volumeMetadata{}, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	if volName == "" {
+		_, volDirPathBeegfsRoot, err := parseBeegfsURL(retained.VolumeID)
+		if err != nil {
+			return volumeMetadata{}, newGrpcErrorFromCause(codes.Internal, err)
+		}
+		volName = path.Base(volDirPathBeegfsRoot)
+	}
+	vol := newBeegfsVolume(retainedVol.mountDirPath, sysMgmtdHost,
+		path.Join(getRetainedVolDirBasePathBeegfsRoot(retainedPathBeegfsRoot), volName), cs.pluginConfig)
+	vol.config = retainedVol.config
+	if err := checkVolDirPathPermitted(vol.sysMgmtdHost, vol.volDirPathBeegfsRoot, cs.pluginConfig); err != nil {
+		return volumeMetadata{}, newGrpcErrorFromCause(codes.PermissionDenied, err)
+	}
+
+	// Obtain exclusive control over the volume.
+	if !cs.volumeIDsInFlight.obtainLockOnString(vol.volumeID) {
+		return volumeMetadata{}, status.Errorf(codes.Aborted, "volumeID %s is in use by another request", vol.volumeID)
+	}
+	defer cs.volumeIDsInFlight.releaseLockOnString(vol.volumeID)
+
+	if exists, err := fsutil.Exists(vol.volDirPath); err != nil {
+		return volumeMetadata{}, newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	} else if exists {
+		return volumeMetadata{}, status.Errorf(codes.AlreadyExists, "volume %s already exists", vol.volumeID)
+	}
+	LogDebug(ctx, "Restoring retained volume", "retainedVolumeID", retainedVolumeID, "volumeID", vol.volumeID)
+	if err := fs.Rename(path.Join(retainedVol.volDirPath, retainedVolumeDirName), vol.volDirPath); err != nil {
+		return volumeMetadata{}, newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	}
+
+	// From here on, the volume is restored. Failing to clean up only leaves a retention directory without a volume.
+	metadata := retained.volumeMetadata
+	metadata.VolumeID = vol.volumeID
+	if vol.volumeID != retained.VolumeID {
+		// The volume is no longer the one the original CreateVolume request created.
+		metadata.Name = path.Base(vol.volDirPathBeegfsRoot)
+	}
+	if err := writeVolumeMetadata(vol, metadata); err != nil {
+		LogError(ctx, err, "Failed to write volume metadata", "volumeID", vol.volumeID)
+	}
+	if cs.nodeUnstageTimeout > 0 {
+		nodesDirPath := path.Join(vol.csiDirPathBeegfsRoot, "nodes")
+		if err := cs.ctlExec.createDirectoryForVolume(ctx, vol, nodesDirPath, permissionsConfig{mode: 0750}); err != nil {
+			LogError(ctx, err, "Failed to create subdirectory for node tracking", "path", nodesDirPath,
+				"volumeID", vol.volumeID)
+		}
+	}
+	if err := fsutil.RemoveAll(retainedVol.volDirPath); err != nil {
+		LogError(ctx, errors.WithStack(err), "Failed to remove retention directory", "path", retainedPathBeegfsRoot)
+	}
+	_ = fs.Remove(path.Dir(retainedVol.volDirPath)) // Only succeeds if no other volume was deleted that day.
+	if err := removeRecord(cs.volumeRetainer.recordDirPath, retainedVolumeID); err != nil {
+		LogError(ctx, err, "Failed to remove retention record", "retainedVolumeID", retainedVolumeID)
+	}
+	cs.volumeRetainer.updateMetrics()
+	// A previous DeleteVolume for the same volumeID must not prevent the next one.
+	cs.volumeStatusMap.writeStatus(vol.volumeID, statusCreated)
+	return metadata, nil
+}
+
+// newRestoredPersistentVolume returns a PersistentVolume of the driver driverName for the restored volume metadata
+// describes. The PersistentVolume is named after the volume (or, for volumes created by older versions of the driver,
+// after the volume's directory). If that is the name of the PersistentVolume the volume was originally provisioned
+// for, it is pre-bound to the original PersistentVolumeClaim. The driver does
+// not know the volume's StorageClass or access modes, so it uses ReadWriteMany and no StorageClass.
+func newRestoredPersistentVolume(driverName string, metadata volumeMetadata) (*corev1.PersistentVolume, error) {
+	_, volDirPathBeegfsRoot, err := parseBeegfsURL(metadata.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	name := metadata.Name
+	if name == "" {
+		name = path.Base(volDirPathBeegfsRoot)
+	}
+	pv := &corev1.PersistentVolume{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PersistentVolumeSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: *resource.NewQuantity(metadata.CapacityBytes, resource.BinarySI),
+			},
+			// Restoring a volume again is easier than restoring it twice.
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: metadata.VolumeID},
+			},
+		},
+	}
+	if pv.Name == metadata.PVName && metadata.PVCName != "" {
+		pv.Spec.ClaimRef = &corev1.ObjectReference{Namespace: metadata.PVCNamespace, Name: metadata.PVCName}
+	}
+	return pv, nil
+}
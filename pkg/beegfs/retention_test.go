@@ -0,0 +1,314 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/afero"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTestRetentionControllerServer returns a controllerServer with a csDataDir in a temporary directory that uses a
+// FakeMounter. It creates a volume with a retention period of one hour (and a file in its directory) and returns a
+// volume that refers to it in the controllerServer's mount cache.
+func newTestRetentionControllerServer(t *testing.T) (*controllerServer, beegfsVolume) {
+	fs = afero.NewOsFs()
+	fsutil = afero.Afero{Fs: fs}
+	tempDir := t.TempDir()
+	confTemplatePath := path.Join(tempDir, "beegfs-client.conf")
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	cs := newControllerServerSanity("node", beegfsv1.PluginConfig{}, confTemplatePath, tempDir, 0)
+
+	ctx := context.Background()
+	_, err := cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: "pvc-1",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		}},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 1 << 30},
+		Parameters: map[string]string{
+			sysMgmtdHostKey:    "127.0.0.1",
+			volDirBasePathKey:  "/scratch",
+			retentionPeriodKey: "1h",
+			pvcNameKey:         "data",
+			pvcNamespaceKey:    "default",
+			pvNameKey:          "pvc-1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// The fake beegfs-ctl doesn't create directories.
+	vol, release, err := cs.mountCache.acquire(ctx, cs.newBeegfsVolume("127.0.0.1", "/scratch", "pvc-1"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	t.Cleanup(release)
+	if err := fs.MkdirAll(vol.volDirPath, 0750); err != nil {
+		t.Fatalf("failed to create volume directory: %v", err)
+	}
+	if err := fsutil.WriteFile(path.Join(vol.volDirPath, "file"), []byte("data"), 0640); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	return cs, vol
+}
+
+func TestDeleteVolumeRetainsVolume(t *testing.T) {
+	cs, vol := newTestRetentionControllerServer(t)
+	ctx := context.Background()
+	createdMetadata, err := readVolumeMetadata(vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.volumeID}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exists, _ := fsutil.Exists(vol.volDirPath); exists {
+		t.Errorf("expected %s to be moved", vol.volDirPath)
+	}
+	if exists, _ := fsutil.Exists(vol.csiDirPath); exists {
+		t.Errorf("expected %s to be removed", vol.csiDirPath)
+	}
+
+	retainedVolumes := cs.volumeRetainer.list(ctx)
+	if len(retainedVolumes) != 1 {
+		t.Fatalf("expected 1 retained volume, got: %+v", retainedVolumes)
+	}
+	retained := retainedVolumes[0]
+	wantMetadata := volumeMetadata{
+		VolumeID:        vol.volumeID,
+		CreationToken:   createdMetadata.CreationToken,
+		CreationTime:    createdMetadata.CreationTime,
+		Name:            "pvc-1",
+		PVCNamespace:    "default",
+		PVCName:         "data",
+		PVName:          "pvc-1",
+		CapacityBytes:   1 << 30,
+		RetentionPeriod: metav1.Duration{Duration: time.Hour},
+	}
+	if retained.volumeMetadata != wantMetadata {
+		t.Errorf("expected metadata %+v, got: %+v", wantMetadata, retained.volumeMetadata)
+	}
+	if retained.RetainUntil.Sub(retained.Deleted) != time.Hour {
+		t.Errorf("expected volume to be retained for 1h, got: %s", retained.RetainUntil.Sub(retained.Deleted))
+	}
+	_, retainedPathBeegfsRoot, _ := parseBeegfsURL(retained.RetainedVolumeID)
+	if !isRetainedPath(retainedPathBeegfsRoot) || getRetainedVolDirBasePathBeegfsRoot(retainedPathBeegfsRoot) != "/scratch" {
+		t.Errorf("expected volume to be retained in /scratch/.csi/retained, got: %s", retainedPathBeegfsRoot)
+	}
+	if exists, _ := fsutil.Exists(path.Join(vol.mountPath, retainedPathBeegfsRoot, retainedVolumeDirName, "file")); !exists {
+		t.Errorf("expected volume contents to be retained in %s", retainedPathBeegfsRoot)
+	}
+}
+
+// failingRenameFs is an afero.Fs that fails to rename anything.
+type failingRenameFs struct {
+	afero.Fs
+}
+
+func (failingRenameFs) Rename(oldname, newname string) error {
+	return errors.New("rename failed")
+}
+
+func TestDeleteVolumeRetryAfterFailedRename(t *testing.T) {
+	cs, vol := newTestRetentionControllerServer(t)
+	ctx := context.Background()
+	osFs := fs
+	fs = failingRenameFs{Fs: osFs}
+	fsutil = afero.Afero{Fs: fs}
+	_, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.volumeID})
+	fs = osFs
+	fsutil = afero.Afero{Fs: fs}
+	if err == nil {
+		t.Fatal("expected error when the volume directory cannot be moved")
+	}
+	if exists, _ := fsutil.Exists(path.Join(vol.csiDirPath, volumeMetadataFileName)); !exists {
+		t.Fatalf("expected the ownership marker to remain after a failed deletion")
+	}
+
+	// The retry still finds the ownership marker (and the volume's retentionPeriod).
+	if _, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.volumeID}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exists, _ := fsutil.Exists(vol.csiDirPath); exists {
+		t.Errorf("expected %s to be removed", vol.csiDirPath)
+	}
+	if retained := cs.volumeRetainer.list(ctx); len(retained) != 1 || retained[0].VolumeID != vol.volumeID {
+		t.Errorf("expected the volume to be retained, got: %+v", retained)
+	}
+}
+
+func TestVolumeRetainerPurgeExpired(t *testing.T) {
+	cs, vol := newTestRetentionControllerServer(t)
+	ctx := context.Background()
+	cs.volumeDeleter.start(ctx)
+	defer cs.volumeDeleter.stop()
+	if _, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.volumeID}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	retainedDirPath := path.Join(vol.mountPath, getRetainedDirPathBeegfsRoot(vol))
+	purgedBefore := testutil.ToFloat64(retainedVolumesPurgedTotal)
+
+	cs.volumeRetainer.purgeExpired(ctx, time.Now())
+	if retainedVolumes := cs.volumeRetainer.list(ctx); len(retainedVolumes) != 1 {
+		t.Fatalf("expected 1 retained volume before its retention period ends, got: %d", len(retainedVolumes))
+	}
+	if retained := testutil.ToFloat64(volumesRetained); retained != 1 {
+		t.Errorf("expected 1 retained volume, got: %v", retained)
+	}
+
+	cs.volumeRetainer.purgeExpired(ctx, time.Now().Add(2*time.Hour))
+	waitForDeletions(t, cs.volumeDeleter)
+	if retainedVolumes := cs.volumeRetainer.list(ctx); len(retainedVolumes) != 0 {
+		t.Fatalf("expected no retained volumes after the retention period ends, got: %+v", retainedVolumes)
+	}
+	if entries, _ := fsutil.ReadDir(retainedDirPath); len(entries) != 0 {
+		t.Errorf("expected %s to be empty, got %d entries", retainedDirPath, len(entries))
+	}
+	if entries, _ := fsutil.ReadDir(path.Join(vol.mountPath, getTrashDirPathBeegfsRoot(vol))); len(entries) != 0 {
+		t.Errorf("expected trash to be empty, got %d entries", len(entries))
+	}
+	if purged := testutil.ToFloat64(retainedVolumesPurgedTotal) - purgedBefore; purged != 1 {
+		t.Errorf("expected 1 purged volume, got: %v", purged)
+	}
+	if retained := testutil.ToFloat64(volumesRetained); retained != 0 {
+		t.Errorf("expected no retained volumes, got: %v", retained)
+	}
+}
+
+func TestRestoreVolume(t *testing.T) {
+	tests := map[string]struct {
+		retainedVolumeID string // the retained volume if empty
+		volumeName       string
+		wantVolumeID     string
+		wantCode         codes.Code
+		conflict         bool
+	}{
+		"original name": {wantVolumeID: "beegfs://127.0.0.1/scratch/pvc-1"},
+		"new name":      {volumeName: "pvc-2", wantVolumeID: "beegfs://127.0.0.1/scratch/pvc-2"},
+		"invalid name":  {volumeName: "../pvc-2", wantCode: codes.InvalidArgument},
+		"conflict":      {conflict: true, wantCode: codes.AlreadyExists},
+		"not retained": {
+			retainedVolumeID: "beegfs://127.0.0.1/scratch/pvc-1",
+			wantCode:         codes.InvalidArgument,
+		},
+		"not found": {
+			retainedVolumeID: "beegfs://127.0.0.1/scratch/.csi/retained/2026-01-01/pvc-1.1",
+			wantCode:         codes.NotFound,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cs, vol := newTestRetentionControllerServer(t)
+			ctx := context.Background()
+			if _, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.volumeID}); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			retainedVolumeID := cs.volumeRetainer.list(ctx)[0].RetainedVolumeID
+			if tc.retainedVolumeID != "" {
+				retainedVolumeID = tc.retainedVolumeID
+			}
+			if tc.conflict {
+				if err := fs.MkdirAll(vol.volDirPath, 0750); err != nil {
+					t.Fatalf("failed to create volume directory: %v", err)
+				}
+			}
+
+			metadata, err := cs.restoreVolume(ctx, retainedVolumeID, tc.volumeName)
+			if tc.wantCode != codes.OK {
+				if status.Code(err) != tc.wantCode {
+					t.Fatalf("expected error code %s, got: %v", tc.wantCode, err)
+				}
+				if len(cs.volumeRetainer.list(ctx)) != 1 {
+					t.Fatalf("expected volume to remain retained")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if metadata.VolumeID != tc.wantVolumeID || metadata.PVCName != "data" {
+				t.Errorf("expected metadata for %s, got: %+v", tc.wantVolumeID, metadata)
+			}
+			restoredVol, _ := newBeegfsVolumeFromID(vol.mountDirPath, tc.wantVolumeID, cs.pluginConfig)
+			if exists, _ := fsutil.Exists(path.Join(restoredVol.volDirPath, "file")); !exists {
+				t.Errorf("expected volume contents to be restored to %s", restoredVol.volDirPath)
+			}
+			if restoredMetadata, err := readVolumeMetadata(restoredVol); err != nil || restoredMetadata != metadata {
+				t.Errorf("expected metadata to be restored, got: %+v, %v", restoredMetadata, err)
+			}
+			if retainedVolumes := cs.volumeRetainer.list(ctx); len(retainedVolumes) != 0 {
+				t.Errorf("expected no retained volumes, got: %+v", retainedVolumes)
+			}
+			if entries, _ := fsutil.ReadDir(path.Join(vol.mountPath, getRetainedDirPathBeegfsRoot(vol))); len(entries) != 0 {
+				t.Errorf("expected retention directory to be empty, got %d entries", len(entries))
+			}
+
+			// The restored volume keeps its retention period.
+			if _, err := cs.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: tc.wantVolumeID}); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if retainedVolumes := cs.volumeRetainer.list(ctx); len(retainedVolumes) != 1 {
+				t.Errorf("expected 1 retained volume, got: %+v", retainedVolumes)
+			}
+		})
+	}
+}
+
+func TestNewRestoredPersistentVolume(t *testing.T) {
+	metadata := volumeMetadata{
+		VolumeID:      "beegfs://127.0.0.1/scratch/pvc-1",
+		PVCNamespace:  "default",
+		PVCName:       "data",
+		PVName:        "pvc-1",
+		CapacityBytes: 1 << 30,
+	}
+	pv, err := newRestoredPersistentVolume(DefaultDriverName, metadata)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if pv.Name != "pvc-1" || pv.Spec.CSI.VolumeHandle != metadata.VolumeID || pv.Spec.CSI.Driver != DefaultDriverName {
+		t.Errorf("expected PersistentVolume pvc-1 for %s, got: %+v", metadata.VolumeID, pv)
+	}
+	if capacity := pv.Spec.Capacity.Storage().String(); capacity != "1Gi" {
+		t.Errorf("expected capacity 1Gi, got: %s", capacity)
+	}
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Namespace != "default" || pv.Spec.ClaimRef.Name != "data" {
+		t.Errorf("expected PersistentVolume to be pre-bound to default/data, got: %+v", pv.Spec.ClaimRef)
+	}
+
+	// A volume restored to a new directory is not pre-bound.
+	metadata.VolumeID = "beegfs://127.0.0.1/scratch/pvc-2"
+	if pv, err = newRestoredPersistentVolume(DefaultDriverName, metadata); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if pv.Name != "pvc-2" || pv.Spec.ClaimRef != nil {
+		t.Errorf("expected PersistentVolume pvc-2 without a claimRef, got: %+v", pv)
+	}
+
+	// A volume laid out by a volDirTemplate is named after the volume, not its directory.
+	metadata.VolumeID = "beegfs://127.0.0.1/scratch/default/data"
+	metadata.Name = "pvc-1"
+	if pv, err = newRestoredPersistentVolume(DefaultDriverName, metadata); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if pv.Name != "pvc-1" || pv.Spec.ClaimRef == nil {
+		t.Errorf("expected PersistentVolume pvc-1 pre-bound to default/data, got: %+v", pv)
+	}
+}
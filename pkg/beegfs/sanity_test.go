@@ -6,6 +6,7 @@ Licensed under the Apache License, Version 2.0.
 package beegfs
 
 import (
+	"context"
 	"os"
 	"path"
 	"testing"
@@ -33,7 +34,7 @@ func TestSanity(t *testing.T) {
 	}
 
 	// Create and run the driver.
-	driver, err := NewBeegfsDriverSanity("", "", "", csDataDirPath, "testDriver", endpoint, "testID",
+	driver, err := NewBeegfsDriverSanity("", "", "", csDataDirPath, "testDriver", endpoint, "", "testID",
 		clientConfTemplatePath, "v0.1", 10)
 	if err != nil {
 		t.Fatal(err)
@@ -44,6 +45,11 @@ func TestSanity(t *testing.T) {
 	reqParams := make(map[string]string)
 	reqParams[sysMgmtdHostKey] = "localhost"
 	reqParams[volDirBasePathKey] = "unittest"
+	// ListVolumes lists the volumes under the volDirBasePaths of the driver's StorageClasses.
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return []map[string]string{{sysMgmtdHostKey: "localhost", volDirBasePathKey: "unittest"}}, nil
+	}
+	defer func() { listStorageClassParameters = listStorageClassParametersFromAPIServer }()
 	cfg := sanity.NewTestConfig()
 	cfg.StagingPath = path.Join(sanityDir, "mnt-stage")
 	cfg.TargetPath = path.Join(sanityDir, "mnt")
@@ -89,6 +89,10 @@ func (e grpcError) Format(s fmt.State, verb rune) {
 }
 func (e grpcError) GetStatusErr() error { return e.statusErr }
 
+// GRPCStatus allows status.Code and status.FromError to find the code of a grpcError (e.g. when code that is not a gRPC
+// server maps it to an HTTP status).
+func (e grpcError) GRPCStatus() *status.Status { return status.Convert(e.statusErr) }
+
 func newNonBlockingGRPCServer() *nonBlockingGRPCServer {
 	return &nonBlockingGRPCServer{}
 }
@@ -111,7 +115,9 @@ func (s *nonBlockingGRPCServer) Wait() {
 }
 
 func (s *nonBlockingGRPCServer) Stop() {
-	s.server.GracefulStop()
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
 }
 
 func (s *nonBlockingGRPCServer) ForceStop() {
@@ -119,6 +125,7 @@ func (s *nonBlockingGRPCServer) ForceStop() {
 }
 
 func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+	defer s.wg.Done()
 
 	proto, addr, err := parseEndpoint(endpoint)
 	if err != nil {
@@ -0,0 +1,324 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"k8s.io/mount-utils"
+)
+
+// By default, the node service mounts BeeGFS to the staging target path of every volume it stages, so every staged
+// volume has its own BeeGFS client instance (with its own beegfs-client.conf and connClientPort). In shared mount
+// mode, the node service instead mounts each file system once per effective configuration (sysMgmtdHost, BeeGFS
+// client configuration, and mount options) to a subdirectory of a shared mount directory and bind mounts the
+// volDirPath of each staged volume from there to its staging target path. A shared mount is unmounted when the last
+// volume that uses it is unstaged.
+//
+// The node service tracks the volumes that use each shared mount in memory. When it starts, it rebuilds this state
+// from the mount table (/proc/mounts), in which every bind mount of a BeeGFS file system has the same cfgFile mount
+// option as the file system itself.
+
+// sharedStagingDirName is the subdirectory of a staging target path the node service bind mounts a volume's
+// volDirPath to in shared mount mode. NodePublishVolume bind mounts it to the target path.
+const sharedStagingDirName = "volume"
+
+// sharedMountTable tracks the shared mounts in a shared mount directory and the staging target paths that use them. A
+// nil *sharedMountTable indicates that shared mount mode is disabled.
+type sharedMountTable struct {
+	dirPath string
+	mutex   sync.Mutex
+	locks   map[string]*sync.Mutex // key -> lock serializing the staging and unstaging of volumes that use the mount
+	staged  map[string]string      // staging target path -> key
+}
+
+// EnableSharedMounts configures the node service to share one BeeGFS mount per file system and configuration between
+// all volumes it stages. The shared mounts are made in subdirectories of dirPath, which must be the same path inside
+// the driver's container and on the host. It must be called before Run.
+func (b *beegfs) EnableSharedMounts(dirPath string) (err error) {
+	b.ns.sharedMounts, err = newSharedMountTable(context.TODO(), dirPath, b.ns.mounter)
+	return err
+}
+
+// newSharedMountTable returns a sharedMountTable for the shared mount directory at dirPath (which it creates if
+// necessary) with state rebuilt from the mounts mounter lists. It unmounts any shared mount no staged volume uses
+// (e.g. because the node service restarted while staging the first volume that would have used it).
+func newSharedMountTable(ctx context.Context, dirPath string, mounter mount.Interface) (*sharedMountTable, error) {
+	if !path.IsAbs(dirPath) {
+		return nil, errors.Errorf("shared mount directory %s is not an absolute path", dirPath)
+	}
+	if err := fs.MkdirAll(dirPath, 0750); err != nil {
+		return nil, errors.Wrap(err, "failed to create shared mount directory")
+	}
+	t := &sharedMountTable{
+		dirPath: path.Clean(dirPath),
+		locks:   make(map[string]*sync.Mutex),
+		staged:  make(map[string]string),
+	}
+
+	mountPoints, err := mounter.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing mounted filesystems")
+	}
+	mountedKeys := make(map[string]bool)
+	for _, mountPoint := range mountPoints {
+		key, ok := t.getKeyFromMountOptions(mountPoint.Opts)
+		if !ok {
+			continue
+		}
+		mountPath := trimHostRootPath(mountPoint.Path)
+		if mountPath == path.Join(t.getMountDirPath(key), "mount") {
+			mountedKeys[key] = true
+		} else if path.Base(mountPath) == sharedStagingDirName {
+			t.staged[path.Dir(mountPath)] = key
+		}
+	}
+	for key := range mountedKeys {
+		if t.countUsers(key) > 0 {
+			LogDebug(ctx, "Found shared mount", "path", t.getMountDirPath(key), "users", t.countUsers(key))
+			continue
+		}
+		vol := newBeegfsVolume(t.getMountDirPath(key), "", "/", beegfsv1.PluginConfig{})
+		LogDebug(ctx, "Unmounting unused shared mount", "path", vol.mountDirPath)
+		if err := unmountAndCleanUpIfNecessary(ctx, vol, true, mounter); err != nil {
+			LogError(ctx, err, "Failed to unmount unused shared mount", "path", vol.mountDirPath)
+		}
+	}
+	return t, nil
+}
+
+// getMountDirPath returns the mountDirPath of the shared mount identified by key.
+func (t *sharedMountTable) getMountDirPath(key string) string {
+	return path.Join(t.dirPath, key)
+}
+
+// getKeyFromMountOptions returns the key of the shared mount whose beegfs-client.conf file is referenced by the
+// cfgFile option in mountOptions. It returns false if mountOptions do not belong to a shared mount (or a bind mount of
+// one).
+func (t *sharedMountTable) getKeyFromMountOptions(mountOptions []string) (string, bool) {
+	for _, opt := range mountOptions {
+		if !strings.HasPrefix(opt, "cfgFile=") {
+			continue
+		}
+		mountDirPath := path.Dir(trimHostRootPath(strings.TrimPrefix(opt, "cfgFile=")))
+		if path.Dir(mountDirPath) == t.dirPath {
+			return path.Base(mountDirPath), true
+		}
+	}
+	return "", false
+}
+
+// lock obtains the lock for the shared mount identified by key and returns a function that releases it.
+func (t *sharedMountTable) lock(key string) (unlock func()) {
+	t.mutex.Lock()
+	lock, ok := t.locks[key]
+	if !ok {
+		// Locks are never removed, so a goroutine waiting on one never holds a stale lock.
+		lock = &sync.Mutex{}
+		t.locks[key] = lock
+	}
+	t.mutex.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+// findKey returns the key of the shared mount the volume staged at stagingTargetPath uses. It returns false if shared
+// mount mode is disabled or no volume is staged at stagingTargetPath in shared mount mode.
+func (t *sharedMountTable) findKey(stagingTargetPath string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	key, ok := t.staged[stagingTargetPath]
+	return key, ok
+}
+
+// addUser records that the volume staged at stagingTargetPath uses the shared mount identified by key.
+func (t *sharedMountTable) addUser(stagingTargetPath, key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.staged[stagingTargetPath] = key
+}
+
+// removeUser records that no volume is staged at stagingTargetPath.
+func (t *sharedMountTable) removeUser(stagingTargetPath string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.staged, stagingTargetPath)
+}
+
+// countUsers returns the number of staged volumes that use the shared mount identified by key.
+func (t *sharedMountTable) countUsers(key string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	count := 0
+	for _, stagedKey := range t.staged {
+		if stagedKey == key {
+			count++
+		}
+	}
+	return count
+}
+
+// getMountKey returns the name of the subdirectory of a shared mount directory (on a node) or mount cache directory
+// (on the controller) that a volume's file system is mounted to. Volumes share a mount only if they have the same
+// sysMgmtdHost, effective configuration (including connAuth and TLS certificate), and mount options. The key begins
+// with the sysMgmtdHost to make the directory easier to navigate.
+func getMountKey(vol beegfsVolume, mountOptions []string) (string, error) {
+	// BeegfsConfig.MarshalJSON redacts secrets, so they are hashed separately.
+	configJSON, err := json.Marshal(vol.config)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	sortedMountOptions := append([]string{}, mountOptions...)
+	sort.Strings(sortedMountOptions)
+	hash := sha256.New()
+	for _, field := range []string{vol.sysMgmtdHost, string(configJSON), vol.config.ConnAuth, vol.config.TLSCert,
+		strings.Join(sortedMountOptions, ",")} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	return sanitizeVolumeID(vol.sysMgmtdHost) + "_" + hex.EncodeToString(hash.Sum(nil)[:8]), nil
+}
+
+// trimHostRootPath returns mountPath relative to the host's root file system. The node service runs in a container
+// that mounts the host's root file system at /host, so a mount it makes may appear at both /path and /host/path.
+func trimHostRootPath(mountPath string) string {
+	if strings.HasPrefix(mountPath, "/host/") {
+		return strings.TrimPrefix(mountPath, "/host")
+	}
+	return mountPath
+}
+
+// stageSharedVolume stages vol (whose config already includes any secrets from the request) in shared mount mode. It
+// mounts vol's file system to the appropriate shared mount if necessary and bind mounts vol's volDirPath from there
+// to the sharedStagingDirName subdirectory of vol.mountDirPath (the staging target path). Like NodeStageVolume, it
+// returns a gRPC error.
+func (ns *nodeServer) stageSharedVolume(ctx context.Context, vol beegfsVolume, mountOptions []string) error {
+	mountOptions = removeInvalidMountOptions(ctx, mountOptions)
+	key, err := getMountKey(vol, mountOptions)
+	if err != nil {
+		return newGrpcErrorFromCause(codes.Internal, err)
+	}
+	sharedVol := newBeegfsVolume(ns.sharedMounts.getMountDirPath(key), vol.sysMgmtdHost, vol.volDirPathBeegfsRoot,
+		ns.pluginConfig)
+	sharedVol.config = vol.config
+
+	unlock := ns.sharedMounts.lock(key)
+	defer unlock()
+
+	// Don't leave an unused shared mount (or its client files) behind if we fail to stage the first volume that would
+	// use it.
+	cleanUpUnused := func() {
+		if ns.sharedMounts.countUsers(key) == 0 {
+			if err := unmountAndCleanUpIfNecessary(ctx, sharedVol, true, ns.mounter); err != nil {
+				LogError(ctx, err, "Failed to clean up unused shared mount", "path", sharedVol.mountDirPath)
+			}
+		}
+	}
+
+	// Write client files only if the file system is not already mounted, as they include the connClientPort of the
+	// mounted BeeGFS client.
+	notMnt, err := ns.mounter.IsLikelyNotMountPoint(sharedVol.mountPath)
+	if err != nil && !os.IsNotExist(err) {
+		return newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	}
+	if err != nil || notMnt {
+		if err := fs.MkdirAll(sharedVol.mountDirPath, 0750); err != nil {
+			return newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+		}
+		if err := writeClientFiles(ctx, sharedVol, ns.clientConfTemplatePath); err != nil {
+			cleanUpUnused()
+			return newGrpcErrorFromCause(codes.Internal, err)
+		}
+	}
+
+	// Only mount BeeGFS if beegfs-ctl reports our target directory exists.
+	if _, err := ns.ctlExec.statDirectoryForVolume(ctx, sharedVol, sharedVol.volDirPathBeegfsRoot); err != nil {
+		cleanUpUnused()
+		if errors.As(err, &ctlNotExistError{}) {
+			return newGrpcErrorFromCause(codes.NotFound, err)
+		}
+		return newGrpcErrorFromCause(codes.Internal, err)
+	}
+	if err := mountIfNecessary(ctx, sharedVol, mountOptions, ns.mounter); err != nil {
+		cleanUpUnused()
+		return newGrpcErrorFromCause(codes.Internal, err)
+	}
+
+	// Use mounter.IsMountPoint because mounter.IsLikelyNotMountPoint can't detect bind mounts.
+	bindPath := path.Join(vol.mountDirPath, sharedStagingDirName)
+	isMnt, err := ns.mounter.IsMountPoint(bindPath)
+	if os.IsNotExist(err) {
+		if err = fs.MkdirAll(bindPath, 0750); err != nil {
+			cleanUpUnused()
+			return newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+		}
+	} else if err != nil {
+		cleanUpUnused()
+		return newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	}
+	if !isMnt {
+		LogDebug(ctx, "Bind mounting volume from shared mount", "volumeID", vol.volumeID, "volDirPath",
+			sharedVol.volDirPath, "path", bindPath)
+		if err := ns.mounter.Mount(sharedVol.volDirPath, bindPath, "beegfs", []string{"bind"}); err != nil {
+			cleanUpUnused()
+			return newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+		}
+	}
+	ns.sharedMounts.addUser(vol.mountDirPath, key)
+
+	ns.createNodeTrackingFile(ctx, sharedVol)
+	return nil
+}
+
+// unstageSharedVolume unstages the volume identified by volumeID that stageSharedVolume staged at stagingTargetPath.
+// It unmounts the shared mount the volume used if no other staged volume uses it. It does nothing and returns false
+// if no volume is staged at stagingTargetPath in shared mount mode. Like NodeUnstageVolume, it returns a gRPC error.
+func (ns *nodeServer) unstageSharedVolume(ctx context.Context, volumeID, stagingTargetPath string) (bool, error) {
+	key, ok := ns.sharedMounts.findKey(stagingTargetPath)
+	if !ok {
+		return false, nil
+	}
+	sharedVol, err := newBeegfsVolumeFromID(ns.sharedMounts.getMountDirPath(key), volumeID, ns.pluginConfig)
+	if err != nil {
+		return true, newGrpcErrorFromCause(codes.Internal, err)
+	}
+
+	unlock := ns.sharedMounts.lock(key)
+	defer unlock()
+
+	// While the volume is still mounted, delete our nodeID from the appropriate tracking directory.
+	ns.deleteNodeTrackingFile(ctx, sharedVol)
+
+	bindPath := path.Join(stagingTargetPath, sharedStagingDirName)
+	LogDebug(ctx, "Unmounting bind mount of shared mount", "volumeID", volumeID, "path", bindPath)
+	if err := mount.CleanupMountPoint(bindPath, ns.mounter, true); err != nil {
+		return true, newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	}
+	ns.sharedMounts.removeUser(stagingTargetPath)
+
+	if users := ns.sharedMounts.countUsers(key); users > 0 {
+		LogDebug(ctx, "Shared mount is still in use", "path", sharedVol.mountDirPath, "users", users)
+		return true, nil
+	}
+	if err := unmountAndCleanUpIfNecessary(ctx, sharedVol, true, ns.mounter); err != nil {
+		return true, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	return true, nil
+}
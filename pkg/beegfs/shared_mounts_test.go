@@ -0,0 +1,209 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/spf13/afero"
+	"k8s.io/mount-utils"
+)
+
+func TestGetMountKey(t *testing.T) {
+	vol := newBeegfsVolume("/staging", "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	vol.config.ConnAuth = "secret1"
+	baseKey, err := getMountKey(vol, []string{"rw", "nosuid"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.HasPrefix(baseKey, "127.0.0.1_") {
+		t.Errorf("expected key to begin with the sysMgmtdHost, got: %s", baseKey)
+	}
+
+	otherVolume := newBeegfsVolume("/other-staging", "127.0.0.1", "/scratch/pvc-2", beegfsv1.PluginConfig{})
+	otherVolume.config.ConnAuth = "secret1"
+	otherConnAuth := vol
+	otherConnAuth.config.ConnAuth = "secret2"
+	otherConfig := vol
+	otherConfig.config = *beegfsv1.NewBeegfsConfig()
+	otherConfig.config.ConnAuth = "secret1"
+	otherConfig.config.ConnInterfaces = []string{"ib0"}
+	otherSysMgmtdHost := newBeegfsVolume("/staging", "127.0.0.2", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	otherSysMgmtdHost.config.ConnAuth = "secret1"
+
+	tests := map[string]struct {
+		vol          beegfsVolume
+		mountOptions []string
+		wantSame     bool
+	}{
+		"different volume":             {vol: otherVolume, mountOptions: []string{"rw", "nosuid"}, wantSame: true},
+		"mount options in other order": {vol: vol, mountOptions: []string{"nosuid", "rw"}, wantSame: true},
+		"different mount options":      {vol: vol, mountOptions: []string{"ro", "nosuid"}},
+		"different connAuth":           {vol: otherConnAuth, mountOptions: []string{"rw", "nosuid"}},
+		"different config":             {vol: otherConfig, mountOptions: []string{"rw", "nosuid"}},
+		"different sysMgmtdHost":       {vol: otherSysMgmtdHost, mountOptions: []string{"rw", "nosuid"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			key, err := getMountKey(tc.vol, tc.mountOptions)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if (key == baseKey) != tc.wantSame {
+				t.Fatalf("expected same key: %t, got: %s and %s", tc.wantSame, baseKey, key)
+			}
+		})
+	}
+}
+
+func TestNewSharedMountTable(t *testing.T) {
+	fs = afero.NewOsFs()
+	fsutil = afero.Afero{Fs: fs}
+	sharedDir := t.TempDir()
+	const (
+		stagingA = "/var/lib/kubelet/plugins/kubernetes.io/csi/beegfs.csi.netapp.com/a/globalmount"
+		stagingB = "/var/lib/kubelet/plugins/kubernetes.io/csi/beegfs.csi.netapp.com/b/globalmount"
+		stagingC = "/var/lib/kubelet/plugins/kubernetes.io/csi/beegfs.csi.netapp.com/c/globalmount"
+		target   = "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~csi/pvc-a/mount"
+	)
+	usedOpts := []string{"rw", "cfgFile=" + path.Join(sharedDir, "used", "beegfs-client.conf")}
+	unusedOpts := []string{"rw", "cfgFile=" + path.Join(sharedDir, "unused", "beegfs-client.conf")}
+	legacyOpts := []string{"rw", "cfgFile=" + path.Join(stagingC, "beegfs-client.conf")}
+	for _, dir := range []string{path.Join(sharedDir, "used", "mount"), path.Join(sharedDir, "unused", "mount")} {
+		if err := fs.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("failed to create mount point: %v", err)
+		}
+	}
+	mounter := mount.NewFakeMounter([]mount.MountPoint{
+		// The shared mounts.
+		{Device: "beegfs_nodev", Path: path.Join(sharedDir, "used", "mount"), Type: "beegfs", Opts: usedOpts},
+		{Device: "beegfs_nodev", Path: path.Join(sharedDir, "unused", "mount"), Type: "beegfs", Opts: unusedOpts},
+		// Volumes staged in shared mount mode (one of which also appears under /host).
+		{Device: "beegfs_nodev", Path: path.Join(stagingA, "volume"), Type: "beegfs", Opts: usedOpts},
+		{Device: "beegfs_nodev", Path: path.Join("/host", stagingA, "volume"), Type: "beegfs", Opts: usedOpts},
+		{Device: "beegfs_nodev", Path: path.Join(stagingB, "volume"), Type: "beegfs", Opts: usedOpts},
+		// A volume published from a shared mount.
+		{Device: "beegfs_nodev", Path: target, Type: "beegfs", Opts: usedOpts},
+		// A volume staged normally.
+		{Device: "beegfs_nodev", Path: path.Join(stagingC, "mount"), Type: "beegfs", Opts: legacyOpts},
+	})
+
+	table, err := newSharedMountTable(context.Background(), sharedDir, mounter)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for stagingTargetPath, wantKey := range map[string]string{stagingA: "used", stagingB: "used", stagingC: ""} {
+		key, ok := table.findKey(stagingTargetPath)
+		if key != wantKey || ok != (wantKey != "") {
+			t.Errorf("expected key %q for %s, got: %q", wantKey, stagingTargetPath, key)
+		}
+	}
+	if users := table.countUsers("used"); users != 2 {
+		t.Errorf("expected 2 users of shared mount, got: %d", users)
+	}
+	mountPoints, _ := mounter.List()
+	for _, mountPoint := range mountPoints {
+		if mountPoint.Path == path.Join(sharedDir, "unused", "mount") {
+			t.Errorf("expected unused shared mount to be unmounted")
+		}
+	}
+	if exists, _ := fsutil.Exists(path.Join(sharedDir, "unused")); exists {
+		t.Errorf("expected unused shared mount directory to be removed")
+	}
+
+	if _, err := newSharedMountTable(context.Background(), "relative", mounter); err == nil {
+		t.Errorf("expected error for relative shared mount directory")
+	}
+}
+
+func TestStageSharedVolume(t *testing.T) {
+	fs = afero.NewOsFs()
+	fsutil = afero.Afero{Fs: fs}
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	confTemplatePath := path.Join(tempDir, "beegfs-client.conf")
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	mounter := mount.NewFakeMounter(nil)
+	ns := newNodeServerSanity("testID", beegfsv1.PluginConfig{}, confTemplatePath)
+	ns.mounter = mounter
+	var err error
+	if ns.sharedMounts, err = newSharedMountTable(ctx, path.Join(tempDir, "shared"), mounter); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	volCap := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}}
+	volumeIDs := []string{"beegfs://127.0.0.1/scratch/pvc-1", "beegfs://127.0.0.1/scratch/pvc-2"}
+	stagingTargetPaths := []string{path.Join(tempDir, "staging-1"), path.Join(tempDir, "staging-2")}
+	for i := range volumeIDs {
+		if err := fs.MkdirAll(stagingTargetPaths[i], 0750); err != nil {
+			t.Fatalf("failed to create staging target path: %v", err)
+		}
+		// Stage each volume twice to verify NodeStageVolume is idempotent.
+		for j := 0; j < 2; j++ {
+			if _, err := ns.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{VolumeId: volumeIDs[i],
+				StagingTargetPath: stagingTargetPaths[i], VolumeCapability: volCap}); err != nil {
+				t.Fatalf("expected no error staging volume, got: %v", err)
+			}
+		}
+	}
+
+	key, ok := ns.sharedMounts.findKey(stagingTargetPaths[0])
+	if !ok {
+		t.Fatalf("expected volume to be staged in shared mount mode")
+	}
+	sharedMountPath := path.Join(ns.sharedMounts.getMountDirPath(key), "mount")
+	var sharedMounts, bindMounts int
+	mountPoints, _ := mounter.List()
+	for _, mountPoint := range mountPoints {
+		if mountPoint.Path == sharedMountPath {
+			sharedMounts++
+		} else if path.Base(mountPoint.Path) == sharedStagingDirName {
+			bindMounts++
+		}
+	}
+	if sharedMounts != 1 || bindMounts != 2 {
+		t.Fatalf("expected 1 shared mount and 2 bind mounts, got: %d and %d", sharedMounts, bindMounts)
+	}
+
+	targetPath := path.Join(tempDir, "target")
+	if _, err := ns.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{VolumeId: volumeIDs[0],
+		StagingTargetPath: stagingTargetPaths[0], TargetPath: targetPath, VolumeCapability: volCap}); err != nil {
+		t.Fatalf("expected no error publishing volume, got: %v", err)
+	}
+	// Like Linux, FakeMounter reports the source of a bind mount of a bind mount as the original source.
+	for _, action := range mounter.GetLog() {
+		if action.Action == mount.FakeActionMount && action.Target == targetPath &&
+			action.Source != path.Join(sharedMountPath, "scratch", "pvc-1") {
+			t.Errorf("expected volume to be published from its bind mount, got: %s", action.Source)
+		}
+	}
+	if _, err := ns.NodeUnpublishVolume(ctx, &csi.NodeUnpublishVolumeRequest{VolumeId: volumeIDs[0],
+		TargetPath: targetPath}); err != nil {
+		t.Fatalf("expected no error unpublishing volume, got: %v", err)
+	}
+
+	// The shared mount must remain until the last volume is unstaged.
+	for i := range volumeIDs {
+		if _, err := ns.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{VolumeId: volumeIDs[i],
+			StagingTargetPath: stagingTargetPaths[i]}); err != nil {
+			t.Fatalf("expected no error unstaging volume, got: %v", err)
+		}
+		notMnt, _ := mounter.IsLikelyNotMountPoint(sharedMountPath)
+		if wantMounted := i < len(volumeIDs)-1; notMnt == wantMounted {
+			t.Fatalf("expected shared mount mounted: %t after unstaging %d volumes", wantMounted, i+1)
+		}
+	}
+	if exists, _ := fsutil.Exists(ns.sharedMounts.getMountDirPath(key)); exists {
+		t.Errorf("expected shared mount directory to be removed")
+	}
+}
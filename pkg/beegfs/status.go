@@ -0,0 +1,116 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The controller service can periodically probe every file system in its configuration and report the results by
+// annotating its own Pod. The operator reads the annotation to populate the status of a BeegfsDriver, but an
+// administrator can also read it directly (e.g. with kubectl describe pod).
+
+// statusProbeDirName is the directory in csDataDir the controller service writes client files to when it probes a
+// file system. Each file system gets its own subdirectory named after its sysMgmtdHost.
+const statusProbeDirName = ".status"
+
+// EnableStatusReporting configures the controller service to probe each file system in its configuration every
+// interval and report the results as JSON in the beegfsv1.AnnotationFileSystemStatus annotation on the Pod identified
+// by pod (in the form <namespace>/<name>). It must be called before Run.
+func (b *beegfs) EnableStatusReporting(pod string, interval time.Duration) error {
+	namespace, name, found := strings.Cut(pod, "/")
+	if !found || namespace == "" || name == "" {
+		return errors.Errorf("invalid status report Pod %s (expected <namespace>/<name>)", pod)
+	}
+	if interval <= 0 {
+		return errors.Errorf("invalid status report interval %s", interval)
+	}
+	b.statusReportPodNamespace = namespace
+	b.statusReportPodName = name
+	b.statusReportInterval = interval
+	return nil
+}
+
+// reportStatusPeriodically calls reportStatus every b.statusReportInterval until stop is closed.
+func (b *beegfs) reportStatusPeriodically(stop <-chan struct{}) {
+	ticker := time.NewTicker(b.statusReportInterval)
+	defer ticker.Stop()
+	for {
+		// Don't let a hung beegfs-ctl delay the next report indefinitely.
+		ctx, cancel := context.WithTimeout(context.Background(), b.statusReportInterval)
+		if err := b.reportStatus(ctx); err != nil {
+			LogError(ctx, err, "Failed to report file system status", "pod",
+				b.statusReportPodNamespace+"/"+b.statusReportPodName)
+		}
+		cancel()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportStatus probes every file system and annotates the status report Pod with the results.
+func (b *beegfs) reportStatus(ctx context.Context) error {
+	statuses := b.cs.probeFileSystems(ctx)
+	statusesJSON, err := json.Marshal(statuses)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return patchPodAnnotation(ctx, b.statusReportPodNamespace, b.statusReportPodName,
+		beegfsv1.AnnotationFileSystemStatus, string(statusesJSON))
+}
+
+// probeFileSystems probes every file system with a fileSystemSpecificConfig in the controller service's configuration.
+// The controller service has no way to know about other file systems until it is asked to create a volume on them.
+func (cs *controllerServer) probeFileSystems(ctx context.Context) []beegfsv1.FileSystemStatus {
+	statuses := make([]beegfsv1.FileSystemStatus, 0, len(cs.pluginConfig.FileSystemSpecificConfigs))
+	for _, fsConfig := range cs.pluginConfig.FileSystemSpecificConfigs {
+		statuses = append(statuses, cs.probeFileSystem(ctx, fsConfig.SysMgmtdHost))
+	}
+	return statuses
+}
+
+// probeFileSystem uses beegfs-ctl to determine whether the management service of the file system at sysMgmtdHost is
+// reachable and which version of BeeGFS it runs. probeFileSystem does not mount the file system.
+func (cs *controllerServer) probeFileSystem(ctx context.Context, sysMgmtdHost string) beegfsv1.FileSystemStatus {
+	status := beegfsv1.FileSystemStatus{SysMgmtdHost: sysMgmtdHost, LastProbeTime: metav1.Now()}
+	mountDirPath := path.Join(cs.csDataDir, statusProbeDirName, sysMgmtdHost)
+	vol := newBeegfsVolume(mountDirPath, sysMgmtdHost, "/", cs.pluginConfig)
+
+	defer func() {
+		if err := cleanUpIfNecessary(ctx, vol, true); err != nil {
+			LogError(ctx, err, "Failed to clean up path after probing file system", "path", vol.mountDirPath)
+		}
+	}()
+	if err := fs.MkdirAll(vol.mountDirPath, 0750); err != nil {
+		status.Message = errors.WithStack(err).Error()
+		return status
+	}
+	if err := writeClientFiles(ctx, vol, cs.clientConfTemplatePath); err != nil {
+		status.Message = err.Error()
+		return status
+	}
+
+	version, err := cs.ctlExec.getBeegfsVersion(ctx, vol)
+	if err != nil {
+		LogDebug(ctx, "File system is not reachable", "sysMgmtdHost", sysMgmtdHost, "error", err)
+		status.Message = err.Error()
+		return status
+	}
+	status.Reachable = true
+	status.BeegfsVersion = version
+	return status
+}
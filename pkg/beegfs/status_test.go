@@ -0,0 +1,154 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/spf13/afero"
+)
+
+// unreachableBeegfsCtlExecutor behaves like fakeBeegfsCtlExecutor except that no file system is ever reachable.
+type unreachableBeegfsCtlExecutor struct {
+	fakeBeegfsCtlExecutor
+}
+
+func (*unreachableBeegfsCtlExecutor) getBeegfsVersion(ctx context.Context, vol beegfsVolume) (string, error) {
+	return "", errors.New("unable to verify if this is a BeeGFS 7 or 8 volume")
+}
+
+func TestEnableStatusReporting(t *testing.T) {
+	tests := map[string]struct {
+		pod      string
+		interval time.Duration
+		wantErr  bool
+	}{
+		"valid":              {pod: "beegfs-csi/csi-beegfs-controller-0", interval: time.Minute},
+		"missing namespace":  {pod: "/csi-beegfs-controller-0", interval: time.Minute, wantErr: true},
+		"missing name":       {pod: "beegfs-csi/", interval: time.Minute, wantErr: true},
+		"missing separator":  {pod: "csi-beegfs-controller-0", interval: time.Minute, wantErr: true},
+		"nonpositive period": {pod: "beegfs-csi/csi-beegfs-controller-0", interval: 0, wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := new(beegfs)
+			err := b.EnableStatusReporting(tc.pod, tc.interval)
+			if tc.wantErr && err == nil {
+				t.Fatal("expected an error")
+			}
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("expected no error: %v", err)
+				}
+				if b.statusReportPodNamespace != "beegfs-csi" || b.statusReportPodName != "csi-beegfs-controller-0" {
+					t.Fatalf("unexpected Pod %s/%s", b.statusReportPodNamespace, b.statusReportPodName)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeFileSystems(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	fsutil = afero.Afero{Fs: fs}
+	confTemplatePath := "/testTemplate/beegfs-client.conf"
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	pluginConfig := beegfsv1.PluginConfig{
+		FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+			{SysMgmtdHost: "127.0.0.1"},
+			{SysMgmtdHost: "127.0.0.2"},
+		},
+	}
+
+	tests := map[string]struct {
+		ctlExec       beegfsCtlExecutorInterface
+		wantReachable bool
+		wantVersion   string
+	}{
+		"reachable":   {ctlExec: &fakeBeegfsCtlExecutor{}, wantReachable: true, wantVersion: "8"},
+		"unreachable": {ctlExec: &unreachableBeegfsCtlExecutor{}, wantReachable: false, wantVersion: ""},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cs := newControllerServerSanity("node", pluginConfig, confTemplatePath, "/csDataDir", 0)
+			cs.ctlExec = tc.ctlExec
+			statuses := cs.probeFileSystems(context.Background())
+			if len(statuses) != 2 {
+				t.Fatalf("expected 2 statuses, got %d", len(statuses))
+			}
+			for i, status := range statuses {
+				if status.SysMgmtdHost != pluginConfig.FileSystemSpecificConfigs[i].SysMgmtdHost {
+					t.Errorf("expected sysMgmtdHost %s, got %s",
+						pluginConfig.FileSystemSpecificConfigs[i].SysMgmtdHost, status.SysMgmtdHost)
+				}
+				if status.Reachable != tc.wantReachable || status.BeegfsVersion != tc.wantVersion {
+					t.Errorf("expected reachable=%t and version %q, got reachable=%t and version %q",
+						tc.wantReachable, tc.wantVersion, status.Reachable, status.BeegfsVersion)
+				}
+				if !tc.wantReachable && status.Message == "" {
+					t.Error("expected a message explaining why the file system is not reachable")
+				}
+				if status.LastProbeTime.IsZero() {
+					t.Error("expected lastProbeTime to be set")
+				}
+				probeDirPath := path.Join("/csDataDir", statusProbeDirName, status.SysMgmtdHost)
+				if exists, _ := fsutil.Exists(probeDirPath); exists {
+					t.Errorf("expected %s to be cleaned up", probeDirPath)
+				}
+			}
+		})
+	}
+}
+
+func TestReportStatus(t *testing.T) {
+	fs = afero.NewMemMapFs()
+	fsutil = afero.Afero{Fs: fs}
+	confTemplatePath := "/testTemplate/beegfs-client.conf"
+	if err := fsutil.WriteFile(confTemplatePath, []byte(TestWriteClientFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	pluginConfig := beegfsv1.PluginConfig{
+		FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{{SysMgmtdHost: "127.0.0.1"}},
+	}
+
+	var gotNamespace, gotName, gotKey, gotValue string
+	defer func(orig func(ctx context.Context, namespace, name, key, value string) error) {
+		patchPodAnnotation = orig
+	}(patchPodAnnotation)
+	patchPodAnnotation = func(ctx context.Context, namespace, name, key, value string) error {
+		gotNamespace, gotName, gotKey, gotValue = namespace, name, key, value
+		return nil
+	}
+
+	b := &beegfs{cs: newControllerServerSanity("node", pluginConfig, confTemplatePath, "/csDataDir", 0)}
+	if err := b.EnableStatusReporting("beegfs-csi/csi-beegfs-controller-0", time.Minute); err != nil {
+		t.Fatalf("expected no error: %v", err)
+	}
+	if err := b.reportStatus(context.Background()); err != nil {
+		t.Fatalf("expected no error: %v", err)
+	}
+	if gotNamespace != "beegfs-csi" || gotName != "csi-beegfs-controller-0" {
+		t.Fatalf("expected Pod beegfs-csi/csi-beegfs-controller-0 to be patched, got %s/%s", gotNamespace, gotName)
+	}
+	if gotKey != beegfsv1.AnnotationFileSystemStatus {
+		t.Fatalf("expected annotation %s, got %s", beegfsv1.AnnotationFileSystemStatus, gotKey)
+	}
+	var statuses []beegfsv1.FileSystemStatus
+	if err := json.Unmarshal([]byte(gotValue), &statuses); err != nil {
+		t.Fatalf("failed to unmarshal annotation: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].SysMgmtdHost != "127.0.0.1" || !statuses[0].Reachable {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
@@ -0,0 +1,514 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+)
+
+// Removing a volume directory with millions of files can take hours, so DeleteVolume only renames it into the trash
+// directory of its volDirBasePath (volDirBasePath/.csi/trash/<volName>.<timestamp>) and returns. The controller
+// service's volumeDeleter then removes the contents of the trash in the background with a pool of workers that each
+// list and remove a single directory at a time, so the directories of a large volume are removed in parallel.
+//
+// The volumeDeleter records every trash directory it is removing in csDataDir/.deletions, so it can resume after the
+// controller service restarts. It also looks for trash directories it has no record of (e.g. because the controller
+// service moved to another node) whenever it moves a volume into a trash directory. The volumeDeleter mounts a file
+// system with the controller service's configuration (not the secrets from a DeleteVolume request) when it resumes.
+
+const (
+	// trashDirName is the directory in volDirBasePath/.csi that DeleteVolume moves volume directories into.
+	trashDirName = "trash"
+	// deletionRecordDirName is the directory in csDataDir the volumeDeleter records pending deletions in.
+	deletionRecordDirName = ".deletions"
+	// defaultDeletionWorkers is the default number of directories the volumeDeleter removes in parallel.
+	defaultDeletionWorkers = 16
+	// deletionBatchSize is the number of directory entries the volumeDeleter reads at a time.
+	deletionBatchSize = 1000
+	// deletionRetryInterval is how long the volumeDeleter waits to retry a deletion that failed once. The wait doubles
+	// with each consecutive failure up to deletionMaxRetryInterval.
+	deletionRetryInterval = time.Minute
+	// deletionMaxRetryInterval is the longest the volumeDeleter waits to retry a deletion that failed.
+	deletionMaxRetryInterval = time.Hour
+	// deletionProgressInterval is the number of entries the volumeDeleter removes from a trash directory between
+	// progress messages.
+	deletionProgressInterval = 100000
+)
+
+// volumeDeleter removes trash directories in the background.
+type volumeDeleter struct {
+	recordDirPath string
+	pluginConfig  beegfsv1.PluginConfig
+	mountCache    *mountCache
+	workers       int
+	wg            sync.WaitGroup
+	mutex         sync.Mutex // guards everything below
+	cond          *sync.Cond // signaled when a task is added or the volumeDeleter is stopped
+	stopped       bool
+	tasks         []*deletionTask         // directories ready to be listed and removed
+	jobs          map[string]*deletionJob // trash directory volumeID -> job (including failed jobs awaiting a retry)
+}
+
+// deletionJob tracks the removal of a single trash directory.
+type deletionJob struct {
+	vol            beegfsVolume // a volume whose volDirPath is the trash directory
+	release        func()       // releases the cached mount the job uses
+	started        time.Time
+	entriesRemoved int64     // accessed atomically
+	failures       int       // guarded by volumeDeleter.mutex; consecutive failed attempts
+	retryAt        time.Time // guarded by volumeDeleter.mutex; zero unless the job is waiting to be retried
+	mutex          sync.Mutex
+	err            error // guarded by mutex; the first error the current attempt encountered
+	lastErr        error // guarded by mutex; the first error the previous (failed) attempt encountered
+}
+
+// deletionTask is a directory (within a trash directory) to list and remove. A deletionTask is removed once all of its
+// subdirectories are removed.
+type deletionTask struct {
+	path    string
+	parent  *deletionTask
+	job     *deletionJob
+	pending int32 // accessed atomically; subdirectories not yet removed, plus one until the directory has been listed
+}
+
+// deletionProgress describes the progress of a deletionJob. The diagnostics endpoint serves it at
+// diagnosticsDeletionsPath.
+type deletionProgress struct {
+	VolumeID       string     `json:"volumeID"`      // the volumeID of the deleted volume
+	TrashVolumeID  string     `json:"trashVolumeID"` // a volumeID that refers to the trash directory
+	Started        time.Time  `json:"started"`
+	EntriesRemoved int64      `json:"entriesRemoved"`
+	Failures       int        `json:"failures,omitempty"` // consecutive failed attempts
+	RetryAt        *time.Time `json:"retryAt,omitempty"`  // when a failed deletion will be retried
+	Error          string     `json:"error,omitempty"`    // the error of the current or most recent failed attempt
+}
+
+// SetDeletionWorkers configures the number of directories the controller service removes from the trash in
+// parallel. It must be called before Run.
+func (b *beegfs) SetDeletionWorkers(workers int) error {
+	if workers < 1 {
+		return errors.Errorf("invalid number of deletion workers %d", workers)
+	}
+	b.cs.volumeDeleter.workers = workers
+	return nil
+}
+
+// newVolumeDeleter returns a volumeDeleter that records pending deletions in recordDirPath and mounts file systems with
+// mountCache. It does nothing until it is started.
+func newVolumeDeleter(recordDirPath string, pluginConfig beegfsv1.PluginConfig, mountCache *mountCache) *volumeDeleter {
+	d := &volumeDeleter{
+		recordDirPath: recordDirPath,
+		pluginConfig:  pluginConfig,
+		mountCache:    mountCache,
+		workers:       defaultDeletionWorkers,
+		jobs:          make(map[string]*deletionJob),
+	}
+	d.cond = sync.NewCond(&d.mutex)
+	return d
+}
+
+// getTrashDirPathBeegfsRoot returns the path of the trash directory for vol's volDirBasePath from the BeeGFS root.
+func getTrashDirPathBeegfsRoot(vol beegfsVolume) string {
+	return path.Join(vol.volDirBasePathBeegfsRoot, ".csi", trashDirName)
+}
+
+// newTrashVolume returns a volume whose volDirPath is the trash directory at trashPathBeegfsRoot on the file system
+// at sysMgmtdHost. mountCache.acquire selects the mountDirPath.
+func newTrashVolume(sysMgmtdHost, trashPathBeegfsRoot string, config beegfsv1.BeegfsConfig) beegfsVolume {
+	vol := newBeegfsVolume("", sysMgmtdHost, trashPathBeegfsRoot, beegfsv1.PluginConfig{})
+	vol.config = config
+	return vol
+}
+
+// getTrashedVolumeID returns the volumeID of the volume that was moved into the trash directory trashVol refers to.
+func getTrashedVolumeID(trashVol beegfsVolume) string {
+	volName := path.Base(trashVol.volDirPathBeegfsRoot)
+	if i := strings.LastIndex(volName, "."); i > 0 {
+		volName = volName[:i]
+	}
+	// volDirBasePathBeegfsRoot is volDirBasePath/.csi/trash.
+	volDirBasePathBeegfsRoot := path.Dir(path.Dir(trashVol.volDirBasePathBeegfsRoot))
+	return NewBeegfsURL(trashVol.sysMgmtdHost, path.Join(volDirBasePathBeegfsRoot, volName))
+}
+
+// moveVolumeToTrash renames vol's volDirPath (on a mounted file system) into the trash directory for its
+// volDirBasePath. It returns the path of the new trash directory from the BeeGFS root or an empty string if vol's
+// volDirPath does not exist.
+func moveVolumeToTrash(ctx context.Context, vol beegfsVolume) (string, error) {
+	if exists, err := fsutil.DirExists(vol.volDirPath); err != nil {
+		return "", errors.WithStack(err)
+	} else if !exists {
+		LogDebug(ctx, "BeeGFS directory already deleted", "path", vol.volDirPathBeegfsRoot, "volumeID", vol.volumeID)
+		return "", nil
+	}
+	trashPathBeegfsRoot, err := moveToTrash(vol.mountPath, vol.volDirPathBeegfsRoot, vol.volDirBasePathBeegfsRoot,
+		path.Base(vol.volDirPathBeegfsRoot))
+	if err != nil {
+		return "", err
+	}
+	LogDebug(ctx, "Moved BeeGFS directory to trash", "path", vol.volDirPathBeegfsRoot, "trashPath",
+		trashPathBeegfsRoot, "volumeID", vol.volumeID)
+	return trashPathBeegfsRoot, nil
+}
+
+// moveToTrash renames dirPathBeegfsRoot (on the file system mounted at mountPath) into the trash directory of
+// volDirBasePathBeegfsRoot under a new name that identifies the volume volName. It returns the path of the new trash
+// directory from the BeeGFS root.
+func moveToTrash(mountPath, dirPathBeegfsRoot, volDirBasePathBeegfsRoot, volName string) (string, error) {
+	trashDirPathBeegfsRoot := path.Join(volDirBasePathBeegfsRoot, ".csi", trashDirName)
+	if err := fs.MkdirAll(path.Join(mountPath, trashDirPathBeegfsRoot), 0750); err != nil {
+		return "", errors.WithStack(err)
+	}
+	trashPathBeegfsRoot := path.Join(trashDirPathBeegfsRoot, fmt.Sprintf("%s.%d", volName, time.Now().UnixNano()))
+	if err := fs.Rename(path.Join(mountPath, dirPathBeegfsRoot), path.Join(mountPath, trashPathBeegfsRoot)); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return trashPathBeegfsRoot, nil
+}
+
+// start starts the volumeDeleter's workers and resumes every deletion recorded in its record directory.
+func (d *volumeDeleter) start(ctx context.Context) {
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	for _, trashVolumeID := range readRecords(ctx, d.recordDirPath) {
+		sysMgmtdHost, trashPathBeegfsRoot, err := parseBeegfsURL(trashVolumeID)
+		if err != nil {
+			LogError(ctx, err, "Invalid deletion record", "trashVolumeID", trashVolumeID)
+			continue
+		}
+		LogDebug(ctx, "Resuming deletion", "trashVolumeID", trashVolumeID)
+		d.add(ctx, newTrashVolume(sysMgmtdHost, trashPathBeegfsRoot,
+			squashConfigForSysMgmtdHost(sysMgmtdHost, d.pluginConfig)))
+	}
+}
+
+// stop stops the volumeDeleter's workers once they finish the directories they are working on and abandons all other
+// work. The volumeDeleter resumes the abandoned deletions the next time it starts. stop does not release the cached
+// mounts in use by abandoned deletions, so it is only appropriate at shutdown.
+func (d *volumeDeleter) stop() {
+	d.mutex.Lock()
+	d.stopped = true
+	d.tasks = nil
+	d.cond.Broadcast()
+	d.mutex.Unlock()
+	d.wg.Wait()
+}
+
+// addTrash starts removing every trash directory in the trash directory for vol's volDirBasePath (on a mounted file
+// system) that the volumeDeleter is not already removing.
+func (d *volumeDeleter) addTrash(ctx context.Context, vol beegfsVolume) {
+	trashDirPathBeegfsRoot := getTrashDirPathBeegfsRoot(vol)
+	entries, err := fsutil.ReadDir(path.Join(vol.mountPath, trashDirPathBeegfsRoot))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			LogError(ctx, errors.WithStack(err), "Failed to read trash directory", "path", trashDirPathBeegfsRoot)
+		}
+		return
+	}
+	for _, entry := range entries {
+		d.add(ctx, newTrashVolume(vol.sysMgmtdHost, path.Join(trashDirPathBeegfsRoot, entry.Name()), vol.config))
+	}
+}
+
+// add starts removing the trash directory trashVol refers to unless the volumeDeleter is already removing it.
+func (d *volumeDeleter) add(ctx context.Context, trashVol beegfsVolume) {
+	d.mutex.Lock()
+	if _, ok := d.jobs[trashVol.volumeID]; ok || d.stopped {
+		d.mutex.Unlock()
+		return
+	}
+	job := &deletionJob{vol: trashVol, started: time.Now()}
+	d.jobs[trashVol.volumeID] = job
+	volumeDeletionsPending.Set(float64(len(d.jobs)))
+	d.mutex.Unlock()
+
+	if err := writeRecord(d.recordDirPath, trashVol.volumeID); err != nil {
+		// We can still remove the trash directory. We just won't resume if we restart before we finish.
+		LogError(ctx, err, "Failed to record deletion", "trashVolumeID", trashVol.volumeID)
+	}
+	d.run(ctx, job)
+}
+
+// run starts an attempt to remove job's trash directory.
+func (d *volumeDeleter) run(ctx context.Context, job *deletionJob) {
+	cachedVol, release, err := d.mountCache.acquire(ctx, job.vol)
+	if err != nil {
+		job.setErr(err)
+		d.finishJob(ctx, job)
+		return
+	}
+	job.release = release
+	LogDebug(ctx, "Removing BeeGFS directory from trash", "path", job.vol.volDirPathBeegfsRoot,
+		"volumeID", getTrashedVolumeID(job.vol))
+	d.push(&deletionTask{path: cachedVol.volDirPath, job: job, pending: 1})
+}
+
+// retry starts another attempt to remove the trash directory of job (which failed) unless the volumeDeleter is stopped
+// or job is no longer waiting to be retried.
+func (d *volumeDeleter) retry(ctx context.Context, job *deletionJob) {
+	d.mutex.Lock()
+	if d.stopped || d.jobs[job.vol.volumeID] != job || job.retryAt.IsZero() {
+		d.mutex.Unlock()
+		return
+	}
+	job.retryAt = time.Time{}
+	failures := job.failures
+	d.mutex.Unlock()
+
+	job.mutex.Lock()
+	job.lastErr, job.err = job.err, nil
+	job.mutex.Unlock()
+	LogDebug(ctx, "Retrying removal of BeeGFS directory from trash", "path", job.vol.volDirPathBeegfsRoot,
+		"failures", failures)
+	d.run(ctx, job)
+}
+
+// push queues task for a worker.
+func (d *volumeDeleter) push(task *deletionTask) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped {
+		return
+	}
+	d.tasks = append(d.tasks, task)
+	d.cond.Signal()
+}
+
+// work removes directories until the volumeDeleter is stopped.
+func (d *volumeDeleter) work() {
+	defer d.wg.Done()
+	for {
+		d.mutex.Lock()
+		for len(d.tasks) == 0 && !d.stopped {
+			d.cond.Wait()
+		}
+		if d.stopped {
+			d.mutex.Unlock()
+			return
+		}
+		// Work depth first to limit the number of queued directories.
+		task := d.tasks[len(d.tasks)-1]
+		d.tasks = d.tasks[:len(d.tasks)-1]
+		d.mutex.Unlock()
+		d.removeDir(context.TODO(), task)
+	}
+}
+
+// removeDir removes every file in task's directory and queues its subdirectories. The directory itself is removed by
+// finishTask once its subdirectories are removed.
+func (d *volumeDeleter) removeDir(ctx context.Context, task *deletionTask) {
+	dir, err := fs.Open(task.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			task.job.setErr(errors.WithStack(err))
+		}
+		d.finishTask(ctx, task)
+		return
+	}
+	defer d.finishTask(ctx, task)
+	defer dir.Close()
+	for {
+		infos, err := dir.Readdir(deletionBatchSize)
+		for _, info := range infos {
+			entryPath := path.Join(task.path, info.Name())
+			if info.IsDir() {
+				atomic.AddInt32(&task.pending, 1)
+				d.push(&deletionTask{path: entryPath, parent: task, job: task.job, pending: 1})
+				continue
+			}
+			if err := fs.Remove(entryPath); err != nil && !os.IsNotExist(err) {
+				task.job.setErr(errors.WithStack(err))
+				continue
+			}
+			d.countRemoved(ctx, task.job)
+		}
+		if err != nil {
+			if err != io.EOF {
+				task.job.setErr(errors.WithStack(err))
+			}
+			return
+		}
+	}
+}
+
+// finishTask records that task's directory has been listed or one of its subdirectories has been removed. Once both
+// are true of all of them, it removes task's directory and finishes its parent (or its job).
+func (d *volumeDeleter) finishTask(ctx context.Context, task *deletionTask) {
+	if atomic.AddInt32(&task.pending, -1) > 0 {
+		return
+	}
+	if err := fs.Remove(task.path); err != nil && !os.IsNotExist(err) {
+		task.job.setErr(errors.WithStack(err))
+	} else if err == nil {
+		d.countRemoved(ctx, task.job)
+	}
+	if task.parent != nil {
+		d.finishTask(ctx, task.parent)
+		return
+	}
+	d.finishJob(ctx, task.job)
+}
+
+// countRemoved records that job removed an entry.
+func (d *volumeDeleter) countRemoved(ctx context.Context, job *deletionJob) {
+	volumeDeletionEntriesRemovedTotal.Inc()
+	if removed := atomic.AddInt64(&job.entriesRemoved, 1); removed%deletionProgressInterval == 0 {
+		LogDebug(ctx, "Removing BeeGFS directory from trash", "path", job.vol.volDirPathBeegfsRoot,
+			"entriesRemoved", removed, "elapsed", time.Since(job.started).Round(time.Second).String())
+	}
+}
+
+// finishJob releases job's cached mount and either forgets job (if it succeeded) or keeps it (with its error) and
+// schedules it to be retried.
+func (d *volumeDeleter) finishJob(ctx context.Context, job *deletionJob) {
+	if job.release != nil {
+		job.release()
+		job.release = nil
+	}
+
+	if err := job.getErr(); err != nil {
+		d.mutex.Lock()
+		job.failures++
+		retryIn := getDeletionRetryInterval(job.failures)
+		job.retryAt = time.Now().Add(retryIn)
+		failures := job.failures
+		d.mutex.Unlock()
+		volumeDeletionFailuresTotal.Inc()
+		LogError(ctx, err, "Failed to remove BeeGFS directory from trash", "path", job.vol.volDirPathBeegfsRoot,
+			"failures", failures, "retryIn", retryIn.String())
+		time.AfterFunc(retryIn, func() { d.retry(context.TODO(), job) })
+		return
+	}
+	d.mutex.Lock()
+	delete(d.jobs, job.vol.volumeID)
+	volumeDeletionsPending.Set(float64(len(d.jobs)))
+	d.mutex.Unlock()
+	volumeDeletionsCompletedTotal.Inc()
+	LogDebug(ctx, "Removed BeeGFS directory from trash", "path", job.vol.volDirPathBeegfsRoot,
+		"entriesRemoved", atomic.LoadInt64(&job.entriesRemoved),
+		"elapsed", time.Since(job.started).Round(time.Second).String())
+	if err := removeRecord(d.recordDirPath, job.vol.volumeID); err != nil {
+		LogError(ctx, err, "Failed to remove deletion record", "trashVolumeID", job.vol.volumeID)
+	}
+}
+
+// getDeletionRetryInterval returns how long the volumeDeleter waits to retry a deletion after its failures-th
+// consecutive failure.
+func getDeletionRetryInterval(failures int) time.Duration {
+	interval := deletionRetryInterval
+	for i := 1; i < failures && interval < deletionMaxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > deletionMaxRetryInterval {
+		return deletionMaxRetryInterval
+	}
+	return interval
+}
+
+// getRecordPath returns the path of the file in recordDirPath that records volumeID.
+func getRecordPath(recordDirPath, volumeID string) string {
+	return path.Join(recordDirPath, sanitizeVolumeID(volumeID))
+}
+
+// writeRecord records volumeID in recordDirPath.
+func writeRecord(recordDirPath, volumeID string) error {
+	if err := fs.MkdirAll(recordDirPath, 0750); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := fsutil.WriteFile(getRecordPath(recordDirPath, volumeID), []byte(volumeID), 0640); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// removeRecord removes the record of volumeID from recordDirPath (if there is one).
+func removeRecord(recordDirPath, volumeID string) error {
+	if err := fs.Remove(getRecordPath(recordDirPath, volumeID)); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// readRecords returns every volumeID recorded in recordDirPath. It logs (but skips) records it cannot read.
+func readRecords(ctx context.Context, recordDirPath string) []string {
+	entries, err := fsutil.ReadDir(recordDirPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			LogError(ctx, errors.WithStack(err), "Failed to read records", "path", recordDirPath)
+		}
+		return nil
+	}
+	var volumeIDs []string
+	for _, entry := range entries {
+		volumeID, err := fsutil.ReadFile(path.Join(recordDirPath, entry.Name()))
+		if err != nil {
+			LogError(ctx, errors.WithStack(err), "Failed to read record", "path", recordDirPath, "name", entry.Name())
+			continue
+		}
+		volumeIDs = append(volumeIDs, string(volumeID))
+	}
+	return volumeIDs
+}
+
+// progress returns the progress of every pending deletion, sorted by start time.
+func (d *volumeDeleter) progress() []deletionProgress {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	progress := make([]deletionProgress, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		p := deletionProgress{
+			VolumeID:       getTrashedVolumeID(job.vol),
+			TrashVolumeID:  job.vol.volumeID,
+			Started:        job.started,
+			EntriesRemoved: atomic.LoadInt64(&job.entriesRemoved),
+			Failures:       job.failures,
+		}
+		if !job.retryAt.IsZero() {
+			retryAt := job.retryAt
+			p.RetryAt = &retryAt
+		}
+		job.mutex.Lock()
+		if err := job.err; err != nil {
+			p.Error = err.Error()
+		} else if err := job.lastErr; err != nil {
+			p.Error = err.Error()
+		}
+		job.mutex.Unlock()
+		progress = append(progress, p)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Started.Before(progress[j].Started) })
+	return progress
+}
+
+// setErr records err unless job has already encountered an error.
+func (job *deletionJob) setErr(err error) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if job.err == nil {
+		job.err = err
+	}
+}
+
+// getErr returns the first error the current attempt of job encountered.
+func (job *deletionJob) getErr() error {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.err
+}
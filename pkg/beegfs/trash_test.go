@@ -0,0 +1,237 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/afero"
+)
+
+func TestMoveVolumeToTrash(t *testing.T) {
+	fs = afero.NewOsFs()
+	fsutil = afero.Afero{Fs: fs}
+	vol := newBeegfsVolume(t.TempDir(), "127.0.0.1", "/scratch/pvc-1", beegfsv1.PluginConfig{})
+	if err := fs.MkdirAll(vol.volDirPath, 0750); err != nil {
+		t.Fatalf("failed to create volume directory: %v", err)
+	}
+	if err := fsutil.WriteFile(path.Join(vol.volDirPath, "file"), []byte("data"), 0640); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	trashPathBeegfsRoot, err := moveVolumeToTrash(context.Background(), vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if path.Dir(trashPathBeegfsRoot) != "/scratch/.csi/trash" {
+		t.Errorf("expected volume to be moved to /scratch/.csi/trash, got: %s", trashPathBeegfsRoot)
+	}
+	if exists, _ := fsutil.DirExists(vol.volDirPath); exists {
+		t.Errorf("expected %s to be moved", vol.volDirPath)
+	}
+	if exists, _ := fsutil.Exists(path.Join(vol.mountPath, trashPathBeegfsRoot, "file")); !exists {
+		t.Errorf("expected volume contents to be moved to %s", trashPathBeegfsRoot)
+	}
+	trashVol := newTrashVolume(vol.sysMgmtdHost, trashPathBeegfsRoot, vol.config)
+	if volumeID := getTrashedVolumeID(trashVol); volumeID != vol.volumeID {
+		t.Errorf("expected trash directory to refer to %s, got: %s", vol.volumeID, volumeID)
+	}
+
+	// A volume whose directory no longer exists has already been deleted.
+	if trashPathBeegfsRoot, err = moveVolumeToTrash(context.Background(), vol); err != nil || trashPathBeegfsRoot != "" {
+		t.Errorf("expected nothing to move, got: %q, %v", trashPathBeegfsRoot, err)
+	}
+}
+
+func TestDeleteVolumeUntilWaitContextDone(t *testing.T) {
+	fs = afero.NewMemMapFs() // Set up a new memory-mapped file system.
+	fsutil = afero.Afero{Fs: fs}
+	vol := newBeegfsVolume("mountDirPath", "sysMgmtdHost", "volDirPathBeegfsRoot", beegfsv1.PluginConfig{})
+	nodesPath := path.Join(vol.csiDirPath, "nodes")
+	if err := fs.MkdirAll(nodesPath, 0750); err != nil {
+		t.Fatal("error in setup")
+	}
+	if _, err := fs.Create(path.Join(nodesPath, "node")); err != nil {
+		t.Fatal("error in setup")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := deleteVolumeUntilWait(ctx, vol, 60); err == nil {
+		t.Fatal("expected error when context is done")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected deleteVolumeUntilWait to return when context is done, took %s", elapsed)
+	}
+	if exists, _ := fsutil.DirExists(nodesPath); !exists {
+		t.Errorf("expected %s to remain", nodesPath)
+	}
+}
+
+// newTestVolumeDeleter returns a volumeDeleter that uses a mountCache in a temporary directory and a trash directory
+// it contains with numDirs subdirectories of numFiles files each.
+func newTestVolumeDeleter(t *testing.T, numDirs, numFiles int) (*volumeDeleter, beegfsVolume) {
+	c, _ := newTestMountCache(t)
+	d := newVolumeDeleter(path.Join(t.TempDir(), deletionRecordDirName), beegfsv1.PluginConfig{}, c)
+	d.workers = 4
+	trashVol := newTrashVolume("127.0.0.1", "/scratch/.csi/trash/pvc-1.1", *beegfsv1.NewBeegfsConfig())
+	cachedVol, release, err := c.acquire(context.Background(), trashVol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+	for i := 0; i < numDirs; i++ {
+		dirPath := path.Join(cachedVol.volDirPath, fmt.Sprintf("dir%d", i), "nested")
+		if err := fs.MkdirAll(dirPath, 0750); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		for j := 0; j < numFiles; j++ {
+			if err := fsutil.WriteFile(path.Join(dirPath, fmt.Sprintf("file%d", j)), nil, 0640); err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+		}
+	}
+	return d, cachedVol
+}
+
+// waitForDeletions waits for d to finish every pending deletion.
+func waitForDeletions(t *testing.T, d *volumeDeleter) {
+	deadline := time.Now().Add(10 * time.Second)
+	for len(d.progress()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for deletions: %+v", d.progress())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVolumeDeleter(t *testing.T) {
+	const numDirs, numFiles = 10, 20
+	d, cachedVol := newTestVolumeDeleter(t, numDirs, numFiles)
+	trashVol := newTrashVolume(cachedVol.sysMgmtdHost, cachedVol.volDirPathBeegfsRoot, cachedVol.config)
+	removedBefore := testutil.ToFloat64(volumeDeletionEntriesRemovedTotal)
+	completedBefore := testutil.ToFloat64(volumeDeletionsCompletedTotal)
+
+	d.start(context.Background())
+	defer d.stop()
+	d.add(context.Background(), trashVol)
+	waitForDeletions(t, d)
+
+	if exists, _ := fsutil.Exists(cachedVol.volDirPath); exists {
+		t.Errorf("expected %s to be removed", cachedVol.volDirPath)
+	}
+	if exists, _ := fsutil.Exists(getRecordPath(d.recordDirPath, trashVol.volumeID)); exists {
+		t.Errorf("expected deletion record to be removed")
+	}
+	// Each directory contains a nested directory of files, and the trash directory itself is removed too.
+	wantRemoved := float64(numDirs*(2+numFiles) + 1)
+	if removed := testutil.ToFloat64(volumeDeletionEntriesRemovedTotal) - removedBefore; removed != wantRemoved {
+		t.Errorf("expected %v entries removed, got: %v", wantRemoved, removed)
+	}
+	if completed := testutil.ToFloat64(volumeDeletionsCompletedTotal) - completedBefore; completed != 1 {
+		t.Errorf("expected 1 completed deletion, got: %v", completed)
+	}
+	if pending := testutil.ToFloat64(volumeDeletionsPending); pending != 0 {
+		t.Errorf("expected no pending deletions, got: %v", pending)
+	}
+}
+
+func TestVolumeDeleterResume(t *testing.T) {
+	d, cachedVol := newTestVolumeDeleter(t, 3, 3)
+	// Simulate a deletion recorded by a previous instance of the controller service.
+	if err := writeRecord(d.recordDirPath, cachedVol.volumeID); err != nil {
+		t.Fatalf("failed to write deletion record: %v", err)
+	}
+
+	d.start(context.Background())
+	defer d.stop()
+	waitForDeletions(t, d)
+	if exists, _ := fsutil.Exists(cachedVol.volDirPath); exists {
+		t.Errorf("expected %s to be removed", cachedVol.volDirPath)
+	}
+	if exists, _ := fsutil.Exists(getRecordPath(d.recordDirPath, cachedVol.volumeID)); exists {
+		t.Errorf("expected deletion record to be removed")
+	}
+}
+
+func TestVolumeDeleterAddTrash(t *testing.T) {
+	d, cachedVol := newTestVolumeDeleter(t, 3, 3)
+	// Any volume in the same volDirBasePath leads to the trash directory.
+	vol := newBeegfsVolume(cachedVol.mountDirPath, cachedVol.sysMgmtdHost, "/scratch/pvc-2", beegfsv1.PluginConfig{})
+	vol.config = cachedVol.config
+
+	d.start(context.Background())
+	defer d.stop()
+	d.addTrash(context.Background(), vol)
+	waitForDeletions(t, d)
+	if exists, _ := fsutil.Exists(cachedVol.volDirPath); exists {
+		t.Errorf("expected %s to be removed", cachedVol.volDirPath)
+	}
+}
+
+func TestVolumeDeleterRetry(t *testing.T) {
+	d, cachedVol := newTestVolumeDeleter(t, 3, 3)
+	trashVol := newTrashVolume(cachedVol.sysMgmtdHost, cachedVol.volDirPathBeegfsRoot, cachedVol.config)
+	failuresBefore := testutil.ToFloat64(volumeDeletionFailuresTotal)
+
+	d.start(context.Background())
+	defer d.stop()
+	// Nothing can be removed from a read-only file system.
+	osFs := fs
+	fs = afero.NewReadOnlyFs(osFs)
+	fsutil = afero.Afero{Fs: fs}
+	d.add(context.Background(), trashVol)
+	deadline := time.Now().Add(10 * time.Second)
+	for progress := d.progress(); len(progress) != 1 || progress[0].RetryAt == nil; progress = d.progress() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for deletion to fail: %+v", progress)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if progress := d.progress()[0]; progress.Failures != 1 || progress.Error == "" {
+		t.Errorf("expected failed deletion to keep its error, got: %+v", progress)
+	}
+	if failures := testutil.ToFloat64(volumeDeletionFailuresTotal) - failuresBefore; failures != 1 {
+		t.Errorf("expected 1 failed deletion, got: %v", failures)
+	}
+	// A failed deletion is not started again until it is retried.
+	d.add(context.Background(), trashVol)
+	if progress := d.progress(); len(progress) != 1 || progress[0].RetryAt == nil {
+		t.Errorf("expected failed deletion to wait for a retry, got: %+v", progress)
+	}
+
+	fs = osFs
+	fsutil = afero.Afero{Fs: fs}
+	d.mutex.Lock()
+	job := d.jobs[trashVol.volumeID]
+	d.mutex.Unlock()
+	d.retry(context.Background(), job)
+	waitForDeletions(t, d)
+	if exists, _ := fsutil.Exists(cachedVol.volDirPath); exists {
+		t.Errorf("expected %s to be removed", cachedVol.volDirPath)
+	}
+}
+
+func TestGetDeletionRetryInterval(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:   deletionRetryInterval,
+		2:   2 * deletionRetryInterval,
+		3:   4 * deletionRetryInterval,
+		100: deletionMaxRetryInterval,
+	}
+	for failures, want := range tests {
+		if got := getDeletionRetryInterval(failures); got != want {
+			t.Errorf("expected %s after %d failures, got: %s", want, failures, got)
+		}
+	}
+}
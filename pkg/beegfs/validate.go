@@ -0,0 +1,120 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// placeholderMountDirPath is a mountDirPath used to generate (but never write) client files when no real mountDirPath
+// exists (e.g. during offline validation).
+const placeholderMountDirPath = "/placeholder-mount-dir"
+
+// ValidateConfigFiles performs offline validation of the files normally passed to the driver via the --config-path,
+// --connauth-path, --tlscerts-path, and --client-conf-template-path flags. It parses and validates the files exactly
+// as the driver does at startup, then applies the effective configuration for each file system to the template
+// beegfs-client.conf file to catch beegfsClientConf keys the template does not contain. It writes the effective
+// (squashed) configuration for each file system to w with connAuth and tlsCert values redacted. ValidateConfigFiles
+// never mounts a BeeGFS file system, runs a BeeGFS CLI, or checks for the BeeGFS client kernel module.
+//
+// Any empty path is skipped. If clientConfTemplatePath is empty, the default template locations are searched and
+// template checks are skipped if none is found. nodeID selects the applicable nodeSpecificConfigs by nodeList. If
+// nodeLabels is not nil, nodeSelector entries are evaluated against it instead of the labels of the Kubernetes Node
+// object. ValidateConfigFiles is exported for use by the validate-config subcommand in cmd/beegfs-csi-driver.
+func ValidateConfigFiles(w io.Writer, connAuthPath, tlsCertsPath, configPath, clientConfTemplatePath, nodeID string,
+	nodeLabels map[string]string) error {
+	if nodeLabels != nil {
+		// validate-config runs as a one shot command, so it is safe to replace the package level function here.
+		getNodeLabels = func(ctx context.Context, nodeName string) (map[string]string, error) {
+			return nodeLabels, nil
+		}
+	}
+
+	var err error
+	var pluginConfig beegfsv1.PluginConfig
+	if configPath != "" {
+		if pluginConfig, err = parseConfigFromFile(configPath, nodeID); err != nil {
+			return errors.WithMessage(err, "failed to handle configuration file")
+		}
+	}
+	if connAuthPath != "" {
+		if err = parseConnAuthFromFile(connAuthPath, &pluginConfig); err != nil {
+			return errors.WithMessage(err, "failed to handle connAuth file")
+		}
+	}
+	if tlsCertsPath != "" {
+		if err = parseTLSCertsFromFile(tlsCertsPath, &pluginConfig); err != nil {
+			return errors.WithMessage(err, "failed to handle tlsCerts file")
+		}
+	}
+
+	if clientConfTemplatePath == "" {
+		if clientConfTemplatePath = getDefaultClientConfTemplatePath(DefaultDriverName); clientConfTemplatePath == "" {
+			fmt.Fprintln(w, "# WARNING: no template beegfs-client.conf file found; skipping template checks")
+		}
+	}
+	if clientConfTemplatePath != "" {
+		if _, err := fsutil.ReadFile(clientConfTemplatePath); err != nil {
+			return errors.WithMessage(err, "failed to read client configuration template file")
+		}
+	}
+
+	// The default configuration applies to any file system without a fileSystemSpecificConfig. An empty sysMgmtdHost
+	// is used to represent it.
+	sysMgmtdHosts := []string{""}
+	for _, fsConfig := range pluginConfig.FileSystemSpecificConfigs {
+		sysMgmtdHosts = append(sysMgmtdHosts, fsConfig.SysMgmtdHost)
+	}
+
+	var failures []string
+	for i, sysMgmtdHost := range sysMgmtdHosts {
+		vol := newBeegfsVolume(placeholderMountDirPath, sysMgmtdHost, "/", pluginConfig)
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		var out []byte
+		if sysMgmtdHost == "" {
+			fmt.Fprintln(w, "# Effective default configuration (applies to file systems not listed below)")
+			out, err = yaml.Marshal(vol.config)
+		} else {
+			fmt.Fprintf(w, "# Effective configuration for sysMgmtdHost %s\n", sysMgmtdHost)
+			out, err = yaml.Marshal(beegfsv1.FileSystemSpecificConfig{SysMgmtdHost: sysMgmtdHost, Config: vol.config,
+				AllowUnmarkedVolumeDeletion: allowsUnmarkedVolumeDeletion(sysMgmtdHost, pluginConfig),
+				SetVolumeMetadataXAttrs:     setsVolumeMetadataXAttrs(sysMgmtdHost, pluginConfig)})
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal effective configuration")
+		}
+		if _, err = w.Write(out); err != nil {
+			return errors.Wrap(err, "failed to write effective configuration")
+		}
+
+		if clientConfTemplatePath != "" {
+			// The connClientPort is normally chosen at mount time, so any value works here.
+			if _, _, err := generateClientFiles(context.TODO(), vol, clientConfTemplatePath, "0"); err != nil {
+				name := "default configuration"
+				if sysMgmtdHost != "" {
+					name = "sysMgmtdHost " + sysMgmtdHost
+				}
+				fmt.Fprintf(w, "# ERROR: %v\n", err)
+				failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+
+	if len(failures) != 0 {
+		return errors.Errorf("configuration can not be applied to template beegfs-client.conf file %s: %s",
+			clientConfTemplatePath, strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+const TestValidateConfigFilesTemplate = `# A minimal configuration file that allows everything in testdata/basic.yaml.
+sysMgmtdHost          =
+connClientPort        =
+connMgmtdPort         = 8008
+connUseRDMA           = false
+connInterfacesFile    =
+connNetFilterFile     =
+connTcpOnlyFilterFile =
+connAuthFile          =
+`
+
+func TestValidateConfigFiles(t *testing.T) {
+	fs = afero.NewOsFs()
+	fsutil = afero.Afero{Fs: fs}
+	defer func() { getNodeLabels = getNodeLabelsFromAPIServer }()
+
+	templateDir := t.TempDir()
+	goodTemplatePath := path.Join(templateDir, "good-beegfs-client.conf")
+	if err := fsutil.WriteFile(goodTemplatePath, []byte(TestValidateConfigFilesTemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+	noRDMATemplatePath := path.Join(templateDir, "no-rdma-beegfs-client.conf")
+	noRDMATemplate := strings.Replace(TestValidateConfigFilesTemplate, "connUseRDMA           = false\n", "", 1)
+	if err := fsutil.WriteFile(noRDMATemplatePath, []byte(noRDMATemplate), 0644); err != nil {
+		t.Fatalf("failed to write template beegfs-client.conf: %v", err)
+	}
+
+	tests := map[string]struct {
+		configFile   string
+		connAuthFile string
+		templatePath string
+		nodeID       string
+		nodeLabels   map[string]string
+		wantOutput   []string // Strings expected to be in the output.
+		noOutput     []string // Strings expected NOT to be in the output.
+		errRegex     string   // A regular expression expected to be in err.Error(). Empty if validation should succeed.
+	}{
+		"basic configuration is valid": {
+			configFile:   "testdata/basic.yaml",
+			templatePath: goodTemplatePath,
+			wantOutput:   []string{"sysMgmtdHost 127.0.0.0", "connUseRDMA: \"true\""},
+		},
+		"beegfsClientConf key not in template": {
+			configFile:   "testdata/basic.yaml",
+			templatePath: noRDMATemplatePath,
+			wantOutput:   []string{"# ERROR: connUseRDMA not in template"},
+			errRegex:     "connUseRDMA not in template",
+		},
+		"connAuth is redacted": {
+			connAuthFile: "testdata/connauthfile.yaml",
+			templatePath: goodTemplatePath,
+			wantOutput:   []string{"sysMgmtdHost 127.0.0.0", "connAuth: '******'"},
+			noOutput:     []string{"secret1"},
+		},
+		"missing quotes": {
+			configFile:   "testdata/no-quotes-integer.yaml",
+			templatePath: goodTemplatePath,
+			errRegex:     "likely missing quotes",
+		},
+		"node selector evaluated against provided labels": {
+			configFile:   "testdata/node-selector-override.yaml",
+			templatePath: goodTemplatePath,
+			nodeID:       "othernode",
+			nodeLabels:   map[string]string{"example.com/rdma": "true"},
+			wantOutput:   []string{"- ib1"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := ValidateConfigFiles(&out, tc.connAuthFile, "", tc.configFile, tc.templatePath, tc.nodeID,
+				tc.nodeLabels)
+			if tc.errRegex == "" && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			} else if tc.errRegex != "" {
+				if err == nil {
+					t.Fatalf("expected error matching %s, got none", tc.errRegex)
+				} else if !regexp.MustCompile(tc.errRegex).MatchString(err.Error()) {
+					t.Fatalf("expected error matching %s, got: %v", tc.errRegex, err)
+				}
+			}
+			for _, want := range tc.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tc.noOutput {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("expected output not to contain %q, got:\n%s", notWant, out.String())
+				}
+			}
+		})
+	}
+}
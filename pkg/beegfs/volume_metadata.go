@@ -0,0 +1,305 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/rest"
+)
+
+// volumeMetadataFileName is the name of the file in a volume's CSI metadata directory
+// (volDirBasePath/.csi/volumes/<volName>) that CreateVolume records information about the volume in.
+const volumeMetadataFileName = "metadata.json"
+
+// volumeMetadata is information about a volume that is not available to later requests (e.g. the PVC it was created
+// for or the parameters of its StorageClass). CreateVolume records it in the volume's CSI metadata directory. Volumes
+// created by older versions of the driver have no volumeMetadata.
+//
+// volumeMetadata also serves as the volume's ownership marker. DeleteVolume only deletes a directory whose
+// volumeMetadata has the volume's VolumeID and a CreationToken (see ownsVolume).
+type volumeMetadata struct {
+	VolumeID string `json:"volumeID"`
+	// CreationToken is a random token CreateVolume generates when it first creates the volume.
+	CreationToken string `json:"creationToken,omitempty"`
+	// Name is the name of the CreateVolume request. It identifies the volume if its directory was not named after
+	// it (e.g. because a volDirTemplate laid it out).
+	Name string `json:"name,omitempty"`
+	// ClusterID is the --cluster-id of the controller service that created the volume.
+	ClusterID     string `json:"clusterID,omitempty"`
+	DriverName    string `json:"driverName,omitempty"`
+	DriverVersion string `json:"driverVersion,omitempty"`
+	PVCNamespace  string `json:"pvcNamespace,omitempty"`
+	PVCName       string `json:"pvcName,omitempty"`
+	PVName        string `json:"pvName,omitempty"`
+	// StorageClassName is looked up from the PersistentVolumeClaim (if the driver runs in Kubernetes).
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// CreationTime is when CreateVolume first created the volume (in RFC 3339 format).
+	CreationTime  string `json:"creationTime,omitempty"`
+	CapacityBytes int64  `json:"capacityBytes,omitempty"`
+	// RetentionPeriod is how long DeleteVolume retains the volume's directory (zero if it deletes it immediately).
+	RetentionPeriod metav1.Duration `json:"retentionPeriod,omitempty"`
+}
+
+// volumeMetadataXAttrPrefix is the prefix of the extended attributes setVolumeMetadataXAttrs sets.
+const volumeMetadataXAttrPrefix = "user.beegfs-csi."
+
+// SetClusterID makes the controller service record clusterID in the metadata of each volume it creates so BeeGFS
+// administrators can tell the volumes of different clusters apart.
+func (b *beegfs) SetClusterID(clusterID string) {
+	b.cs.clusterID = clusterID
+}
+
+// newCreationToken returns a new random volumeMetadata.CreationToken.
+func newCreationToken() string {
+	return string(uuid.NewUUID())
+}
+
+// ownsVolume reports whether metadata marks the directory of the volume with volumeID as created by the driver. A
+// volumeMetadata describing another volume (e.g. one found under a statically provisioned volumeID that points at the
+// same directory through a different path) does not mark the volume as owned.
+func (metadata volumeMetadata) ownsVolume(volumeID string) bool {
+	return metadata.VolumeID == volumeID && metadata.CreationToken != ""
+}
+
+// attributes returns the non-empty fields of metadata (except the CreationToken) keyed by their JSON names (e.g.
+// "pvcNamespace"). Administrators see these as the extended attributes of a volume's directory and in the volume
+// context of the volume's ListVolumes entry.
+func (metadata volumeMetadata) attributes() map[string]string {
+	attributes := map[string]string{
+		"volumeID":         metadata.VolumeID,
+		"name":             metadata.Name,
+		"clusterID":        metadata.ClusterID,
+		"driverName":       metadata.DriverName,
+		"driverVersion":    metadata.DriverVersion,
+		"pvcNamespace":     metadata.PVCNamespace,
+		"pvcName":          metadata.PVCName,
+		"pvName":           metadata.PVName,
+		"storageClassName": metadata.StorageClassName,
+		"creationTime":     metadata.CreationTime,
+	}
+	if metadata.CapacityBytes != 0 {
+		attributes["capacityBytes"] = strconv.FormatInt(metadata.CapacityBytes, 10)
+	}
+	if metadata.RetentionPeriod.Duration != 0 {
+		attributes["retentionPeriod"] = metadata.RetentionPeriod.Duration.String()
+	}
+	for key, value := range attributes {
+		if value == "" {
+			delete(attributes, key)
+		}
+	}
+	return attributes
+}
+
+// setVolumeMetadataXAttrs sets the attributes of metadata as user.beegfs-csi.* extended attributes of the directory of
+// vol (on a mounted file system).
+func setVolumeMetadataXAttrs(vol beegfsVolume, metadata volumeMetadata) error {
+	for key, value := range metadata.attributes() {
+		if err := unix.Setxattr(vol.volDirPath, volumeMetadataXAttrPrefix+key, []byte(value), 0); err != nil {
+			return errors.Wrapf(err, "failed to set extended attribute %s on %s (are extended attributes enabled "+
+				"on the file system?)", volumeMetadataXAttrPrefix+key, vol.volDirPathBeegfsRoot)
+		}
+	}
+	return nil
+}
+
+// lookUpStorageClassName returns the name of the StorageClass of the named PersistentVolumeClaim or an empty string
+// if it cannot be determined. The external-provisioner does not pass the StorageClass to CreateVolume, so it is only
+// known if the driver runs in Kubernetes.
+func lookUpStorageClassName(ctx context.Context, namespace, name string) string {
+	if namespace == "" || name == "" {
+		return ""
+	}
+	storageClassName, err := getPVCStorageClassName(ctx, namespace, name)
+	if errors.Is(err, rest.ErrNotInCluster) {
+		return ""
+	} else if err != nil {
+		LogError(ctx, err, "Failed to look up StorageClass", "pvcNamespace", namespace, "pvcName", name)
+		return ""
+	}
+	return storageClassName
+}
+
+// volumeLocation is a volDirBasePath on a file system volumes are created under. volDirDepth is the number of
+// directories between the volDirBasePath and a volume's directory (e.g. 1 for a volDirTemplate of
+// "${pvc.namespace}/${pvc.name}").
+type volumeLocation struct {
+	sysMgmtdHost             string
+	volDirBasePathBeegfsRoot string
+	volDirDepth              int
+}
+
+// listVolumeLocations returns the volumeLocations of the StorageClasses of the controller service's driver in a
+// stable order. StorageClasses with invalid parameters are ignored. Outside of Kubernetes (e.g. in Nomad) there are no
+// StorageClasses, so listVolumeLocations returns no volumeLocations.
+func (cs *controllerServer) listVolumeLocations(ctx context.Context) ([]volumeLocation, error) {
+	parameters, err := listStorageClassParameters(ctx, cs.driverName)
+	if errors.Is(err, rest.ErrNotInCluster) {
+		LogDebug(ctx, "Not running in Kubernetes, so there are no StorageClasses to find volumes in")
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	found := make(map[volumeLocation]bool)
+	var locations []volumeLocation
+	for _, storageClassParams := range parameters {
+		// validateReqParams modifies its argument, so give it a copy.
+		paramsCopy := make(map[string]string, len(storageClassParams))
+		for key, value := range storageClassParams {
+			paramsCopy[key] = value
+		}
+		for key := range paramsCopy {
+			if strings.HasPrefix(key, "csi.storage.k8s.io/") {
+				delete(paramsCopy, key) // The external-provisioner removes these before CreateVolume.
+			}
+		}
+		params, err := validateReqParams(paramsCopy)
+		if err != nil {
+			LogDebug(ctx, "Ignoring StorageClass with invalid parameters", "error", err.Error())
+			continue
+		}
+		location := volumeLocation{sysMgmtdHost: params.sysMgmtdHost,
+			volDirBasePathBeegfsRoot: params.volDirBasePathBeegfsRoot}
+		if params.volDirTemplate != "" {
+			location.volDirDepth = strings.Count(params.volDirTemplate, "/")
+		}
+		if !found[location] {
+			found[location] = true
+			locations = append(locations, location)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].sysMgmtdHost != locations[j].sysMgmtdHost {
+			return locations[i].sysMgmtdHost < locations[j].sysMgmtdHost
+		}
+		if locations[i].volDirBasePathBeegfsRoot != locations[j].volDirBasePathBeegfsRoot {
+			return locations[i].volDirBasePathBeegfsRoot < locations[j].volDirBasePathBeegfsRoot
+		}
+		return locations[i].volDirDepth < locations[j].volDirDepth
+	})
+	return locations, nil
+}
+
+// listVolumeMetadata returns the volumeMetadata of each volume the controller service finds in the
+// volumeLocations of its StorageClasses in the order of their volumeIDs. Only volumes with an ownership marker (see
+// ownsVolume) are listed, so volumes created by older versions of the driver and statically provisioned volumes are
+// not. A volumeLocation that can't be searched (e.g. because its file system is unreachable) is logged and skipped, so
+// it does not hide the volumes in the others. Like the controller service RPCs, listVolumeMetadata returns a gRPC
+// error.
+func (cs *controllerServer) listVolumeMetadata(ctx context.Context) ([]volumeMetadata, error) {
+	locations, err := cs.listVolumeLocations(ctx)
+	if err != nil {
+		return nil, newGrpcErrorFromCause(codes.Internal, err)
+	}
+	found := make(map[string]volumeMetadata)
+	for _, location := range locations {
+		if err := cs.findVolumeMetadata(ctx, location, found); err != nil {
+			LogError(ctx, err, "Failed to find volumes", "sysMgmtdHost", location.sysMgmtdHost,
+				"volDirBasePath", location.volDirBasePathBeegfsRoot)
+		}
+	}
+	volumes := make([]volumeMetadata, 0, len(found))
+	for _, metadata := range found {
+		volumes = append(volumes, metadata)
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].VolumeID < volumes[j].VolumeID })
+	return volumes, nil
+}
+
+// findVolumeMetadata adds the volumeMetadata of each volume in location to found (keyed by volumeID).
+func (cs *controllerServer) findVolumeMetadata(ctx context.Context, location volumeLocation,
+	found map[string]volumeMetadata) error {
+	baseVol, release, err := cs.mountCache.acquire(ctx, newBeegfsVolume("", location.sysMgmtdHost,
+		location.volDirBasePathBeegfsRoot, cs.pluginConfig))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// The metadata of a volume with the directory <parent>/<name> is in <parent>/.csi/volumes/<name>.
+	pattern := path.Join(baseVol.volDirPath, strings.Repeat("*/", location.volDirDepth), ".csi", "volumes", "*",
+		volumeMetadataFileName)
+	metadataPaths, err := afero.Glob(fs, pattern)
+	if err != nil {
+		return newGrpcErrorFromCause(codes.Internal, errors.WithStack(err))
+	}
+	for _, metadataPath := range metadataPaths {
+		csiDirPath := path.Dir(metadataPath)
+		parentPath := path.Dir(path.Dir(path.Dir(csiDirPath)))
+		volDirPathBeegfsRoot := path.Join("/", strings.TrimPrefix(parentPath, baseVol.mountPath),
+			path.Base(csiDirPath))
+		vol := newBeegfsVolume(baseVol.mountDirPath, location.sysMgmtdHost, volDirPathBeegfsRoot,
+			beegfsv1.PluginConfig{})
+		metadata, err := readVolumeMetadata(vol)
+		if err != nil {
+			LogError(ctx, err, "Failed to read volume metadata", "path", volDirPathBeegfsRoot)
+			continue
+		}
+		if metadata.ownsVolume(vol.volumeID) {
+			found[vol.volumeID] = metadata
+		}
+	}
+	return nil
+}
+
+// writeVolumeMetadata writes metadata to the CSI metadata directory of vol (on a mounted file system).
+func writeVolumeMetadata(vol beegfsVolume, metadata volumeMetadata) error {
+	if err := fs.MkdirAll(vol.csiDirPath, 0750); err != nil {
+		return errors.WithStack(err)
+	}
+	return writeJSONFile(path.Join(vol.csiDirPath, volumeMetadataFileName), metadata)
+}
+
+// readVolumeMetadata reads the volumeMetadata from the CSI metadata directory of vol (on a mounted file system). It
+// returns an error that satisfies os.IsNotExist if vol has no volumeMetadata.
+func readVolumeMetadata(vol beegfsVolume) (volumeMetadata, error) {
+	var metadata volumeMetadata
+	err := readJSONFile(path.Join(vol.csiDirPath, volumeMetadataFileName), &metadata)
+	return metadata, err
+}
+
+// writeJSONFile writes the JSON encoding of v to filePath. It writes a temporary file first and renames it so readers
+// never see a partially written file.
+func writeJSONFile(filePath string, v interface{}) error {
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tempPath := filePath + ".tmp"
+	if err := fsutil.WriteFile(tempPath, append(contents, '\n'), 0640); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := fs.Rename(tempPath, filePath); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// readJSONFile decodes the JSON file at filePath into v. It returns an error that satisfies os.IsNotExist if the file
+// does not exist.
+func readJSONFile(filePath string, v interface{}) error {
+	contents, err := fsutil.ReadFile(filePath)
+	if err != nil {
+		return err // Don't wrap, so callers can use os.IsNotExist.
+	}
+	if err := json.Unmarshal(contents, v); err != nil {
+		return errors.Wrapf(err, "failed to parse %s", filePath)
+	}
+	return nil
+}
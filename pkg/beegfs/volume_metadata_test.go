@@ -0,0 +1,205 @@
+/*
+Copyright 2026 NetApp, Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0.
+*/
+
+package beegfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	beegfsv1 "github.com/netapp/beegfs-csi-driver/operator/api/v1"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/rest"
+	"k8s.io/mount-utils"
+)
+
+// unreachableMounter fails to mount the file system at sysMgmtdHost (as if its servers were unreachable).
+type unreachableMounter struct {
+	mount.Interface
+	sysMgmtdHost string
+}
+
+func (m *unreachableMounter) Mount(source, target, fstype string, options []string) error {
+	if strings.Contains(target, "/"+m.sysMgmtdHost+"_") {
+		return errors.New("unreachable")
+	}
+	return m.Interface.Mount(source, target, fstype, options)
+}
+
+func TestCreateVolumeRecordsMetadata(t *testing.T) {
+	cs, _ := newTestRetentionControllerServer(t)
+	cs.driverName, cs.driverVersion, cs.clusterID = DefaultDriverName, "v1.2.3", "cluster-a"
+	cs.pluginConfig = beegfsv1.PluginConfig{FileSystemSpecificConfigs: []beegfsv1.FileSystemSpecificConfig{
+		{SysMgmtdHost: "127.0.0.1", SetVolumeMetadataXAttrs: true},
+	}}
+	getPVCStorageClassName = func(ctx context.Context, namespace, name string) (string, error) {
+		return "fast", nil
+	}
+	defer func() { getPVCStorageClassName = getPVCStorageClassNameFromAPIServer }()
+	ctx := context.Background()
+
+	// The fake beegfs-ctl doesn't create directories, but extended attributes can only be set on one.
+	vol, release, err := cs.mountCache.acquire(ctx, cs.newBeegfsVolume("127.0.0.1", "/scratch", "pvc-2"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer release()
+	if err := fs.MkdirAll(vol.volDirPath, 0750); err != nil {
+		t.Fatalf("failed to create volume directory: %v", err)
+	}
+	if err := unix.Setxattr(vol.volDirPath, "user.test", []byte("test"), 0); errors.Is(err, unix.ENOTSUP) {
+		t.Skip("the file system of the temporary directory does not support user extended attributes")
+	}
+
+	start := time.Now().Add(-time.Second)
+	_, err = cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: "pvc-2",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		}},
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 1 << 30},
+		Parameters: map[string]string{
+			sysMgmtdHostKey:   "127.0.0.1",
+			volDirBasePathKey: "/scratch",
+			pvcNameKey:        "data",
+			pvcNamespaceKey:   "default",
+			pvNameKey:         "pvc-2",
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	metadata, err := readVolumeMetadata(vol)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if metadata.ClusterID != "cluster-a" || metadata.DriverName != DefaultDriverName ||
+		metadata.DriverVersion != "v1.2.3" || metadata.StorageClassName != "fast" ||
+		metadata.CapacityBytes != 1<<30 {
+		t.Errorf("expected metadata to describe the cluster, driver, and StorageClass, got: %+v", metadata)
+	}
+	if creationTime, err := time.Parse(time.RFC3339, metadata.CreationTime); err != nil ||
+		creationTime.Before(start) {
+		t.Errorf("expected a current creation time, got: %s, %v", metadata.CreationTime, err)
+	}
+
+	for key, want := range map[string]string{
+		"pvcNamespace":     "default",
+		"pvcName":          "data",
+		"storageClassName": "fast",
+		"clusterID":        "cluster-a",
+		"capacityBytes":    "1073741824",
+	} {
+		value := make([]byte, 256)
+		n, err := unix.Getxattr(vol.volDirPath, volumeMetadataXAttrPrefix+key, value)
+		if err != nil || string(value[:n]) != want {
+			t.Errorf("expected extended attribute %s=%s, got: %s, %v", volumeMetadataXAttrPrefix+key, want,
+				value[:n], err)
+		}
+	}
+	if _, err := unix.Getxattr(vol.volDirPath, volumeMetadataXAttrPrefix+"creationToken", nil); err == nil {
+		t.Errorf("expected the creation token not to be set as an extended attribute")
+	}
+}
+
+func TestListVolumes(t *testing.T) {
+	cs, _ := newTestRetentionControllerServer(t) // beegfs://127.0.0.1/scratch/pvc-1
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return []map[string]string{
+			{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"},
+			{
+				sysMgmtdHostKey:   "127.0.0.1",
+				volDirBasePathKey: "/scratch",
+				volDirTemplateKey: "${pvc.namespace}/${pvc.name}",
+				"csi.storage.k8s.io/provisioner-secret-name": "secret",
+			},
+			{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch", "invalid": "parameter"},
+		}, nil
+	}
+	defer func() { listStorageClassParameters = listStorageClassParametersFromAPIServer }()
+	ctx := context.Background()
+	_, err := cs.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name: "pvc-2",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		}},
+		Parameters: map[string]string{
+			sysMgmtdHostKey:   "127.0.0.1",
+			volDirBasePathKey: "/scratch",
+			volDirTemplateKey: "${pvc.namespace}/${pvc.name}",
+			pvcNameKey:        "logs",
+			pvcNamespaceKey:   "default",
+			pvNameKey:         "pvc-2",
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// List one volume at a time.
+	wantVolumeIDs := []string{"beegfs://127.0.0.1/scratch/default/logs", "beegfs://127.0.0.1/scratch/pvc-1"}
+	wantPVCNames := []string{"logs", "data"}
+	var startingToken string
+	for i, wantVolumeID := range wantVolumeIDs {
+		resp, err := cs.ListVolumes(ctx, &csi.ListVolumesRequest{MaxEntries: 1, StartingToken: startingToken})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(resp.GetEntries()) != 1 || resp.GetEntries()[0].GetVolume().GetVolumeId() != wantVolumeID {
+			t.Fatalf("expected volume %s, got: %+v", wantVolumeID, resp.GetEntries())
+		}
+		if pvcName := resp.GetEntries()[0].GetVolume().GetVolumeContext()["pvcName"]; pvcName != wantPVCNames[i] {
+			t.Errorf("expected volume context to contain pvcName %s, got: %s", wantPVCNames[i], pvcName)
+		}
+		startingToken = resp.GetNextToken()
+	}
+	if startingToken != "" {
+		t.Errorf("expected no next token after the last volume, got: %s", startingToken)
+	}
+
+	if _, err := cs.ListVolumes(ctx, &csi.ListVolumesRequest{StartingToken: "invalid"}); status.Code(err) !=
+		codes.Aborted {
+		t.Errorf("expected Aborted for an invalid starting token, got: %v", err)
+	}
+
+	// A file system that can't be mounted does not hide the volumes on the others.
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return []map[string]string{
+			{sysMgmtdHostKey: "127.0.0.1", volDirBasePathKey: "/scratch"},
+			{sysMgmtdHostKey: "127.0.0.2", volDirBasePathKey: "/scratch"},
+		}, nil
+	}
+	cs.mountCache.mounter = &unreachableMounter{Interface: cs.mountCache.mounter, sysMgmtdHost: "127.0.0.2"}
+	resp, err := cs.ListVolumes(ctx, &csi.ListVolumesRequest{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(resp.GetEntries()) != 1 || resp.GetEntries()[0].GetVolume().GetVolumeId() != wantVolumeIDs[1] {
+		t.Errorf("expected volume %s, got: %+v", wantVolumeIDs[1], resp.GetEntries())
+	}
+
+	// Outside of Kubernetes there are no StorageClasses to find volumes in.
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return nil, fmt.Errorf("failed to load in-cluster Kubernetes configuration: %w", rest.ErrNotInCluster)
+	}
+	if resp, err = cs.ListVolumes(ctx, &csi.ListVolumesRequest{}); err != nil || len(resp.GetEntries()) != 0 {
+		t.Errorf("expected no volumes and no error outside of Kubernetes, got: %+v, %v", resp.GetEntries(), err)
+	}
+	listStorageClassParameters = func(ctx context.Context, driverName string) ([]map[string]string, error) {
+		return nil, errors.New("forbidden")
+	}
+	if _, err = cs.ListVolumes(ctx, &csi.ListVolumesRequest{}); status.Code(err) != codes.Internal {
+		t.Errorf("expected Internal if StorageClasses can't be listed, got: %v", err)
+	}
+}